@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/spf13/cobra"
+
+	"github.com/cloudflare/tf-migrate/internal/address"
+	"github.com/cloudflare/tf-migrate/internal/handlers"
+	"github.com/cloudflare/tf-migrate/internal/inventory"
+	"github.com/cloudflare/tf-migrate/internal/transform"
+)
+
+// Output formats of the inventory command
+const (
+	inventoryFormatTable = "table"
+	inventoryFormatJSON  = "json"
+)
+
+func newInventoryCommand(log hclog.Logger, cfg *config) *cobra.Command {
+	var format string
+
+	cmd := &cobra.Command{
+		Use:   "inventory",
+		Short: "List the Cloudflare resources and data sources to migrate, without changing any file",
+		Long: `Scan --config-dir recursively and the --state-file, and list every Cloudflare resource
+and data source type found, with its counts per module, whether a migrator is registered
+for the version path, and the constructs that will need manual work.`,
+		Example: `  # Inventory the current directory
+  tf-migrate inventory
+
+  # Include the state file and print JSON for further processing
+  tf-migrate --config-dir ./terraform --state-file terraform.tfstate inventory --format json`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			*cfg = projectConfig(cmd, *cfg)
+			if cfg.configDir == "" {
+				cfg.configDir = "."
+			}
+			if cfg.sourceVersion == "" {
+				cfg.sourceVersion = "v4"
+			}
+			if cfg.targetVersion == "" {
+				cfg.targetVersion = "v5"
+			}
+			if format != inventoryFormatTable && format != inventoryFormatJSON {
+				return fmt.Errorf("invalid --format %q: must be %q or %q", format, inventoryFormatTable, inventoryFormatJSON)
+			}
+			if err := validateSingleHop(*cfg); err != nil {
+				return err
+			}
+
+			inv, err := runInventory(log, *cfg)
+			if err != nil {
+				return err
+			}
+			if format == inventoryFormatJSON {
+				return inv.WriteJSON(os.Stdout)
+			}
+			return inv.WriteTable(os.Stdout)
+		},
+	}
+
+	cmd.Flags().StringVar(&format, "format", inventoryFormatTable, "Output format: 'table' or 'json'")
+
+	return cmd
+}
+
+// runInventory scans the configuration and state files without modifying them
+func runInventory(log hclog.Logger, cfg config) (*inventory.Inventory, error) {
+	providers := getProviders(cfg)
+	inv := inventory.New(cfg.sourceVersion, cfg.targetVersion, providers)
+
+	files, err := findTerraformFilesWithRecursion(cfg.configDir, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list .tf files: %w", err)
+	}
+
+	modules := address.DiscoverModules(cfg.configDir)
+	updates := collectReferenceUpdates(log, cfg)
+	parse := handlers.NewParseHandler(log)
+
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		relPath, err := filepath.Rel(cfg.configDir, file)
+		if err != nil {
+			relPath = file
+		}
+		relPath = filepath.ToSlash(relPath)
+
+		ctx := &transform.Context{
+			Content:     content,
+			Filename:    filepath.Base(file),
+			Diagnostics: make(hcl.Diagnostics, 0),
+		}
+		if _, err := parse.Handle(ctx); err != nil {
+			// Files that cannot be parsed cannot be migrated either
+			inv.AddDiagnostics(relPath, ctx.Diagnostics)
+			continue
+		}
+
+		modulePaths, ok := modules.For(filepath.Dir(file))
+		if !ok {
+			// Directories that are not called from the root module are listed by directory
+			modulePaths = []string{"./" + filepath.ToSlash(filepath.Dir(relPath))}
+		}
+		inv.AddConfigFile(relPath, modulePaths, ctx.CFGFile)
+
+		// References to removed attributes have to be fixed by hand; the rewritten content is discarded
+		if _, _, removed := updateReferences(log, cfg, relPath, content, updates, nil); len(removed) > 0 {
+			inv.AddDiagnostics(relPath, removed)
+		}
+	}
+
+	if cfg.stateFile != "" {
+		content, err := os.ReadFile(cfg.stateFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read state file: %w", err)
+		}
+		if err := inv.AddState(string(content)); err != nil {
+			return nil, fmt.Errorf("failed to read state file %s: %w", cfg.stateFile, err)
+		}
+	}
+
+	return inv, nil
+}
@@ -1,43 +1,99 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/cloudflare/cloudflare-go/v6"
 	"github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hclwrite"
 	"github.com/spf13/cobra"
+	"github.com/zclconf/go-cty/cty"
 
 	"github.com/cloudflare/tf-migrate/internal"
+	"github.com/cloudflare/tf-migrate/internal/address"
+	"github.com/cloudflare/tf-migrate/internal/backup"
+	"github.com/cloudflare/tf-migrate/internal/diff"
 	"github.com/cloudflare/tf-migrate/internal/logger"
+	"github.com/cloudflare/tf-migrate/internal/moved"
 	"github.com/cloudflare/tf-migrate/internal/pipeline"
+	"github.com/cloudflare/tf-migrate/internal/plugin"
+	"github.com/cloudflare/tf-migrate/internal/project"
 	"github.com/cloudflare/tf-migrate/internal/registry"
+	"github.com/cloudflare/tf-migrate/internal/report"
+	"github.com/cloudflare/tf-migrate/internal/schema"
+	"github.com/cloudflare/tf-migrate/internal/staging"
 	"github.com/cloudflare/tf-migrate/internal/transform"
+	tfhcl "github.com/cloudflare/tf-migrate/internal/transform/hcl"
 )
 
 type config struct {
+	// Project file given with --config, and the file loaded for the run
+	projectFile string
+	project     *project.File
+
 	// Input paths
 	configDir string
 	stateFile string
+	// State in an S3-compatible bucket given with --state-backend, and its backend settings
+	stateBackend  string
+	backendConfig string
 
 	// Output paths
 	outputDir   string
 	outputState string
+	diffOutput  string
+	reportFile  string
+	schemaFile  string
 
 	// Migration options
 	resourcesToMigrate []string
+	excludeResources   []string
 	sourceVersion      string
 	targetVersion      string
 	dryRun             bool
 	backup             bool
+	continueOnError    bool
 	recursive          bool
+	renameStrategy     string
+	terraformVersion   string
+	parallelism        int
 	logLevel           string
+	// Fail the run, once finished, when references to removed attributes remain
+	failOnRemovedReferences bool
+	// Options of individual migrators, set in the project file
+	migratorOptions map[string]map[string]cty.Value
+	// Directory of the plugin executables, and the plugins started from it for the run
+	pluginsDir string
+	plugins    []*plugin.Plugin
+	// Directory of rule files, loaded in addition to the rules embedded in the binary
+	rulesDir string
+	// Hops of a migration across several provider versions, set by runMigration
+	hops []transform.Hop
+	// beforeCommit runs once every output is staged, before any file is written. When it
+	// fails the run fails with no file changed. It is not called in dry-run mode.
+	beforeCommit func(run *migrationRun) error
 }
 
+// version is the tf-migrate version, recorded in backup manifests
+const version = "0.1.0"
+
+// Rename strategies for resources whose type changes between versions
+const (
+	// renameStrategyState rewrites the resource type in the state file
+	renameStrategyState = "state"
+	// renameStrategyMoved leaves the state untouched and writes moved blocks instead
+	renameStrategyMoved = "moved"
+)
+
 var (
 	rootCmd = &cobra.Command{
 		Use:   "tf-migrate",
@@ -50,7 +106,13 @@ This tool provides automated transformations for:
 - Attribute migrations
 - State file updates
 - Import generation for new resources
-- Moved blocks for resource renames`,
+- Moved blocks for resource renames
+
+Options can also be set in a project file, .tf-migrate.hcl in the working directory or
+the file given with --config. Flags given on the command line take precedence over the
+settings of a directory block, which take precedence over the top-level settings of the
+file, which take precedence over the flag defaults. When the file has directory blocks
+and --config-dir is not given, migrate runs once for every directory block.`,
 		Example: `  # Migrate all .tf files in current directory
   tf-migrate migrate
 
@@ -64,33 +126,60 @@ This tool provides automated transformations for:
   tf-migrate --dry-run migrate
 
   # Run with debug logging
-  tf-migrate --log-level debug migrate`,
+  tf-migrate --log-level debug migrate
+
+  # Run the migrations described in a project file
+  tf-migrate --config ci/.tf-migrate.hcl migrate`,
 	}
 )
 
-var validVersionPath = map[string]struct{}{
-	"v4-v5": {},
-}
-
 func main() {
 	// Register all resource migrations
 	registry.RegisterAllMigrations()
 
 	cfg := &config{}
+	rootCmd.PersistentFlags().StringVar(&cfg.projectFile, "config", "", "Project file with migration settings (default: "+project.FileName+" in the working directory, if present)")
 	rootCmd.PersistentFlags().StringVar(&cfg.configDir, "config-dir", "", "Directory containing Terraform configuration files")
 	rootCmd.PersistentFlags().StringVar(&cfg.stateFile, "state-file", "", "Path to Terraform state file")
 	rootCmd.PersistentFlags().StringSliceVar(&cfg.resourcesToMigrate, "resources", []string{}, "Comma-separated list of resources to migrate (empty = all)")
+	rootCmd.PersistentFlags().StringSliceVar(&cfg.excludeResources, "exclude-resources", []string{}, "Comma-separated list of resources not to migrate")
 	rootCmd.PersistentFlags().BoolVar(&cfg.dryRun, "dry-run", false, "Perform a dry run without making changes")
 	rootCmd.PersistentFlags().StringVar(&cfg.sourceVersion, "source-version", "", "Source provider version (e.g., v4, v5)")
 	rootCmd.PersistentFlags().StringVar(&cfg.targetVersion, "target-version", "", "Target provider version (e.g., v5, v6)")
+	rootCmd.PersistentFlags().StringVar(&cfg.rulesDir, "rules-dir", "", "Directory of migration rule files, which take precedence over the built-in migrators of the same resource types")
+	rootCmd.PersistentFlags().StringVar(&cfg.pluginsDir, "plugins-dir", "", "Directory of migrator plugin executables, which take precedence over the built-in migrators")
 
 	rootCmd.PersistentFlags().StringVarP(&cfg.logLevel, "log-level", "l", "warn", "Set log level (debug, info, warn, error, off)")
 
 	// Create logger instance
 	log := logger.New(cfg.logLevel)
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		if cmd.Name() == "version" {
+			return nil
+		}
+		if err := loadProject(cmd, cfg); err != nil {
+			return err
+		}
+		if err := loadRules(log, cmd, cfg); err != nil {
+			return err
+		}
+		if err := startPlugins(log, cmd, cfg); err != nil {
+			return err
+		}
+		return checkMigratorOptions(cmd, *cfg)
+	}
 	rootCmd.AddCommand(newMigrateCommand(log, cfg))
+	rootCmd.AddCommand(newInventoryCommand(log, cfg))
+	rootCmd.AddCommand(newValidateCommand(log, cfg))
+	rootCmd.AddCommand(newVerifyCommand(log, cfg))
+	rootCmd.AddCommand(newRollbackCommand(cfg))
+	rootCmd.AddCommand(newSchemaDiffCommand(cfg))
 	rootCmd.AddCommand(newVersionCommand())
-	if err := rootCmd.Execute(); err != nil {
+	err := rootCmd.Execute()
+	if closeErr := plugin.CloseAll(cfg.plugins); closeErr != nil {
+		log.Warn("Failed to close plugins", "error", closeErr)
+	}
+	if err != nil {
 		os.Exit(1)
 	}
 }
@@ -116,52 +205,114 @@ Uses the global flags --config-dir, --state-file, and --resources to determine w
   # Dry run to preview changes
   tf-migrate --dry-run migrate
 
+  # Dry run and save the combined patch for review
+  tf-migrate --dry-run migrate --diff-output migration.patch
+
+  # Write a machine-readable report of everything that was migrated
+  tf-migrate migrate --report report.json
+
+  # Write moved blocks for renamed resources instead of editing the state
+  tf-migrate --state-file terraform.tfstate migrate --rename-strategy moved
+
+  # Migrate the state stored in R2, with the settings of the s3 backend
+  tf-migrate migrate --state-backend s3://tf-state/prod/terraform.tfstate --backend-config backend.hcl
+
+  # Write the files that migrate even if others fail
+  tf-migrate migrate --continue-on-error
+
+  # Check the migrated files against the v5 provider schema
+  tf-migrate --state-file terraform.tfstate migrate --schema cloudflare-v5-schema.json
+
   # Run with debug logging
-  tf-migrate --log-level debug migrate`,
-		RunE: func(cmd *cobra.Command, args []string) error {
-			if cfg.configDir == "" {
-				cfg.configDir = "."
-			}
-			if cfg.sourceVersion == "" {
-				cfg.sourceVersion = "v4"
-			}
-			if cfg.targetVersion == "" {
-				cfg.targetVersion = "v5"
-			}
+  tf-migrate --log-level debug migrate
 
+  # Migrate every directory block of .tf-migrate.hcl in the working directory
+  tf-migrate migrate`,
+		RunE: func(cmd *cobra.Command, args []string) error {
 			fmt.Println("Cloudflare Terraform Provider Migration Tool")
 			fmt.Println("============================================")
-			fmt.Println()
-
-			fmt.Printf("Configuration directory: %s\n", cfg.configDir)
-			if cfg.outputDir != "" {
-				fmt.Printf("Output directory: %s\n", cfg.outputDir)
-			} else {
-				fmt.Println("Output directory: in-place")
-			}
 
-			if cfg.dryRun {
-				fmt.Println("\n DRY RUN MODE - No changes will be made")
+			configs := projectConfigs(cmd, *cfg, cfg.project)
+			for _, c := range configs {
+				fmt.Println()
+				if err := migrateDirectory(log, c); err != nil {
+					if len(configs) > 1 {
+						return fmt.Errorf("%s: %w", c.configDir, err)
+					}
+					return err
+				}
 			}
-
-			return runMigration(log, *cfg)
+			return nil
 		},
 	}
 
 	cmd.Flags().StringVar(&cfg.outputDir, "output-dir", "", "Output directory for migrated configuration files (default: in-place)")
 	cmd.Flags().StringVar(&cfg.outputState, "output-state", "", "Output path for migrated state file (default: in-place)")
-	cmd.Flags().BoolVar(&cfg.backup, "backup", true, "Create backup of original files before migration")
+	cmd.Flags().StringVar(&cfg.stateBackend, "state-backend", "", "Migrate the state stored in an S3-compatible bucket, such as s3://bucket/path/terraform.tfstate, instead of --state-file")
+	cmd.Flags().StringVar(&cfg.backendConfig, "backend-config", "", "File with s3 backend settings such as endpoint and region, in the format of 'terraform init -backend-config'")
+	cmd.Flags().BoolVar(&cfg.backup, "backup", true, "Back up files changed in place to .tf-migrate/backups in the config directory, for 'tf-migrate rollback'")
+	cmd.Flags().BoolVar(&cfg.continueOnError, "continue-on-error", false, "Write every file that migrates successfully and print the status of each file, instead of changing no file when any fails")
+	cmd.Flags().BoolVar(&cfg.failOnRemovedReferences, "fail-on-removed-references", false, "Exit with an error when references to removed attributes must be updated by hand, after the migration is written")
 	cmd.Flags().BoolVar(&cfg.recursive, "recursive", false, "Recursively process subdirectories (useful for module structures)")
+	cmd.Flags().StringVar(&cfg.diffOutput, "diff-output", "", "Write the combined dry-run patch to this file (requires --dry-run)")
+	cmd.Flags().StringVar(&cfg.reportFile, "report", "", "Write a JSON report of all migrated files and resources to this file")
+	cmd.Flags().StringVar(&cfg.terraformVersion, "terraform-version", "", "Set terraform_version in the migrated state file (default: keep the existing value)")
+	cmd.Flags().StringVar(&cfg.schemaFile, "schema", "", "Validate the migrated files against this provider schema from 'terraform providers schema -json'")
+	cmd.Flags().IntVar(&cfg.parallelism, "parallelism", runtime.NumCPU(), "Number of configuration files transformed concurrently")
+	cmd.Flags().StringVar(&cfg.renameStrategy, "rename-strategy", renameStrategyState, "How renamed resource types are migrated: 'state' rewrites the state file, 'moved' writes moved blocks and leaves the state untouched")
 
 	return cmd
 }
 
+// migrateDirectory checks the options of a migration and runs it
+func migrateDirectory(log hclog.Logger, cfg config) error {
+	if cfg.configDir == "" {
+		cfg.configDir = "."
+	}
+	if cfg.sourceVersion == "" {
+		cfg.sourceVersion = "v4"
+	}
+	if cfg.targetVersion == "" {
+		cfg.targetVersion = "v5"
+	}
+
+	fmt.Printf("Configuration directory: %s\n", cfg.configDir)
+	if cfg.outputDir != "" {
+		fmt.Printf("Output directory: %s\n", cfg.outputDir)
+	} else {
+		fmt.Println("Output directory: in-place")
+	}
+
+	if cfg.dryRun {
+		fmt.Println("\n DRY RUN MODE - No changes will be made")
+	} else if cfg.diffOutput != "" {
+		return fmt.Errorf("--diff-output can only be used with --dry-run")
+	}
+	if cfg.parallelism < 1 {
+		return fmt.Errorf("invalid --parallelism %d: must be at least 1", cfg.parallelism)
+	}
+	if cfg.renameStrategy != renameStrategyState && cfg.renameStrategy != renameStrategyMoved {
+		return fmt.Errorf("invalid --rename-strategy %q: must be %q or %q", cfg.renameStrategy, renameStrategyState, renameStrategyMoved)
+	}
+
+	if cfg.stateBackend != "" {
+		if cfg.stateFile != "" || cfg.outputState != "" {
+			return fmt.Errorf("--state-backend cannot be used with --state-file or --output-state")
+		}
+		return migrateRemoteState(log, cfg)
+	}
+	if cfg.backendConfig != "" {
+		return fmt.Errorf("--backend-config can only be used with --state-backend")
+	}
+	return runMigration(log, cfg)
+}
+
 func newVersionCommand() *cobra.Command {
 	return &cobra.Command{
 		Use:   "version",
 		Short: "Print version information",
 		Run: func(cmd *cobra.Command, args []string) {
-			fmt.Println("tf-migrate version 0.1.0")
+			fmt.Printf("tf-migrate version %s\n", version)
 		},
 	}
 }
@@ -190,12 +341,239 @@ func initAPIClient() *cloudflare.Client {
 	return nil
 }
 
+// migrationRun collects the outputs of a single migration run
+type migrationRun struct {
+	patch  *diff.Patch    // set in dry-run mode
+	report *report.Report // set when --report is given
+	// moves holds the moved blocks to write per output directory, set with --rename-strategy=moved
+	moves map[string][]moved.Move
+	// renames holds the resource type renames of each hop
+	renames []map[string]string
+	// validator checks the migrated files, set with --schema
+	validator  *schema.Validator
+	validation hcl.Diagnostics
+	// backups keeps the original content of files changed in place, set with --backup
+	backups *backup.Run
+	// staged holds every output until the whole run succeeds, unset with --continue-on-error
+	staged *staging.Transaction
+	// backupPaths are the staged files to back up when they are written
+	backupPaths map[string]bool
+	// results holds the outcome of every file, printed with --continue-on-error
+	results []fileResult
+	// removedReferences counts the references to removed attributes left to update by hand
+	removedReferences int
+}
+
+// fileResult is the outcome of migrating a single file
+type fileResult struct {
+	path string
+	err  error
+}
+
+// record adds the outcome of a file to the run
+func (r *migrationRun) record(path string, err error) {
+	r.results = append(r.results, fileResult{path: path, err: err})
+}
+
+// write writes an output file, or stages it when the run is transactional. The content
+// is checked first, so a file that would not parse is never written. With backup, the
+// original content of the file is backed up before it is overwritten.
+func (r *migrationRun) write(path string, content []byte, backup bool) error {
+	if err := checkOutput(path, content); err != nil {
+		return err
+	}
+	if r.staged != nil {
+		if backup {
+			abs, err := filepath.Abs(path)
+			if err != nil {
+				return err
+			}
+			r.backupPaths[abs] = true
+		}
+		return r.staged.Write(path, content)
+	}
+
+	if backup && r.backups != nil {
+		if err := r.backups.AddFile(path); err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// readFile reads an output file, including its staged content
+func (r *migrationRun) readFile(path string) ([]byte, error) {
+	if r.staged != nil {
+		return r.staged.ReadFile(path)
+	}
+	return os.ReadFile(path)
+}
+
+// commit replaces the original files with the staged outputs, backing them up first
+func (r *migrationRun) commit() error {
+	paths := r.staged.Paths()
+	if len(paths) == 0 {
+		return nil
+	}
+	err := r.staged.Commit(func(path string) error {
+		if r.backups != nil && r.backupPaths[path] {
+			return r.backups.AddFile(path)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write migrated files: %w", err)
+	}
+	fmt.Printf("\n✓ Wrote %d migrated files\n", len(paths))
+	return nil
+}
+
+// printResults prints the status of every file of the run
+func (r *migrationRun) printResults(cfg config) {
+	if len(r.results) == 0 {
+		return
+	}
+	failed := 0
+	fmt.Println("\nMigration summary:")
+	for _, result := range r.results {
+		path := relativePath(cfg.configDir, result.path)
+		if result.err != nil {
+			failed++
+			fmt.Printf("  ✗ %s: %v\n", path, result.err)
+			continue
+		}
+		fmt.Printf("  ✓ %s\n", path)
+	}
+	fmt.Printf("%d files migrated, %d failed\n", len(r.results)-failed, failed)
+}
+
+// checkOutput checks that a migrated file can be parsed: configuration files as HCL and
+// state files as JSON
+func checkOutput(path string, content []byte) error {
+	if strings.HasSuffix(path, ".tf") {
+		if _, diags := hclwrite.ParseConfig(content, path, hcl.InitialPos); diags.HasErrors() {
+			return fmt.Errorf("migrated %s is not valid HCL: %w", path, diags)
+		}
+		return nil
+	}
+	if !json.Valid(content) {
+		return fmt.Errorf("migrated %s is not valid JSON", path)
+	}
+	return nil
+}
+
+// validateConfig checks a migrated configuration file against the provider schema, if one was given
+func (r *migrationRun) validateConfig(cfg config, outputPath string, content []byte) {
+	if r.validator == nil {
+		return
+	}
+	diags := r.validator.ValidateConfig(relativePath(cfg.outputDir, outputPath), content)
+	r.validation = append(r.validation, diags...)
+	if r.report != nil && len(diags) > 0 {
+		r.report.AddDiagnostics(relativePath(cfg.outputDir, outputPath), diags)
+	}
+}
+
+// validateState checks a migrated state file against the provider schema, if one was given
+func (r *migrationRun) validateState(path string, content []byte) {
+	if r.validator == nil {
+		return
+	}
+	diags := r.validator.ValidateState(path, content)
+	r.validation = append(r.validation, diags...)
+	if r.report != nil && len(diags) > 0 {
+		r.report.AddDiagnostics(path, diags)
+	}
+}
+
 // runMigration performs the actual migration using the pipeline
-func runMigration(log hclog.Logger, cfg config) error {
-	err := validateVersions(cfg)
+func runMigration(log hclog.Logger, cfg config) (err error) {
+	path, err := versionPath(cfg)
 	if err != nil {
 		return err
 	}
+	if len(path) > 2 {
+		cfg.hops = migrationHops(log, cfg, path)
+		fmt.Printf("Migrating through %s\n", strings.Join(path, " → "))
+	}
+
+	if cfg.outputDir == "" {
+		cfg.outputDir = cfg.configDir
+	}
+
+	run := &migrationRun{}
+	// Deferred first so it runs last, once the files and the report are written
+	defer func() {
+		if err == nil && cfg.failOnRemovedReferences && run.removedReferences > 0 {
+			err = fmt.Errorf("%d references to removed attributes must be updated manually", run.removedReferences)
+		}
+	}()
+	if cfg.schemaFile != "" {
+		// Load the schema first, so a bad schema file fails before anything is written
+		run.validator, err = loadValidator(cfg.schemaFile)
+		if err != nil {
+			return err
+		}
+	}
+	if cfg.renameStrategy == renameStrategyMoved {
+		run.moves = make(map[string][]moved.Move)
+		for _, hopCfg := range cfg.hopConfigs() {
+			run.renames = append(run.renames, collectResourceRenames(log, hopCfg))
+		}
+	}
+	// In dry-run mode the transformed output is collected into a patch instead of being written
+	if cfg.dryRun {
+		run.patch = diff.NewPatch()
+	}
+	if cfg.backup && !cfg.dryRun {
+		run.backups = backup.NewRun(cfg.configDir, version, cfg.sourceVersion, cfg.targetVersion, time.Now())
+		// Write the manifest even when the migration fails, so a partial run can be rolled back
+		defer func() {
+			manifest, finishErr := run.backups.Finish()
+			if finishErr != nil {
+				err = errors.Join(err, finishErr)
+				return
+			}
+			if manifest != nil {
+				fmt.Printf("✓ Backed up %d files to %s (undo with 'tf-migrate --config-dir %s rollback --run %s')\n",
+					len(manifest.Files), run.backups.Dir(), cfg.configDir, manifest.ID)
+			}
+		}()
+	}
+	if !cfg.dryRun && !cfg.continueOnError {
+		run.staged, err = staging.New()
+		if err != nil {
+			return err
+		}
+		run.backupPaths = make(map[string]bool)
+		// Nothing is written unless the whole run succeeds
+		defer func() {
+			err = errors.Join(err, run.staged.Discard())
+			if err != nil {
+				fmt.Println("\n✗ Migration failed, no files were changed")
+			}
+		}()
+	}
+	if cfg.reportFile != "" {
+		run.report = report.New(cfg.sourceVersion, cfg.targetVersion)
+		if cfg.hops != nil {
+			run.report.VersionPath = path
+		}
+		// Write the report even when the migration fails, so failures can be tracked
+		defer func() {
+			if writeErr := run.report.WriteFile(cfg.reportFile); writeErr != nil {
+				err = errors.Join(err, writeErr)
+				return
+			}
+			fmt.Printf("✓ Wrote migration report to %s\n", cfg.reportFile)
+		}()
+	}
 
 	// Load state file first if present (needed for cross-referencing in config transformations)
 	var stateJSON string
@@ -211,29 +589,154 @@ func runMigration(log hclog.Logger, cfg config) error {
 	// Initialize API client if credentials are available
 	apiClient := initAPIClient()
 
-	providers := getProviders(cfg.resourcesToMigrate...)
+	// With --continue-on-error, failures are collected and the run goes on
+	var failures []error
+	fail := func(err error) error {
+		if !cfg.continueOnError {
+			return err
+		}
+		failures = append(failures, err)
+		return nil
+	}
+
+	providers := getProviders(cfg)
 	configPipeline := pipeline.BuildConfigPipeline(log, providers)
 	parsedConfigs := make(map[string]*hclwrite.File)
 	if cfg.configDir != "" {
-		parsedConfigs, err = processConfigFiles(log, configPipeline, cfg, stateJSON, apiClient)
+		parsedConfigs, err = processConfigFiles(log, configPipeline, cfg, stateJSON, apiClient, run)
 		if err != nil {
-			return fmt.Errorf("failed to process configuration files: %w", err)
+			if err := fail(fmt.Errorf("failed to process configuration files: %w", err)); err != nil {
+				return err
+			}
 		}
 	}
 	log.Debug("Finished processing configuration files")
 
 	statePipeline := pipeline.BuildStatePipeline(log, providers)
 	if cfg.stateFile != "" {
-		if err := processStateFile(log, statePipeline, cfg, apiClient, parsedConfigs); err != nil {
-			return fmt.Errorf("failed to process state file: %w", err)
+		if err := processStateFile(log, statePipeline, cfg, apiClient, parsedConfigs, run); err != nil {
+			if err := fail(fmt.Errorf("failed to process state file: %w", err)); err != nil {
+				return err
+			}
 		}
 	}
 	log.Debug("Finished processing state file")
 
+	if run.moves != nil {
+		if err := writeMovedBlocks(log, cfg, run); err != nil {
+			if err := fail(fmt.Errorf("failed to write moved blocks: %w", err)); err != nil {
+				return err
+			}
+		}
+	}
+
+	if run.patch != nil {
+		if err := writePatch(cfg, run.patch); err != nil {
+			return err
+		}
+	}
+
+	if run.validator != nil {
+		// Outputs that fail validation are not written in a transactional run
+		if err := reportValidation(run.validation); err != nil {
+			if err := fail(err); err != nil {
+				return err
+			}
+		}
+	}
+
+	if cfg.beforeCommit != nil && !cfg.dryRun {
+		if err := cfg.beforeCommit(run); err != nil {
+			if err := fail(err); err != nil {
+				return err
+			}
+		}
+	}
+	if run.staged != nil {
+		return run.commit()
+	}
+	if cfg.continueOnError && !cfg.dryRun {
+		run.printResults(cfg)
+	}
+	return errors.Join(failures...)
+}
+
+// stateDiffPath returns the path of the JSON state diff written next to the --diff-output patch
+func stateDiffPath(diffOutput string) string {
+	return strings.TrimSuffix(diffOutput, filepath.Ext(diffOutput)) + ".state.json"
+}
+
+// writePatch prints the dry-run patch and optionally saves it to --diff-output, with the
+// structural state diff as JSON next to it
+func writePatch(cfg config, patch *diff.Patch) error {
+	if patch.IsEmpty() {
+		fmt.Println("\nNo changes would be made")
+		return nil
+	}
+
+	fmt.Println("\nPlanned changes:")
+	fmt.Println()
+	fmt.Print(patch.String())
+
+	if cfg.diffOutput != "" {
+		if err := os.WriteFile(cfg.diffOutput, []byte(patch.String()), 0644); err != nil {
+			return fmt.Errorf("failed to write diff output %s: %w", cfg.diffOutput, err)
+		}
+		fmt.Printf("\n✓ Wrote patch to %s\n", cfg.diffOutput)
+
+		stateJSON, err := patch.StateJSON()
+		if err != nil {
+			return fmt.Errorf("failed to encode the state diff: %w", err)
+		}
+		if stateJSON != nil {
+			path := stateDiffPath(cfg.diffOutput)
+			if err := os.WriteFile(path, append(stateJSON, '\n'), 0644); err != nil {
+				return fmt.Errorf("failed to write state diff %s: %w", path, err)
+			}
+			fmt.Printf("✓ Wrote state diff to %s\n", path)
+		}
+	}
+
 	return nil
 }
 
-func processConfigFiles(log hclog.Logger, p *pipeline.Pipeline, cfg config, stateJSON string, apiClient *cloudflare.Client) (map[string]*hclwrite.File, error) {
+// writeMovedBlocks writes the moved blocks collected during the run, one file per directory
+func writeMovedBlocks(log hclog.Logger, cfg config, run *migrationRun) error {
+	dirs := make([]string, 0, len(run.moves))
+	for dir, moves := range run.moves {
+		if len(moves) > 0 {
+			dirs = append(dirs, dir)
+		}
+	}
+	sort.Strings(dirs)
+
+	for _, dir := range dirs {
+		path := filepath.Join(dir, moved.FileName)
+		content := moved.Render(run.moves[dir])
+
+		if cfg.dryRun {
+			existing, _ := os.ReadFile(path)
+			relPath, err := filepath.Rel(cfg.outputDir, path)
+			if err != nil {
+				relPath = path
+			}
+			run.patch.AddFile(filepath.ToSlash(relPath), existing, content)
+			continue
+		}
+
+		err := run.write(path, content, cfg.outputDir == cfg.configDir)
+		run.record(path, err)
+		if err != nil {
+			return err
+		}
+		log.Debug("Wrote moved blocks", "file", path, "count", len(run.moves[dir]))
+		fmt.Printf("✓ Wrote moved blocks to %s\n", path)
+	}
+
+	return nil
+}
+
+func processConfigFiles(log hclog.Logger, p *pipeline.Pipeline, cfg config, stateJSON string, apiClient *cloudflare.Client, run *migrationRun) (map[string]*hclwrite.File, error) {
 	if cfg.outputDir == "" {
 		cfg.outputDir = cfg.configDir
 	}
@@ -250,28 +753,28 @@ func processConfigFiles(log hclog.Logger, p *pipeline.Pipeline, cfg config, stat
 
 	fmt.Printf("\nFound %d configuration files to migrate\n", len(files))
 
+	// Map each directory to the module paths that call it, so state lookups match
+	// resources in the right module. The config directory is the root module.
+	modules := address.DiscoverModules(cfg.configDir)
+
 	// Store file paths for global postprocessing
 	outputPaths := make([]string, 0, len(files))
 
-	parsedConfigs := make(map[string]*hclwrite.File)
-	for i, file := range files {
-		fmt.Printf("[%d/%d] Processing %s... ", i+1, len(files), filepath.Base(file))
-		log.Debug("Processing file", "file", file, "index", i+1)
+	// Original and transformed content kept in memory for the dry-run patch
+	originals := make(map[string][]byte)
+	dryRunOutputs := make(map[string][]byte)
 
+	ctxs := make([]*transform.Context, len(files))
+	// The pipeline replaces the content of a context, so the original is kept for the patch
+	contents := make([][]byte, len(files))
+	for i, file := range files {
 		content, err := os.ReadFile(file)
 		if err != nil {
 			return nil, fmt.Errorf("failed to read %s: %w", file, err)
 		}
+		contents[i] = content
 
-		if cfg.backup && !cfg.dryRun && cfg.outputDir == cfg.configDir {
-			backupPath := file + ".backup"
-			if err := os.WriteFile(backupPath, content, 0644); err != nil {
-				return nil, fmt.Errorf("failed to create backup %s: %w", backupPath, err)
-			}
-			log.Debug("Created backup", "path", backupPath)
-		}
-
-		ctx := &transform.Context{
+		ctxs[i] = &transform.Context{
 			Content:       content,
 			Filename:      filepath.Base(file),
 			Diagnostics:   make(hcl.Diagnostics, 0),
@@ -281,14 +784,26 @@ func processConfigFiles(log hclog.Logger, p *pipeline.Pipeline, cfg config, stat
 			Resources:     cfg.resourcesToMigrate,
 			StateJSON:     stateJSON, // For cross-referencing in config transformations
 			APIClient:     apiClient,
+			Options:       cfg.migratorOptions,
+			Hops:          cfg.hops,
 		}
-		transformed, err := p.Transform(ctx)
-		if err != nil {
-			return nil, fmt.Errorf("failed to transform %s: %w", file, err)
+		if cfg.hops != nil {
+			ctxs[i].TargetVersion = cfg.hops[0].TargetVersion
+		}
+		if modulePaths, ok := modules.For(filepath.Dir(file)); ok {
+			ctxs[i].Modules = modulePaths
+		} else {
+			log.Debug("Directory is not called from the root module, state lookups match any module", "dir", filepath.Dir(file))
 		}
+	}
 
-		if ctx.CFGFile != nil {
-			parsedConfigs[file] = ctx.CFGFile
+	// writeOutput records a transformed file and writes it, or keeps it for the dry-run patch
+	parsedConfigs := make(map[string]*hclwrite.File)
+	writeOutput := func(file string, ctx *transform.Context, original, transformed []byte) error {
+		// The state is migrated against the configuration of the last hop
+		chain := ctx.Chain()
+		if last := chain[len(chain)-1]; last.CFGFile != nil {
+			parsedConfigs[file] = last.CFGFile
 		}
 
 		// Calculate output path maintaining directory structure when recursive
@@ -297,49 +812,122 @@ func processConfigFiles(log hclog.Logger, p *pipeline.Pipeline, cfg config, stat
 			// Preserve directory structure relative to config dir
 			relPath, err := filepath.Rel(cfg.configDir, file)
 			if err != nil {
-				return nil, fmt.Errorf("failed to compute relative path: %w", err)
+				return fmt.Errorf("failed to compute relative path: %w", err)
 			}
 			outputPath = filepath.Join(cfg.outputDir, relPath)
 		} else {
 			outputPath = filepath.Join(cfg.outputDir, filepath.Base(file))
 		}
 
+		if run.moves != nil {
+			// Addresses in a configuration file are local to the module in its directory.
+			// Every hop moves the resources it renamed, so the moves of several hops are chained.
+			dir := filepath.Dir(outputPath)
+			for i, hopCtx := range ctx.Chain() {
+				run.moves[dir] = append(run.moves[dir], moved.FromChanges(hopCtx.ResourceChanges(), run.renames[i])...)
+			}
+		}
+
 		if cfg.dryRun {
 			fmt.Println("(dry run)")
 			log.Debug("Would write file", "output", outputPath)
 			outputPaths = append(outputPaths, outputPath)
-			continue
+			originals[outputPath] = original
+			dryRunOutputs[outputPath] = transformed
+			return nil
 		}
 
-		// Create output directory (including subdirectories if needed)
-		outputDir := filepath.Dir(outputPath)
-		if err := os.MkdirAll(outputDir, 0755); err != nil {
-			return nil, fmt.Errorf("failed to create output directory: %w", err)
-		}
-
-		if err := os.WriteFile(outputPath, transformed, 0644); err != nil {
-			return nil, fmt.Errorf("failed to write %s: %w", outputPath, err)
+		// Files changed in place are backed up before they are overwritten
+		if err := run.write(outputPath, transformed, cfg.outputDir == cfg.configDir); err != nil {
+			return err
 		}
 		fmt.Println("✓")
 		log.Debug("Migrated file", "output", outputPath)
 		outputPaths = append(outputPaths, outputPath)
+		return nil
+	}
+
+	// Files are transformed concurrently; results are handled in file order
+	var errs []error
+	p.TransformAll(ctxs, cfg.parallelism, func(i int, transformed []byte, err error) {
+		file, ctx := files[i], ctxs[i]
+		fmt.Printf("[%d/%d] Processing %s... ", i+1, len(files), filepath.Base(file))
+		log.Debug("Processed file", "file", file, "index", i+1)
 
+		if run.report != nil {
+			reportPath, relErr := filepath.Rel(cfg.configDir, file)
+			if relErr != nil {
+				reportPath = file
+			}
+			run.report.AddConfigFile(filepath.ToSlash(reportPath), ctx)
+		}
+		if err != nil {
+			err = fmt.Errorf("failed to transform %s: %w", file, err)
+		} else {
+			err = writeOutput(file, ctx, contents[i], transformed)
+		}
+		if err != nil {
+			fmt.Println("✗")
+			errs = append(errs, err)
+		}
+		run.record(file, err)
+	})
+	// With --continue-on-error the files that were transformed are still processed
+	if len(errs) > 0 && !cfg.continueOnError {
+		return nil, errors.Join(errs...)
+	}
+	log.Debug("Transformed configuration files", "files", len(files), "parallelism", cfg.parallelism)
+
+	if cfg.dryRun {
+		// Apply cross-file reference updates in memory so the patch matches a real run
+		updates := collectReferenceUpdates(log, cfg)
+		except := renameExceptions(outputPaths, func(path string) ([]byte, error) { return dryRunOutputs[path], nil }, updates)
+		var removed hcl.Diagnostics
+		for _, outputPath := range outputPaths {
+			transformed, _, diags := updateReferences(log, cfg, outputPath, dryRunOutputs[outputPath], updates, except[filepath.Dir(outputPath)])
+			removed = append(removed, diags...)
+			recordReferenceDiagnostics(cfg, run, outputPath, diags)
+			run.validateConfig(cfg, outputPath, transformed)
+
+			relPath, err := filepath.Rel(cfg.outputDir, outputPath)
+			if err != nil {
+				relPath = outputPath
+			}
+			run.patch.AddFile(filepath.ToSlash(relPath), originals[outputPath], transformed)
+		}
+		if len(removed) > 0 {
+			printReferenceDiagnostics(removed)
+		}
+		return parsedConfigs, errors.Join(errs...)
 	}
 
 	// Apply global postprocessing for cross-file reference updates
-	if !cfg.dryRun && len(outputPaths) > 0 {
-		if err := applyGlobalPostprocessing(log, cfg, outputPaths); err != nil {
-			return nil, fmt.Errorf("failed to apply global postprocessing: %w", err)
+	if len(outputPaths) > 0 {
+		if err := applyGlobalPostprocessing(log, cfg, outputPaths, run); err != nil {
+			errs = append(errs, fmt.Errorf("failed to apply global postprocessing: %w", err))
+			if !cfg.continueOnError {
+				return nil, errors.Join(errs...)
+			}
 		}
 	}
 
-	return parsedConfigs, nil
+	if run.validator != nil {
+		for _, outputPath := range outputPaths {
+			content, err := run.readFile(outputPath)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s for validation: %w", outputPath, err)
+			}
+			run.validateConfig(cfg, outputPath, content)
+		}
+	}
+
+	return parsedConfigs, errors.Join(errs...)
 }
 
-// applyGlobalPostprocessing applies cross-file reference updates for resource renames
-func applyGlobalPostprocessing(log hclog.Logger, cfg config, outputPaths []string) error {
-	// Collect resource renames from all migrators
-	providers := getProviders(cfg.resourcesToMigrate...)
+// collectResourceRenames collects old type -> new type mappings from all migrators
+// implementing the ResourceRenamer interface
+func collectResourceRenames(log hclog.Logger, cfg config) map[string]string {
+	providers := getProviders(cfg)
 	migrators := providers.GetAllMigrators(cfg.sourceVersion, cfg.targetVersion, cfg.resourcesToMigrate...)
 
 	// Map to store old type -> new type mappings
@@ -369,48 +957,249 @@ func applyGlobalPostprocessing(log hclog.Logger, cfg config, outputPaths []strin
 		}
 	}
 
+	return renames
+}
+
+// referenceUpdates holds the renames applied to references across all configuration files
+type referenceUpdates struct {
+	renames    map[string]string
+	attributes map[string]tfhcl.AttributeChanges
+	// filters select the resources the attribute renames of a type apply to
+	filters map[string]transform.AttributeRenameFilter
+}
+
+func (u referenceUpdates) isEmpty() bool {
+	return len(u.renames) == 0 && len(u.attributes) == 0
+}
+
+// collectReferenceUpdates collects the reference updates of every hop of the migration, to be
+// applied in order
+func collectReferenceUpdates(log hclog.Logger, cfg config) []referenceUpdates {
+	var updates []referenceUpdates
+	for _, hopCfg := range cfg.hopConfigs() {
+		updates = append(updates, collectHopReferenceUpdates(log, hopCfg))
+	}
+	return updates
+}
+
+// collectHopReferenceUpdates collects resource type renames and attribute changes from all
+// migrators of a single hop
+func collectHopReferenceUpdates(log hclog.Logger, cfg config) referenceUpdates {
+	updates := referenceUpdates{
+		renames:    collectResourceRenames(log, cfg),
+		attributes: make(map[string]tfhcl.AttributeChanges),
+	}
+
+	providers := getProviders(cfg)
+	for _, migrator := range providers.GetAllMigrators(cfg.sourceVersion, cfg.targetVersion, cfg.resourcesToMigrate...) {
+		changes := attributeChanges(migrator)
+		if len(changes.Renames) == 0 && len(changes.Removed) == 0 {
+			continue
+		}
+		// References are matched after resource type renames have been applied
+		updates.attributes[migrator.GetResourceType()] = changes
+		if filter, ok := migrator.(transform.AttributeRenameFilter); ok {
+			if updates.filters == nil {
+				updates.filters = make(map[string]transform.AttributeRenameFilter)
+			}
+			updates.filters[migrator.GetResourceType()] = filter
+		}
+		log.Debug("Collected attribute changes", "type", migrator.GetResourceType(), "renames", len(changes.Renames), "removed", len(changes.Removed))
+	}
+
+	// Data source references are keyed as data.<type>
+	if dataSources, ok := providers.(transform.DataSourceProvider); ok {
+		for _, migrator := range dataSources.GetAllDataSourceMigrators(cfg.sourceVersion, cfg.targetVersion) {
+			if renamer, ok := migrator.(transform.ResourceRenamer); ok {
+				if oldType, newType := renamer.GetResourceRename(); oldType != "" && newType != "" && oldType != newType {
+					updates.renames["data."+oldType] = "data." + newType
+					log.Debug("Collected data source rename", "old", oldType, "new", newType)
+				}
+			}
+			if changes := attributeChanges(migrator); len(changes.Renames) > 0 || len(changes.Removed) > 0 {
+				updates.attributes["data."+migrator.GetDataSourceType()] = changes
+			}
+		}
+	}
+
+	return updates
+}
+
+// attributeChanges returns the attribute renames and removals a migrator exposes
+func attributeChanges(migrator any) tfhcl.AttributeChanges {
+	var changes tfhcl.AttributeChanges
+	if renamer, ok := migrator.(transform.AttributeRenamer); ok {
+		changes.Renames = renamer.GetAttributeRenames()
+	}
+	if remover, ok := migrator.(transform.AttributeRemover); ok {
+		changes.Removed = remover.GetRemovedAttributes()
+	}
+	return changes
+}
+
+// applyGlobalPostprocessing applies cross-file reference updates for resource and attribute renames
+func applyGlobalPostprocessing(log hclog.Logger, cfg config, outputPaths []string, run *migrationRun) error {
+	updates := collectReferenceUpdates(log, cfg)
+	empty, renames := true, 0
+	for _, u := range updates {
+		empty = empty && u.isEmpty()
+		renames += len(u.renames)
+	}
+
 	// If no renames found, skip global postprocessing
-	if len(renames) == 0 {
+	if empty {
 		log.Debug("No resource renames found, skipping global postprocessing")
 		return nil
 	}
 
-	fmt.Printf("\nApplying cross-file reference updates (%d renames across %d files)...\n", len(renames), len(outputPaths))
+	fmt.Printf("\nApplying cross-file reference updates (%d renames across %d files)...\n", renames, len(outputPaths))
 
 	// Apply renames to all files
+	except := renameExceptions(outputPaths, run.readFile, updates)
+	var removed hcl.Diagnostics
 	for _, outputPath := range outputPaths {
-		content, err := os.ReadFile(outputPath)
+		content, err := run.readFile(outputPath)
 		if err != nil {
 			log.Warn("Failed to read file for global postprocessing", "file", outputPath, "error", err)
 			continue
 		}
 
-		contentStr := string(content)
-		modified := false
-
-		// Apply all renames
-		for oldType, newType := range renames {
-			newContent := strings.ReplaceAll(contentStr, oldType+".", newType+".")
-			if newContent != contentStr {
-				modified = true
-				contentStr = newContent
-				log.Debug("Updated references", "file", filepath.Base(outputPath), "old", oldType, "new", newType)
-			}
-		}
+		updated, modified, diags := updateReferences(log, cfg, outputPath, content, updates, except[filepath.Dir(outputPath)])
+		removed = append(removed, diags...)
+		recordReferenceDiagnostics(cfg, run, outputPath, diags)
 
 		// Write back if modified
 		if modified {
-			if err := os.WriteFile(outputPath, []byte(contentStr), 0644); err != nil {
+			log.Debug("Updated references", "file", filepath.Base(outputPath))
+			if err := run.write(outputPath, updated, false); err != nil {
 				return fmt.Errorf("failed to write updated file %s: %w", outputPath, err)
 			}
 		}
 	}
 
-	fmt.Printf("✓ Updated cross-file references (%d renames applied)\n", len(renames))
+	fmt.Printf("✓ Updated cross-file references (%d renames applied)\n", renames)
+
+	if len(removed) > 0 {
+		printReferenceDiagnostics(removed)
+	}
 	return nil
 }
 
-func processStateFile(log hclog.Logger, p *pipeline.Pipeline, cfg config, apiClient *cloudflare.Client, parsedConfigs map[string]*hclwrite.File) error {
+// renameExceptions returns, for every directory, the names of the resources of each type that
+// the attribute renames of their migrator do not apply to
+func renameExceptions(outputPaths []string, read func(path string) ([]byte, error), updates []referenceUpdates) map[string]map[string]map[string]bool {
+	filters := make(map[string]transform.AttributeRenameFilter)
+	for _, u := range updates {
+		for resourceType, filter := range u.filters {
+			filters[resourceType] = filter
+		}
+	}
+	if len(filters) == 0 {
+		return nil
+	}
+
+	except := make(map[string]map[string]map[string]bool)
+	for _, outputPath := range outputPaths {
+		content, err := read(outputPath)
+		if err != nil {
+			continue
+		}
+		file, diags := hclwrite.ParseConfig(content, outputPath, hcl.InitialPos)
+		if diags.HasErrors() {
+			continue
+		}
+		for _, block := range file.Body().Blocks() {
+			if block.Type() != "resource" || len(block.Labels()) != 2 {
+				continue
+			}
+			resourceType, name := block.Labels()[0], block.Labels()[1]
+			filter, ok := filters[resourceType]
+			if !ok || filter.RenamesAttributesOf(block) {
+				continue
+			}
+			dir := filepath.Dir(outputPath)
+			if except[dir] == nil {
+				except[dir] = make(map[string]map[string]bool)
+			}
+			if except[dir][resourceType] == nil {
+				except[dir][resourceType] = make(map[string]bool)
+			}
+			except[dir][resourceType][name] = true
+		}
+	}
+	return except
+}
+
+// updateReferences applies all resource type and attribute renames to references in the content,
+// one hop after the other. References to removed attributes are returned as error diagnostics.
+// The attribute renames do not apply to the resources in except, keyed by type and name.
+func updateReferences(log hclog.Logger, cfg config, path string, content []byte, updates []referenceUpdates, except map[string]map[string]bool) ([]byte, bool, hcl.Diagnostics) {
+	opts := tfhcl.ReferenceOptions{
+		// With moved blocks the state keeps the old types, so existing moves must keep them too
+		KeepMovedFrom: cfg.renameStrategy == renameStrategyMoved,
+	}
+	updated := content
+	var rewrites []tfhcl.ReferenceRewrite
+	var removed hcl.Diagnostics
+	for _, hopUpdates := range updates {
+		var resourceRewrites, attributeRewrites []tfhcl.ReferenceRewrite
+		var hopRemoved hcl.Diagnostics
+		var err error
+		updated, resourceRewrites, err = tfhcl.RenameResourceReferences(updated, path, hopUpdates.renames, opts)
+		if err != nil {
+			log.Warn("Failed to update references", "file", path, "error", err)
+			return content, false, nil
+		}
+
+		attributes := hopUpdates.attributes
+		if len(except) > 0 {
+			attributes = make(map[string]tfhcl.AttributeChanges, len(hopUpdates.attributes))
+			for resourceType, changes := range hopUpdates.attributes {
+				changes.Except = except[resourceType]
+				attributes[resourceType] = changes
+			}
+		}
+		updated, attributeRewrites, hopRemoved, err = tfhcl.RenameAttributeReferences(updated, path, attributes)
+		if err != nil {
+			log.Warn("Failed to update attribute references", "file", path, "error", err)
+			return content, false, nil
+		}
+		rewrites = append(rewrites, resourceRewrites...)
+		rewrites = append(rewrites, attributeRewrites...)
+		removed = append(removed, hopRemoved...)
+	}
+
+	for _, rewrite := range rewrites {
+		log.Info("Updated reference",
+			"file", path,
+			"line", rewrite.Range.Start.Line,
+			"column", rewrite.Range.Start.Column,
+			"from", rewrite.From,
+			"to", rewrite.To)
+	}
+
+	return updated, len(rewrites) > 0, removed
+}
+
+// recordReferenceDiagnostics counts the references that could not be updated and adds them
+// to the report
+func recordReferenceDiagnostics(cfg config, run *migrationRun, outputPath string, diags hcl.Diagnostics) {
+	run.removedReferences += len(diags)
+	if run.report == nil || len(diags) == 0 {
+		return
+	}
+	run.report.AddUnresolvedReferences(relativePath(cfg.outputDir, outputPath), diags)
+}
+
+// printReferenceDiagnostics prints references that could not be updated automatically
+func printReferenceDiagnostics(diags hcl.Diagnostics) {
+	fmt.Printf("\n✗ Found %d references to removed attributes:\n", len(diags))
+	for _, diag := range diags {
+		fmt.Printf("  %s: %s\n", diag.Subject, diag.Detail)
+	}
+}
+
+func processStateFile(log hclog.Logger, p *pipeline.Pipeline, cfg config, apiClient *cloudflare.Client, parsedConfigs map[string]*hclwrite.File, run *migrationRun) error {
 	if p == nil {
 		return fmt.Errorf("state pipeline is nil")
 	}
@@ -423,19 +1212,32 @@ func processStateFile(log hclog.Logger, p *pipeline.Pipeline, cfg config, apiCli
 		return fmt.Errorf("failed to read state file: %w", err)
 	}
 
+	if run.moves != nil {
+		// The state is only read for instance addresses, which are merged into the moves
+		// derived from the configuration of the directories they are written to
+		stateMoves := make(map[string][]moved.Move)
+		moduleDirs := migratedModuleDirs(cfg)
+		for _, move := range moved.FromState(content, composeRenames(run.renames)) {
+			dir := cfg.outputDir
+			if modulePath, local := move.InModule(); modulePath != "" {
+				if moduleDir, ok := moduleDirs[modulePath]; ok {
+					dir, move = moduleDir, local
+				}
+			}
+			stateMoves[dir] = append(stateMoves[dir], move)
+		}
+		for dir, moves := range stateMoves {
+			run.moves[dir] = moved.Merge(run.moves[dir], moves)
+		}
+		fmt.Println("(read only, --rename-strategy=moved)")
+		return nil
+	}
+
 	// If no output path specified, use input path (in-place)
 	if cfg.outputState == "" {
 		cfg.outputState = cfg.stateFile
 	}
 
-	if cfg.backup && !cfg.dryRun && cfg.outputState == cfg.stateFile {
-		backupPath := cfg.stateFile + ".backup"
-		if err := os.WriteFile(backupPath, content, 0644); err != nil {
-			return fmt.Errorf("failed to create state backup %s: %w", backupPath, err)
-		}
-		log.Debug("Created state backup", "path", backupPath)
-	}
-
 	ctx := &transform.Context{
 		Content:       content,
 		StateJSON:     string(content),
@@ -447,19 +1249,36 @@ func processStateFile(log hclog.Logger, p *pipeline.Pipeline, cfg config, apiCli
 		Resources:     cfg.resourcesToMigrate,
 		APIClient:     apiClient,
 		CFGFiles:      parsedConfigs,
+		Options:       cfg.migratorOptions,
+		Hops:          cfg.hops,
+
+		TerraformVersion: cfg.terraformVersion,
+	}
+	if cfg.hops != nil {
+		ctx.TargetVersion = cfg.hops[0].TargetVersion
 	}
 	transformedContent, err := p.Transform(ctx)
+	if run.report != nil {
+		// A state from --state-backend is reported by its location, not its temporary copy
+		run.report.SetState(defaultString(cfg.stateBackend, cfg.stateFile), ctx)
+	}
 	if err != nil {
+		run.record(cfg.stateFile, err)
 		return fmt.Errorf("failed to transform state file: %w", err)
 	}
+	run.validateState(defaultString(cfg.stateBackend, cfg.outputState), transformedContent)
 
 	if cfg.dryRun {
 		fmt.Println("(dry run)")
 		log.Debug("Would write transformed state", "output", cfg.outputState)
+		run.patch.SetState(filepath.Base(cfg.outputState), content, transformedContent)
 		return nil
 	}
 
-	if err := os.WriteFile(cfg.outputState, transformedContent, 0644); err != nil {
+	// A state changed in place is backed up before it is overwritten
+	err = run.write(cfg.outputState, transformedContent, cfg.outputState == cfg.stateFile)
+	run.record(cfg.outputState, err)
+	if err != nil {
 		return fmt.Errorf("failed to write state %s: %w", cfg.outputState, err)
 	}
 	fmt.Println("✓")
@@ -481,6 +1300,10 @@ func findTerraformFilesWithRecursion(dir string, recursive bool) ([]string, erro
 
 	for _, entry := range entries {
 		path := filepath.Join(dir, entry.Name())
+		if entry.IsDir() && strings.HasPrefix(entry.Name(), ".") {
+			// Skip .terraform, .git and the backups in .tf-migrate
+			continue
+		}
 		if entry.IsDir() && recursive {
 			// Recursively search subdirectories
 			subFiles, err := findTerraformFilesWithRecursion(path, recursive)
@@ -497,20 +1320,180 @@ func findTerraformFilesWithRecursion(dir string, recursive bool) ([]string, erro
 	return files, nil
 }
 
-func validateVersions(c config) error {
-	versionPath := fmt.Sprintf("%s-%s", c.sourceVersion, c.targetVersion)
-	if _, ok := validVersionPath[versionPath]; !ok {
-		return fmt.Errorf("unsupported migration path: %s", versionPath)
+// versionPath returns the provider versions a migration goes through, along the shortest
+// chain of registered migrators and plugins from the source to the target version
+func versionPath(c config) ([]string, error) {
+	hops := make([][2]string, 0, len(c.plugins))
+	for _, p := range c.plugins {
+		hops = append(hops, [2]string{p.SourceVersion(), p.TargetVersion()})
+	}
+	path := internal.VersionPath(c.sourceVersion, c.targetVersion, hops...)
+	if path == nil {
+		return nil, fmt.Errorf("unsupported migration path: %s-%s", c.sourceVersion, c.targetVersion)
+	}
+	return path, nil
+}
+
+// validateSingleHop checks that the versions are migrated directly, for the commands that do
+// not chain migrations across several provider versions
+func validateSingleHop(c config) error {
+	path, err := versionPath(c)
+	if err != nil {
+		return err
+	}
+	if len(path) > 2 {
+		return fmt.Errorf("migration path %s-%s goes through %s, which only the migrate command supports: run this command for each hop",
+			c.sourceVersion, c.targetVersion, strings.Join(path, " → "))
 	}
 	return nil
 }
 
-func getProviders(resources ...string) transform.MigrationProvider {
+// migrationHops returns the hops of a version path. The resources to migrate are named as in
+// the source version, so they follow the resource type renames of every hop.
+func migrationHops(log hclog.Logger, cfg config, path []string) []transform.Hop {
+	hops := make([]transform.Hop, 0, len(path)-1)
+	resources := cfg.resourcesToMigrate
+	for i := 0; i+1 < len(path); i++ {
+		hop := transform.Hop{SourceVersion: path[i], TargetVersion: path[i+1], Resources: resources}
+		hops = append(hops, hop)
+		if len(resources) == 0 {
+			continue
+		}
+		renames := collectResourceRenames(log, cfg.forHop(hop))
+		next := make([]string, len(resources))
+		for j, resourceType := range resources {
+			next[j] = defaultString(renames[resourceType], resourceType)
+		}
+		resources = next
+	}
+	return hops
+}
+
+// forHop returns the configuration of a single hop of the migration
+func (c config) forHop(hop transform.Hop) config {
+	c.sourceVersion = hop.SourceVersion
+	c.targetVersion = hop.TargetVersion
+	c.resourcesToMigrate = hop.Resources
+	c.hops = nil
+	return c
+}
+
+// hopConfigs returns the configuration of every hop of the migration, or c itself when the
+// versions are migrated directly
+func (c config) hopConfigs() []config {
+	if len(c.hops) == 0 {
+		return []config{c}
+	}
+	configs := make([]config, len(c.hops))
+	for i, hop := range c.hops {
+		configs[i] = c.forHop(hop)
+	}
+	return configs
+}
+
+// migratedModuleDirs maps the module paths of the local modules migrated with the root
+// module to their output directories. Moves of other modules, such as remote ones, are
+// written to the root module with their full addresses.
+func migratedModuleDirs(cfg config) map[string]string {
+	dirs := make(map[string]string)
+	if !cfg.recursive {
+		return dirs
+	}
+	for dir, modulePaths := range address.DiscoverModules(cfg.configDir) {
+		relPath, err := filepath.Rel(filepath.Clean(cfg.configDir), dir)
+		if err != nil || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+			continue
+		}
+		for _, modulePath := range modulePaths {
+			if modulePath != "" {
+				dirs[modulePath] = filepath.Join(cfg.outputDir, relPath)
+			}
+		}
+	}
+	return dirs
+}
+
+// composeRenames returns the resource type renames of a whole migration from those of its
+// hops, mapping every type to its type after the last hop
+func composeRenames(hops []map[string]string) map[string]string {
+	composed := make(map[string]string)
+	for _, renames := range hops {
+		next := make(map[string]string, len(composed)+len(renames))
+		// Types renamed by an earlier hop are renamed again under their original type
+		renamedTo := make(map[string]bool, len(composed))
+		for oldType, newType := range composed {
+			next[oldType] = defaultString(renames[newType], newType)
+			renamedTo[newType] = true
+		}
+		for oldType, newType := range renames {
+			if !renamedTo[oldType] {
+				next[oldType] = newType
+			}
+		}
+		composed = next
+	}
+	for oldType, newType := range composed {
+		if oldType == newType {
+			delete(composed, oldType)
+		}
+	}
+	return composed
+}
+
+func getProviders(cfg config) transform.MigrationProvider {
+	excluded := make(map[string]bool, len(cfg.excludeResources))
+	for _, resourceType := range cfg.excludeResources {
+		excluded[resourceType] = true
+	}
+	isExcluded := func(migrator interface{ CanHandle(string) bool }) bool {
+		for resourceType := range excluded {
+			if migrator.CanHandle(resourceType) {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Plugins take precedence over the built-in migrators of the resource types they handle
 	getFunc := func(resourceType string, source string, target string) transform.ResourceTransformer {
+		if excluded[resourceType] {
+			return nil
+		}
+		for _, p := range cfg.plugins {
+			if p.SourceVersion() == source && p.TargetVersion() == target && p.CanHandle(resourceType) {
+				return p
+			}
+		}
 		return internal.GetMigrator(resourceType, source, target)
 	}
 	getAllFunc := func(source string, target string, resourcesToMigrate ...string) []transform.ResourceTransformer {
-		return internal.GetAllMigrators(source, target, resources...)
+		var result []transform.ResourceTransformer
+		for _, p := range cfg.plugins {
+			if p.SourceVersion() == source && p.TargetVersion() == target && !isExcluded(p) && handlesAny(p, resourcesToMigrate) {
+				result = append(result, p)
+			}
+		}
+		for _, migrator := range internal.GetAllMigrators(source, target, resourcesToMigrate...) {
+			if !isExcluded(migrator) {
+				result = append(result, migrator)
+			}
+		}
+		return result
+	}
+	getDataSourceFunc := func(dataSourceType string, source string, target string) transform.DataSourceTransformer {
+		if excluded[dataSourceType] {
+			return nil
+		}
+		return internal.GetDataSourceMigrator(dataSourceType, source, target)
+	}
+	getAllDataSourceFunc := func(source string, target string) []transform.DataSourceTransformer {
+		var result []transform.DataSourceTransformer
+		for _, migrator := range internal.GetAllDataSourceMigrators(source, target) {
+			if !isExcluded(migrator) {
+				result = append(result, migrator)
+			}
+		}
+		return result
 	}
-	return transform.NewMigrationProvider(getFunc, getAllFunc)
+	return transform.NewMigrationProviderWithDataSources(getFunc, getAllFunc, getDataSourceFunc, getAllDataSourceFunc)
 }
@@ -0,0 +1,644 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/cloudflare/tf-migrate/internal"
+	"github.com/cloudflare/tf-migrate/internal/backup"
+	"github.com/cloudflare/tf-migrate/internal/registry"
+	"github.com/cloudflare/tf-migrate/internal/rules"
+)
+
+const recordConfig = `resource "cloudflare_record" "www" {
+  zone_id = "abc"
+  name    = "www"
+  value   = "192.0.2.1"
+  type    = "A"
+}
+`
+
+const migratedRecordConfig = `resource "cloudflare_dns_record" "www" {
+  zone_id = "abc"
+  name    = "www"
+  type    = "A"
+  ttl     = 1
+  content = "192.0.2.1"
+}
+`
+
+// invalidConfig fails to parse, so its file fails to migrate
+const invalidConfig = `resource "cloudflare_record" "bad" {
+  zone_id =
+`
+
+const recordState = `{
+  "version": 4,
+  "terraform_version": "1.5.0",
+  "serial": 1,
+  "resources": [
+    {
+      "mode": "managed",
+      "type": "cloudflare_record",
+      "name": "www",
+      "provider": "provider[\"registry.terraform.io/cloudflare/cloudflare\"]",
+      "instances": [
+        {
+          "schema_version": 3,
+          "attributes": {"id": "1", "zone_id": "abc", "name": "www", "value": "192.0.2.1", "type": "A"}
+        }
+      ]
+    }
+  ]
+}
+`
+
+func TestMain(m *testing.M) {
+	registry.RegisterAllMigrations()
+	os.Exit(m.Run())
+}
+
+// writeTree writes files, named by their slash-separated path, to a new directory
+func writeTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	return dir
+}
+
+// readTree returns the content of every file in dir by its slash-separated path
+func readTree(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	files := make(map[string]string)
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(relPath)] = string(content)
+		return nil
+	})
+	require.NoError(t, err)
+	return files
+}
+
+// migrateConfig returns the configuration of an in-place migration of dir and its state
+// file, with the defaults of the migrate command
+func migrateConfig(dir string) config {
+	return config{
+		configDir:      dir,
+		stateFile:      filepath.Join(dir, "terraform.tfstate"),
+		backup:         true,
+		parallelism:    1,
+		renameStrategy: renameStrategyState,
+	}
+}
+
+// runMigrate runs migrateDirectory and returns what it printed
+func runMigrate(t *testing.T, cfg config) (string, error) {
+	t.Helper()
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	output := make(chan string)
+	go func() {
+		content, _ := io.ReadAll(r)
+		output <- string(content)
+	}()
+	err = migrateDirectory(hclog.NewNullLogger(), cfg)
+	w.Close()
+	return <-output, err
+}
+
+func TestMigrateDirectory(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"main.tf":           recordConfig,
+		"terraform.tfstate": recordState,
+	})
+
+	_, err := runMigrate(t, migrateConfig(dir))
+	require.NoError(t, err)
+
+	files := readTree(t, dir)
+	assert.Equal(t, migratedRecordConfig, files["main.tf"])
+	state := gjson.Parse(files["terraform.tfstate"])
+	assert.Equal(t, "cloudflare_dns_record", state.Get("resources.0.type").String())
+	assert.Equal(t, "192.0.2.1", state.Get("resources.0.instances.0.attributes.content").String())
+
+	// Staged files are moved over the originals, leaving nothing but the backups behind
+	for name := range files {
+		if !strings.HasPrefix(name, ".tf-migrate/backups/") {
+			assert.Contains(t, []string{"main.tf", "terraform.tfstate"}, name)
+		}
+	}
+}
+
+func TestMigrateDirectoryExcludeResources(t *testing.T) {
+	const zonesConfig = `data "cloudflare_zones" "all" {
+  filter {
+    name = "example.com"
+  }
+}
+
+output "zones" {
+  value = data.cloudflare_zones.all.zones
+}
+`
+	for _, exclude := range []bool{false, true} {
+		dir := writeTree(t, map[string]string{"main.tf": recordConfig, "zones.tf": zonesConfig})
+		cfg := migrateConfig(dir)
+		cfg.stateFile = ""
+		if exclude {
+			cfg.excludeResources = []string{"cloudflare_zones"}
+		}
+
+		_, err := runMigrate(t, cfg)
+		require.NoError(t, err)
+
+		files := readTree(t, dir)
+		assert.Equal(t, migratedRecordConfig, files["main.tf"], "exclude %v", exclude)
+		if exclude {
+			assert.Equal(t, zonesConfig, files["zones.tf"], "an excluded data source is left unchanged")
+			continue
+		}
+		assert.NotContains(t, files["zones.tf"], "filter {")
+		assert.Contains(t, files["zones.tf"], "data.cloudflare_zones.all.result")
+	}
+}
+
+func TestMigrateDirectoryDataRecordReferences(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"main.tf": recordConfig + `
+resource "cloudflare_record" "srv" {
+  zone_id = "abc"
+  name    = "_sip._tcp"
+  type    = "SRV"
+  data {
+    priority = 10
+    weight   = 5
+    port     = 5060
+    target   = "sip.example.com"
+  }
+}
+`,
+		"outputs.tf": `output "values" {
+  value = [cloudflare_record.www.value, cloudflare_record.srv.value]
+}
+`,
+	})
+	cfg := migrateConfig(dir)
+	cfg.stateFile = ""
+
+	output, err := runMigrate(t, cfg)
+	require.NoError(t, err)
+
+	// Records that use the data field have no content to rename value to
+	assert.Equal(t, `output "values" {
+  value = [cloudflare_dns_record.www.content, cloudflare_dns_record.srv.value]
+}
+`, readTree(t, dir)["outputs.tf"])
+	assert.Contains(t, output, `The attribute "value" of cloudflare_dns_record.srv has no equivalent`)
+}
+
+func TestMigrateDirectoryFailure(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"bad.tf":            invalidConfig,
+		"main.tf":           recordConfig,
+		"terraform.tfstate": recordState,
+	})
+	before := readTree(t, dir)
+
+	output, err := runMigrate(t, migrateConfig(dir))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to transform "+filepath.Join(dir, "bad.tf"))
+	assert.Contains(t, output, "Migration failed, no files were changed")
+
+	// The files that migrated are not written, the state is untouched and nothing is backed up
+	assert.Equal(t, before, readTree(t, dir))
+}
+
+func TestMigrateDirectoryContinueOnError(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"bad.tf":            invalidConfig,
+		"main.tf":           recordConfig,
+		"terraform.tfstate": recordState,
+	})
+	cfg := migrateConfig(dir)
+	cfg.continueOnError = true
+
+	output, err := runMigrate(t, cfg)
+	require.Error(t, err)
+
+	files := readTree(t, dir)
+	assert.Equal(t, invalidConfig, files["bad.tf"])
+	assert.Equal(t, migratedRecordConfig, files["main.tf"])
+	assert.Equal(t, "cloudflare_dns_record", gjson.Get(files["terraform.tfstate"], "resources.0.type").String())
+
+	summary := output[strings.Index(output, "Migration summary:"):]
+	assert.Contains(t, summary, "  ✗ bad.tf: failed to transform")
+	assert.Contains(t, summary, "  ✓ main.tf\n")
+	assert.Contains(t, summary, "  ✓ terraform.tfstate\n")
+	assert.Contains(t, summary, "2 files migrated, 1 failed\n")
+}
+
+func TestMigrateDirectoryBackup(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"main.tf":           recordConfig,
+		"terraform.tfstate": recordState,
+	})
+
+	_, err := runMigrate(t, migrateConfig(dir))
+	require.NoError(t, err)
+
+	// Files are backed up when the staged outputs are committed, so the backups hold the
+	// originals and the manifest the checksums of the migrated files
+	manifests, err := backup.List(dir)
+	require.NoError(t, err)
+	require.Len(t, manifests, 1)
+	manifest := manifests[0]
+	require.Len(t, manifest.Files, 2)
+
+	originals := map[string]string{"main.tf": recordConfig, "terraform.tfstate": recordState}
+	runDir := filepath.Join(dir, ".tf-migrate", "backups", manifest.ID)
+	for _, file := range manifest.Files {
+		name := filepath.Base(file.Path)
+		content, err := os.ReadFile(filepath.Join(runDir, file.Backup))
+		require.NoError(t, err)
+		assert.Equal(t, originals[name], string(content), name)
+		assert.NotEmpty(t, file.MigratedSHA256, name)
+	}
+
+	rolledBack, err := backup.Rollback(dir, manifest.ID, manifest.CreatedAt)
+	require.NoError(t, err)
+	assert.Equal(t, manifest.ID, rolledBack.ID)
+	files := readTree(t, dir)
+	assert.Equal(t, recordConfig, files["main.tf"])
+	assert.Equal(t, recordState, files["terraform.tfstate"])
+}
+
+func TestMigrateDirectoryDryRun(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"main.tf":           recordConfig,
+		"terraform.tfstate": recordState,
+	})
+	before := readTree(t, dir)
+	cfg := migrateConfig(dir)
+	cfg.dryRun = true
+	cfg.diffOutput = filepath.Join(t.TempDir(), "migration.patch")
+
+	output, err := runMigrate(t, cfg)
+	require.NoError(t, err)
+	assert.Equal(t, before, readTree(t, dir))
+
+	patch, err := os.ReadFile(cfg.diffOutput)
+	require.NoError(t, err)
+	assert.Contains(t, output, string(patch))
+	assert.Contains(t, string(patch), "--- a/main.tf\n+++ b/main.tf\n")
+	assert.Contains(t, string(patch), `-resource "cloudflare_record" "www" {`)
+	assert.Contains(t, string(patch), `+resource "cloudflare_dns_record" "www" {`)
+
+	stateDiff, err := os.ReadFile(strings.TrimSuffix(cfg.diffOutput, ".patch") + ".state.json")
+	require.NoError(t, err)
+	assert.Equal(t, "terraform.tfstate", gjson.GetBytes(stateDiff, "path").String())
+	assert.Equal(t, "cloudflare_dns_record.www", gjson.GetBytes(stateDiff, "resources.0.address").String())
+	assert.Equal(t, "cloudflare_record.www", gjson.GetBytes(stateDiff, "resources.0.previous_address").String())
+}
+
+func TestMigrateDirectoryMoved(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"main.tf": recordConfig + strings.Replace(recordConfig, `"www"`, `"new"`, 1) + `
+module "dns" {
+  source   = "./modules/dns"
+  for_each = toset(["prod"])
+}
+`,
+		"modules/dns/main.tf": strings.Replace(recordConfig, `"www"`, `"api"`, 1),
+		"terraform.tfstate": strings.Replace(recordState, `"resources": [`, `"resources": [
+    {
+      "module": "module.dns[\"prod\"]",
+      "mode": "managed",
+      "type": "cloudflare_record",
+      "name": "api",
+      "instances": [{"index_key": 0, "schema_version": 3, "attributes": {"id": "2"}}]
+    },`, 1),
+	})
+	before := readTree(t, dir)
+	cfg := migrateConfig(dir)
+	cfg.recursive = true
+	cfg.renameStrategy = renameStrategyMoved
+
+	_, err := runMigrate(t, cfg)
+	require.NoError(t, err)
+
+	files := readTree(t, dir)
+	assert.Equal(t, before["terraform.tfstate"], files["terraform.tfstate"])
+	assert.Contains(t, files["main.tf"], `resource "cloudflare_dns_record" "www"`)
+	assert.Contains(t, files["tf_migrate_moved.tf"], `moved {
+  from = cloudflare_record.www
+  to   = cloudflare_dns_record.www
+}`)
+	// The resources missing from the state keep the moves of the configuration
+	assert.Contains(t, files["tf_migrate_moved.tf"], `moved {
+  from = cloudflare_record.new
+  to   = cloudflare_dns_record.new
+}`)
+	assert.Equal(t, 2, strings.Count(files["tf_migrate_moved.tf"], "moved {"))
+	// The moves of a module instance are written to the module with local addresses
+	assert.Contains(t, files["modules/dns/tf_migrate_moved.tf"], `moved {
+  from = cloudflare_record.api[0]
+  to   = cloudflare_dns_record.api[0]
+}`)
+	assert.NotContains(t, files["tf_migrate_moved.tf"], "module.dns")
+	assert.NotContains(t, files["modules/dns/tf_migrate_moved.tf"], "from = cloudflare_record.api\n")
+}
+
+func TestMigrateDirectoryParallelism(t *testing.T) {
+	files := map[string]string{"terraform.tfstate": recordState}
+	for i := 0; i < 8; i++ {
+		files[fmt.Sprintf("record%d.tf", i)] = strings.Replace(recordConfig, `"www"`, fmt.Sprintf(`"www%d"`, i), 1)
+	}
+	dir := writeTree(t, files)
+
+	outputs := make([]map[string]string, 0, 2)
+	for _, parallelism := range []int{1, 4} {
+		cfg := migrateConfig(dir)
+		cfg.stateFile = ""
+		cfg.outputDir = t.TempDir()
+		cfg.parallelism = parallelism
+
+		output, err := runMigrate(t, cfg)
+		require.NoError(t, err)
+		outputs = append(outputs, readTree(t, cfg.outputDir))
+
+		// Files are reported in order whatever the order they are transformed in
+		last := -1
+		for i := 0; i < 8; i++ {
+			index := strings.Index(output, fmt.Sprintf("[%d/8] Processing record%d.tf", i+1, i))
+			require.Greater(t, index, last, "parallelism %d", parallelism)
+			last = index
+		}
+	}
+	require.Len(t, outputs[0], 8)
+	assert.Equal(t, outputs[0], outputs[1])
+	assert.Equal(t, files, readTree(t, dir))
+}
+
+func TestMigrateDirectoryMultiHop(t *testing.T) {
+	migrators, diags := rules.Parse("rules.hcl", []byte(`
+rule "cloudflare_dns_record" {
+  source_version = "v5"
+  target_version = "v6"
+  rename_to      = "cloudflare_test_record"
+
+  rename_attributes = {
+    content = "address"
+  }
+}
+`))
+	require.False(t, diags.HasErrors(), diags.Error())
+	for _, m := range migrators {
+		internal.RegisterMigrator(m.SourceResourceType(), m.SourceVersion(), m.TargetVersion(), m)
+	}
+
+	dir := writeTree(t, map[string]string{
+		"main.tf":           recordConfig,
+		"terraform.tfstate": recordState,
+	})
+	cfg := migrateConfig(dir)
+	cfg.targetVersion = "v6"
+
+	output, err := runMigrate(t, cfg)
+	require.NoError(t, err)
+	assert.Contains(t, output, "Migrating through v4 → v5 → v6")
+
+	files := readTree(t, dir)
+	assert.Contains(t, files["main.tf"], `resource "cloudflare_test_record" "www"`)
+	assert.Contains(t, files["main.tf"], `address = "192.0.2.1"`)
+	state := gjson.Parse(files["terraform.tfstate"])
+	assert.Equal(t, "cloudflare_test_record", state.Get("resources.0.type").String())
+	assert.Equal(t, "192.0.2.1", state.Get("resources.0.instances.0.attributes.address").String())
+	assert.False(t, state.Get("resources.0.instances.0.attributes.content").Exists())
+	assert.Equal(t, int64(2), state.Get("serial").Int(), "the serial is incremented once per run")
+}
+
+// fakeStateBackend is a bucket of an S3-compatible service holding recordState, which
+// rejects the upload of a new state when reject is set
+type fakeStateBackend struct {
+	mu       sync.Mutex
+	objects  map[string][]byte
+	requests []string
+	reject   bool
+}
+
+func (b *fakeStateBackend) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	key := strings.TrimPrefix(r.URL.Path, "/state/")
+	b.requests = append(b.requests, r.Method+" "+key)
+	body, _ := io.ReadAll(r.Body)
+	current, exists := b.objects[key]
+	switch r.Method {
+	case http.MethodGet:
+		if !exists {
+			http.Error(w, "NoSuchKey", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("ETag", `"1"`)
+		w.Write(current)
+	case http.MethodPut:
+		if (r.Header.Get("If-None-Match") == "*" && exists) || (b.reject && key == "terraform.tfstate") {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		b.objects[key] = body
+	case http.MethodDelete:
+		delete(b.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// log returns the requests received, as method and key, with the timestamp of
+// pre-migration copies left out
+func (b *fakeStateBackend) log() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	log := make([]string, len(b.requests))
+	for i, request := range b.requests {
+		if before, _, found := strings.Cut(request, ".pre-migration-"); found {
+			request = before + ".pre-migration-*"
+		}
+		log[i] = request
+	}
+	return log
+}
+
+func TestMigrateDirectoryRemoteState(t *testing.T) {
+	// run migrates recordConfig with the state held by backend
+	run := func(t *testing.T, backend *fakeStateBackend, dryRun bool) (map[string]string, string, error) {
+		server := httptest.NewServer(backend)
+		t.Cleanup(server.Close)
+
+		dir := writeTree(t, map[string]string{"main.tf": recordConfig})
+		backendConfig := filepath.Join(t.TempDir(), "backend.hcl")
+		require.NoError(t, os.WriteFile(backendConfig, []byte(fmt.Sprintf(`
+endpoint       = %q
+region         = "auto"
+use_path_style = true
+access_key     = "AKID"
+secret_key     = "secret"
+`, server.URL)), 0600))
+		cfg := migrateConfig(dir)
+		cfg.stateFile = ""
+		cfg.stateBackend = "s3://state/terraform.tfstate"
+		cfg.backendConfig = backendConfig
+		cfg.dryRun = dryRun
+
+		output, err := runMigrate(t, cfg)
+		return readTree(t, dir), output, err
+	}
+	newBackend := func() *fakeStateBackend {
+		return &fakeStateBackend{objects: map[string][]byte{"terraform.tfstate": []byte(recordState)}}
+	}
+
+	t.Run("The state is uploaded while locked", func(t *testing.T) {
+		backend := newBackend()
+		files, output, err := run(t, backend, false)
+		require.NoError(t, err)
+		assert.Equal(t, migratedRecordConfig, files["main.tf"])
+		assert.Equal(t, "cloudflare_dns_record", gjson.GetBytes(backend.objects["terraform.tfstate"], "resources.0.type").String())
+		assert.Contains(t, output, "✓ Saved the pre-migration state to s3://state/terraform.tfstate.pre-migration-")
+		assert.Contains(t, output, "✓ Uploaded the migrated state to s3://state/terraform.tfstate")
+		assert.Equal(t, []string{
+			"PUT terraform.tfstate.tflock",
+			"GET terraform.tfstate",
+			"PUT terraform.tfstate.pre-migration-*",
+			"PUT terraform.tfstate",
+			"GET terraform.tfstate.tflock",
+			"DELETE terraform.tfstate.tflock",
+		}, backend.log())
+
+		var copies []string
+		for key, body := range backend.objects {
+			if strings.HasPrefix(key, "terraform.tfstate.pre-migration-") {
+				copies = append(copies, key)
+				assert.Equal(t, recordState, string(body))
+			}
+		}
+		assert.Len(t, copies, 1)
+	})
+
+	t.Run("A failed upload leaves the configuration unchanged", func(t *testing.T) {
+		backend := newBackend()
+		backend.reject = true
+		files, output, err := run(t, backend, false)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "the state was changed by another operation")
+		assert.Contains(t, output, "Migration failed, no files were changed")
+		assert.Equal(t, recordConfig, files["main.tf"])
+		assert.Equal(t, recordState, string(backend.objects["terraform.tfstate"]))
+		_, locked := backend.objects["terraform.tfstate.tflock"]
+		assert.False(t, locked, "the lock is released")
+		assert.Equal(t, "DELETE terraform.tfstate.tflock", backend.log()[len(backend.log())-1])
+	})
+
+	t.Run("A locked state is not migrated", func(t *testing.T) {
+		backend := newBackend()
+		backend.objects["terraform.tfstate.tflock"] = []byte(`{"ID":"abc","Operation":"OperationTypeApply","Who":"ci@runner"}`)
+		files, _, err := run(t, backend, false)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `state is locked by terraform.tfstate.tflock (operation "OperationTypeApply" by ci@runner`)
+		assert.Equal(t, recordConfig, files["main.tf"])
+		assert.Equal(t, []string{"PUT terraform.tfstate.tflock", "GET terraform.tfstate.tflock"}, backend.log())
+		assert.Contains(t, string(backend.objects["terraform.tfstate.tflock"]), `"ID":"abc"`, "a lock held by someone else is left alone")
+	})
+
+	t.Run("A missing state releases the lock", func(t *testing.T) {
+		backend := &fakeStateBackend{objects: map[string][]byte{}}
+		_, _, err := run(t, backend, false)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to read state")
+		assert.Empty(t, backend.objects)
+	})
+
+	t.Run("A dry run only reads the state", func(t *testing.T) {
+		backend := newBackend()
+		files, _, err := run(t, backend, true)
+		require.NoError(t, err)
+		assert.Equal(t, recordConfig, files["main.tf"])
+		assert.Equal(t, []string{"GET terraform.tfstate"}, backend.log())
+	})
+}
+
+func TestCheckMigratorOptions(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"rules/records.hcl": `
+rule "cloudflare_project_record" {
+  source_version = "v4"
+  target_version = "v5"
+  rename_to      = "cloudflare_project_dns_record"
+}
+`,
+		".tf-migrate.hcl": `
+rules_dir = "rules"
+
+migrator "cloudflare_record" {
+  default_ttl = 300
+}
+
+migrator "cloudflare_project_record" {}
+`,
+	})
+	load := func(project string) error {
+		cmd := &cobra.Command{}
+		cfg := &config{projectFile: filepath.Join(dir, project)}
+		if err := loadProject(cmd, cfg); err != nil {
+			return err
+		}
+		if err := loadRules(hclog.NewNullLogger(), cmd, cfg); err != nil {
+			return err
+		}
+		return checkMigratorOptions(cmd, *cfg)
+	}
+
+	// Migrators of rule files are loaded before the options are checked
+	require.NoError(t, load(".tf-migrate.hcl"))
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "invalid.hcl"), []byte(`
+rules_dir = "rules"
+
+migrator "cloudflare_project_record" {
+  default_ttl = 300
+}
+`), 0644))
+	err := load("invalid.hcl")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `The migrator for "cloudflare_project_record" has no option "default_ttl"`)
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"github.com/hashicorp/go-hclog"
+	"github.com/spf13/cobra"
+
+	"github.com/cloudflare/tf-migrate/internal/plugin"
+	"github.com/cloudflare/tf-migrate/internal/transform"
+)
+
+// startPlugins starts the plugins in --plugins-dir, or the plugins_dir of the project file.
+// Plugins are started once for the run, and closed when the command returns.
+func startPlugins(log hclog.Logger, cmd *cobra.Command, cfg *config) error {
+	if cfg.project != nil {
+		setting(cmd, "plugins-dir", &cfg.pluginsDir, cfg.project.Settings.PluginsDir)
+	}
+	if cfg.pluginsDir == "" {
+		return nil
+	}
+
+	plugins, err := plugin.Discover(log, cfg.pluginsDir)
+	if err != nil {
+		return err
+	}
+	for _, p := range plugins {
+		log.Info("Loaded plugin", "plugin", p.Name(), "type", p.GetResourceType(),
+			"source", p.SourceVersion(), "target", p.TargetVersion())
+	}
+	cfg.plugins = plugins
+	return nil
+}
+
+// handlesAny reports whether a migrator handles one of the resource types, or any resource
+// type if none is given
+func handlesAny(migrator transform.ResourceTransformer, resourceTypes []string) bool {
+	if len(resourceTypes) == 0 {
+		return true
+	}
+	for _, resourceType := range resourceTypes {
+		if migrator.CanHandle(resourceType) {
+			return true
+		}
+	}
+	return false
+}
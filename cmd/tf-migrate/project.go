@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/cloudflare/tf-migrate/internal/project"
+	"github.com/cloudflare/tf-migrate/internal/transform"
+)
+
+// loadProject loads the project file given with --config, or .tf-migrate.hcl in the working
+// directory if there is one. Its migrator options are checked by checkMigratorOptions once
+// the rules and plugins are loaded.
+func loadProject(cmd *cobra.Command, cfg *config) error {
+	path := cfg.projectFile
+	if path == "" {
+		found, err := project.Find(".")
+		if err != nil || found == "" {
+			return err
+		}
+		path = found
+	}
+
+	file, err := project.Load(path)
+	if err != nil {
+		return err
+	}
+
+	cfg.project = file
+	cfg.migratorOptions = file.Migrators
+	return nil
+}
+
+// checkMigratorOptions checks the migrator options of the project file against the migrators
+// of every version path the file can run, including those of rule files and plugins
+func checkMigratorOptions(cmd *cobra.Command, cfg config) error {
+	if cfg.project == nil {
+		return nil
+	}
+
+	type migrators struct {
+		providers      transform.MigrationProvider
+		source, target string
+	}
+	var all []migrators
+	for _, c := range projectConfigs(cmd, cfg, cfg.project) {
+		// Options of excluded resource types are still checked
+		c.excludeResources = nil
+		all = append(all, migrators{getProviders(c), defaultString(c.sourceVersion, "v4"), defaultString(c.targetVersion, "v5")})
+	}
+	lookup := func(resourceType string) (map[string]cty.Type, bool) {
+		var options map[string]cty.Type
+		found := false
+		for _, m := range all {
+			migrator := m.providers.GetMigrator(resourceType, m.source, m.target)
+			if migrator == nil {
+				continue
+			}
+			found = true
+			if configurable, ok := migrator.(transform.ConfigurableMigrator); ok {
+				if options == nil {
+					options = make(map[string]cty.Type)
+				}
+				for name, optionType := range configurable.GetOptions() {
+					options[name] = optionType
+				}
+			}
+		}
+		return options, found
+	}
+	if diags := cfg.project.CheckMigratorOptions(lookup); diags.HasErrors() {
+		return fmt.Errorf("invalid project file: %w", diags)
+	}
+	return nil
+}
+
+// projectConfigs returns the configurations a command runs with. Without a project file,
+// or with --config-dir, that is a single configuration. Otherwise there is one per
+// directory block, or a single one with the top-level settings if there is none.
+func projectConfigs(cmd *cobra.Command, cfg config, file *project.File) []config {
+	if file == nil {
+		return []config{cfg}
+	}
+	if cmd.Flags().Changed("config-dir") || len(file.Directories) == 0 {
+		dir := defaultString(cfg.configDir, ".")
+		return []config{applySettings(cmd, cfg, file.SettingsFor(dir))}
+	}
+
+	configs := make([]config, 0, len(file.Directories))
+	for _, directory := range file.Directories {
+		c := applySettings(cmd, cfg, file.Settings.Merge(directory.Settings))
+		c.configDir = directory.Path
+		configs = append(configs, c)
+	}
+	return configs
+}
+
+// projectConfig returns the configuration of a command that runs on a single directory:
+// the settings of the project file for --config-dir, or the current directory
+func projectConfig(cmd *cobra.Command, cfg config) config {
+	if cfg.project == nil {
+		return cfg
+	}
+	return applySettings(cmd, cfg, cfg.project.SettingsFor(defaultString(cfg.configDir, ".")))
+}
+
+// applySettings sets the options of the project file that were not given as flags
+func applySettings(cmd *cobra.Command, cfg config, s project.Settings) config {
+	setting(cmd, "source-version", &cfg.sourceVersion, s.SourceVersion)
+	setting(cmd, "target-version", &cfg.targetVersion, s.TargetVersion)
+	setting(cmd, "resources", &cfg.resourcesToMigrate, s.Resources)
+	setting(cmd, "exclude-resources", &cfg.excludeResources, s.ExcludeResources)
+	setting(cmd, "state-file", &cfg.stateFile, s.StateFile)
+	setting(cmd, "state-backend", &cfg.stateBackend, s.StateBackend)
+	setting(cmd, "backend-config", &cfg.backendConfig, s.BackendConfig)
+	setting(cmd, "recursive", &cfg.recursive, s.Recursive)
+	setting(cmd, "backup", &cfg.backup, s.Backup)
+	setting(cmd, "continue-on-error", &cfg.continueOnError, s.ContinueOnError)
+	setting(cmd, "fail-on-removed-references", &cfg.failOnRemovedReferences, s.FailOnRemovedReferences)
+	setting(cmd, "rename-strategy", &cfg.renameStrategy, s.RenameStrategy)
+	setting(cmd, "terraform-version", &cfg.terraformVersion, s.TerraformVersion)
+	setting(cmd, "parallelism", &cfg.parallelism, s.Parallelism)
+	setting(cmd, "output-dir", &cfg.outputDir, s.OutputDir)
+	setting(cmd, "output-state", &cfg.outputState, s.OutputState)
+	setting(cmd, "report", &cfg.reportFile, s.Report)
+	setting(cmd, "diff-output", &cfg.diffOutput, s.DiffOutput)
+	setting(cmd, "schema", &cfg.schemaFile, s.Schema)
+	return cfg
+}
+
+// setting sets a field from the project file, unless its flag was given on the command line
+func setting[T any](cmd *cobra.Command, flag string, field *T, value *T) {
+	if value != nil && !cmd.Flags().Changed(flag) {
+		*field = *value
+	}
+}
+
+func defaultString(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
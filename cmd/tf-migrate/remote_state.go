@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+
+	"github.com/cloudflare/tf-migrate/internal/remotestate"
+)
+
+// newStateClient returns a client for the state given with --state-backend. Settings
+// missing from the URL are read from --backend-config, then from the environment.
+func newStateClient(cfg config) (*remotestate.Client, error) {
+	location, err := remotestate.ParseURL(cfg.stateBackend)
+	if err != nil {
+		return nil, err
+	}
+	var settings remotestate.Config
+	if cfg.backendConfig != "" {
+		settings, err = remotestate.LoadBackendConfig(cfg.backendConfig)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return remotestate.NewClient(settings.Merge(location).WithEnvironment(os.Getenv))
+}
+
+// migrateRemoteState runs a migration with the state stored in an S3-compatible bucket.
+// The state is locked and downloaded to a temporary directory, where it is migrated like
+// a local state file. The migrated state is uploaded after a copy of the original is
+// written next to it, and before the migrated configuration files are written, so they
+// are left unchanged when the upload fails. In dry-run mode the state is only read.
+func migrateRemoteState(log hclog.Logger, cfg config) (err error) {
+	client, err := newStateClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	dir, err := os.MkdirTemp("", "tf-migrate-state-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	name := path.Base(client.Key())
+	cfg.stateFile = filepath.Join(dir, name)
+	cfg.outputState = filepath.Join(dir, "migrated", name)
+	if err := os.Mkdir(filepath.Dir(cfg.outputState), 0700); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	fmt.Printf("State backend: %s\n", cfg.stateBackend)
+	if cfg.dryRun {
+		state, err := client.Get(ctx, client.Key())
+		if err != nil {
+			return fmt.Errorf("failed to read state: %w", err)
+		}
+		if err := os.WriteFile(cfg.stateFile, state.Body, 0600); err != nil {
+			return err
+		}
+		return runMigration(log, cfg)
+	}
+
+	now := time.Now()
+	info := remotestate.NewLockInfo("tf-migrate migrate", version, now)
+	if err := client.Lock(ctx, info); err != nil {
+		return err
+	}
+	defer func() {
+		if unlockErr := client.Unlock(context.WithoutCancel(ctx), info); unlockErr != nil {
+			err = errors.Join(err, unlockErr)
+		}
+	}()
+	log.Debug("Locked remote state", "state", cfg.stateBackend, "lock", remotestate.LockKey(client.Key()))
+
+	original, err := client.Get(ctx, client.Key())
+	if err != nil {
+		return fmt.Errorf("failed to read state: %w", err)
+	}
+	if err := os.WriteFile(cfg.stateFile, original.Body, 0600); err != nil {
+		return err
+	}
+	cfg.beforeCommit = func(run *migrationRun) error {
+		// The state is not written with --rename-strategy=moved
+		migrated, err := run.readFile(cfg.outputState)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		backupKey, err := client.Upload(ctx, original, migrated, now)
+		if backupKey != "" {
+			fmt.Printf("✓ Saved the pre-migration state to %s\n", client.URL(backupKey))
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("✓ Uploaded the migrated state to %s\n", client.URL(client.Key()))
+		return nil
+	}
+	return runMigration(log, cfg)
+}
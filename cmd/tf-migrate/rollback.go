@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cloudflare/tf-migrate/internal/backup"
+)
+
+func newRollbackCommand(cfg *config) *cobra.Command {
+	var runID string
+	var list bool
+
+	cmd := &cobra.Command{
+		Use:   "rollback",
+		Short: "Restore the files changed by a migration run from its backups",
+		Long: `Restore the configuration and state files changed in place by a migration run from the
+backups in .tf-migrate/backups in the configuration directory, and remove the files the run created.
+
+Every run records the checksum of each file after the migration. The rollback is refused,
+and nothing is restored, if any of those files changed since, so edits made after the
+migration are never lost. Without --run the latest run that was not rolled back is used.`,
+		Example: `  # List the backup runs of a directory
+  tf-migrate --config-dir ./terraform rollback --list
+
+  # Undo the latest migration run
+  tf-migrate --config-dir ./terraform rollback
+
+  # Undo a specific run
+  tf-migrate --config-dir ./terraform rollback --run 20261017T031602Z`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if cfg.configDir == "" {
+				cfg.configDir = "."
+			}
+
+			if list {
+				return listBackupRuns(cfg.configDir)
+			}
+
+			manifest, err := backup.Rollback(cfg.configDir, runID, time.Now())
+			if err != nil {
+				return err
+			}
+			for _, file := range manifest.Files {
+				if file.Created {
+					fmt.Printf("  removed  %s\n", file.Path)
+				} else {
+					fmt.Printf("  restored %s\n", file.Path)
+				}
+			}
+			fmt.Printf("✓ Rolled back run %s (%s → %s, %d files)\n", manifest.ID, manifest.SourceVersion, manifest.TargetVersion, len(manifest.Files))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&runID, "run", "", "ID of the backup run to roll back (default: the latest run not rolled back)")
+	cmd.Flags().BoolVar(&list, "list", false, "List the backup runs instead of rolling back")
+
+	return cmd
+}
+
+// listBackupRuns prints the backup runs of a configuration directory, oldest first
+func listBackupRuns(configDir string) error {
+	manifests, err := backup.List(configDir)
+	if err != nil {
+		return err
+	}
+	if len(manifests) == 0 {
+		fmt.Printf("No backup runs found in %s\n", configDir)
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "RUN\tCREATED\tVERSIONS\tFILES\tTOOL\tSTATUS")
+	for _, manifest := range manifests {
+		status := "active"
+		if manifest.RolledBackAt != nil {
+			status = "rolled back " + manifest.RolledBackAt.Local().Format(time.DateTime)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s → %s\t%d\t%s\t%s\n", manifest.ID, manifest.CreatedAt.Local().Format(time.DateTime),
+			manifest.SourceVersion, manifest.TargetVersion, len(manifest.Files), manifest.ToolVersion, status)
+	}
+	return w.Flush()
+}
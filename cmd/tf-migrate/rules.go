@@ -0,0 +1,31 @@
+package main
+
+import (
+	"github.com/hashicorp/go-hclog"
+	"github.com/spf13/cobra"
+
+	"github.com/cloudflare/tf-migrate/internal"
+	"github.com/cloudflare/tf-migrate/internal/rules"
+)
+
+// loadRules registers the migrators of the rule files in --rules-dir, or the rules_dir of the
+// project file. A rule replaces the migrator registered for its resource type and version
+// path, and its version path becomes a hop that migrations can go through.
+func loadRules(log hclog.Logger, cmd *cobra.Command, cfg *config) error {
+	if cfg.project != nil {
+		setting(cmd, "rules-dir", &cfg.rulesDir, cfg.project.Settings.RulesDir)
+	}
+	if cfg.rulesDir == "" {
+		return nil
+	}
+
+	migrators, err := rules.LoadDir(cfg.rulesDir)
+	if err != nil {
+		return err
+	}
+	for _, m := range migrators {
+		internal.RegisterMigrator(m.SourceResourceType(), m.SourceVersion(), m.TargetVersion(), m)
+		log.Info("Loaded rule", "type", m.SourceResourceType(), "source", m.SourceVersion(), "target", m.TargetVersion())
+	}
+	return nil
+}
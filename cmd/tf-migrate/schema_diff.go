@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cloudflare/tf-migrate/internal/scaffold"
+	"github.com/cloudflare/tf-migrate/internal/schema"
+)
+
+func newSchemaDiffCommand(cfg *config) *cobra.Command {
+	var (
+		fromFile  string
+		toFile    string
+		renames   map[string]string
+		generate  []string
+		outputDir string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "schema-diff",
+		Short: "Compare two provider schemas and generate migrator skeletons",
+		Long: `Compare the resource schemas of two provider versions, both produced by
+'terraform providers schema -json', and list per resource type the removed and added
+attributes, the blocks that became attributes (such as MaxItems:1 blocks that became
+objects), the attributes whose type changed and the schema version changes.
+
+Resource types are matched by name; give the types renamed in the target version with
+--rename. Integer and float attributes are both numbers in provider schemas, so an int
+attribute that became a float64 is not reported.
+
+With --generate, a starter migrator package is written for each given source resource
+type into --output-dir: the standard changes are applied and the others are left as TODOs,
+with a table-driven test of the required attributes.`,
+		Example: `  # List the schema changes between v4 and v5
+  tf-migrate schema-diff --from cloudflare-v4-schema.json --to cloudflare-v5-schema.json \
+    --rename cloudflare_record=cloudflare_dns_record
+
+  # Generate internal/resources/dns_record/v4_to_v5.go and its test
+  tf-migrate schema-diff --from cloudflare-v4-schema.json --to cloudflare-v5-schema.json \
+    --rename cloudflare_record=cloudflare_dns_record --generate cloudflare_record`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if fromFile == "" || toFile == "" {
+				return fmt.Errorf("--from and --to are required")
+			}
+			if cfg.sourceVersion == "" {
+				cfg.sourceVersion = "v4"
+			}
+			if cfg.targetVersion == "" {
+				cfg.targetVersion = "v5"
+			}
+
+			from, err := schema.Load(fromFile)
+			if err != nil {
+				return err
+			}
+			to, err := schema.Load(toFile)
+			if err != nil {
+				return err
+			}
+			diff := schema.Compare(from, to, renames)
+			if len(generate) == 0 {
+				return diff.WriteText(os.Stdout)
+			}
+
+			opts := scaffold.Options{Dir: outputDir, SourceVersion: cfg.sourceVersion, TargetVersion: cfg.targetVersion}
+			for _, resourceType := range generate {
+				resourceDiff, err := findResourceDiff(diff, from, resourceType)
+				if err != nil {
+					return err
+				}
+				paths, err := scaffold.Generate(resourceDiff, from.Resources[resourceDiff.SourceType], to.Resources[resourceDiff.TargetType], opts)
+				if err != nil {
+					return err
+				}
+				for _, path := range paths {
+					fmt.Printf("Wrote %s\n", path)
+				}
+			}
+			fmt.Println("Resolve the TODOs, then register the migrators in internal/registry/registry.go")
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&fromFile, "from", "", "Provider schema document of the source version (required)")
+	cmd.Flags().StringVar(&toFile, "to", "", "Provider schema document of the target version (required)")
+	cmd.Flags().StringToStringVar(&renames, "rename", nil, "Resource type renamed in the target version, as old=new (repeatable)")
+	cmd.Flags().StringSliceVar(&generate, "generate", nil, "Source resource types to generate a migrator package for")
+	cmd.Flags().StringVar(&outputDir, "output-dir", "internal/resources", "Directory of the generated migrator packages")
+
+	return cmd
+}
+
+// findResourceDiff returns the changes of a source resource type
+func findResourceDiff(diff *schema.Diff, from *schema.Provider, resourceType string) (*schema.ResourceDiff, error) {
+	for _, d := range diff.Changed {
+		if d.SourceType == resourceType {
+			return d, nil
+		}
+	}
+	if _, ok := from.Resources[resourceType]; !ok {
+		return nil, fmt.Errorf("resource type %s is not in the --from schema", resourceType)
+	}
+	for _, removed := range diff.Removed {
+		if removed == resourceType {
+			return nil, fmt.Errorf("resource type %s is not in the --to schema, give its new name with --rename", resourceType)
+		}
+	}
+	return nil, fmt.Errorf("resource type %s has no schema changes", resourceType)
+}
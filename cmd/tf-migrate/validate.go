@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/spf13/cobra"
+
+	"github.com/cloudflare/tf-migrate/internal/schema"
+)
+
+func newValidateCommand(log hclog.Logger, cfg *config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "validate",
+		Short: "Check configuration and state files against the target provider schema",
+		Long: `Validate migrated configuration and state files offline against a provider schema
+produced by 'terraform providers schema -json' for the target provider version.
+
+Every Cloudflare resource and data block is checked for unknown, read-only and missing
+required arguments, blocks that should be attributes (and the reverse) and literal values
+of the wrong type. Every state instance is checked for unknown attributes, missing required
+attributes and values that do not match the attribute type.
+
+The same checks run after a migration with 'tf-migrate migrate --schema'.`,
+		Example: `  # Dump the v5 provider schema once, from any directory using the v5 provider
+  terraform providers schema -json > cloudflare-v5-schema.json
+
+  # Validate a migrated directory and its state
+  tf-migrate --config-dir ./terraform --state-file terraform.tfstate validate --schema cloudflare-v5-schema.json`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			*cfg = projectConfig(cmd, *cfg)
+			if cfg.configDir == "" {
+				cfg.configDir = "."
+			}
+			if cfg.schemaFile == "" {
+				return fmt.Errorf("--schema is required")
+			}
+
+			validator, err := loadValidator(cfg.schemaFile)
+			if err != nil {
+				return err
+			}
+
+			files, err := findTerraformFilesWithRecursion(cfg.configDir, cfg.recursive)
+			if err != nil {
+				return fmt.Errorf("failed to list .tf files: %w", err)
+			}
+
+			var diags hcl.Diagnostics
+			for _, file := range files {
+				content, err := os.ReadFile(file)
+				if err != nil {
+					return fmt.Errorf("failed to read %s: %w", file, err)
+				}
+				diags = append(diags, validator.ValidateConfig(relativePath(cfg.configDir, file), content)...)
+			}
+			if cfg.stateFile != "" {
+				content, err := os.ReadFile(cfg.stateFile)
+				if err != nil {
+					return fmt.Errorf("failed to read state file: %w", err)
+				}
+				diags = append(diags, validator.ValidateState(cfg.stateFile, content)...)
+			}
+			log.Debug("Validated files", "files", len(files), "state", cfg.stateFile != "")
+
+			return reportValidation(diags)
+		},
+	}
+
+	cmd.Flags().StringVar(&cfg.schemaFile, "schema", "", "Provider schema document from 'terraform providers schema -json' for the target version (required)")
+	cmd.Flags().BoolVar(&cfg.recursive, "recursive", false, "Recursively validate subdirectories")
+
+	return cmd
+}
+
+// loadValidator loads the provider schema used to validate migrated files
+func loadValidator(path string) (*schema.Validator, error) {
+	provider, err := schema.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	return schema.NewValidator(provider), nil
+}
+
+// reportValidation prints schema validation diagnostics and fails if there is any error
+func reportValidation(diags hcl.Diagnostics) error {
+	if len(diags) == 0 {
+		fmt.Println("✓ All files match the provider schema")
+		return nil
+	}
+
+	fmt.Printf("\n✗ Found %d schema validation problems:\n", len(diags))
+	for _, diag := range diags {
+		location := ""
+		if diag.Subject != nil {
+			location = fmt.Sprintf("%s:%d: ", diag.Subject.Filename, diag.Subject.Start.Line)
+		}
+		fmt.Printf("  %s%s: %s\n", location, diag.Summary, diag.Detail)
+	}
+	if diags.HasErrors() {
+		return fmt.Errorf("%d schema validation errors", len(diags.Errs()))
+	}
+	return nil
+}
+
+// relativePath returns path relative to dir with forward slashes, or path if it is not inside dir
+func relativePath(dir, path string) string {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return path
+	}
+	return filepath.ToSlash(rel)
+}
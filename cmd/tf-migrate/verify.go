@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/spf13/cobra"
+
+	"github.com/cloudflare/tf-migrate/internal/verify"
+)
+
+func newVerifyCommand(log hclog.Logger, cfg *config) *cobra.Command {
+	var planFile, format string
+
+	cmd := &cobra.Command{
+		Use:   "verify",
+		Short: "Check that the plan of a migrated directory is a no-op",
+		Long: `Read a plan in JSON format, created with the target provider version after a migration,
+and report every resource it would change. Each change is classified as an in-place update,
+usually a value the migration normalised differently, a replacement, or an unexpected create
+or destroy, and mapped back to the migrator of its resource type. Attributes whose state
+value the migrated configuration does not keep are marked as not carried over.
+
+The command fails if the plan would change any resource.`,
+		Example: `  # Plan the migrated directory with the v5 provider and verify the plan
+  terraform plan -out=plan.out
+  terraform show -json plan.out > plan.json
+  tf-migrate verify --plan plan.json
+
+  # Print JSON for further processing
+  tf-migrate verify --plan plan.json --format json`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			*cfg = projectConfig(cmd, *cfg)
+			if cfg.sourceVersion == "" {
+				cfg.sourceVersion = "v4"
+			}
+			if cfg.targetVersion == "" {
+				cfg.targetVersion = "v5"
+			}
+			if planFile == "" {
+				return fmt.Errorf("--plan is required")
+			}
+			if format != inventoryFormatTable && format != inventoryFormatJSON {
+				return fmt.Errorf("invalid --format %q: must be %q or %q", format, inventoryFormatTable, inventoryFormatJSON)
+			}
+			if err := validateSingleHop(*cfg); err != nil {
+				return err
+			}
+
+			plan, err := os.ReadFile(planFile)
+			if err != nil {
+				return fmt.Errorf("failed to read plan: %w", err)
+			}
+			result, err := verify.Check(plan, cfg.sourceVersion, cfg.targetVersion, getProviders(*cfg))
+			if err != nil {
+				return fmt.Errorf("failed to verify %s: %w", planFile, err)
+			}
+			log.Debug("Verified plan", "plan", planFile, "changes", result.Summary.Changed())
+
+			if format == inventoryFormatJSON {
+				err = result.WriteJSON(os.Stdout)
+			} else {
+				err = result.WriteTable(os.Stdout)
+			}
+			if err != nil {
+				return err
+			}
+			if changed := result.Summary.Changed(); changed > 0 {
+				return fmt.Errorf("the plan is not a no-op: %d resources would change", changed)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&planFile, "plan", "", "Plan from 'terraform show -json' created after the migration (required)")
+	cmd.Flags().StringVar(&format, "format", inventoryFormatTable, "Output format: 'table' or 'json'")
+
+	return cmd
+}
@@ -0,0 +1,212 @@
+// Package address models Terraform resource addresses such as
+// module.dns["prod"].cloudflare_dns_record.www[0], and maps configuration
+// directories to the module paths that call them.
+package address
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/tidwall/gjson"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Mode is the resource mode of an address
+type Mode string
+
+const (
+	ManagedMode Mode = "managed"
+	DataMode    Mode = "data"
+)
+
+// Address is the address of a resource or of a single resource instance
+type Address struct {
+	// Module is the module instance path, e.g. module.dns["prod"].module.records.
+	// It is empty for the root module.
+	Module string
+	Mode   Mode
+	Type   string
+	Name   string
+	// Key is the instance key: an int for count, a string for for_each, nil otherwise
+	Key interface{}
+}
+
+// FromState returns the address of a state resource instance. Pass an empty
+// gjson.Result as indexKey for the address of the resource itself.
+func FromState(resource gjson.Result, indexKey gjson.Result) Address {
+	mode := ManagedMode
+	if resource.Get("mode").String() == string(DataMode) {
+		mode = DataMode
+	}
+
+	a := Address{
+		Module: resource.Get("module").String(),
+		Mode:   mode,
+		Type:   resource.Get("type").String(),
+		Name:   resource.Get("name").String(),
+	}
+	return a.WithIndexKey(indexKey)
+}
+
+// Parse parses a resource or resource instance address such as
+// module.dns["prod"].data.cloudflare_zones.all or cloudflare_dns_record.www[0]
+func Parse(s string) (Address, error) {
+	traversal, diags := hclsyntax.ParseTraversalAbs([]byte(s), "", hcl.InitialPos)
+	if diags.HasErrors() {
+		return Address{}, fmt.Errorf("invalid address %q: %s", s, diags.Error())
+	}
+
+	var a Address
+	stepName := func(i int) (string, bool) {
+		if i >= len(traversal) {
+			return "", false
+		}
+		switch step := traversal[i].(type) {
+		case hcl.TraverseRoot:
+			return step.Name, true
+		case hcl.TraverseAttr:
+			return step.Name, true
+		}
+		return "", false
+	}
+
+	// Module path: module.<name> with an optional instance key, repeated
+	i := 0
+	for {
+		if name, _ := stepName(i); name != "module" {
+			break
+		}
+		if _, ok := stepName(i + 1); !ok {
+			return Address{}, fmt.Errorf("invalid address %q: missing module name", s)
+		}
+		end := traversal[i+1].SourceRange().End.Byte
+		i += 2
+		if i < len(traversal) {
+			if _, ok := traversal[i].(hcl.TraverseIndex); ok {
+				end = traversal[i].SourceRange().End.Byte
+				i++
+			}
+		}
+		a.Module = s[:end]
+	}
+
+	a.Mode = ManagedMode
+	if name, _ := stepName(i); name == "data" {
+		a.Mode = DataMode
+		i++
+	}
+
+	resourceType, ok := stepName(i)
+	if !ok {
+		return Address{}, fmt.Errorf("invalid address %q: missing resource type", s)
+	}
+	name, ok := stepName(i + 1)
+	if !ok {
+		return Address{}, fmt.Errorf("invalid address %q: missing resource name", s)
+	}
+	a.Type, a.Name = resourceType, name
+	i += 2
+
+	if i < len(traversal) {
+		index, ok := traversal[i].(hcl.TraverseIndex)
+		if !ok || i+1 != len(traversal) {
+			return Address{}, fmt.Errorf("invalid address %q: unexpected %s", s, s[traversal[i].SourceRange().Start.Byte:])
+		}
+		switch {
+		case index.Key.Type() == cty.String:
+			a.Key = index.Key.AsString()
+		case index.Key.Type() == cty.Number:
+			n, _ := index.Key.AsBigFloat().Int64()
+			a.Key = int(n)
+		}
+	}
+
+	return a, nil
+}
+
+// WithType returns a copy of the address with a different resource type
+func (a Address) WithType(resourceType string) Address {
+	a.Type = resourceType
+	return a
+}
+
+// WithIndexKey returns a copy of the address with the instance key of a state
+// instance's index_key
+func (a Address) WithIndexKey(indexKey gjson.Result) Address {
+	a.Key = nil
+	switch indexKey.Type {
+	case gjson.Number:
+		a.Key = int(indexKey.Int())
+	case gjson.String:
+		a.Key = indexKey.String()
+	}
+	return a
+}
+
+// Resource returns the address of the resource, without the instance key
+func (a Address) Resource() Address {
+	a.Key = nil
+	return a
+}
+
+// ModulePath returns the module path of the address without module instance keys
+func (a Address) ModulePath() string {
+	return ModulePath(a.Module)
+}
+
+// String renders the address the way Terraform does
+func (a Address) String() string {
+	var sb strings.Builder
+	if a.Module != "" {
+		sb.WriteString(a.Module)
+		sb.WriteString(".")
+	}
+	if a.Mode == DataMode {
+		sb.WriteString("data.")
+	}
+	sb.WriteString(a.Type)
+	sb.WriteString(".")
+	sb.WriteString(a.Name)
+	sb.WriteString(FormatKey(a.Key))
+	return sb.String()
+}
+
+// FormatKey renders an instance key as an HCL index, e.g. [0] or ["a"]
+func FormatKey(key interface{}) string {
+	switch k := key.(type) {
+	case int:
+		return "[" + strconv.Itoa(k) + "]"
+	case string:
+		return "[" + string(hclwrite.TokensForValue(cty.StringVal(k)).Bytes()) + "]"
+	default:
+		return ""
+	}
+}
+
+// ModulePath strips the instance keys from a module instance path, so
+// module.dns["prod"].module.records becomes module.dns.module.records
+func ModulePath(module string) string {
+	if module == "" || !strings.Contains(module, "[") {
+		return module
+	}
+
+	traversal, diags := hclsyntax.ParseTraversalAbs([]byte(module), "", hcl.InitialPos)
+	if diags.HasErrors() {
+		return module
+	}
+
+	var names []string
+	for _, step := range traversal {
+		switch s := step.(type) {
+		case hcl.TraverseRoot:
+			names = append(names, s.Name)
+		case hcl.TraverseAttr:
+			names = append(names, s.Name)
+		}
+	}
+	return strings.Join(names, ".")
+}
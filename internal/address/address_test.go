@@ -0,0 +1,76 @@
+package address
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+func TestFromState(t *testing.T) {
+	tests := []struct {
+		name     string
+		resource string
+		indexKey string
+		expected string
+	}{
+		{
+			name:     "Root managed resource",
+			resource: `{"mode": "managed", "type": "cloudflare_record", "name": "www"}`,
+			expected: "cloudflare_record.www",
+		},
+		{
+			name:     "Count instance in a module",
+			resource: `{"module": "module.dns", "mode": "managed", "type": "cloudflare_record", "name": "www"}`,
+			indexKey: `0`,
+			expected: "module.dns.cloudflare_record.www[0]",
+		},
+		{
+			name:     "For each instance in a keyed module",
+			resource: `{"module": "module.dns[\"prod\"]", "mode": "managed", "type": "cloudflare_record", "name": "www"}`,
+			indexKey: `"a.b"`,
+			expected: `module.dns["prod"].cloudflare_record.www["a.b"]`,
+		},
+		{
+			name:     "Data source",
+			resource: `{"mode": "data", "type": "cloudflare_zones", "name": "all"}`,
+			expected: "data.cloudflare_zones.all",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr := FromState(gjson.Parse(tt.resource), gjson.Parse(tt.indexKey))
+			assert.Equal(t, tt.expected, addr.String())
+
+			parsed, err := Parse(tt.expected)
+			require.NoError(t, err)
+			assert.Equal(t, addr, parsed)
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	addr, err := Parse(`module.a["x.y"].module.b[1].data.cloudflare_zones.all`)
+	require.NoError(t, err)
+	assert.Equal(t, Address{
+		Module: `module.a["x.y"].module.b[1]`,
+		Mode:   DataMode,
+		Type:   "cloudflare_zones",
+		Name:   "all",
+	}, addr)
+	assert.Equal(t, "module.a.module.b", addr.ModulePath())
+	assert.Equal(t, `module.a["x.y"].module.b[1].data.cloudflare_dns_record.all`, addr.WithType("cloudflare_dns_record").String())
+
+	for _, invalid := range []string{"", "cloudflare_record", "module.dns", "cloudflare_record.www.id", `cloudflare_record.www["a"`} {
+		_, err := Parse(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestModulePath(t *testing.T) {
+	assert.Equal(t, "", ModulePath(""))
+	assert.Equal(t, "module.dns", ModulePath("module.dns"))
+	assert.Equal(t, "module.dns.module.records", ModulePath(`module.dns["prod"].module.records[0]`))
+}
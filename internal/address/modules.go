@@ -0,0 +1,103 @@
+package address
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Modules maps configuration directories to the module paths that call them.
+// A directory called by several module blocks has several paths; the root
+// directory has the single path "".
+type Modules map[string][]string
+
+// maxModuleDepth stops the discovery of module calls that refer back to a parent directory
+const maxModuleDepth = 32
+
+// DiscoverModules follows the local module calls (sources starting with ./ or ../)
+// from the root module directory and returns the module paths of every directory found.
+// Files that cannot be read or parsed are skipped; they are reported by the pipeline.
+func DiscoverModules(rootDir string) Modules {
+	modules := make(Modules)
+	modules.discover(filepath.Clean(rootDir), "", 0)
+	for dir := range modules {
+		sort.Strings(modules[dir])
+	}
+	return modules
+}
+
+// For returns the module paths of a configuration directory. It returns false if the
+// directory is not called from the root module.
+func (m Modules) For(dir string) ([]string, bool) {
+	paths, ok := m[filepath.Clean(dir)]
+	return paths, ok
+}
+
+func (m Modules) discover(dir, modulePath string, depth int) {
+	for _, existing := range m[dir] {
+		if existing == modulePath {
+			return
+		}
+	}
+	m[dir] = append(m[dir], modulePath)
+
+	entries, err := os.ReadDir(dir)
+	if err != nil || depth >= maxModuleDepth {
+		return
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".tf") {
+			continue
+		}
+		for name, source := range localModuleCalls(filepath.Join(dir, entry.Name())) {
+			childPath := "module." + name
+			if modulePath != "" {
+				childPath = modulePath + "." + childPath
+			}
+			m.discover(filepath.Join(dir, source), childPath, depth+1)
+		}
+	}
+}
+
+// localModuleCalls returns the name and source of every module block with a local source in a file
+func localModuleCalls(path string) map[string]string {
+	calls := make(map[string]string)
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return calls
+	}
+	file, diags := hclsyntax.ParseConfig(content, path, hcl.InitialPos)
+	if diags.HasErrors() {
+		return calls
+	}
+	body, ok := file.Body.(*hclsyntax.Body)
+	if !ok {
+		return calls
+	}
+
+	for _, block := range body.Blocks {
+		if block.Type != "module" || len(block.Labels) != 1 {
+			continue
+		}
+		attr, ok := block.Body.Attributes["source"]
+		if !ok {
+			continue
+		}
+		value, diags := attr.Expr.Value(nil)
+		if diags.HasErrors() || !value.Type().Equals(cty.String) || value.IsNull() {
+			continue
+		}
+		source := value.AsString()
+		if strings.HasPrefix(source, "./") || strings.HasPrefix(source, "../") {
+			calls[block.Labels[0]] = filepath.FromSlash(source)
+		}
+	}
+	return calls
+}
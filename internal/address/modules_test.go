@@ -0,0 +1,65 @@
+package address
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiscoverModules(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"main.tf": `module "prod" {
+  source = "./modules/dns"
+}
+
+module "staging" {
+  source = "./modules/dns"
+}
+
+module "registry" {
+  source = "cloudflare/dns/cloudflare"
+}
+`,
+		"modules/dns/main.tf": `module "records" {
+  source = "../records"
+}
+`,
+		"modules/records/main.tf": `resource "cloudflare_record" "www" {}`,
+		"modules/unused/main.tf":  `resource "cloudflare_record" "www" {}`,
+		"modules/loop/main.tf": `module "self" {
+  source = "./"
+}
+`,
+	}
+	for path, content := range files {
+		full := filepath.Join(root, path)
+		require.NoError(t, os.MkdirAll(filepath.Dir(full), 0755))
+		require.NoError(t, os.WriteFile(full, []byte(content), 0644))
+	}
+
+	modules := DiscoverModules(root)
+
+	paths, ok := modules.For(root)
+	require.True(t, ok)
+	assert.Equal(t, []string{""}, paths)
+
+	paths, ok = modules.For(filepath.Join(root, "modules", "dns"))
+	require.True(t, ok)
+	assert.Equal(t, []string{"module.prod", "module.staging"}, paths)
+
+	paths, ok = modules.For(filepath.Join(root, "modules", "records") + "/")
+	require.True(t, ok)
+	assert.Equal(t, []string{"module.prod.module.records", "module.staging.module.records"}, paths)
+
+	_, ok = modules.For(filepath.Join(root, "modules", "unused"))
+	assert.False(t, ok)
+
+	// A module calling itself stops at the maximum depth
+	loop := DiscoverModules(filepath.Join(root, "modules", "loop"))
+	paths, _ = loop.For(filepath.Join(root, "modules", "loop"))
+	assert.Len(t, paths, maxModuleDepth+1)
+}
@@ -0,0 +1,381 @@
+// Package backup keeps the original content of every file a migration overwrites in a
+// timestamped run directory, together with a manifest of checksums, so a run can be
+// rolled back safely as long as the migrated files have not been edited since.
+package backup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Dir is the directory, relative to the configuration directory, holding one directory per run
+const Dir = ".tf-migrate/backups"
+
+// ManifestFile is the name of the manifest in a run directory
+const ManifestFile = "manifest.json"
+
+// idFormat is the UTC timestamp used as run ID
+const idFormat = "20060102T150405Z"
+
+// Manifest describes the files changed by a single migration run
+type Manifest struct {
+	ID            string     `json:"id"`
+	CreatedAt     time.Time  `json:"created_at"`
+	ToolVersion   string     `json:"tool_version"`
+	SourceVersion string     `json:"source_version"`
+	TargetVersion string     `json:"target_version"`
+	RolledBackAt  *time.Time `json:"rolled_back_at,omitempty"`
+	Files         []File     `json:"files"`
+}
+
+// File is a file overwritten or created by a migration run
+type File struct {
+	// Path is the absolute path of the file
+	Path string `json:"path"`
+	// Backup is the copy of the original content, relative to the run directory.
+	// It is empty for files created by the run.
+	Backup         string `json:"backup,omitempty"`
+	OriginalSHA256 string `json:"original_sha256,omitempty"`
+	// MigratedSHA256 is the checksum of the file when the run finished, empty if the
+	// file did not exist
+	MigratedSHA256 string `json:"migrated_sha256,omitempty"`
+	Created        bool   `json:"created,omitempty"`
+	// Mode is the permission bits of the original file, restored on rollback. Manifests
+	// written without it restore files with 0644.
+	Mode fs.FileMode `json:"mode,omitempty"`
+}
+
+// Run records the backups of a migration run. The run directory is only created when
+// the first file is added, so runs that change nothing leave no trace.
+type Run struct {
+	dir      string
+	manifest Manifest
+	index    map[string]int
+	created  bool
+}
+
+// NewRun starts a backup run under <configDir>/.tf-migrate/backups
+func NewRun(configDir, toolVersion, sourceVersion, targetVersion string, now time.Time) *Run {
+	now = now.UTC()
+	return &Run{
+		dir: filepath.Join(configDir, Dir),
+		manifest: Manifest{
+			ID:            now.Format(idFormat),
+			CreatedAt:     now,
+			ToolVersion:   toolVersion,
+			SourceVersion: sourceVersion,
+			TargetVersion: targetVersion,
+			Files:         []File{},
+		},
+		index: make(map[string]int),
+	}
+}
+
+// ID returns the run ID
+func (r *Run) ID() string {
+	return r.manifest.ID
+}
+
+// Dir returns the run directory
+func (r *Run) Dir() string {
+	return filepath.Join(r.dir, r.manifest.ID)
+}
+
+// Add saves the original content of a file before it is overwritten. Files already
+// added are ignored, so the first content recorded is always the original one.
+func (r *Run) Add(path string, content []byte) error {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	if _, ok := r.index[path]; ok {
+		return nil
+	}
+	if err := r.init(); err != nil {
+		return err
+	}
+
+	// The backup keeps the mode of the original file, which may hold secrets like a
+	// state file does
+	var originalMode fs.FileMode
+	if info, err := os.Stat(path); err == nil {
+		originalMode = info.Mode().Perm()
+	}
+	mode := originalMode
+	if mode == 0 {
+		mode = 0600
+	}
+
+	name := fmt.Sprintf("files/%03d-%s", len(r.manifest.Files)+1, filepath.Base(path))
+	backupPath := filepath.Join(r.Dir(), filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(backupPath), 0700); err != nil {
+		return fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	if err := os.WriteFile(backupPath, content, mode); err != nil {
+		return fmt.Errorf("failed to create backup %s: %w", backupPath, err)
+	}
+
+	file := File{
+		Path:           path,
+		Backup:         name,
+		OriginalSHA256: checksum(content),
+		Mode:           originalMode,
+	}
+	r.index[path] = len(r.manifest.Files)
+	r.manifest.Files = append(r.manifest.Files, file)
+	return nil
+}
+
+// AddFile saves the original content of a file that may not exist yet. A missing file
+// is recorded as created by the run and removed on rollback.
+func (r *Run) AddFile(path string) error {
+	content, err := os.ReadFile(path)
+	if err == nil {
+		return r.Add(path, content)
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	path, err = filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	if _, ok := r.index[path]; ok {
+		return nil
+	}
+	if err := r.init(); err != nil {
+		return err
+	}
+	r.index[path] = len(r.manifest.Files)
+	r.manifest.Files = append(r.manifest.Files, File{Path: path, Created: true})
+	return nil
+}
+
+// Finish records the checksum of every file as it is now and writes the manifest. It
+// is meant to run even when the migration fails, so partial runs can be rolled back.
+// It does nothing if no file was added.
+func (r *Run) Finish() (*Manifest, error) {
+	if len(r.manifest.Files) == 0 {
+		return nil, nil
+	}
+	for i := range r.manifest.Files {
+		sum, err := fileChecksum(r.manifest.Files[i].Path)
+		if err != nil {
+			return nil, err
+		}
+		r.manifest.Files[i].MigratedSHA256 = sum
+	}
+	if err := writeManifest(r.Dir(), &r.manifest); err != nil {
+		return nil, err
+	}
+	return &r.manifest, nil
+}
+
+// init creates the run directory, picking a unique ID if a run with the same
+// timestamp already exists
+func (r *Run) init() error {
+	if r.created {
+		return nil
+	}
+	if err := os.MkdirAll(r.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	base := r.manifest.ID
+	for i := 2; ; i++ {
+		err := os.Mkdir(r.Dir(), 0700)
+		if err == nil {
+			r.created = true
+			return nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return fmt.Errorf("failed to create backup directory: %w", err)
+		}
+		r.manifest.ID = fmt.Sprintf("%s-%d", base, i)
+	}
+}
+
+// List returns the manifests of all runs under the configuration directory, oldest first
+func List(configDir string) ([]*Manifest, error) {
+	dir := filepath.Join(configDir, Dir)
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list backups: %w", err)
+	}
+
+	var manifests []*Manifest
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		manifest, err := readManifest(filepath.Join(dir, entry.Name()))
+		if errors.Is(err, fs.ErrNotExist) {
+			// The run was interrupted before its manifest was written
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, manifest)
+	}
+	sort.SliceStable(manifests, func(i, j int) bool {
+		return manifests[i].CreatedAt.Before(manifests[j].CreatedAt)
+	})
+	return manifests, nil
+}
+
+// ConflictError is returned by Rollback when files changed after the migration
+type ConflictError struct {
+	Files []string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%d files changed after the migration, refusing to roll back:\n  %s",
+		len(e.Files), strings.Join(e.Files, "\n  "))
+}
+
+// Rollback restores the original files of a run and removes the files it created. If
+// id is empty the latest run that has not been rolled back is used. Nothing is restored
+// if any file changed after the migration; a *ConflictError lists those files.
+func Rollback(configDir, id string, now time.Time) (*Manifest, error) {
+	manifest, err := find(configDir, id)
+	if err != nil {
+		return nil, err
+	}
+	if manifest.RolledBackAt != nil {
+		return nil, fmt.Errorf("backup run %s was already rolled back at %s", manifest.ID, manifest.RolledBackAt.Format(time.RFC3339))
+	}
+	runDir := filepath.Join(configDir, Dir, manifest.ID)
+
+	// Check every file before touching any of them
+	conflict := &ConflictError{}
+	for _, file := range manifest.Files {
+		sum, err := fileChecksum(file.Path)
+		if err != nil {
+			return nil, err
+		}
+		if sum != file.MigratedSHA256 {
+			conflict.Files = append(conflict.Files, file.Path)
+		}
+	}
+	if len(conflict.Files) > 0 {
+		return nil, conflict
+	}
+
+	for _, file := range manifest.Files {
+		if file.Created {
+			if err := os.Remove(file.Path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return nil, fmt.Errorf("failed to remove %s: %w", file.Path, err)
+			}
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(runDir, filepath.FromSlash(file.Backup)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read backup of %s: %w", file.Path, err)
+		}
+		if checksum(content) != file.OriginalSHA256 {
+			return nil, fmt.Errorf("backup of %s does not match its checksum", file.Path)
+		}
+		if err := os.MkdirAll(filepath.Dir(file.Path), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create directory for %s: %w", file.Path, err)
+		}
+		mode := file.Mode
+		if mode == 0 {
+			mode = 0644
+		}
+		if err := os.WriteFile(file.Path, content, mode); err != nil {
+			return nil, fmt.Errorf("failed to restore %s: %w", file.Path, err)
+		}
+		// WriteFile only sets the mode of new files
+		if err := os.Chmod(file.Path, mode); err != nil {
+			return nil, fmt.Errorf("failed to restore the permissions of %s: %w", file.Path, err)
+		}
+	}
+
+	rolledBackAt := now.UTC()
+	manifest.RolledBackAt = &rolledBackAt
+	if err := writeManifest(runDir, manifest); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// find returns the manifest of a run, or of the latest run not rolled back if id is empty
+func find(configDir, id string) (*Manifest, error) {
+	if id != "" {
+		// The ID is joined into the path of the run directory, so it must name a
+		// directory directly in Dir
+		if id == "." || id == ".." || strings.ContainsAny(id, `/\`) || filepath.Base(id) != id {
+			return nil, fmt.Errorf("invalid backup run ID %q", id)
+		}
+		manifest, err := readManifest(filepath.Join(configDir, Dir, id))
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("backup run %s not found in %s", id, filepath.Join(configDir, Dir))
+		}
+		return manifest, err
+	}
+
+	manifests, err := List(configDir)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(manifests) - 1; i >= 0; i-- {
+		if manifests[i].RolledBackAt == nil {
+			return manifests[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no backup runs to roll back in %s", filepath.Join(configDir, Dir))
+}
+
+func readManifest(runDir string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(runDir, ManifestFile))
+	if err != nil {
+		return nil, err
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid backup manifest in %s: %w", runDir, err)
+	}
+	return &manifest, nil
+}
+
+func writeManifest(runDir string, manifest *Manifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal backup manifest: %w", err)
+	}
+	path := filepath.Join(runDir, ManifestFile)
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write backup manifest %s: %w", path, err)
+	}
+	return nil
+}
+
+func checksum(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// fileChecksum returns the checksum of a file, or an empty string if it does not exist
+func fileChecksum(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return checksum(content), nil
+}
@@ -0,0 +1,182 @@
+package backup
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/tf-migrate/internal/testhelpers"
+)
+
+var runTime = time.Date(2026, 10, 17, 3, 16, 2, 0, time.UTC)
+
+// migrate simulates a migration run that rewrites main.tf and the state and creates moved.tf
+func migrate(t *testing.T, dir string, now time.Time) *Manifest {
+	t.Helper()
+	run := NewRun(dir, "0.1.0", "v4", "v5", now)
+
+	for _, name := range []string{"main.tf", "terraform.tfstate"} {
+		path := filepath.Join(dir, name)
+		require.NoError(t, run.Add(path, []byte(testhelpers.ReadFile(t, path))))
+		testhelpers.WriteFile(t, path, testhelpers.ReadFile(t, path)+"# migrated\n")
+	}
+	require.NoError(t, run.AddFile(filepath.Join(dir, "moved.tf")))
+	testhelpers.WriteFile(t, filepath.Join(dir, "moved.tf"), "moved {}\n")
+
+	manifest, err := run.Finish()
+	require.NoError(t, err)
+	return manifest
+}
+
+func TestRunAndRollback(t *testing.T) {
+	dir := t.TempDir()
+	testhelpers.WriteFile(t, filepath.Join(dir, "main.tf"), "resource \"cloudflare_record\" \"www\" {}\n")
+	testhelpers.WriteFile(t, filepath.Join(dir, "terraform.tfstate"), "{}\n")
+
+	manifest := migrate(t, dir, runTime)
+	assert.Equal(t, "20261017T031602Z", manifest.ID)
+	assert.Equal(t, "0.1.0", manifest.ToolVersion)
+	require.Len(t, manifest.Files, 3)
+	assert.Equal(t, "files/001-main.tf", manifest.Files[0].Backup)
+	assert.NotEqual(t, manifest.Files[0].OriginalSHA256, manifest.Files[0].MigratedSHA256)
+	assert.True(t, manifest.Files[2].Created)
+	assert.Empty(t, manifest.Files[2].OriginalSHA256)
+
+	manifests, err := List(dir)
+	require.NoError(t, err)
+	require.Len(t, manifests, 1)
+	assert.Equal(t, manifest.Files, manifests[0].Files)
+
+	rolledBack, err := Rollback(dir, "", runTime.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, "20261017T031602Z", rolledBack.ID)
+	assert.Equal(t, "resource \"cloudflare_record\" \"www\" {}\n", testhelpers.ReadFile(t, filepath.Join(dir, "main.tf")))
+	assert.Equal(t, "{}\n", testhelpers.ReadFile(t, filepath.Join(dir, "terraform.tfstate")))
+	assert.NoFileExists(t, filepath.Join(dir, "moved.tf"))
+
+	_, err = Rollback(dir, "20261017T031602Z", runTime)
+	assert.ErrorContains(t, err, "already rolled back")
+	_, err = Rollback(dir, "", runTime)
+	assert.ErrorContains(t, err, "no backup runs to roll back")
+}
+
+func TestRollbackRefusesEditedFiles(t *testing.T) {
+	dir := t.TempDir()
+	testhelpers.WriteFile(t, filepath.Join(dir, "main.tf"), "original\n")
+	testhelpers.WriteFile(t, filepath.Join(dir, "terraform.tfstate"), "{}\n")
+	migrate(t, dir, runTime)
+
+	testhelpers.WriteFile(t, filepath.Join(dir, "main.tf"), "edited after the migration\n")
+	require.NoError(t, os.Remove(filepath.Join(dir, "moved.tf")))
+
+	_, err := Rollback(dir, "", runTime)
+	var conflict *ConflictError
+	require.ErrorAs(t, err, &conflict)
+	assert.Equal(t, []string{filepath.Join(dir, "main.tf"), filepath.Join(dir, "moved.tf")}, conflict.Files)
+
+	// Nothing was restored
+	assert.Equal(t, "edited after the migration\n", testhelpers.ReadFile(t, filepath.Join(dir, "main.tf")))
+	assert.Equal(t, "{}\n# migrated\n", testhelpers.ReadFile(t, filepath.Join(dir, "terraform.tfstate")))
+}
+
+func TestRollbackRestoresMode(t *testing.T) {
+	dir := t.TempDir()
+	testhelpers.WriteFile(t, filepath.Join(dir, "main.tf"), "original\n")
+	testhelpers.WriteFile(t, filepath.Join(dir, "terraform.tfstate"), "{}\n")
+	require.NoError(t, os.Chmod(filepath.Join(dir, "terraform.tfstate"), 0600))
+
+	manifest := migrate(t, dir, runTime)
+	assert.Equal(t, fs.FileMode(0644), manifest.Files[0].Mode)
+	assert.Equal(t, fs.FileMode(0600), manifest.Files[1].Mode)
+	assert.Zero(t, manifest.Files[2].Mode)
+
+	// The permissions changed after the migration are those of the original after a rollback
+	require.NoError(t, os.Chmod(filepath.Join(dir, "terraform.tfstate"), 0644))
+	_, err := Rollback(dir, "", runTime)
+	require.NoError(t, err)
+
+	info, err := os.Stat(filepath.Join(dir, "terraform.tfstate"))
+	require.NoError(t, err)
+	assert.Equal(t, fs.FileMode(0600), info.Mode().Perm())
+	assert.Equal(t, "{}\n", testhelpers.ReadFile(t, filepath.Join(dir, "terraform.tfstate")))
+}
+
+func TestBackupsKeepMode(t *testing.T) {
+	dir := t.TempDir()
+	testhelpers.WriteFile(t, filepath.Join(dir, "main.tf"), "original\n")
+	testhelpers.WriteFile(t, filepath.Join(dir, "terraform.tfstate"), "{}\n")
+	require.NoError(t, os.Chmod(filepath.Join(dir, "terraform.tfstate"), 0600))
+
+	manifest := migrate(t, dir, runTime)
+	runDir := filepath.Join(dir, Dir, manifest.ID)
+
+	// The state backup is no more readable than the state itself
+	info, err := os.Stat(filepath.Join(runDir, filepath.FromSlash(manifest.Files[1].Backup)))
+	require.NoError(t, err)
+	assert.Equal(t, fs.FileMode(0600), info.Mode().Perm())
+	info, err = os.Stat(filepath.Join(runDir, filepath.FromSlash(manifest.Files[0].Backup)))
+	require.NoError(t, err)
+	assert.Equal(t, fs.FileMode(0644), info.Mode().Perm())
+
+	info, err = os.Stat(runDir)
+	require.NoError(t, err)
+	assert.Equal(t, fs.FileMode(0700), info.Mode().Perm())
+}
+
+func TestRunsDoNotOverwriteEachOther(t *testing.T) {
+	dir := t.TempDir()
+	testhelpers.WriteFile(t, filepath.Join(dir, "main.tf"), "v1\n")
+	testhelpers.WriteFile(t, filepath.Join(dir, "terraform.tfstate"), "{}\n")
+
+	first := migrate(t, dir, runTime)
+	second := migrate(t, dir, runTime)
+	assert.Equal(t, "20261017T031602Z", first.ID)
+	assert.Equal(t, "20261017T031602Z-2", second.ID)
+
+	// The latest run is rolled back first, then the one before it
+	manifest, err := Rollback(dir, "", runTime)
+	require.NoError(t, err)
+	assert.Equal(t, second.ID, manifest.ID)
+	assert.Equal(t, "v1\n# migrated\n", testhelpers.ReadFile(t, filepath.Join(dir, "main.tf")))
+
+	assert.FileExists(t, filepath.Join(dir, "moved.tf"), "moved.tf existed before the second run")
+
+	manifest, err = Rollback(dir, "", runTime)
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, manifest.ID)
+	assert.Equal(t, "v1\n", testhelpers.ReadFile(t, filepath.Join(dir, "main.tf")))
+}
+
+func TestRunWithoutFiles(t *testing.T) {
+	dir := t.TempDir()
+	manifest, err := NewRun(dir, "0.1.0", "v4", "v5", runTime).Finish()
+	require.NoError(t, err)
+	assert.Nil(t, manifest)
+	assert.NoDirExists(t, filepath.Join(dir, ".tf-migrate"))
+
+	_, err = Rollback(dir, "missing", runTime)
+	assert.ErrorContains(t, err, "backup run missing not found")
+}
+
+func TestRollbackRejectsInvalidIDs(t *testing.T) {
+	dir := t.TempDir()
+	testhelpers.WriteFile(t, filepath.Join(dir, "main.tf"), "v1\n")
+	testhelpers.WriteFile(t, filepath.Join(dir, "terraform.tfstate"), "{}\n")
+	manifest := migrate(t, dir, runTime)
+
+	// A manifest outside of the backups directory must not be used
+	data, err := os.ReadFile(filepath.Join(dir, Dir, manifest.ID, ManifestFile))
+	require.NoError(t, err)
+	testhelpers.WriteFile(t, filepath.Join(dir, ".tf-migrate", "outside", ManifestFile), string(data))
+
+	for _, id := range []string{"../outside", "..", ".", "runs/" + manifest.ID, `..\outside`} {
+		_, err := Rollback(dir, id, runTime)
+		assert.ErrorContains(t, err, "invalid backup run ID", id)
+	}
+	assert.Equal(t, "v1\n# migrated\n", testhelpers.ReadFile(t, filepath.Join(dir, "main.tf")))
+}
@@ -0,0 +1,44 @@
+package accounts
+
+import (
+	"github.com/hashicorp/hcl/v2/hclwrite"
+
+	"github.com/cloudflare/tf-migrate/internal"
+	"github.com/cloudflare/tf-migrate/internal/transform"
+)
+
+// V4ToV5Migrator handles migration of the cloudflare_accounts data source from v4 to v5
+type V4ToV5Migrator struct{}
+
+func NewV4ToV5Migrator() transform.DataSourceTransformer {
+	migrator := &V4ToV5Migrator{}
+	internal.RegisterDataSourceMigrator("cloudflare_accounts", "v4", "v5", migrator)
+	return migrator
+}
+
+func (m *V4ToV5Migrator) GetDataSourceType() string {
+	return "cloudflare_accounts"
+}
+
+func (m *V4ToV5Migrator) CanHandle(dataSourceType string) bool {
+	return dataSourceType == "cloudflare_accounts"
+}
+
+// GetResourceRename implements the ResourceRenamer interface
+// The data source keeps its name in v5
+func (m *V4ToV5Migrator) GetResourceRename() (string, string) {
+	return "cloudflare_accounts", "cloudflare_accounts"
+}
+
+// GetAttributeRenames implements the AttributeRenamer interface
+func (m *V4ToV5Migrator) GetAttributeRenames() map[string]string {
+	return map[string]string{"accounts": "result"}
+}
+
+func (m *V4ToV5Migrator) TransformConfig(ctx *transform.Context, block *hclwrite.Block) (*transform.TransformResult, error) {
+	// The name filter is unchanged, only the accounts attribute is renamed to result
+	return &transform.TransformResult{
+		Blocks:         []*hclwrite.Block{block},
+		RemoveOriginal: false,
+	}, nil
+}
@@ -0,0 +1,34 @@
+package accounts
+
+import (
+	"testing"
+
+	"github.com/cloudflare/tf-migrate/internal/testhelpers"
+)
+
+func TestV4ToV5Transformation(t *testing.T) {
+	migrator := NewV4ToV5Migrator()
+
+	tests := []testhelpers.ConfigTestCase{
+		{
+			Name: "Accounts filtered by name",
+			Input: `
+data "cloudflare_accounts" "example" {
+  name = "Example Account"
+}`,
+			Expected: `data "cloudflare_accounts" "example" {
+  name = "Example Account"
+}`,
+		},
+		{
+			Name: "All accounts",
+			Input: `
+data "cloudflare_accounts" "all" {
+}`,
+			Expected: `data "cloudflare_accounts" "all" {
+}`,
+		},
+	}
+
+	testhelpers.RunDataSourceConfigTransformTests(t, tests, migrator)
+}
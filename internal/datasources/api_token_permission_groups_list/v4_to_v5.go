@@ -0,0 +1,51 @@
+package api_token_permission_groups_list
+
+import (
+	"github.com/hashicorp/hcl/v2/hclwrite"
+
+	"github.com/cloudflare/tf-migrate/internal"
+	"github.com/cloudflare/tf-migrate/internal/transform"
+	tfhcl "github.com/cloudflare/tf-migrate/internal/transform/hcl"
+)
+
+// V4ToV5Migrator handles migration of the cloudflare_api_token_permission_groups data source
+// to cloudflare_api_token_permission_groups_list
+type V4ToV5Migrator struct{}
+
+func NewV4ToV5Migrator() transform.DataSourceTransformer {
+	migrator := &V4ToV5Migrator{}
+	// Register the OLD (v4) data source name
+	internal.RegisterDataSourceMigrator("cloudflare_api_token_permission_groups", "v4", "v5", migrator)
+	return migrator
+}
+
+func (m *V4ToV5Migrator) GetDataSourceType() string {
+	// Return the NEW (v5) data source name
+	return "cloudflare_api_token_permission_groups_list"
+}
+
+func (m *V4ToV5Migrator) CanHandle(dataSourceType string) bool {
+	return dataSourceType == "cloudflare_api_token_permission_groups"
+}
+
+// GetResourceRename implements the ResourceRenamer interface
+func (m *V4ToV5Migrator) GetResourceRename() (string, string) {
+	return "cloudflare_api_token_permission_groups", "cloudflare_api_token_permission_groups_list"
+}
+
+// GetRemovedAttributes implements the AttributeRemover interface
+// The v4 name to ID maps are replaced by a result list of {id, name, scopes} objects,
+// so lookups such as .zone["DNS Read"] have to be rewritten by hand.
+func (m *V4ToV5Migrator) GetRemovedAttributes() []string {
+	return []string{"permissions", "zone", "account", "user", "r2"}
+}
+
+func (m *V4ToV5Migrator) TransformConfig(ctx *transform.Context, block *hclwrite.Block) (*transform.TransformResult, error) {
+	// The v4 data source has no arguments, so only the type changes
+	tfhcl.RenameResourceType(block, "cloudflare_api_token_permission_groups", "cloudflare_api_token_permission_groups_list")
+
+	return &transform.TransformResult{
+		Blocks:         []*hclwrite.Block{block},
+		RemoveOriginal: false,
+	}, nil
+}
@@ -0,0 +1,24 @@
+package api_token_permission_groups_list
+
+import (
+	"testing"
+
+	"github.com/cloudflare/tf-migrate/internal/testhelpers"
+)
+
+func TestV4ToV5Transformation(t *testing.T) {
+	migrator := NewV4ToV5Migrator()
+
+	tests := []testhelpers.ConfigTestCase{
+		{
+			Name: "Rename data source type",
+			Input: `
+data "cloudflare_api_token_permission_groups" "all" {
+}`,
+			Expected: `data "cloudflare_api_token_permission_groups_list" "all" {
+}`,
+		},
+	}
+
+	testhelpers.RunDataSourceConfigTransformTests(t, tests, migrator)
+}
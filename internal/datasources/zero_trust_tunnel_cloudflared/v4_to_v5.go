@@ -0,0 +1,79 @@
+package zero_trust_tunnel_cloudflared
+
+import (
+	"github.com/hashicorp/hcl/v2/hclwrite"
+
+	"github.com/cloudflare/tf-migrate/internal"
+	"github.com/cloudflare/tf-migrate/internal/transform"
+	tfhcl "github.com/cloudflare/tf-migrate/internal/transform/hcl"
+)
+
+// V4ToV5Migrator handles migration of the cloudflare_tunnel data source to
+// cloudflare_zero_trust_tunnel_cloudflared
+type V4ToV5Migrator struct{}
+
+func NewV4ToV5Migrator() transform.DataSourceTransformer {
+	migrator := &V4ToV5Migrator{}
+	// Register the OLD (v4) data source name
+	internal.RegisterDataSourceMigrator("cloudflare_tunnel", "v4", "v5", migrator)
+	return migrator
+}
+
+func (m *V4ToV5Migrator) GetDataSourceType() string {
+	// Return the NEW (v5) data source name
+	return "cloudflare_zero_trust_tunnel_cloudflared"
+}
+
+func (m *V4ToV5Migrator) CanHandle(dataSourceType string) bool {
+	return dataSourceType == "cloudflare_tunnel"
+}
+
+// GetResourceRename implements the ResourceRenamer interface
+func (m *V4ToV5Migrator) GetResourceRename() (string, string) {
+	return "cloudflare_tunnel", "cloudflare_zero_trust_tunnel_cloudflared"
+}
+
+// GetAttributeRenames implements the AttributeRenamer interface
+func (m *V4ToV5Migrator) GetAttributeRenames() map[string]string {
+	return map[string]string{"tunnel_type": "tun_type"}
+}
+
+// GetRemovedAttributes implements the AttributeRemover interface
+func (m *V4ToV5Migrator) GetRemovedAttributes() []string {
+	return []string{"remote_config"}
+}
+
+// TransformConfig renames the data source and moves the lookup arguments into the filter object:
+//
+//	data "cloudflare_tunnel" "t" {        data "cloudflare_zero_trust_tunnel_cloudflared" "t" {
+//	  account_id = var.account_id           account_id = var.account_id
+//	  name       = "my-tunnel"       =>     filter = {
+//	  is_deleted = false                      name       = "my-tunnel"
+//	}                                         is_deleted = false
+//	                                        }
+//	                                      }
+func (m *V4ToV5Migrator) TransformConfig(ctx *transform.Context, block *hclwrite.Block) (*transform.TransformResult, error) {
+	tfhcl.RenameResourceType(block, "cloudflare_tunnel", "cloudflare_zero_trust_tunnel_cloudflared")
+
+	body := block.Body()
+	var filter []hclwrite.ObjectAttrTokens
+	for _, name := range []string{"name", "is_deleted"} {
+		attr := body.GetAttribute(name)
+		if attr == nil {
+			continue
+		}
+		filter = append(filter, hclwrite.ObjectAttrTokens{
+			Name:  hclwrite.TokensForIdentifier(name),
+			Value: attr.Expr().BuildTokens(nil),
+		})
+		body.RemoveAttribute(name)
+	}
+	if len(filter) > 0 {
+		body.SetAttributeRaw("filter", hclwrite.TokensForObject(filter))
+	}
+
+	return &transform.TransformResult{
+		Blocks:         []*hclwrite.Block{block},
+		RemoveOriginal: false,
+	}, nil
+}
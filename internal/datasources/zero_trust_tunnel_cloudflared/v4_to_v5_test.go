@@ -0,0 +1,46 @@
+package zero_trust_tunnel_cloudflared
+
+import (
+	"testing"
+
+	"github.com/cloudflare/tf-migrate/internal/testhelpers"
+)
+
+func TestV4ToV5Transformation(t *testing.T) {
+	migrator := NewV4ToV5Migrator()
+
+	tests := []testhelpers.ConfigTestCase{
+		{
+			Name: "Lookup by name",
+			Input: `
+data "cloudflare_tunnel" "example" {
+  account_id = "f037e56e89293a057740de681ac9abbe"
+  name       = "my-tunnel"
+}`,
+			Expected: `data "cloudflare_zero_trust_tunnel_cloudflared" "example" {
+  account_id = "f037e56e89293a057740de681ac9abbe"
+  filter = {
+    name = "my-tunnel"
+  }
+}`,
+		},
+		{
+			Name: "Lookup by name including deleted tunnels",
+			Input: `
+data "cloudflare_tunnel" "example" {
+  account_id = var.account_id
+  name       = var.tunnel_name
+  is_deleted = true
+}`,
+			Expected: `data "cloudflare_zero_trust_tunnel_cloudflared" "example" {
+  account_id = var.account_id
+  filter = {
+    name       = var.tunnel_name
+    is_deleted = true
+  }
+}`,
+		},
+	}
+
+	testhelpers.RunDataSourceConfigTransformTests(t, tests, migrator)
+}
@@ -0,0 +1,136 @@
+package zones
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+
+	"github.com/cloudflare/tf-migrate/internal"
+	"github.com/cloudflare/tf-migrate/internal/transform"
+	tfhcl "github.com/cloudflare/tf-migrate/internal/transform/hcl"
+)
+
+// V4ToV5Migrator handles migration of the cloudflare_zones data source from v4 to v5
+type V4ToV5Migrator struct{}
+
+func NewV4ToV5Migrator() transform.DataSourceTransformer {
+	migrator := &V4ToV5Migrator{}
+	internal.RegisterDataSourceMigrator("cloudflare_zones", "v4", "v5", migrator)
+	return migrator
+}
+
+func (m *V4ToV5Migrator) GetDataSourceType() string {
+	return "cloudflare_zones"
+}
+
+func (m *V4ToV5Migrator) CanHandle(dataSourceType string) bool {
+	return dataSourceType == "cloudflare_zones"
+}
+
+// GetResourceRename implements the ResourceRenamer interface
+// The data source keeps its name in v5
+func (m *V4ToV5Migrator) GetResourceRename() (string, string) {
+	return "cloudflare_zones", "cloudflare_zones"
+}
+
+// GetAttributeRenames implements the AttributeRenamer interface
+func (m *V4ToV5Migrator) GetAttributeRenames() map[string]string {
+	return map[string]string{"zones": "result"}
+}
+
+// TransformConfig hoists the v4 filter block to top-level arguments:
+//
+//	filter {                          account = { id = var.account_id }
+//	  account_id  = var.account_id    name    = "contains:example"
+//	  name        = "example"    =>   status  = "active"
+//	  lookup_type = "contains"
+//	  status      = "active"
+//	}
+//
+// The regular expression match and the paused filter have no v5 equivalent and
+// are dropped with a warning.
+func (m *V4ToV5Migrator) TransformConfig(ctx *transform.Context, block *hclwrite.Block) (*transform.TransformResult, error) {
+	body := block.Body()
+
+	var filter *hclwrite.Block
+	if filters := tfhcl.FindBlocksByTypeWithDynamic(body, "filter"); len(filters) > 0 {
+		filter = filters[0]
+	}
+	if filter != nil && tfhcl.DynamicBlockOf(body, filter) != nil {
+		// The filter attributes move to the data source itself, which a dynamic block
+		// cannot be rewritten to
+		ctx.Diagnostics = append(ctx.Diagnostics, &hcl.Diagnostic{
+			Severity: hcl.DiagWarning,
+			Summary:  "Dynamic cloudflare_zones filter is not migrated",
+			Detail:   "Set the account, name and status attributes of the v5 cloudflare_zones data source in place of the dynamic filter block.",
+		})
+		filter = nil
+	}
+	if filter != nil {
+		filterBody := filter.Body()
+
+		if accountID := filterBody.GetAttribute("account_id"); accountID != nil {
+			body.SetAttributeRaw("account", hclwrite.TokensForObject([]hclwrite.ObjectAttrTokens{{
+				Name:  hclwrite.TokensForIdentifier("id"),
+				Value: accountID.Expr().BuildTokens(nil),
+			}}))
+		}
+
+		if name := filterBody.GetAttribute("name"); name != nil {
+			if tfhcl.ExtractStringFromAttribute(filterBody.GetAttribute("lookup_type")) == "contains" {
+				body.SetAttributeRaw("name", prefixedStringTokens("contains:", name.Expr().BuildTokens(nil)))
+			} else {
+				body.SetAttributeRaw("name", name.Expr().BuildTokens(nil))
+			}
+		}
+
+		if status := filterBody.GetAttribute("status"); status != nil {
+			body.SetAttributeRaw("status", status.Expr().BuildTokens(nil))
+		}
+
+		for _, unsupported := range []string{"match", "paused"} {
+			if filterBody.GetAttribute(unsupported) == nil {
+				continue
+			}
+			ctx.Diagnostics = append(ctx.Diagnostics, &hcl.Diagnostic{
+				Severity: hcl.DiagWarning,
+				Summary:  fmt.Sprintf("Unsupported cloudflare_zones filter: %s", unsupported),
+				Detail: fmt.Sprintf("The v5 cloudflare_zones data source cannot filter by %s. "+
+					"Filter the result attribute with a for expression instead.", unsupported),
+			})
+		}
+
+		body.RemoveBlock(filter)
+	}
+	ctx.Diagnostics = append(ctx.Diagnostics, tfhcl.UnconvertibleDynamicBlocks(body, "filter")...)
+
+	return &transform.TransformResult{
+		Blocks:         []*hclwrite.Block{block},
+		RemoveOriginal: false,
+	}, nil
+}
+
+// prefixedStringTokens returns a string that starts with prefix followed by the expression.
+// Quoted literals are prefixed in place, other expressions are interpolated.
+func prefixedStringTokens(prefix string, expr hclwrite.Tokens) hclwrite.Tokens {
+	if len(expr) == 3 && expr[0].Type == hclsyntax.TokenOQuote && expr[1].Type == hclsyntax.TokenQuotedLit && expr[2].Type == hclsyntax.TokenCQuote {
+		return hclwrite.Tokens{
+			expr[0],
+			{Type: hclsyntax.TokenQuotedLit, Bytes: append([]byte(prefix), expr[1].Bytes...)},
+			expr[2],
+		}
+	}
+
+	tokens := hclwrite.Tokens{
+		{Type: hclsyntax.TokenOQuote, Bytes: []byte{'"'}},
+		{Type: hclsyntax.TokenQuotedLit, Bytes: []byte(prefix)},
+		{Type: hclsyntax.TokenTemplateInterp, Bytes: []byte("${")},
+	}
+	tokens = append(tokens, expr...)
+	return append(tokens,
+		&hclwrite.Token{Type: hclsyntax.TokenTemplateSeqEnd, Bytes: []byte{'}'}},
+		&hclwrite.Token{Type: hclsyntax.TokenCQuote, Bytes: []byte{'"'}},
+	)
+}
@@ -0,0 +1,124 @@
+package zones
+
+import (
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/tf-migrate/internal/testhelpers"
+	"github.com/cloudflare/tf-migrate/internal/transform"
+)
+
+func TestV4ToV5Transformation(t *testing.T) {
+	migrator := NewV4ToV5Migrator()
+
+	tests := []testhelpers.ConfigTestCase{
+		{
+			Name: "Filter by account and name",
+			Input: `
+data "cloudflare_zones" "example" {
+  filter {
+    account_id = var.account_id
+    name       = "example.com"
+    status     = "active"
+  }
+}`,
+			Expected: `data "cloudflare_zones" "example" {
+  account = {
+    id = var.account_id
+  }
+  name   = "example.com"
+  status = "active"
+}`,
+		},
+		{
+			Name: "Contains lookup with a literal name",
+			Input: `
+data "cloudflare_zones" "example" {
+  filter {
+    name        = "example"
+    lookup_type = "contains"
+  }
+}`,
+			Expected: `data "cloudflare_zones" "example" {
+  name = "contains:example"
+}`,
+		},
+		{
+			Name: "Contains lookup with an expression",
+			Input: `
+data "cloudflare_zones" "example" {
+  filter {
+    name        = var.zone_name
+    lookup_type = "contains"
+  }
+}`,
+			Expected: `data "cloudflare_zones" "example" {
+  name = "contains:${var.zone_name}"
+}`,
+		},
+		{
+			Name: "Empty filter",
+			Input: `
+data "cloudflare_zones" "all" {
+  filter {}
+}`,
+			Expected: `data "cloudflare_zones" "all" {
+}`,
+		},
+	}
+
+	testhelpers.RunDataSourceConfigTransformTests(t, tests, migrator)
+}
+
+func TestUnsupportedFiltersWarn(t *testing.T) {
+	input := `data "cloudflare_zones" "example" {
+  filter {
+    match  = "^prod-"
+    paused = false
+  }
+}
+`
+	file, diags := hclwrite.ParseConfig([]byte(input), "test.tf", hcl.InitialPos)
+	require.False(t, diags.HasErrors())
+
+	ctx := &transform.Context{CFGFile: file}
+	_, err := NewV4ToV5Migrator().TransformConfig(ctx, file.Body().Blocks()[0])
+	require.NoError(t, err)
+
+	require.Len(t, ctx.Diagnostics, 2)
+	assert.Equal(t, hcl.DiagWarning, ctx.Diagnostics[0].Severity)
+	assert.Contains(t, ctx.Diagnostics[0].Summary, "match")
+	assert.Contains(t, ctx.Diagnostics[1].Summary, "paused")
+	assert.Nil(t, file.Body().Blocks()[0].Body().FirstMatchingBlock("filter", nil))
+}
+
+func TestDynamicFilterWarns(t *testing.T) {
+	for _, name := range []string{"filter.value.name", `filter["value"].name`} {
+		input := `data "cloudflare_zones" "example" {
+  dynamic "filter" {
+    for_each = var.filters
+    content {
+      name = ` + name + `
+    }
+  }
+}
+`
+		file, diags := hclwrite.ParseConfig([]byte(input), "test.tf", hcl.InitialPos)
+		require.False(t, diags.HasErrors())
+
+		ctx := &transform.Context{CFGFile: file}
+		_, err := NewV4ToV5Migrator().TransformConfig(ctx, file.Body().Blocks()[0])
+		require.NoError(t, err)
+
+		require.Len(t, ctx.Diagnostics, 1, name)
+		assert.Equal(t, hcl.DiagWarning, ctx.Diagnostics[0].Severity)
+		assert.Contains(t, ctx.Diagnostics[0].Summary, "Dynamic")
+		body := file.Body().Blocks()[0].Body()
+		assert.NotNil(t, body.FirstMatchingBlock("dynamic", []string{"filter"}))
+		assert.Nil(t, body.GetAttribute("name"))
+	}
+}
@@ -0,0 +1,81 @@
+package diff
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// Patch collects the previews of every file touched by a migration run
+type Patch struct {
+	files     map[string]string
+	statePath string
+	state     *StateDiff
+}
+
+// NewPatch creates an empty patch
+func NewPatch() *Patch {
+	return &Patch{
+		files: make(map[string]string),
+	}
+}
+
+// AddFile records the unified diff for a configuration file.
+// Files whose content is unchanged are not recorded.
+func (p *Patch) AddFile(path string, before, after []byte) {
+	if d := Unified(path, before, after); d != "" {
+		p.files[path] = d
+	}
+}
+
+// SetState records the structural diff for the state file
+func (p *Patch) SetState(path string, before, after []byte) {
+	p.statePath = path
+	p.state = State(before, after)
+}
+
+// State returns the structural state diff, or nil if no state was recorded
+func (p *Patch) State() *StateDiff {
+	return p.state
+}
+
+// StateJSON returns the structural state diff as indented JSON, or nil if the state is unchanged
+func (p *Patch) StateJSON() ([]byte, error) {
+	if p.state.IsEmpty() {
+		return nil, nil
+	}
+	return json.MarshalIndent(struct {
+		Path string `json:"path"`
+		*StateDiff
+	}{p.statePath, p.state}, "", "  ")
+}
+
+// IsEmpty reports whether the patch contains any changes
+func (p *Patch) IsEmpty() bool {
+	return len(p.files) == 0 && p.state.IsEmpty()
+}
+
+// String renders the combined patch: unified diffs for configuration files in
+// path order, followed by the structural diff of the state file.
+func (p *Patch) String() string {
+	paths := make([]string, 0, len(p.files))
+	for path := range p.files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var sb strings.Builder
+	for _, path := range paths {
+		sb.WriteString(p.files[path])
+	}
+
+	if !p.state.IsEmpty() {
+		if sb.Len() > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString("# State: " + p.statePath + "\n")
+		sb.WriteString(p.state.String())
+	}
+
+	return sb.String()
+}
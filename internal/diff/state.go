@@ -0,0 +1,318 @@
+package diff
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/cloudflare/tf-migrate/internal/address"
+)
+
+// Change actions used in StateDiff
+const (
+	ActionAdd    = "add"
+	ActionRemove = "remove"
+	ActionUpdate = "update"
+)
+
+// StateDiff is a structural diff of two Terraform state files, keyed by
+// resource instance address rather than by line.
+type StateDiff struct {
+	Resources []ResourceDiff `json:"resources"`
+}
+
+// ResourceDiff describes the changes made to a single resource instance
+type ResourceDiff struct {
+	Address         string            `json:"address"`
+	PreviousAddress string            `json:"previous_address,omitempty"`
+	Action          string            `json:"action"`
+	Changes         []AttributeChange `json:"changes,omitempty"`
+}
+
+// AttributeChange describes a change to a single leaf value of an instance
+type AttributeChange struct {
+	Path   string `json:"path"`
+	Action string `json:"action"`
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+}
+
+// sensitiveValue is shown in place of the values listed in sensitive_attributes, so the
+// diff can be shared in a pull request
+const sensitiveValue = "(sensitive value)"
+
+// stateInstance is a resource instance flattened for comparison
+type stateInstance struct {
+	address string
+	// identity is the address without the resource type, used to pair renamed resources
+	identity string
+	values   map[string]string
+	// sensitive holds the paths of values that must not be shown
+	sensitive map[string]bool
+}
+
+// display returns the value at path as shown in the diff
+func (inst *stateInstance) display(path string) string {
+	if inst.sensitive[path] {
+		return sensitiveValue
+	}
+	return inst.values[path]
+}
+
+// State computes a structural diff between two state documents.
+// Instances are matched by address; an instance whose type changed but whose
+// module, mode, name and index key are unchanged is reported as a rename.
+func State(before, after []byte) *StateDiff {
+	oldInstances := collectInstances(gjson.ParseBytes(before))
+	newInstances := collectInstances(gjson.ParseBytes(after))
+
+	oldByAddress := make(map[string]*stateInstance, len(oldInstances))
+	for _, inst := range oldInstances {
+		oldByAddress[inst.address] = inst
+	}
+	newByAddress := make(map[string]*stateInstance, len(newInstances))
+	for _, inst := range newInstances {
+		newByAddress[inst.address] = inst
+	}
+
+	// Index unmatched old instances by identity so renamed resources can be paired
+	unmatchedOld := make(map[string][]*stateInstance)
+	for _, inst := range oldInstances {
+		if _, ok := newByAddress[inst.address]; !ok {
+			unmatchedOld[inst.identity] = append(unmatchedOld[inst.identity], inst)
+		}
+	}
+
+	result := &StateDiff{Resources: []ResourceDiff{}}
+	paired := make(map[string]bool)
+
+	for _, newInst := range newInstances {
+		oldInst, ok := oldByAddress[newInst.address]
+		if !ok {
+			if candidates := unmatchedOld[newInst.identity]; len(candidates) == 1 && !paired[candidates[0].address] {
+				oldInst = candidates[0]
+			}
+		}
+
+		if oldInst == nil {
+			result.Resources = append(result.Resources, ResourceDiff{
+				Address: newInst.address,
+				Action:  ActionAdd,
+				Changes: compareValues(nil, newInst),
+			})
+			continue
+		}
+
+		paired[oldInst.address] = true
+		changes := compareValues(oldInst, newInst)
+		if len(changes) == 0 && oldInst.address == newInst.address {
+			continue
+		}
+
+		rd := ResourceDiff{
+			Address: newInst.address,
+			Action:  ActionUpdate,
+			Changes: changes,
+		}
+		if oldInst.address != newInst.address {
+			rd.PreviousAddress = oldInst.address
+		}
+		result.Resources = append(result.Resources, rd)
+	}
+
+	for _, oldInst := range oldInstances {
+		if _, ok := newByAddress[oldInst.address]; ok || paired[oldInst.address] {
+			continue
+		}
+		result.Resources = append(result.Resources, ResourceDiff{
+			Address: oldInst.address,
+			Action:  ActionRemove,
+		})
+	}
+
+	return result
+}
+
+// IsEmpty reports whether the diff contains no changes
+func (d *StateDiff) IsEmpty() bool {
+	return d == nil || len(d.Resources) == 0
+}
+
+// String renders the diff in a human readable form
+//
+// Example output:
+//
+//	~ cloudflare_dns_record.www (was cloudflare_record.www)
+//	    + attributes.content = "192.0.2.1"
+//	    - attributes.value = "192.0.2.1"
+//	- data.cloudflare_zones.all
+func (d *StateDiff) String() string {
+	if d.IsEmpty() {
+		return ""
+	}
+
+	var sb strings.Builder
+	for _, r := range d.Resources {
+		switch r.Action {
+		case ActionAdd:
+			fmt.Fprintf(&sb, "+ %s\n", r.Address)
+		case ActionRemove:
+			fmt.Fprintf(&sb, "- %s\n", r.Address)
+		default:
+			if r.PreviousAddress != "" {
+				fmt.Fprintf(&sb, "~ %s (was %s)\n", r.Address, r.PreviousAddress)
+			} else {
+				fmt.Fprintf(&sb, "~ %s\n", r.Address)
+			}
+		}
+
+		for _, c := range r.Changes {
+			switch c.Action {
+			case ActionAdd:
+				fmt.Fprintf(&sb, "    + %s = %s\n", c.Path, c.After)
+			case ActionRemove:
+				fmt.Fprintf(&sb, "    - %s = %s\n", c.Path, c.Before)
+			default:
+				fmt.Fprintf(&sb, "    ~ %s: %s -> %s\n", c.Path, c.Before, c.After)
+			}
+		}
+	}
+	return sb.String()
+}
+
+// collectInstances flattens every resource instance of a state document.
+// The private blob is left out and values listed in sensitive_attributes are marked sensitive.
+func collectInstances(state gjson.Result) []*stateInstance {
+	var result []*stateInstance
+
+	state.Get("resources").ForEach(func(_, resource gjson.Result) bool {
+		resource.Get("instances").ForEach(func(_, instance gjson.Result) bool {
+			addr := address.FromState(resource, instance.Get("index_key"))
+			values := make(map[string]string)
+			flatten("", instance, values)
+			result = append(result, &stateInstance{
+				address:   addr.String(),
+				identity:  addr.WithType("").String(),
+				values:    values,
+				sensitive: sensitivePaths(instance, values),
+			})
+			return true
+		})
+		return true
+	})
+
+	return result
+}
+
+// flatten records every leaf value of a JSON document keyed by its path.
+// The index key is part of the instance address and is not compared, and the
+// private blob is opaque provider data that is not shown.
+func flatten(path string, value gjson.Result, out map[string]string) {
+	switch {
+	case value.IsObject():
+		empty := true
+		value.ForEach(func(k, v gjson.Result) bool {
+			empty = false
+			if path == "" && (k.String() == "index_key" || k.String() == "private") {
+				return true
+			}
+			childPath := k.String()
+			if path != "" {
+				childPath = path + "." + childPath
+			}
+			flatten(childPath, v, out)
+			return true
+		})
+		if empty && path != "" {
+			out[path] = "{}"
+		}
+	case value.IsArray():
+		empty := true
+		value.ForEach(func(k, v gjson.Result) bool {
+			empty = false
+			flatten(fmt.Sprintf("%s[%d]", path, k.Int()), v, out)
+			return true
+		})
+		if empty {
+			out[path] = "[]"
+		}
+	default:
+		out[path] = value.Raw
+	}
+}
+
+// sensitivePaths returns the flattened paths of the values an instance lists in
+// sensitive_attributes, including every leaf below a sensitive object or list
+func sensitivePaths(instance gjson.Result, values map[string]string) map[string]bool {
+	var prefixes []string
+	instance.Get("sensitive_attributes").ForEach(func(_, steps gjson.Result) bool {
+		path := "attributes"
+		steps.ForEach(func(_, step gjson.Result) bool {
+			// get_attr steps hold the attribute name, index steps hold a typed key
+			value := step.Get("value")
+			if step.Get("type").String() == "index" {
+				value = value.Get("value")
+			}
+			if value.Type == gjson.Number {
+				path += fmt.Sprintf("[%d]", value.Int())
+			} else {
+				path += "." + value.String()
+			}
+			return true
+		})
+		prefixes = append(prefixes, path)
+		return true
+	})
+	if len(prefixes) == 0 {
+		return nil
+	}
+
+	sensitive := make(map[string]bool)
+	for path := range values {
+		for _, prefix := range prefixes {
+			if path == prefix || strings.HasPrefix(path, prefix+".") || strings.HasPrefix(path, prefix+"[") {
+				sensitive[path] = true
+				break
+			}
+		}
+	}
+	return sensitive
+}
+
+// compareValues compares two flattened instances and returns the changed leaves in path order.
+// A nil instance has no values. Sensitive values are compared but not shown.
+func compareValues(before, after *stateInstance) []AttributeChange {
+	if before == nil {
+		before = &stateInstance{}
+	}
+	paths := make(map[string]struct{}, len(before.values)+len(after.values))
+	for p := range before.values {
+		paths[p] = struct{}{}
+	}
+	for p := range after.values {
+		paths[p] = struct{}{}
+	}
+
+	sorted := make([]string, 0, len(paths))
+	for p := range paths {
+		sorted = append(sorted, p)
+	}
+	sort.Strings(sorted)
+
+	var changes []AttributeChange
+	for _, p := range sorted {
+		oldVal, hadOld := before.values[p]
+		newVal, hasNew := after.values[p]
+		switch {
+		case hadOld && !hasNew:
+			changes = append(changes, AttributeChange{Path: p, Action: ActionRemove, Before: before.display(p)})
+		case !hadOld && hasNew:
+			changes = append(changes, AttributeChange{Path: p, Action: ActionAdd, After: after.display(p)})
+		case oldVal != newVal:
+			changes = append(changes, AttributeChange{Path: p, Action: ActionUpdate, Before: before.display(p), After: after.display(p)})
+		}
+	}
+	return changes
+}
@@ -0,0 +1,145 @@
+package diff
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestState(t *testing.T) {
+	tests := []struct {
+		name     string
+		before   string
+		after    string
+		expected []ResourceDiff
+	}{
+		{
+			name:     "Unchanged state",
+			before:   `{"resources":[{"mode":"managed","type":"cloudflare_zone","name":"z","instances":[{"attributes":{"id":"1"}}]}]}`,
+			after:    `{"resources":[{"mode":"managed","type":"cloudflare_zone","name":"z","instances":[{"attributes":{"id":"1"}}]}]}`,
+			expected: []ResourceDiff{},
+		},
+		{
+			name: "Attribute changes on the same address",
+			before: `{"resources":[{"mode":"managed","type":"cloudflare_zone_dnssec","name":"z","instances":[
+				{"schema_version":1,"attributes":{"flags":257,"modified_on":"Tue, 04 Nov 2025 21:52:44 +0000"}}]}]}`,
+			after: `{"resources":[{"mode":"managed","type":"cloudflare_zone_dnssec","name":"z","instances":[
+				{"schema_version":0,"attributes":{"flags":257,"modified_on":"2025-11-04T21:52:44Z"}}]}]}`,
+			expected: []ResourceDiff{
+				{
+					Address: "cloudflare_zone_dnssec.z",
+					Action:  ActionUpdate,
+					Changes: []AttributeChange{
+						{Path: "attributes.modified_on", Action: ActionUpdate, Before: `"Tue, 04 Nov 2025 21:52:44 +0000"`, After: `"2025-11-04T21:52:44Z"`},
+						{Path: "schema_version", Action: ActionUpdate, Before: "1", After: "0"},
+					},
+				},
+			},
+		},
+		{
+			name: "Renamed resource in a module with index keys",
+			before: `{"resources":[{"module":"module.dns","mode":"managed","type":"cloudflare_record","name":"www","instances":[
+				{"index_key":"a","attributes":{"value":"192.0.2.1","ttl":1}},
+				{"index_key":"b","attributes":{"value":"192.0.2.2","ttl":1}}]}]}`,
+			after: `{"resources":[{"module":"module.dns","mode":"managed","type":"cloudflare_dns_record","name":"www","instances":[
+				{"index_key":"a","attributes":{"content":"192.0.2.1","ttl":1}},
+				{"index_key":"b","attributes":{"content":"192.0.2.2","ttl":1}}]}]}`,
+			expected: []ResourceDiff{
+				{
+					Address:         `module.dns.cloudflare_dns_record.www["a"]`,
+					PreviousAddress: `module.dns.cloudflare_record.www["a"]`,
+					Action:          ActionUpdate,
+					Changes: []AttributeChange{
+						{Path: "attributes.content", Action: ActionAdd, After: `"192.0.2.1"`},
+						{Path: "attributes.value", Action: ActionRemove, Before: `"192.0.2.1"`},
+					},
+				},
+				{
+					Address:         `module.dns.cloudflare_dns_record.www["b"]`,
+					PreviousAddress: `module.dns.cloudflare_record.www["b"]`,
+					Action:          ActionUpdate,
+					Changes: []AttributeChange{
+						{Path: "attributes.content", Action: ActionAdd, After: `"192.0.2.2"`},
+						{Path: "attributes.value", Action: ActionRemove, Before: `"192.0.2.2"`},
+					},
+				},
+			},
+		},
+		{
+			name: "Removed data source and nested arrays",
+			before: `{"resources":[
+				{"mode":"data","type":"cloudflare_zones","name":"all","instances":[{"attributes":{"id":"1"}}]},
+				{"mode":"managed","type":"cloudflare_teams_list","name":"l","instances":[{"index_key":0,"attributes":{"items":["a"]}}]}]}`,
+			after: `{"resources":[
+				{"mode":"managed","type":"cloudflare_zero_trust_list","name":"l","instances":[{"index_key":0,"attributes":{"items":[{"value":"a"}]}}]}]}`,
+			expected: []ResourceDiff{
+				{
+					Address:         "cloudflare_zero_trust_list.l[0]",
+					PreviousAddress: "cloudflare_teams_list.l[0]",
+					Action:          ActionUpdate,
+					Changes: []AttributeChange{
+						{Path: "attributes.items[0]", Action: ActionRemove, Before: `"a"`},
+						{Path: "attributes.items[0].value", Action: ActionAdd, After: `"a"`},
+					},
+				},
+				{
+					Address: "data.cloudflare_zones.all",
+					Action:  ActionRemove,
+				},
+			},
+		},
+		{
+			name: "Sensitive values and private data are not shown",
+			before: `{"resources":[{"mode":"managed","type":"cloudflare_api_token","name":"t","instances":[
+				{"attributes":{"value":"secret-1","policy":[{"id":"p"}]},"private":"c2VjcmV0",
+				"sensitive_attributes":[[{"type":"get_attr","value":"value"}],[{"type":"get_attr","value":"policy"},{"type":"index","value":{"value":0,"type":"number"}}]]}]}]}`,
+			after: `{"resources":[{"mode":"managed","type":"cloudflare_api_token","name":"t","instances":[
+				{"attributes":{"value":"secret-2","policy":[{"id":"q"}]},"private":"b3RoZXI=",
+				"sensitive_attributes":[[{"type":"get_attr","value":"value"}],[{"type":"get_attr","value":"policy"},{"type":"index","value":{"value":0,"type":"number"}}]]}]}]}`,
+			expected: []ResourceDiff{
+				{
+					Address: "cloudflare_api_token.t",
+					Action:  ActionUpdate,
+					Changes: []AttributeChange{
+						{Path: "attributes.policy[0].id", Action: ActionUpdate, Before: sensitiveValue, After: sensitiveValue},
+						{Path: "attributes.value", Action: ActionUpdate, Before: sensitiveValue, After: sensitiveValue},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := State([]byte(tt.before), []byte(tt.after))
+			assert.Equal(t, tt.expected, result.Resources)
+		})
+	}
+}
+
+func TestStateDiffString(t *testing.T) {
+	d := &StateDiff{
+		Resources: []ResourceDiff{
+			{
+				Address:         "cloudflare_dns_record.www",
+				PreviousAddress: "cloudflare_record.www",
+				Action:          ActionUpdate,
+				Changes: []AttributeChange{
+					{Path: "attributes.content", Action: ActionAdd, After: `"192.0.2.1"`},
+					{Path: "attributes.value", Action: ActionRemove, Before: `"192.0.2.1"`},
+					{Path: "schema_version", Action: ActionUpdate, Before: "2", After: "0"},
+				},
+			},
+			{Address: "data.cloudflare_zones.all", Action: ActionRemove},
+		},
+	}
+
+	expected := `~ cloudflare_dns_record.www (was cloudflare_record.www)
+    + attributes.content = "192.0.2.1"
+    - attributes.value = "192.0.2.1"
+    ~ schema_version: 2 -> 0
+- data.cloudflare_zones.all
+`
+	assert.Equal(t, expected, d.String())
+	assert.Equal(t, "", (&StateDiff{}).String())
+}
@@ -0,0 +1,176 @@
+// Package diff renders previews of migration results so that changes can be
+// reviewed before they are written to disk.
+package diff
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// contextLines is the number of unchanged lines shown around each change
+const contextLines = 3
+
+// lineOp is a single line of a line-level diff
+type lineOp struct {
+	op   diffmatchpatch.Operation
+	text string
+}
+
+// Unified returns a unified diff between before and after for the given path.
+// An empty string is returned when the contents are identical.
+//
+// Example output:
+//
+//	--- a/main.tf
+//	+++ b/main.tf
+//	@@ -1,3 +1,3 @@
+//	-resource "cloudflare_record" "www" {
+//	+resource "cloudflare_dns_record" "www" {
+//	   zone_id = "abc123"
+func Unified(path string, before, after []byte) string {
+	if string(before) == string(after) {
+		return ""
+	}
+
+	ops := lineDiff(string(before), string(after))
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- a/%s\n", path)
+	fmt.Fprintf(&sb, "+++ b/%s\n", path)
+
+	for _, h := range buildHunks(ops) {
+		sb.WriteString(h)
+	}
+
+	return sb.String()
+}
+
+// lineDiff computes a line-level diff between two texts
+func lineDiff(before, after string) []lineOp {
+	dmp := diffmatchpatch.New()
+	a, b, lines := dmp.DiffLinesToChars(before, after)
+	diffs := dmp.DiffMain(a, b, false)
+	diffs = dmp.DiffCharsToLines(diffs, lines)
+
+	var ops []lineOp
+	for _, d := range diffs {
+		for _, line := range splitLines(d.Text) {
+			ops = append(ops, lineOp{op: d.Type, text: line})
+		}
+	}
+	return ops
+}
+
+// splitLines splits text into lines, keeping the trailing newline on each line
+func splitLines(text string) []string {
+	var lines []string
+	for text != "" {
+		idx := strings.IndexByte(text, '\n')
+		if idx < 0 {
+			lines = append(lines, text)
+			break
+		}
+		lines = append(lines, text[:idx+1])
+		text = text[idx+1:]
+	}
+	return lines
+}
+
+// buildHunks groups changed lines together with their surrounding context
+func buildHunks(ops []lineOp) []string {
+	var hunks []string
+
+	i := 0
+	for i < len(ops) {
+		// Skip ahead to the next change
+		if ops[i].op == diffmatchpatch.DiffEqual {
+			i++
+			continue
+		}
+
+		start := i - contextLines
+		if start < 0 {
+			start = 0
+		}
+
+		// Extend the hunk while changes are close enough to share context
+		end := i
+		for end < len(ops) {
+			if ops[end].op != diffmatchpatch.DiffEqual {
+				end++
+				continue
+			}
+			run := end
+			for run < len(ops) && ops[run].op == diffmatchpatch.DiffEqual {
+				run++
+			}
+			if run == len(ops) || run-end > 2*contextLines {
+				break
+			}
+			end = run
+		}
+		stop := end + contextLines
+		if stop > len(ops) {
+			stop = len(ops)
+		}
+
+		hunks = append(hunks, renderHunk(ops, start, stop))
+		i = stop
+	}
+
+	return hunks
+}
+
+// renderHunk renders ops[start:stop] as a single hunk with its header
+func renderHunk(ops []lineOp, start, stop int) string {
+	oldStart, newStart := 1, 1
+	for _, op := range ops[:start] {
+		if op.op != diffmatchpatch.DiffInsert {
+			oldStart++
+		}
+		if op.op != diffmatchpatch.DiffDelete {
+			newStart++
+		}
+	}
+
+	var body strings.Builder
+	oldCount, newCount := 0, 0
+	for _, op := range ops[start:stop] {
+		prefix := " "
+		switch op.op {
+		case diffmatchpatch.DiffDelete:
+			prefix = "-"
+			oldCount++
+		case diffmatchpatch.DiffInsert:
+			prefix = "+"
+			newCount++
+		default:
+			oldCount++
+			newCount++
+		}
+		body.WriteString(prefix)
+		body.WriteString(op.text)
+		if !strings.HasSuffix(op.text, "\n") {
+			body.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+
+	// An empty range starts at the line before the hunk, as in diff(1)
+	if oldCount == 0 {
+		oldStart--
+	}
+	if newCount == 0 {
+		newStart--
+	}
+
+	return fmt.Sprintf("@@ -%s +%s @@\n%s", hunkRange(oldStart, oldCount), hunkRange(newStart, newCount), body.String())
+}
+
+func hunkRange(start, count int) string {
+	if count == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
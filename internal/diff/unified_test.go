@@ -0,0 +1,131 @@
+package diff
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name     string
+		before   string
+		after    string
+		expected string
+	}{
+		{
+			name:     "Identical content produces no diff",
+			before:   "a\nb\n",
+			after:    "a\nb\n",
+			expected: "",
+		},
+		{
+			name: "Single line change with context",
+			before: `resource "cloudflare_record" "www" {
+  zone_id = "abc123"
+  value   = "192.0.2.1"
+}
+`,
+			after: `resource "cloudflare_dns_record" "www" {
+  zone_id = "abc123"
+  content = "192.0.2.1"
+}
+`,
+			expected: `--- a/main.tf
++++ b/main.tf
+@@ -1,4 +1,4 @@
+-resource "cloudflare_record" "www" {
++resource "cloudflare_dns_record" "www" {
+   zone_id = "abc123"
+-  value   = "192.0.2.1"
++  content = "192.0.2.1"
+ }
+`,
+		},
+		{
+			name:   "Distant changes produce separate hunks",
+			before: "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+			after:  "one\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\ntwelve\n",
+			expected: `--- a/main.tf
++++ b/main.tf
+@@ -1,4 +1,4 @@
+-1
++one
+ 2
+ 3
+ 4
+@@ -9,4 +9,4 @@
+ 9
+ 10
+ 11
+-12
++twelve
+`,
+		},
+		{
+			name:   "Pure insertion",
+			before: "a\nb\n",
+			after:  "a\nb\nc\n",
+			expected: `--- a/main.tf
++++ b/main.tf
+@@ -1,2 +1,3 @@
+ a
+ b
++c
+`,
+		},
+		{
+			name:   "New file",
+			before: "",
+			after:  "a\n",
+			expected: `--- a/main.tf
++++ b/main.tf
+@@ -0,0 +1 @@
++a
+`,
+		},
+		{
+			name:   "Missing trailing newline",
+			before: "a\nb",
+			after:  "a\nc",
+			expected: `--- a/main.tf
++++ b/main.tf
+@@ -1,2 +1,2 @@
+ a
+-b
+\ No newline at end of file
++c
+\ No newline at end of file
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Unified("main.tf", []byte(tt.before), []byte(tt.after))
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestPatch(t *testing.T) {
+	patch := NewPatch()
+	assert.True(t, patch.IsEmpty())
+
+	patch.AddFile("unchanged.tf", []byte("a\n"), []byte("a\n"))
+	assert.True(t, patch.IsEmpty(), "unchanged files should not be recorded")
+
+	patch.AddFile("b.tf", []byte("b\n"), []byte("B\n"))
+	patch.AddFile("a.tf", []byte("a\n"), []byte("A\n"))
+	patch.SetState("terraform.tfstate",
+		[]byte(`{"resources":[{"mode":"managed","type":"cloudflare_record","name":"www","instances":[{"attributes":{"value":"x"}}]}]}`),
+		[]byte(`{"resources":[{"mode":"managed","type":"cloudflare_dns_record","name":"www","instances":[{"attributes":{"content":"x"}}]}]}`),
+	)
+
+	assert.False(t, patch.IsEmpty())
+
+	output := patch.String()
+	assert.Less(t, strings.Index(output, "--- a/a.tf"), strings.Index(output, "--- a/b.tf"), "files should be sorted by path")
+	assert.Contains(t, output, "# State: terraform.tfstate\n~ cloudflare_dns_record.www (was cloudflare_record.www)\n")
+}
@@ -24,6 +24,10 @@ func (h *PreprocessHandler) Handle(ctx *transform.Context) (*transform.Context,
 
 func (h *PreprocessHandler) applyAllPreprocessors(ctx *transform.Context, content string) string {
 	for _, migrator := range h.provider.GetAllMigrators(ctx.SourceVersion, ctx.TargetVersion, ctx.Resources...) {
+		if preprocessor, ok := migrator.(transform.ContextPreprocessor); ok {
+			content = preprocessor.PreprocessContext(ctx, content)
+			continue
+		}
 		content = migrator.Preprocess(content)
 	}
 	return content
@@ -35,6 +35,13 @@ func (h *ResourceTransformHandler) Handle(ctx *transform.Context) (*transform.Co
 	var blocksToAdd []*hclwrite.Block
 
 	for _, block := range blocks {
+		if block.Type() == "data" {
+			if remove, add := h.transformDataSource(ctx, block); remove {
+				blocksToRemove = append(blocksToRemove, block)
+				blocksToAdd = append(blocksToAdd, add...)
+			}
+			continue
+		}
 		if block.Type() != "resource" {
 			continue
 		}
@@ -51,6 +58,13 @@ func (h *ResourceTransformHandler) Handle(ctx *transform.Context) (*transform.Co
 			continue
 		}
 
+		oldAddress := resourceType
+		if len(labels) >= 2 {
+			oldAddress += "." + labels[1]
+		}
+		diagsBefore := len(ctx.Diagnostics)
+		before := string(block.BuildTokens(nil).Bytes())
+
 		result, err := migrator.TransformConfig(ctx, block)
 		if err != nil {
 			h.log.Error("Error transforming resource", "type", resourceType, "error", err)
@@ -59,6 +73,12 @@ func (h *ResourceTransformHandler) Handle(ctx *transform.Context) (*transform.Co
 				Summary:  fmt.Sprintf("Failed to transform %s resource", resourceType),
 				Detail:   err.Error(),
 			})
+			ctx.RecordResourceChange(transform.ResourceChange{
+				OldType:     resourceType,
+				OldAddress:  oldAddress,
+				Migrator:    fmt.Sprintf("%T", migrator),
+				Diagnostics: diagnosticsSince(ctx, diagsBefore),
+			})
 			continue
 		}
 
@@ -68,12 +88,8 @@ func (h *ResourceTransformHandler) Handle(ctx *transform.Context) (*transform.Co
 				blocksToAdd = append(blocksToAdd, result.Blocks...)
 			}
 		}
-		key := fmt.Sprintf("transformed_%s", resourceType)
-		if count, ok := ctx.Metadata[key]; ok {
-			ctx.Metadata[key] = count.(int) + 1
-		} else {
-			ctx.Metadata[key] = 1
-		}
+		h.recordChanges(ctx, fmt.Sprintf("%T", migrator), "resource", resourceType, oldAddress, before, block, result, diagnosticsSince(ctx, diagsBefore))
+		countTransformed(ctx, fmt.Sprintf("transformed_%s", resourceType))
 	}
 
 	for _, block := range blocksToRemove {
@@ -86,3 +102,117 @@ func (h *ResourceTransformHandler) Handle(ctx *transform.Context) (*transform.Co
 
 	return h.Next(ctx)
 }
+
+// transformDataSource dispatches a data block to its data source migrator.
+// It reports whether the original block must be removed, and the blocks that replace it.
+func (h *ResourceTransformHandler) transformDataSource(ctx *transform.Context, block *hclwrite.Block) (bool, []*hclwrite.Block) {
+	provider, ok := h.provider.(transform.DataSourceProvider)
+	if !ok {
+		return false, nil
+	}
+
+	labels := block.Labels()
+	if len(labels) < 2 {
+		return false, nil
+	}
+
+	dataSourceType := labels[0]
+	migrator := provider.GetDataSourceMigrator(dataSourceType, ctx.SourceVersion, ctx.TargetVersion)
+	if migrator == nil || !migrator.CanHandle(dataSourceType) {
+		h.log.Debug("No migrator found for data source type", "type", dataSourceType, "source", ctx.SourceVersion, "target", ctx.TargetVersion)
+		return false, nil
+	}
+
+	oldAddress := "data." + dataSourceType + "." + labels[1]
+	diagsBefore := len(ctx.Diagnostics)
+	before := string(block.BuildTokens(nil).Bytes())
+
+	result, err := migrator.TransformConfig(ctx, block)
+	if err != nil {
+		h.log.Error("Error transforming data source", "type", dataSourceType, "error", err)
+		ctx.Diagnostics = append(ctx.Diagnostics, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  fmt.Sprintf("Failed to transform %s data source", dataSourceType),
+			Detail:   err.Error(),
+		})
+		ctx.RecordResourceChange(transform.ResourceChange{
+			OldType:     dataSourceType,
+			OldAddress:  oldAddress,
+			Migrator:    fmt.Sprintf("%T", migrator),
+			Diagnostics: diagnosticsSince(ctx, diagsBefore),
+		})
+		return false, nil
+	}
+
+	h.recordChanges(ctx, fmt.Sprintf("%T", migrator), "data", dataSourceType, oldAddress, before, block, result, diagnosticsSince(ctx, diagsBefore))
+	countTransformed(ctx, fmt.Sprintf("transformed_data_%s", dataSourceType))
+
+	return result.RemoveOriginal, result.Blocks
+}
+
+// recordChanges records the resources or data sources produced from a single transformed block.
+// A split records one change per resulting block of the same kind; a removal records a
+// change without a new address. The changes are marked as changed unless the resulting
+// blocks are the same as the block before the transformation.
+func (h *ResourceTransformHandler) recordChanges(ctx *transform.Context, migrator, blockType, oldType, oldAddress, before string, block *hclwrite.Block, result *transform.TransformResult, diags hcl.Diagnostics) {
+	resultBlocks := []*hclwrite.Block{block}
+	if result.RemoveOriginal {
+		resultBlocks = result.Blocks
+	}
+
+	var after []byte
+	for _, b := range resultBlocks {
+		after = append(after, b.BuildTokens(nil).Bytes()...)
+	}
+	changed := string(after) != before
+
+	prefix := ""
+	if blockType == "data" {
+		prefix = "data."
+	}
+
+	recorded := false
+	for _, b := range resultBlocks {
+		labels := b.Labels()
+		if b.Type() != blockType || len(labels) < 2 {
+			continue
+		}
+		ctx.RecordResourceChange(transform.ResourceChange{
+			OldType:     oldType,
+			NewType:     labels[0],
+			OldAddress:  oldAddress,
+			NewAddress:  prefix + labels[0] + "." + labels[1],
+			Migrator:    migrator,
+			Changed:     changed,
+			Diagnostics: diags,
+		})
+		recorded = true
+	}
+
+	if !recorded {
+		ctx.RecordResourceChange(transform.ResourceChange{
+			OldType:     oldType,
+			OldAddress:  oldAddress,
+			Migrator:    migrator,
+			Changed:     changed,
+			Diagnostics: diags,
+		})
+	}
+}
+
+// countTransformed increments a transformed block counter in the context metadata
+func countTransformed(ctx *transform.Context, key string) {
+	if count, ok := ctx.Metadata[key]; ok {
+		ctx.Metadata[key] = count.(int) + 1
+	} else {
+		ctx.Metadata[key] = 1
+	}
+}
+
+// diagnosticsSince returns a copy of the diagnostics appended to the context after index start
+func diagnosticsSince(ctx *transform.Context, start int) hcl.Diagnostics {
+	if start >= len(ctx.Diagnostics) {
+		return nil
+	}
+	return append(hcl.Diagnostics(nil), ctx.Diagnostics[start:]...)
+}
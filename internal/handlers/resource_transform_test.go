@@ -16,6 +16,7 @@ import (
 
 type MockResourceTransformer struct {
 	resourceType       string
+	renamedFrom        string
 	preprocessCalls    int
 	preprocessFunc     func(content string) string
 	transformFunc      func(ctx *transform.Context, block *hclwrite.Block) (*transform.TransformResult, error)
@@ -30,6 +31,10 @@ func (m *MockResourceTransformer) GetResourceType() string {
 	return m.resourceType
 }
 
+func (m *MockResourceTransformer) GetResourceRename() (string, string) {
+	return m.renamedFrom, m.resourceType
+}
+
 func (m *MockResourceTransformer) TransformConfig(ctx *transform.Context, block *hclwrite.Block) (*transform.TransformResult, error) {
 	if m.transformFunc != nil {
 		return m.transformFunc(ctx, block)
@@ -82,6 +87,54 @@ func (m *MockMigratorProvider) GetAllMigrators(sourceVersion string, targetVersi
 	return m.orderedTransformers
 }
 
+type MockDataSourceTransformer struct {
+	dataSourceType string
+	renamedFrom    string
+}
+
+func (m *MockDataSourceTransformer) CanHandle(dataSourceType string) bool {
+	return dataSourceType == m.renamedFrom
+}
+
+func (m *MockDataSourceTransformer) GetDataSourceType() string {
+	return m.dataSourceType
+}
+
+func (m *MockDataSourceTransformer) GetResourceRename() (string, string) {
+	return m.renamedFrom, m.dataSourceType
+}
+
+func (m *MockDataSourceTransformer) TransformConfig(ctx *transform.Context, block *hclwrite.Block) (*transform.TransformResult, error) {
+	block.SetLabels([]string{m.dataSourceType, block.Labels()[1]})
+	return &transform.TransformResult{
+		Blocks:         []*hclwrite.Block{block},
+		RemoveOriginal: false,
+	}, nil
+}
+
+// MockDataSourceProvider adds data source migrators to a MockMigratorProvider
+type MockDataSourceProvider struct {
+	*MockMigratorProvider
+	dataSources []*MockDataSourceTransformer
+}
+
+func (m *MockDataSourceProvider) GetDataSourceMigrator(dataSourceType string, sourceVersion string, targetVersion string) transform.DataSourceTransformer {
+	for _, d := range m.dataSources {
+		if d.renamedFrom == dataSourceType {
+			return d
+		}
+	}
+	return nil
+}
+
+func (m *MockDataSourceProvider) GetAllDataSourceMigrators(sourceVersion string, targetVersion string) []transform.DataSourceTransformer {
+	result := make([]transform.DataSourceTransformer, 0, len(m.dataSources))
+	for _, d := range m.dataSources {
+		result = append(result, d)
+	}
+	return result
+}
+
 var log = hclog.New(&hclog.LoggerOptions{})
 
 func TestResourceTransformHandler(t *testing.T) {
@@ -187,6 +240,81 @@ resource "keep_me" "example" {
 				}
 			},
 		},
+		{
+			name: "Record changes for split and removed resources",
+			input: `resource "combined" "example" {
+  name = "test"
+}
+resource "deprecated" "to_remove" {
+  name = "remove_me"
+}`,
+			transformers: []*MockResourceTransformer{
+				{
+					resourceType: "combined",
+					transformFunc: func(ctx *transform.Context, block *hclwrite.Block) (*transform.TransformResult, error) {
+						return &transform.TransformResult{
+							Blocks: []*hclwrite.Block{
+								hclwrite.NewBlock("resource", []string{"split_a", "part_a"}),
+								hclwrite.NewBlock("resource", []string{"split_b", "part_b"}),
+							},
+							RemoveOriginal: true,
+						}, nil
+					},
+				},
+				{
+					resourceType: "deprecated",
+					transformFunc: func(ctx *transform.Context, block *hclwrite.Block) (*transform.TransformResult, error) {
+						return &transform.TransformResult{RemoveOriginal: true}, nil
+					},
+				},
+			},
+			checkResult: func(t *testing.T, ctx *transform.Context) {
+				changes := ctx.ResourceChanges()
+				if len(changes) != 3 {
+					t.Fatalf("Expected 3 recorded changes, got %d", len(changes))
+				}
+				if changes[0].OldAddress != "combined.example" || changes[0].NewAddress != "split_a.part_a" {
+					t.Errorf("Unexpected first change: %+v", changes[0])
+				}
+				if changes[1].OldAddress != "combined.example" || changes[1].NewAddress != "split_b.part_b" {
+					t.Errorf("Unexpected second change: %+v", changes[1])
+				}
+				if changes[2].OldAddress != "deprecated.to_remove" || changes[2].NewAddress != "" {
+					t.Errorf("Expected removal to have no new address, got %+v", changes[2])
+				}
+			},
+		},
+		{
+			name: "Record whether a resource was changed",
+			input: `resource "modify_me" "example" {
+  old_attribute = "old_value"
+}
+resource "keep_me" "example" {
+  name = "keep"
+}`,
+			transformers: []*MockResourceTransformer{
+				{
+					resourceType: "modify_me",
+					transformFunc: func(ctx *transform.Context, block *hclwrite.Block) (*transform.TransformResult, error) {
+						block.Body().RenameAttribute("old_attribute", "new_attribute")
+						return &transform.TransformResult{Blocks: []*hclwrite.Block{block}}, nil
+					},
+				},
+				{resourceType: "keep_me"},
+			},
+			checkResult: func(t *testing.T, ctx *transform.Context) {
+				changes := ctx.ResourceChanges()
+				if len(changes) != 2 {
+					t.Fatalf("Expected 2 recorded changes, got %d", len(changes))
+				}
+				if !changes[0].Changed {
+					t.Errorf("Expected the modified resource to be changed: %+v", changes[0])
+				}
+				if changes[1].Changed {
+					t.Errorf("Expected the untouched resource to be unchanged: %+v", changes[1])
+				}
+			},
+		},
 		{
 			name: "In-place modification",
 			input: `resource "modify_me" "example" {
@@ -418,6 +546,77 @@ resource "tracked_resource" "three" {}`
 	}
 }
 
+func TestResourceTransformHandlerDataSources(t *testing.T) {
+	input := `data "old_lookup" "example" {
+  name = "test"
+}
+
+resource "old_lookup" "example" {
+  name = "test"
+}
+
+data "other_lookup" "example" {}
+`
+	tests := []struct {
+		name     string
+		provider transform.MigrationProvider
+		expected string
+		changes  int
+	}{
+		{
+			name: "Dispatch data blocks to data source migrators",
+			provider: &MockDataSourceProvider{
+				MockMigratorProvider: NewMockMigratorProvider(nil),
+				dataSources:          []*MockDataSourceTransformer{{dataSourceType: "new_lookup", renamedFrom: "old_lookup"}},
+			},
+			expected: `data "new_lookup" "example"`,
+			changes:  1,
+		},
+		{
+			name:     "Leave data blocks alone without data source migrators",
+			provider: NewMockMigratorProvider(nil),
+			expected: `data "old_lookup" "example"`,
+			changes:  0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := &transform.Context{
+				Content:  []byte(input),
+				Metadata: make(map[string]interface{}),
+			}
+			ctx, _ = handlers.NewParseHandler(log).Handle(ctx)
+
+			result, err := handlers.NewResourceTransformHandler(log, tt.provider).Handle(ctx)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			output := string(result.CFGFile.Bytes())
+			if !strings.Contains(output, tt.expected) {
+				t.Errorf("Expected output to contain %q, got:\n%s", tt.expected, output)
+			}
+			if !strings.Contains(output, `resource "old_lookup" "example"`) {
+				t.Errorf("Expected resource with the same type to be left alone, got:\n%s", output)
+			}
+
+			changes := result.ResourceChanges()
+			if len(changes) != tt.changes {
+				t.Fatalf("Expected %d recorded changes, got %d", tt.changes, len(changes))
+			}
+			if tt.changes > 0 {
+				if changes[0].OldAddress != "data.old_lookup.example" || changes[0].NewAddress != "data.new_lookup.example" {
+					t.Errorf("Unexpected data source change: %+v", changes[0])
+				}
+				if count := result.Metadata["transformed_data_old_lookup"]; count != 1 {
+					t.Errorf("Expected data source transformation count of 1, got %v", count)
+				}
+			}
+		})
+	}
+}
+
 func contains(slice []string, item string) bool {
 	for _, s := range slice {
 		if s == item {
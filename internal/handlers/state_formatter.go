@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 
@@ -24,19 +25,16 @@ func (h *StateFormatterHandler) Handle(ctx *transform.Context) (*transform.Conte
 	if len(ctx.Content) == 0 {
 		return ctx, fmt.Errorf("state content is empty - cannot format")
 	}
-	var formatted interface{}
 
-	if err := json.Unmarshal(ctx.Content, &formatted); err != nil {
+	// Indenting the raw JSON keeps the field order and number formatting of the state,
+	// which decoding and encoding it again would lose
+	var prettyJSON bytes.Buffer
+	prettyJSON.Grow(len(ctx.Content) + len(ctx.Content)/4)
+	if err := json.Indent(&prettyJSON, ctx.Content, "", "  "); err != nil {
 		// If we can't parse it, leave it as is
 		return h.Next(ctx)
 	}
 
-	prettyJSON, err := json.MarshalIndent(formatted, "", "  ")
-	if err != nil {
-		// If we can't format it, leave it as is
-		return h.Next(ctx)
-	}
-
-	ctx.Content = prettyJSON
+	ctx.Content = prettyJSON.Bytes()
 	return h.Next(ctx)
 }
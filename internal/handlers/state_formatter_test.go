@@ -64,6 +64,32 @@ func TestStateFormatterHandler(t *testing.T) {
 				}
 			},
 		},
+		{
+			name:  "Keep field order and number formatting",
+			input: `{"version":4,"serial":12345678901234567890,"resources":[{"type":"test","instances":[{"attributes":{"zeta":1.50,"alpha":"<a&b>"}}]}]}`,
+			checkResult: func(t *testing.T, result []byte) {
+				expected := `{
+  "version": 4,
+  "serial": 12345678901234567890,
+  "resources": [
+    {
+      "type": "test",
+      "instances": [
+        {
+          "attributes": {
+            "zeta": 1.50,
+            "alpha": "<a&b>"
+          }
+        }
+      ]
+    }
+  ]
+}`
+				if string(result) != expected {
+					t.Errorf("JSON not formatted as expected.\nGot:\n%s\nExpected:\n%s", result, expected)
+				}
+			},
+		},
 		{
 			name:  "Leave invalid JSON unchanged",
 			input: `{invalid json}`,
@@ -1,13 +1,17 @@
 package handlers
 
 import (
+	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/hcl/v2"
 	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
 
+	"github.com/cloudflare/tf-migrate/internal/address"
 	"github.com/cloudflare/tf-migrate/internal/transform"
 )
 
@@ -24,6 +28,11 @@ func NewStateTransformHandler(log hclog.Logger, provider transform.MigrationProv
 	}
 }
 
+// Handle transforms every managed resource instance in the state and removes the data
+// sources. The state is decoded once: each resource is rebuilt from its original bytes
+// with only the transformed instances and renamed types replaced, so the cost grows
+// linearly with the size of the state and untouched fields keep their order and number
+// formatting.
 func (h *StateTransformHandler) Handle(ctx *transform.Context) (*transform.Context, error) {
 	if len(ctx.Content) == 0 {
 		return ctx, fmt.Errorf("state content is empty")
@@ -33,121 +42,348 @@ func (h *StateTransformHandler) Handle(ctx *transform.Context) (*transform.Conte
 	if !gjson.Valid(stateJSON) {
 		return ctx, fmt.Errorf("invalid JSON in state file")
 	}
-	result := gjson.Parse(stateJSON)
 
-	resources := result.Get("resources")
-	if !resources.Exists() {
+	resources := gjson.Get(stateJSON, "resources")
+	if !resources.IsArray() {
 		h.log.Warn("No resources found in state file")
 		return h.Next(ctx)
 	}
+	if !locatedIn(stateJSON, resources) {
+		return ctx, fmt.Errorf("failed to locate resources in state file")
+	}
 
-	modifiedState := stateJSON
+	dependencies := h.newDependencyRewriter(ctx, resources)
 	transformedCount := 0
-	datasourceIndices := []int{} // Track datasource indices to remove them later
+	datasourcesRemoved := 0
 
+	var out strings.Builder
+	out.Grow(len(stateJSON))
+	out.WriteString(stateJSON[:resources.Index])
+	out.WriteByte('[')
+	kept := 0
 	resources.ForEach(func(key, resource gjson.Result) bool {
 		// Skip datasources (mode="data") - they are ephemeral and will be refreshed by Terraform
 		// Only process managed resources (mode="managed")
-		mode := resource.Get("mode").String()
-		if mode == "data" {
-			datasourceIndices = append(datasourceIndices, int(key.Int()))
-			h.log.Debug("Marking datasource for removal during state migration (datasources are ephemeral)", "type", resource.Get("type").String())
+		if resource.Get("mode").String() == "data" {
+			datasourcesRemoved++
+			h.log.Debug("Removing datasource from state (datasources are ephemeral)", "type", resource.Get("type").String(), "index", key.Int())
+			ctx.RecordResourceChange(transform.ResourceChange{
+				OldType:    resource.Get("type").String(),
+				OldAddress: address.FromState(resource, gjson.Result{}).String(),
+				Diagnostics: hcl.Diagnostics{{
+					Severity: hcl.DiagWarning,
+					Summary:  "Data source removed from state",
+					Detail:   "Data sources are ephemeral and will be read again by Terraform on the next plan or refresh",
+					Extra:    transform.FollowUpRefresh,
+				}},
+			})
 			return true
 		}
 
-		resourceType := resource.Get("type").String()
-		if resourceType == "" {
-			return true
+		if kept > 0 {
+			out.WriteByte(',')
 		}
+		kept++
+		raw, transformed := h.transformResource(ctx, int(key.Int()), resource, dependencies)
+		out.WriteString(raw)
+		transformedCount += transformed
+		return true
+	})
+	out.WriteByte(']')
+	out.WriteString(stateJSON[resources.Index+len(resources.Raw):])
 
-		migrator := h.provider.GetMigrator(resourceType, ctx.SourceVersion, ctx.TargetVersion)
-		if migrator == nil {
-			ctx.Diagnostics = append(ctx.Diagnostics, &hcl.Diagnostic{
-				Severity: hcl.DiagWarning,
-				Summary:  fmt.Sprintf("Failed to transform resource: %s", resourceType),
-				Detail:   fmt.Sprintf("No migrator found for state resource: %s (v%s -> v%s)", resourceType, ctx.SourceVersion, ctx.TargetVersion),
-			})
-			h.log.Debug("No migrator found for state resource", "type", resourceType, "source", ctx.SourceVersion, "target", ctx.TargetVersion)
-			return true
+	if datasourcesRemoved > 0 {
+		h.log.Info("Removed datasources from state (will be refreshed by Terraform)", "count", datasourcesRemoved)
+	}
+
+	// Unresolved dependencies are reported after the instance changes they belong to
+	ctx.Diagnostics = append(ctx.Diagnostics, dependencies.diagnostics...)
+
+	modifiedState := stateJSON
+	if transformedCount > 0 || datasourcesRemoved > 0 || dependencies.rewritten > 0 {
+		modifiedState = out.String()
+		ctx.Content = []byte(modifiedState)
+		h.log.Debug("Transformed state resources", "count", transformedCount)
+	}
+
+	ctx.StateJSON = modifiedState
+	ctx.Metadata["state_transformations"] = transformedCount
+	ctx.Metadata["datasources_removed"] = datasourcesRemoved
+	ctx.Metadata["dependencies_rewritten"] = dependencies.rewritten
+
+	return h.Next(ctx)
+}
+
+// stateEdit replaces the bytes from start to end of a resource with text
+type stateEdit struct {
+	start, end int
+	text       string
+}
+
+// transformResource transforms the instances of a managed resource and returns the
+// resource JSON with its type and changed instances replaced, and the number of
+// transformed instances
+func (h *StateTransformHandler) transformResource(ctx *transform.Context, index int, resource gjson.Result, dependencies *dependencyRewriter) (string, int) {
+	resourceAddress := address.FromState(resource, gjson.Result{})
+	resourceType := resourceAddress.Type
+	var migrator transform.ResourceTransformer
+	if resourceType != "" {
+		migrator = h.provider.GetMigrator(resourceType, ctx.SourceVersion, ctx.TargetVersion)
+	}
+	if resourceType != "" && migrator == nil {
+		diag := &hcl.Diagnostic{
+			Severity: hcl.DiagWarning,
+			Summary:  fmt.Sprintf("Failed to transform resource: %s", resourceType),
+			Detail:   fmt.Sprintf("No migrator found for state resource: %s (v%s -> v%s)", resourceType, ctx.SourceVersion, ctx.TargetVersion),
 		}
+		ctx.Diagnostics = append(ctx.Diagnostics, diag)
+		ctx.RecordResourceChange(transform.ResourceChange{
+			OldType:     resourceType,
+			NewType:     resourceType,
+			OldAddress:  resourceAddress.String(),
+			NewAddress:  resourceAddress.String(),
+			Diagnostics: hcl.Diagnostics{diag},
+		})
+		h.log.Debug("No migrator found for state resource", "type", resourceType, "source", ctx.SourceVersion, "target", ctx.TargetVersion)
+	}
+	instances := resource.Get("instances")
+	if !instances.Exists() {
+		return resource.Raw, 0
+	}
 
-		instances := resource.Get("instances")
-		if !instances.Exists() {
-			return true
+	var edits []stateEdit
+	// replace queues the replacement of a value read from the resource
+	replace := func(value gjson.Result, text string) {
+		start := value.Index - resource.Index
+		edits = append(edits, stateEdit{start: start, end: start + len(value.Raw), text: text})
+	}
+
+	newResourceType := resourceType
+	if migrator != nil && migrator.CanHandle(resourceType) {
+		// Update the resource type if it changed (e.g., teams_list -> zero_trust_list)
+		if t := migrator.GetResourceType(); t != "" && t != resourceType {
+			newResourceType = t
+			quoted, _ := json.Marshal(newResourceType)
+			replace(resource.Get("type"), string(quoted))
+			h.log.Debug("Updated resource type", "from", resourceType, "to", newResourceType)
 		}
+	}
 
-		// Check if this migrator can handle the resource and transform the type
-		if migrator.CanHandle(resourceType) {
-			// Update the resource type if it changed (e.g., teams_list -> zero_trust_list)
-			newResourceType := migrator.GetResourceType()
-			if newResourceType != "" && newResourceType != resourceType {
-				resourcePath := fmt.Sprintf("resources.%d.type", key.Int())
-				modifiedState, _ = sjson.Set(modifiedState, resourcePath, newResourceType)
-				h.log.Debug("Updated resource type", "from", resourceType, "to", newResourceType)
+	resourceName := resource.Get("name").String()
+	transformedCount := 0
+	instances.ForEach(func(instKey, instance gjson.Result) bool {
+		instanceJSON := instance.Raw
+		instanceAddress := resourceAddress.WithIndexKey(instance.Get("index_key"))
+		if migrator != nil {
+			if transformedJSON, ok := h.transformInstance(ctx, migrator, instance, instanceAddress, index, int(instKey.Int()), newResourceType, resourceName); ok {
+				instanceJSON = transformedJSON
+				transformedCount++
 			}
 		}
 
-		resourceName := resource.Get("name").String()
-		instances.ForEach(func(instKey, instance gjson.Result) bool {
-			resourcePath := fmt.Sprintf("resources.%d.instances.%d", key.Int(), instKey.Int())
-
-			transformedJSON, err := migrator.TransformState(ctx, instance, resourcePath, resourceName)
-			if err != nil {
-				h.log.Error("Error transforming state resource",
-					"type", resourceType,
-					"path", resourcePath,
-					"error", err)
-				ctx.Diagnostics = append(ctx.Diagnostics, &hcl.Diagnostic{
-					Severity: hcl.DiagError,
-					Summary:  fmt.Sprintf("Failed to transform resource: %s", resourceType),
-					Detail:   err.Error(),
-				})
-				return true
-			}
+		instanceJSON = dependencies.rewrite(instanceAddress, instanceJSON)
+		if instanceJSON != instance.Raw {
+			replace(instance, instanceJSON)
+		}
+		return true
+	})
+
+	if len(edits) == 0 {
+		return resource.Raw, transformedCount
+	}
+
+	sort.Slice(edits, func(i, j int) bool { return edits[i].start < edits[j].start })
+	var out strings.Builder
+	last := 0
+	for _, edit := range edits {
+		out.WriteString(resource.Raw[last:edit.start])
+		out.WriteString(edit.text)
+		last = edit.end
+	}
+	out.WriteString(resource.Raw[last:])
+	return out.String(), transformedCount
+}
+
+// transformInstance runs the migrator on a single instance and records the change. It
+// returns the transformed instance JSON, or false if the instance is left unchanged.
+func (h *StateTransformHandler) transformInstance(ctx *transform.Context, migrator transform.ResourceTransformer, instance gjson.Result,
+	instanceAddress address.Address, index, instanceIndex int, newResourceType, resourceName string) (string, bool) {
+	resourceType := instanceAddress.Type
+	resourcePath := fmt.Sprintf("resources.%d.instances.%d", index, instanceIndex)
+	diagsBefore := len(ctx.Diagnostics)
+	defer func() {
+		ctx.RecordResourceChange(transform.ResourceChange{
+			OldType:     resourceType,
+			NewType:     newResourceType,
+			OldAddress:  instanceAddress.String(),
+			NewAddress:  instanceAddress.WithType(newResourceType).String(),
+			Migrator:    fmt.Sprintf("%T", migrator),
+			Diagnostics: diagnosticsSince(ctx, diagsBefore),
+		})
+	}()
+
+	transformedJSON, err := migrator.TransformState(ctx, instance, resourcePath, resourceName)
+	if err != nil {
+		h.log.Error("Error transforming state resource",
+			"type", resourceType,
+			"path", resourcePath,
+			"error", err)
+		ctx.Diagnostics = append(ctx.Diagnostics, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  fmt.Sprintf("Failed to transform resource: %s", resourceType),
+			Detail:   err.Error(),
+		})
+		return "", false
+	}
+	if transformedJSON == "" {
+		return "", false
+	}
+
+	if !gjson.Valid(transformedJSON) {
+		h.log.Error("Failed to update state JSON",
+			"path", resourcePath,
+			"error", "migrator returned invalid JSON")
+		ctx.Diagnostics = append(ctx.Diagnostics, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  fmt.Sprintf("Failed to update state JSON for resource: %s", resourceType),
+			Detail:   fmt.Sprintf("The migrator returned invalid JSON for %s", resourcePath),
+		})
+		return "", false
+	}
+	return transformedJSON, true
+}
 
-			if transformedJSON != "" {
-				newState, err := sjson.SetRaw(modifiedState, resourcePath, transformedJSON)
-				if err != nil {
-					h.log.Error("Failed to update state JSON",
-						"path", resourcePath,
-						"error", err)
-					ctx.Diagnostics = append(ctx.Diagnostics, &hcl.Diagnostic{
-						Severity: hcl.DiagError,
-						Summary:  fmt.Sprintf("Failed to update state JSON for resource: %s", resourceType),
-						Detail:   err.Error(),
-					})
-					return true
+// locatedIn reports whether a value read from a document points at its own bytes in it,
+// so it can be replaced without searching the document
+func locatedIn(doc string, value gjson.Result) bool {
+	return value.Index > 0 && value.Index+len(value.Raw) <= len(doc) && doc[value.Index:value.Index+len(value.Raw)] == value.Raw
+}
+
+// dependencyRewriter applies resource type renames to the dependencies of every
+// instance in the state, so destroy ordering still refers to existing resources.
+// Dependencies on managed resources that are not in the migrated state are reported
+// as warnings.
+type dependencyRewriter struct {
+	renames map[string]string
+	known   map[string]bool
+	// reported holds the unresolved dependencies already warned about
+	reported    map[string]bool
+	rewritten   int
+	diagnostics hcl.Diagnostics
+	log         hclog.Logger
+}
+
+func (h *StateTransformHandler) newDependencyRewriter(ctx *transform.Context, resources gjson.Result) *dependencyRewriter {
+	renames := make(map[string]string)
+	for _, migrator := range h.provider.GetAllMigrators(ctx.SourceVersion, ctx.TargetVersion, ctx.Resources...) {
+		if renamer, ok := migrator.(transform.ResourceRenamer); ok {
+			if oldType, newType := renamer.GetResourceRename(); oldType != "" && newType != "" && oldType != newType {
+				renames[oldType] = newType
+			}
+		}
+	}
+	if provider, ok := h.provider.(transform.DataSourceProvider); ok {
+		for _, migrator := range provider.GetAllDataSourceMigrators(ctx.SourceVersion, ctx.TargetVersion) {
+			if renamer, ok := migrator.(transform.ResourceRenamer); ok {
+				if oldType, newType := renamer.GetResourceRename(); oldType != "" && newType != "" && oldType != newType {
+					renames["data."+oldType] = newType
 				}
-				modifiedState = newState
-				transformedCount++
 			}
+		}
+	}
 
+	// Dependencies are resource addresses without instance keys, in modules too. Data
+	// sources are removed from the migrated state, and managed resources may change type.
+	known := make(map[string]bool)
+	resources.ForEach(func(_, resource gjson.Result) bool {
+		if resource.Get("mode").String() == "data" {
 			return true
-		})
-
+		}
+		addr := address.FromState(resource, gjson.Result{}).WithType(h.migratedType(ctx, resource))
+		addr.Module = addr.ModulePath()
+		known[addr.String()] = true
 		return true
 	})
 
-	// Remove datasources from state (in reverse order to avoid index shifting)
-	for i := len(datasourceIndices) - 1; i >= 0; i-- {
-		idx := datasourceIndices[i]
-		resourcePath := fmt.Sprintf("resources.%d", idx)
-		modifiedState, _ = sjson.Delete(modifiedState, resourcePath)
-		h.log.Debug("Removed datasource from state", "index", idx)
+	return &dependencyRewriter{renames: renames, known: known, reported: make(map[string]bool), log: h.log}
+}
+
+// migratedType returns the type of a managed resource in the migrated state
+func (h *StateTransformHandler) migratedType(ctx *transform.Context, resource gjson.Result) string {
+	resourceType := resource.Get("type").String()
+	if resourceType == "" || !resource.Get("instances").Exists() {
+		return resourceType
+	}
+	migrator := h.provider.GetMigrator(resourceType, ctx.SourceVersion, ctx.TargetVersion)
+	if migrator == nil || !migrator.CanHandle(resourceType) {
+		return resourceType
+	}
+	if t := migrator.GetResourceType(); t != "" {
+		return t
 	}
+	return resourceType
+}
 
-	if len(datasourceIndices) > 0 {
-		h.log.Info("Removed datasources from state (will be refreshed by Terraform)", "count", len(datasourceIndices))
+// rewrite returns the instance JSON with its dependencies renamed
+func (d *dependencyRewriter) rewrite(instanceAddress address.Address, instanceJSON string) string {
+	dependencies := gjson.Get(instanceJSON, "dependencies")
+	if !dependencies.IsArray() {
+		return instanceJSON
 	}
 
-	if transformedCount > 0 || len(datasourceIndices) > 0 {
-		ctx.Content = []byte(modifiedState)
-		h.log.Debug("Transformed state resources", "count", transformedCount)
+	updated := make([]string, 0, len(dependencies.Array()))
+	changed := false
+	for _, dependency := range dependencies.Array() {
+		dependencyAddress := dependency.String()
+		if renamed := renameDependency(dependencyAddress, d.renames); renamed != dependencyAddress {
+			d.log.Debug("Updated state dependency", "from", dependencyAddress, "to", renamed)
+			dependencyAddress = renamed
+			changed = true
+			d.rewritten++
+		}
+		updated = append(updated, dependencyAddress)
+
+		if !d.known[dependencyAddress] && !d.reported[dependencyAddress] && !isDataAddress(dependencyAddress) {
+			d.reported[dependencyAddress] = true
+			d.diagnostics = append(d.diagnostics, &hcl.Diagnostic{
+				Severity: hcl.DiagWarning,
+				Summary:  fmt.Sprintf("Unresolved state dependency: %s", dependencyAddress),
+				Detail: fmt.Sprintf("%s depends on %s, which is not in the migrated state. Terraform may destroy resources in the wrong order.",
+					instanceAddress.String(), dependencyAddress),
+			})
+		}
 	}
 
-	ctx.StateJSON = modifiedState
-	ctx.Metadata["state_transformations"] = transformedCount
-	ctx.Metadata["datasources_removed"] = len(datasourceIndices)
+	if !changed {
+		return instanceJSON
+	}
+	rewritten, err := sjson.Set(instanceJSON, "dependencies", updated)
+	if err != nil {
+		return instanceJSON
+	}
+	return rewritten
+}
 
-	return h.Next(ctx)
+// renameDependency applies a type rename to a dependency address such as
+// module.x.cloudflare_tunnel.t. Data source renames are keyed as data.<type>.
+func renameDependency(dependency string, renames map[string]string) string {
+	addr, err := address.Parse(dependency)
+	if err != nil {
+		return dependency
+	}
+	key := addr.Type
+	if addr.Mode == address.DataMode {
+		key = "data." + key
+	}
+	newType, ok := renames[key]
+	if !ok {
+		return dependency
+	}
+	return addr.WithType(newType).String()
+}
+
+// isDataAddress reports whether a resource address refers to a data source
+func isDataAddress(dependency string) bool {
+	addr, err := address.Parse(dependency)
+	return err == nil && addr.Mode == address.DataMode
 }
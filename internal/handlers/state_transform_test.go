@@ -2,6 +2,7 @@ package handlers_test
 
 import (
 	"encoding/json"
+	"strings"
 	"testing"
 
 	"github.com/tidwall/gjson"
@@ -15,6 +16,7 @@ func TestStateTransformHandler(t *testing.T) {
 		name        string
 		input       string
 		transformer *MockResourceTransformer
+		dataSources []*MockDataSourceTransformer
 		expectError bool
 		checkResult func(*testing.T, *transform.Context)
 	}{
@@ -71,6 +73,184 @@ func TestStateTransformHandler(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "Record instance and data source changes",
+			input: `{
+  "version": 4,
+  "resources": [
+    {
+      "mode": "data",
+      "type": "cloudflare_zones",
+      "name": "all",
+      "instances": [{"attributes": {"id": "1"}}]
+    },
+    {
+      "module": "module.dns",
+      "mode": "managed",
+      "type": "old_resource",
+      "name": "example",
+      "instances": [
+        {"index_key": "a", "attributes": {"id": "123"}},
+        {"index_key": "b", "attributes": {"id": "456"}}
+      ]
+    }
+  ]
+}`,
+			transformer: &MockResourceTransformer{
+				resourceType: "old_resource",
+				stateTransformFunc: func(json gjson.Result, path string) (string, error) {
+					return json.Raw, nil
+				},
+			},
+			checkResult: func(t *testing.T, ctx *transform.Context) {
+				changes := ctx.ResourceChanges()
+				if len(changes) != 3 {
+					t.Fatalf("Expected 3 recorded changes, got %d", len(changes))
+				}
+				if changes[0].OldAddress != "data.cloudflare_zones.all" || changes[0].NewAddress != "" {
+					t.Errorf("Expected removed data source, got %+v", changes[0])
+				}
+				if len(changes[0].Diagnostics) != 1 || transform.FollowUpFor(changes[0].Diagnostics[0]) != transform.FollowUpRefresh {
+					t.Errorf("Expected data source removal to need a refresh, got %v", changes[0].Diagnostics)
+				}
+				if changes[1].NewAddress != `module.dns.old_resource.example["a"]` {
+					t.Errorf("Unexpected first instance address: %s", changes[1].NewAddress)
+				}
+				if changes[2].NewAddress != `module.dns.old_resource.example["b"]` {
+					t.Errorf("Unexpected second instance address: %s", changes[2].NewAddress)
+				}
+			},
+		},
+		{
+			name: "Rewrite dependencies of renamed resources",
+			input: `{
+  "version": 4,
+  "resources": [
+    {
+      "mode": "managed",
+      "type": "new_resource",
+      "name": "a",
+      "instances": [{"attributes": {"id": "1"}}]
+    },
+    {
+      "module": "module.x[\"prod\"]",
+      "mode": "managed",
+      "type": "new_resource",
+      "name": "b",
+      "instances": [{"attributes": {"id": "2"}}]
+    },
+    {
+      "mode": "managed",
+      "type": "new_resource",
+      "name": "c",
+      "instances": [
+        {
+          "index_key": 0,
+          "attributes": {"id": "3"},
+          "dependencies": ["data.old_resource.lookup", "module.x.old_resource.b", "old_resource.a", "old_resource.missing"]
+        },
+        {
+          "index_key": 1,
+          "attributes": {"id": "4"},
+          "dependencies": ["old_resource.missing"]
+        }
+      ]
+    }
+  ]
+}`,
+			transformer: &MockResourceTransformer{
+				resourceType: "new_resource",
+				renamedFrom:  "old_resource",
+			},
+			checkResult: func(t *testing.T, ctx *transform.Context) {
+				dependencies := gjson.GetBytes(ctx.Content, "resources.2.instances.0.dependencies").Array()
+				expected := []string{"data.old_resource.lookup", "module.x.new_resource.b", "new_resource.a", "new_resource.missing"}
+				if len(dependencies) != len(expected) {
+					t.Fatalf("Expected %d dependencies, got %d", len(expected), len(dependencies))
+				}
+				for i, dependency := range dependencies {
+					if dependency.String() != expected[i] {
+						t.Errorf("Expected dependency %q, got %q", expected[i], dependency.String())
+					}
+				}
+
+				// Reported once, though both instances of c depend on it
+				if len(ctx.Diagnostics) != 1 {
+					t.Fatalf("Expected 1 diagnostic for the unresolved dependency, got %d", len(ctx.Diagnostics))
+				}
+				if !strings.Contains(ctx.Diagnostics[0].Summary, "new_resource.missing") {
+					t.Errorf("Expected diagnostic for new_resource.missing, got %q", ctx.Diagnostics[0].Summary)
+				}
+			},
+		},
+		{
+			name: "Rewrite dependencies on renamed data sources",
+			input: `{
+  "version": 4,
+  "resources": [
+    {
+      "mode": "data",
+      "type": "old_lookup",
+      "name": "lookup",
+      "instances": [{"attributes": {"id": "1"}}]
+    },
+    {
+      "mode": "managed",
+      "type": "new_resource",
+      "name": "a",
+      "instances": [
+        {
+          "attributes": {"id": "2"},
+          "dependencies": ["data.old_lookup.lookup", "module.x.data.old_lookup.lookup"]
+        }
+      ]
+    }
+  ]
+}`,
+			transformer: &MockResourceTransformer{
+				resourceType: "new_resource",
+			},
+			dataSources: []*MockDataSourceTransformer{{dataSourceType: "new_lookup", renamedFrom: "old_lookup"}},
+			checkResult: func(t *testing.T, ctx *transform.Context) {
+				dependencies := gjson.GetBytes(ctx.Content, "resources.0.instances.0.dependencies").Array()
+				expected := []string{"data.new_lookup.lookup", "module.x.data.new_lookup.lookup"}
+				if len(dependencies) != len(expected) {
+					t.Fatalf("Expected %d dependencies, got %d", len(expected), len(dependencies))
+				}
+				for i, dependency := range dependencies {
+					if dependency.String() != expected[i] {
+						t.Errorf("Expected dependency %q, got %q", expected[i], dependency.String())
+					}
+				}
+			},
+		},
+		{
+			name: "Keep field order and number formatting",
+			input: `{"version":4,"serial":12345678901234567890,"resources":[` +
+				`{"mode":"managed","type":"old_resource","name":"a","instances":[{"schema_version":0,"attributes":{"zeta":1.50,"alpha":1e3}},{"attributes":{"zeta":2}}]},` +
+				`{"mode":"data","type":"lookup","name":"b","instances":[]},` +
+				`{"mode":"managed","type":"other","name":"c","instances":[{"attributes":{"zeta":0.10,"alpha":-0}}]}],"check_results":null}`,
+			transformer: &MockResourceTransformer{
+				resourceType: "old_resource",
+				stateTransformFunc: func(json gjson.Result, path string) (string, error) {
+					if path != "resources.0.instances.1" {
+						return "", nil
+					}
+					return `{"attributes":{"zeta":2.0,"beta":"new"}}`, nil
+				},
+			},
+			checkResult: func(t *testing.T, ctx *transform.Context) {
+				expected := `{"version":4,"serial":12345678901234567890,"resources":[` +
+					`{"mode":"managed","type":"old_resource","name":"a","instances":[{"schema_version":0,"attributes":{"zeta":1.50,"alpha":1e3}},{"attributes":{"zeta":2.0,"beta":"new"}}]},` +
+					`{"mode":"managed","type":"other","name":"c","instances":[{"attributes":{"zeta":0.10,"alpha":-0}}]}],"check_results":null}`
+				if string(ctx.Content) != expected {
+					t.Errorf("Unexpected state.\nGot:\n%s\nExpected:\n%s", ctx.Content, expected)
+				}
+				if ctx.Metadata["state_transformations"] != 1 || ctx.Metadata["datasources_removed"] != 1 {
+					t.Errorf("Unexpected metadata: %v", ctx.Metadata)
+				}
+			},
+		},
 		{
 			name:        "Handle invalid JSON",
 			input:       `{invalid json`,
@@ -125,7 +305,10 @@ func TestStateTransformHandler(t *testing.T) {
 			if tt.transformer != nil {
 				transformers = []*MockResourceTransformer{tt.transformer}
 			}
-			provider := NewMockMigratorProvider(transformers)
+			var provider transform.MigrationProvider = NewMockMigratorProvider(transformers)
+			if tt.dataSources != nil {
+				provider = &MockDataSourceProvider{MockMigratorProvider: NewMockMigratorProvider(transformers), dataSources: tt.dataSources}
+			}
 
 			handler := handlers.NewStateTransformHandler(log, provider)
 			ctx := &transform.Context{
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+
+	"github.com/cloudflare/tf-migrate/internal/transform"
+)
+
+// supportedStateVersion is the only state format version tf-migrate can migrate
+const supportedStateVersion = 4
+
+// StateVersionHandler checks the state format version before any transformation and
+// updates the top-level state fields so the migrated file is accepted as a new revision:
+// serial is incremented, lineage is kept and terraform_version is optionally replaced.
+// In a migration across several provider versions the fields are only updated by the
+// last hop, so the serial is incremented once per run.
+type StateVersionHandler struct {
+	transform.BaseHandler
+	log hclog.Logger
+}
+
+func NewStateVersionHandler(log hclog.Logger) transform.TransformationHandler {
+	return &StateVersionHandler{
+		log: log,
+	}
+}
+
+func (h *StateVersionHandler) Handle(ctx *transform.Context) (*transform.Context, error) {
+	if len(ctx.Content) == 0 {
+		return ctx, fmt.Errorf("state content is empty")
+	}
+	if !gjson.ValidBytes(ctx.Content) {
+		return ctx, fmt.Errorf("invalid JSON in state file")
+	}
+
+	version := gjson.GetBytes(ctx.Content, "version")
+	if !version.Exists() {
+		return ctx, fmt.Errorf("state file has no format version: only version %d state files are supported", supportedStateVersion)
+	}
+	if version.Type != gjson.Number || version.Int() != supportedStateVersion {
+		return ctx, fmt.Errorf("unsupported state format version %s: only version %d state files (Terraform 0.12 and later) are supported", version.Raw, supportedStateVersion)
+	}
+
+	if !ctx.LastHop() {
+		return h.Next(ctx)
+	}
+
+	content := ctx.Content
+	var err error
+
+	if serial := gjson.GetBytes(content, "serial"); serial.Exists() {
+		content, err = sjson.SetBytes(content, "serial", serial.Int()+1)
+		if err != nil {
+			return ctx, fmt.Errorf("failed to update state serial: %w", err)
+		}
+		h.log.Debug("Incremented state serial", "from", serial.Int(), "to", serial.Int()+1)
+	} else {
+		h.log.Warn("State file has no serial, leaving it unset")
+	}
+
+	if ctx.TerraformVersion != "" {
+		content, err = sjson.SetBytes(content, "terraform_version", ctx.TerraformVersion)
+		if err != nil {
+			return ctx, fmt.Errorf("failed to update state terraform_version: %w", err)
+		}
+		h.log.Debug("Stamped terraform_version on state", "version", ctx.TerraformVersion)
+	}
+
+	ctx.Content = content
+	ctx.StateJSON = string(content)
+	return h.Next(ctx)
+}
@@ -0,0 +1,102 @@
+package handlers_test
+
+import (
+	"testing"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/cloudflare/tf-migrate/internal/handlers"
+	"github.com/cloudflare/tf-migrate/internal/transform"
+)
+
+func TestStateVersionHandler(t *testing.T) {
+	tests := []struct {
+		name             string
+		input            string
+		terraformVersion string
+		expectError      bool
+		checkResult      func(*testing.T, *transform.Context)
+	}{
+		{
+			name:  "Increment serial and keep lineage",
+			input: `{"version": 4, "terraform_version": "1.5.0", "serial": 41, "lineage": "abc-123", "resources": []}`,
+			checkResult: func(t *testing.T, ctx *transform.Context) {
+				if serial := gjson.GetBytes(ctx.Content, "serial").Int(); serial != 42 {
+					t.Errorf("Expected serial 42, got %d", serial)
+				}
+				if lineage := gjson.GetBytes(ctx.Content, "lineage").String(); lineage != "abc-123" {
+					t.Errorf("Expected lineage to be kept, got %q", lineage)
+				}
+				if version := gjson.GetBytes(ctx.Content, "terraform_version").String(); version != "1.5.0" {
+					t.Errorf("Expected terraform_version to be kept, got %q", version)
+				}
+			},
+		},
+		{
+			name:             "Stamp terraform_version",
+			input:            `{"version": 4, "terraform_version": "1.5.0", "serial": 1, "lineage": "abc-123"}`,
+			terraformVersion: "1.9.8",
+			checkResult: func(t *testing.T, ctx *transform.Context) {
+				if version := gjson.GetBytes(ctx.Content, "terraform_version").String(); version != "1.9.8" {
+					t.Errorf("Expected terraform_version 1.9.8, got %q", version)
+				}
+				if serial := gjson.GetBytes(ctx.Content, "serial").Int(); serial != 2 {
+					t.Errorf("Expected serial 2, got %d", serial)
+				}
+			},
+		},
+		{
+			name:  "State without serial",
+			input: `{"version": 4, "lineage": "abc-123"}`,
+			checkResult: func(t *testing.T, ctx *transform.Context) {
+				if gjson.GetBytes(ctx.Content, "serial").Exists() {
+					t.Error("Expected serial to remain unset")
+				}
+			},
+		},
+		{
+			name:        "Refuse version 3 state",
+			input:       `{"version": 3, "serial": 1, "modules": []}`,
+			expectError: true,
+		},
+		{
+			name:        "Refuse state without version",
+			input:       `{"serial": 1, "resources": []}`,
+			expectError: true,
+		},
+		{
+			name:        "Refuse invalid JSON",
+			input:       `{invalid json`,
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := handlers.NewStateVersionHandler(log)
+			ctx := &transform.Context{
+				Content:          []byte(tt.input),
+				Filename:         "terraform.tfstate",
+				Metadata:         make(map[string]interface{}),
+				TerraformVersion: tt.terraformVersion,
+			}
+
+			result, err := handler.Handle(ctx)
+
+			if tt.expectError {
+				if err == nil {
+					t.Fatal("Expected error but got nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if tt.checkResult != nil {
+				tt.checkResult(t, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,294 @@
+package hcl
+
+import (
+	"bytes"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+)
+
+// CommentedObjectAttr is an attribute of an object together with its comments
+type CommentedObjectAttr struct {
+	hclwrite.ObjectAttrTokens
+	// LeadComments are written on the lines above the attribute
+	LeadComments hclwrite.Tokens
+	// LineComments are written after the value, on the same line
+	LineComments hclwrite.Tokens
+}
+
+// CommentedTupleElem is an element of a tuple together with the comments above it
+type CommentedTupleElem struct {
+	Value        hclwrite.Tokens
+	LeadComments hclwrite.Tokens
+}
+
+// LeadComments returns the comments on the lines directly above a block
+func LeadComments(block *hclwrite.Block) hclwrite.Tokens {
+	return leadingComments(block.BuildTokens(nil))
+}
+
+// AttributeComments returns the comments on the lines directly above an attribute of a
+// body and the comment after its value on the same line. Comments inside the value are
+// part of its expression and are not returned. Comments added above the attribute as
+// unstructured tokens, like those SetAttributeFromBlock keeps, are found too.
+func AttributeComments(body *hclwrite.Body, name string) (lead, line hclwrite.Tokens) {
+	attr := body.GetAttribute(name)
+	if attr == nil {
+		return nil, nil
+	}
+	tokens := attr.BuildTokens(nil)
+	own := leadingComments(tokens)
+	// The attribute is its lead comments, name, equals sign, expression, and either a
+	// line comment or a newline
+	end := len(own) + 2 + len(attr.Expr().BuildTokens(nil))
+	for _, token := range tokens[min(end, len(tokens)):] {
+		if token.Type == hclsyntax.TokenComment {
+			line = append(line, token)
+		}
+	}
+	return bodyLeadComments(body)[name], line
+}
+
+// OwnAttributeComments returns the comments that belong to an attribute of a body, and
+// are removed along with it: those on the lines directly above it and the comment after
+// its value. Unlike AttributeComments, comments added above the attribute as
+// unstructured tokens are not returned.
+func OwnAttributeComments(body *hclwrite.Body, name string) (lead, line hclwrite.Tokens) {
+	attr := body.GetAttribute(name)
+	if attr == nil {
+		return nil, nil
+	}
+	_, line = AttributeComments(body, name)
+	return leadingComments(attr.BuildTokens(nil)), line
+}
+
+// SetAttributeWithComments sets an attribute like SetAttributeRaw, with the lead comments
+// written above it and the line comments after its value. The lead comments are only
+// written when the attribute is added, not when the value of an existing one is replaced.
+func SetAttributeWithComments(body *hclwrite.Body, name string, tokens, lead, line hclwrite.Tokens) {
+	if len(lead) > 0 && body.GetAttribute(name) == nil {
+		body.AppendUnstructuredTokens(lead)
+	}
+	if len(line) > 0 {
+		tokens = append(append(hclwrite.Tokens{}, tokens...), line...)
+		// SetAttributeRaw ends the attribute with a newline, which a # or // comment holds
+		if last := tokens[len(tokens)-1]; isLineComment(last) {
+			tokens[len(tokens)-1] = &hclwrite.Token{
+				Type:  last.Type,
+				Bytes: bytes.TrimSuffix(last.Bytes, []byte{'\n'}),
+			}
+		}
+	}
+	body.SetAttributeRaw(name, tokens)
+}
+
+// bodyLeadComments returns the comments on the lines directly above each attribute of a
+// body, without a blank line in between
+func bodyLeadComments(body *hclwrite.Body) map[string]hclwrite.Tokens {
+	comments := make(map[string]hclwrite.Tokens)
+	tokens := body.BuildTokens(nil)
+	var pending hclwrite.Tokens
+	depth := 0
+	lineStart := true
+	for i, token := range tokens {
+		switch token.Type {
+		case hclsyntax.TokenOBrace, hclsyntax.TokenOBrack, hclsyntax.TokenOParen, hclsyntax.TokenTemplateInterp, hclsyntax.TokenTemplateControl:
+			depth++
+		case hclsyntax.TokenCBrace, hclsyntax.TokenCBrack, hclsyntax.TokenCParen, hclsyntax.TokenTemplateSeqEnd:
+			depth--
+		}
+		if depth > 0 || !lineStart {
+			if depth == 0 && (token.Type == hclsyntax.TokenNewline || isLineComment(token)) {
+				lineStart = true
+			}
+			pending = nil
+			continue
+		}
+
+		switch {
+		case token.Type == hclsyntax.TokenComment:
+			// Comments starting with # or // end the line, /* */ comments may not
+			pending = append(pending, token)
+		case token.Type == hclsyntax.TokenIdent && i+1 < len(tokens) && tokens[i+1].Type == hclsyntax.TokenEqual:
+			if len(pending) > 0 {
+				comments[string(token.Bytes)] = pending
+			}
+			pending = nil
+			lineStart = false
+		case token.Type == hclsyntax.TokenNewline:
+			// A blank line separates comments from what follows
+			pending = nil
+		default:
+			pending = nil
+			lineStart = false
+		}
+	}
+	return comments
+}
+
+func isLineComment(token *hclwrite.Token) bool {
+	return token.Type == hclsyntax.TokenComment && bytes.HasSuffix(token.Bytes, []byte{'\n'})
+}
+
+// leadingComments returns the comment tokens at the start of tokens
+func leadingComments(tokens hclwrite.Tokens) hclwrite.Tokens {
+	var comments hclwrite.Tokens
+	for _, token := range tokens {
+		if token.Type != hclsyntax.TokenComment {
+			break
+		}
+		comments = append(comments, token)
+	}
+	return comments
+}
+
+// TokensForCommentedObject creates object tokens like hclwrite.TokensForObject, with the
+// comments of each attribute written around it
+func TokensForCommentedObject(attrs []CommentedObjectAttr) hclwrite.Tokens {
+	plain := make([]hclwrite.ObjectAttrTokens, len(attrs))
+	commented := false
+	for i, attr := range attrs {
+		plain[i] = attr.ObjectAttrTokens
+		commented = commented || len(attr.LeadComments) > 0 || len(attr.LineComments) > 0
+	}
+	if !commented {
+		return hclwrite.TokensForObject(plain)
+	}
+
+	tokens := hclwrite.Tokens{{Type: hclsyntax.TokenOBrace, Bytes: []byte{'{'}}}
+	if len(attrs) > 0 {
+		tokens = append(tokens, newlineToken())
+	}
+	for _, attr := range attrs {
+		tokens = append(tokens, attr.LeadComments...)
+		tokens = append(tokens, attr.Name...)
+		tokens = append(tokens, &hclwrite.Token{Type: hclsyntax.TokenEqual, Bytes: []byte{'='}})
+		tokens = append(tokens, attr.Value...)
+		tokens = appendLineComments(tokens, attr.LineComments)
+	}
+	tokens = append(tokens, &hclwrite.Token{Type: hclsyntax.TokenCBrace, Bytes: []byte{'}'}})
+	return formatExpression(tokens)
+}
+
+// TokensForCommentedTuple creates tuple tokens like hclwrite.TokensForTuple. A tuple with
+// comments is written with one element per line, each below its comments.
+func TokensForCommentedTuple(elems []CommentedTupleElem) hclwrite.Tokens {
+	values := make([]hclwrite.Tokens, len(elems))
+	commented := false
+	for i, elem := range elems {
+		values[i] = elem.Value
+		commented = commented || len(elem.LeadComments) > 0
+	}
+	if !commented {
+		return hclwrite.TokensForTuple(values)
+	}
+
+	return tokensForCommentedList(
+		hclwrite.Tokens{{Type: hclsyntax.TokenOBrack, Bytes: []byte{'['}}},
+		elems,
+		&hclwrite.Token{Type: hclsyntax.TokenCBrack, Bytes: []byte{']'}},
+	)
+}
+
+// TokensForCommentedFunctionCall creates function call tokens like
+// hclwrite.TokensForFunctionCall. A call with comments, or with an argument spanning
+// several lines, is written with one argument per line, each below its comments.
+func TokensForCommentedFunctionCall(name string, args []CommentedTupleElem) hclwrite.Tokens {
+	values := make([]hclwrite.Tokens, len(args))
+	multiline := false
+	for i, arg := range args {
+		values[i] = arg.Value
+		multiline = multiline || len(arg.LeadComments) > 0 || hasNewline(arg.Value)
+	}
+	if !multiline {
+		return hclwrite.TokensForFunctionCall(name, values...)
+	}
+
+	return tokensForCommentedList(
+		hclwrite.Tokens{
+			{Type: hclsyntax.TokenIdent, Bytes: []byte(name)},
+			{Type: hclsyntax.TokenOParen, Bytes: []byte{'('}},
+		},
+		args,
+		&hclwrite.Token{Type: hclsyntax.TokenCParen, Bytes: []byte{')'}},
+	)
+}
+
+func hasNewline(tokens hclwrite.Tokens) bool {
+	for _, token := range tokens {
+		if token.Type == hclsyntax.TokenNewline || isLineComment(token) {
+			return true
+		}
+	}
+	return false
+}
+
+// tokensForCommentedList writes the elements between the open and close tokens, one per
+// line and each below its comments
+func tokensForCommentedList(open hclwrite.Tokens, elems []CommentedTupleElem, close *hclwrite.Token) hclwrite.Tokens {
+	tokens := append(open, newlineToken())
+	for _, elem := range elems {
+		tokens = append(tokens, elem.LeadComments...)
+		tokens = append(tokens, elem.Value...)
+		tokens = append(tokens, &hclwrite.Token{Type: hclsyntax.TokenComma, Bytes: []byte{','}}, newlineToken())
+	}
+	tokens = append(tokens, close)
+	return formatExpression(tokens)
+}
+
+// BuildTupleFromBlocks creates tuple tokens with an object for each block, built with
+// BuildObjectFromBlock, and the comments above each block written above its object
+func BuildTupleFromBlocks(blocks []*hclwrite.Block) hclwrite.Tokens {
+	elems := make([]CommentedTupleElem, len(blocks))
+	for i, block := range blocks {
+		elems[i] = CommentedTupleElem{
+			Value:        BuildObjectFromBlock(block),
+			LeadComments: LeadComments(block),
+		}
+	}
+	return TokensForCommentedTuple(elems)
+}
+
+// SetAttributeFromBlock sets an attribute to the given tokens in place of a block, which
+// is removed. Comments above the block are kept above the attribute, unless the attribute
+// already exists.
+func SetAttributeFromBlock(body *hclwrite.Body, block *hclwrite.Block, attrName string, tokens hclwrite.Tokens) {
+	if comments := LeadComments(block); len(comments) > 0 && body.GetAttribute(attrName) == nil {
+		body.AppendUnstructuredTokens(comments)
+	}
+	body.SetAttributeRaw(attrName, tokens)
+	body.RemoveBlock(block)
+}
+
+// appendLineComments ends an attribute with its line comments, or a newline
+func appendLineComments(tokens, comments hclwrite.Tokens) hclwrite.Tokens {
+	if len(comments) == 0 {
+		return append(tokens, newlineToken())
+	}
+	tokens = append(tokens, comments...)
+	// Comments starting with # or // end with the newline, /* */ comments do not
+	if last := comments[len(comments)-1]; !bytes.HasSuffix(last.Bytes, []byte{'\n'}) {
+		tokens = append(tokens, newlineToken())
+	}
+	return tokens
+}
+
+// formatExpression gives the tokens of an expression canonical spacing, as the
+// hclwrite.TokensFor functions do. The tokens are returned unchanged if they do not parse.
+func formatExpression(tokens hclwrite.Tokens) hclwrite.Tokens {
+	src := hclwrite.Format(append([]byte("x = "), tokens.Bytes()...))
+	file, diags := hclwrite.ParseConfig(src, "", hcl.InitialPos)
+	if diags.HasErrors() {
+		return tokens
+	}
+	attr := file.Body().GetAttribute("x")
+	if attr == nil {
+		return tokens
+	}
+	return attr.Expr().BuildTokens(nil)
+}
+
+func newlineToken() *hclwrite.Token {
+	return &hclwrite.Token{Type: hclsyntax.TokenNewline, Bytes: []byte{'\n'}}
+}
@@ -85,40 +85,70 @@ func BuildResourceReference(resourceType, resourceName string) hclwrite.Tokens {
 	}
 }
 
+// BuildForExpression creates tokens for a for expression building a list, like
+// [for key, value in collection : result]. The key is left out when it is empty.
+func BuildForExpression(key, value string, collection, result hclwrite.Tokens) hclwrite.Tokens {
+	tokens := hclwrite.Tokens{
+		{Type: hclsyntax.TokenOBrack, Bytes: []byte{'['}},
+		{Type: hclsyntax.TokenIdent, Bytes: []byte("for")},
+	}
+	if key != "" {
+		tokens = append(tokens,
+			&hclwrite.Token{Type: hclsyntax.TokenIdent, Bytes: []byte(key), SpacesBefore: 1},
+			&hclwrite.Token{Type: hclsyntax.TokenComma, Bytes: []byte{','}},
+		)
+	}
+	tokens = append(tokens,
+		&hclwrite.Token{Type: hclsyntax.TokenIdent, Bytes: []byte(value), SpacesBefore: 1},
+		&hclwrite.Token{Type: hclsyntax.TokenIdent, Bytes: []byte("in"), SpacesBefore: 1},
+	)
+	tokens = append(tokens, spacedTokens(collection)...)
+	tokens = append(tokens, &hclwrite.Token{Type: hclsyntax.TokenColon, Bytes: []byte{':'}, SpacesBefore: 1})
+	tokens = append(tokens, spacedTokens(result)...)
+	tokens = append(tokens, &hclwrite.Token{Type: hclsyntax.TokenCBrack, Bytes: []byte{']'}})
+	return tokens
+}
+
+// spacedTokens returns a copy of tokens whose first token is preceded by a space
+func spacedTokens(tokens hclwrite.Tokens) hclwrite.Tokens {
+	if len(tokens) == 0 {
+		return nil
+	}
+	first := *tokens[0]
+	first.SpacesBefore = 1
+	return append(hclwrite.Tokens{&first}, tokens[1:]...)
+}
+
 // CreateMovedBlock creates a moved block for resource migration
-// This is used when resources are renamed or restructured between provider versions
+// This is used when resources are renamed or restructured between provider versions.
+// Addresses may include module paths and instance keys, e.g. module.dns.cloudflare_record.www["a"]
 func CreateMovedBlock(from, to string) *hclwrite.Block {
 	block := hclwrite.NewBlock("moved", nil)
 	body := block.Body()
 
-	// Create traversals for from and to
-	fromParts := strings.Split(from, ".")
-	toParts := strings.Split(to, ".")
+	body.SetAttributeTraversal("from", parseAddress(from))
+	body.SetAttributeTraversal("to", parseAddress(to))
 
-	// Build from traversal
-	fromTraversal := hcl.Traversal{}
-	for i, part := range fromParts {
-		if i == 0 {
-			fromTraversal = append(fromTraversal, hcl.TraverseRoot{Name: part})
-		} else {
-			fromTraversal = append(fromTraversal, hcl.TraverseAttr{Name: part})
-		}
+	return block
+}
+
+// parseAddress converts a resource address into a traversal.
+// Addresses that are not valid traversals are split on dots.
+func parseAddress(address string) hcl.Traversal {
+	traversal, diags := hclsyntax.ParseTraversalAbs([]byte(address), "", hcl.InitialPos)
+	if !diags.HasErrors() {
+		return traversal
 	}
 
-	// Build to traversal
-	toTraversal := hcl.Traversal{}
-	for i, part := range toParts {
+	traversal = hcl.Traversal{}
+	for i, part := range strings.Split(address, ".") {
 		if i == 0 {
-			toTraversal = append(toTraversal, hcl.TraverseRoot{Name: part})
+			traversal = append(traversal, hcl.TraverseRoot{Name: part})
 		} else {
-			toTraversal = append(toTraversal, hcl.TraverseAttr{Name: part})
+			traversal = append(traversal, hcl.TraverseAttr{Name: part})
 		}
 	}
-
-	body.SetAttributeTraversal("from", fromTraversal)
-	body.SetAttributeTraversal("to", toTraversal)
-
-	return block
+	return traversal
 }
 
 // CreateImportBlock creates an import block for a resource
@@ -154,13 +184,14 @@ func CreateImportBlockWithTokens(resourceType, resourceName string, idTokens hcl
 }
 
 // BuildObjectFromBlock creates object tokens from a block's attributes
-// Useful for converting block syntax to object syntax
+// Useful for converting block syntax to object syntax. The comments above each
+// attribute and after its value are kept.
 func BuildObjectFromBlock(block *hclwrite.Block) hclwrite.Tokens {
 	// Get attributes in their original order
 	orderedAttrs := AttributesOrdered(block.Body())
 
 	// Build a list of attribute tokens preserving the original order
-	var attrs []hclwrite.ObjectAttrTokens
+	var attrs []CommentedObjectAttr
 
 	for _, attrInfo := range orderedAttrs {
 		// Create tokens for the attribute name (as a simple identifier)
@@ -169,14 +200,19 @@ func BuildObjectFromBlock(block *hclwrite.Block) hclwrite.Tokens {
 		// Get the value tokens from the attribute's expression
 		valueTokens := attrInfo.Attribute.Expr().BuildTokens(nil)
 
-		attrs = append(attrs, hclwrite.ObjectAttrTokens{
-			Name:  nameTokens,
-			Value: valueTokens,
+		lead, line := AttributeComments(block.Body(), attrInfo.Name)
+		attrs = append(attrs, CommentedObjectAttr{
+			ObjectAttrTokens: hclwrite.ObjectAttrTokens{
+				Name:  nameTokens,
+				Value: valueTokens,
+			},
+			LeadComments: lead,
+			LineComments: line,
 		})
 	}
 
-	// Use the built-in TokensForObject function to create properly formatted object tokens
-	return hclwrite.TokensForObject(attrs)
+	// Without comments this is the same as hclwrite.TokensForObject
+	return TokensForCommentedObject(attrs)
 }
 
 // SetAttributeValue is a helper that sets an attribute value based on its Go type
@@ -170,6 +170,41 @@ func TestBuildResourceReference(t *testing.T) {
 	}
 }
 
+func TestBuildForExpression(t *testing.T) {
+	tests := []struct {
+		name       string
+		key        string
+		value      string
+		collection hclwrite.Tokens
+		expected   string
+	}{
+		{
+			name:       "Value only",
+			value:      "policy",
+			collection: hclwrite.TokensForTraversal(hcl.Traversal{hcl.TraverseRoot{Name: "var"}, hcl.TraverseAttr{Name: "policies"}}),
+			expected:   "[for policy in var.policies : policy]",
+		},
+		{
+			name:       "Key and value",
+			key:        "zone_key",
+			value:      "zone",
+			collection: hclwrite.TokensForTraversal(hcl.Traversal{hcl.TraverseRoot{Name: "var"}, hcl.TraverseAttr{Name: "zones"}}),
+			expected:   "[for zone_key, zone in var.zones : zone]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens := BuildForExpression(tt.key, tt.value, tt.collection, hclwrite.TokensForIdentifier(tt.value))
+			result := string(tokens.Bytes())
+
+			if result != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, result)
+			}
+		})
+	}
+}
+
 func TestCreateMovedBlock(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -202,6 +237,15 @@ func TestCreateMovedBlock(t *testing.T) {
 			expected: `moved {
   from = cloudflare_record.example[0]
   to   = cloudflare_dns_record.example[0]
+}`,
+		},
+		{
+			name: "Module instance with string keys",
+			from: `module.dns["prod"].cloudflare_record.example["a.b"]`,
+			to:   `module.dns["prod"].cloudflare_dns_record.example["a.b"]`,
+			expected: `moved {
+  from = module.dns["prod"].cloudflare_record.example["a.b"]
+  to   = module.dns["prod"].cloudflare_dns_record.example["a.b"]
 }`,
 		},
 	}
@@ -362,6 +406,30 @@ func TestBuildObjectFromBlock(t *testing.T) {
 			hcl:  `empty {}`,
 			expected: `{}`,
 		},
+		{
+			name: "Block with comments",
+			hcl: `policy {
+  # Allow the deploy pipeline
+  # See ticket SEC-42
+  effect = "allow" # reviewed yearly
+
+  # Not attached to an attribute
+
+  resources = {
+    "com.cloudflare.api.account.*" = "*" # every account
+  }
+  /* legacy */ name = "deploy"
+}`,
+			expected: `{
+  # Allow the deploy pipeline
+  # See ticket SEC-42
+  effect = "allow" # reviewed yearly
+  resources = {
+    "com.cloudflare.api.account.*" = "*" # every account
+  }
+  /* legacy */ name = "deploy"
+}`,
+		},
 	}
 
 	for _, tt := range tests {
@@ -771,4 +839,38 @@ func normalizeHCL(hcl string) string {
 		}
 	}
 	return strings.Join(normalized, "\n")
-}
\ No newline at end of file
+}
+func TestBuildTupleFromBlocks(t *testing.T) {
+	file, diags := hclwrite.ParseConfig([]byte(`# First entry
+entry {
+  name = "a" # primary
+}
+entry {
+  name = "b"
+}
+`), "", hcl.InitialPos)
+	if diags.HasErrors() {
+		t.Fatalf("Failed to parse HCL: %v", diags)
+	}
+
+	result := string(hclwrite.Format(BuildTupleFromBlocks(file.Body().Blocks()).Bytes()))
+	expected := `[
+  # First entry
+  {
+    name = "a" # primary
+  },
+  {
+    name = "b"
+  },
+]`
+	if result != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, result)
+	}
+
+	// Without comments the tuple is the same as hclwrite.TokensForTuple
+	file, _ = hclwrite.ParseConfig([]byte("entry {\n  name = \"a\"\n}\n"), "", hcl.InitialPos)
+	result = string(BuildTupleFromBlocks(file.Body().Blocks()).Bytes())
+	if result != "[{\n  name = \"a\"\n}]" {
+		t.Errorf("Expected the tuple without comments on one line, got:\n%s", result)
+	}
+}
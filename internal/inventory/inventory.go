@@ -0,0 +1,358 @@
+// Package inventory lists the Cloudflare resources and data sources found in a set of
+// configuration files and a state file, and whether a migrator is registered for each,
+// so a migration can be planned before any file is touched.
+package inventory
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/tidwall/gjson"
+
+	"github.com/cloudflare/tf-migrate/internal/address"
+	"github.com/cloudflare/tf-migrate/internal/transform"
+)
+
+// cloudflarePrefix is the type prefix of every resource and data source of the provider
+const cloudflarePrefix = "cloudflare_"
+
+// Inventory is the result of scanning a repository
+type Inventory struct {
+	SourceVersion string      `json:"source_version"`
+	TargetVersion string      `json:"target_version"`
+	Types         []TypeEntry `json:"types"`
+	ManualWork    []Finding   `json:"manual_work"`
+	Summary       Summary     `json:"summary"`
+
+	providers transform.MigrationProvider
+}
+
+// TypeEntry describes a resource or data source type
+type TypeEntry struct {
+	// Mode is "managed" for resources and "data" for data sources
+	Mode address.Mode `json:"mode"`
+	Type string       `json:"type"`
+	// Migrator reports whether a migrator is registered for the version path
+	Migrator bool `json:"migrator"`
+	// NewType is the type in the target version, when the migrator renames it
+	NewType string `json:"new_type,omitempty"`
+	// ConfigBlocks is the number of resource or data blocks in the configuration
+	ConfigBlocks int `json:"config_blocks"`
+	// StateInstances is the number of instances in the state
+	StateInstances int           `json:"state_instances"`
+	Modules        []ModuleEntry `json:"modules"`
+}
+
+// ModuleEntry holds the counts of a type in a single module. Module is the module path,
+// e.g. module.dns, or "" for the root module. Configuration directories that are not called
+// from the root module are listed by directory instead, e.g. ./modules/unused.
+type ModuleEntry struct {
+	Module         string `json:"module"`
+	ConfigBlocks   int    `json:"config_blocks"`
+	StateInstances int    `json:"state_instances"`
+}
+
+// Finding is a construct that the migration cannot handle automatically
+type Finding struct {
+	File    string `json:"file,omitempty"`
+	Line    int    `json:"line,omitempty"`
+	Address string `json:"address,omitempty"`
+	Reason  string `json:"reason"`
+}
+
+// Summary holds totals across the whole inventory
+type Summary struct {
+	Files                int `json:"files"`
+	ConfigBlocks         int `json:"config_blocks"`
+	StateInstances       int `json:"state_instances"`
+	Types                int `json:"types"`
+	TypesWithoutMigrator int `json:"types_without_migrator"`
+	ManualWork           int `json:"manual_work"`
+}
+
+// New creates an empty inventory for the given version path. Migrators are looked up
+// in providers, which should also implement transform.DataSourceProvider to find data
+// source migrators.
+func New(sourceVersion, targetVersion string, providers transform.MigrationProvider) *Inventory {
+	return &Inventory{
+		SourceVersion: sourceVersion,
+		TargetVersion: targetVersion,
+		Types:         []TypeEntry{},
+		ManualWork:    []Finding{},
+		providers:     providers,
+	}
+}
+
+// AddConfigFile adds the Cloudflare resource and data blocks of a parsed configuration file.
+// modules are the module paths that call the file's directory; each block is counted once
+// per module path.
+func (inv *Inventory) AddConfigFile(path string, modules []string, file *hclwrite.File) {
+	inv.Summary.Files++
+
+	for _, block := range file.Body().Blocks() {
+		labels := block.Labels()
+		if len(labels) != 2 || !strings.HasPrefix(labels[0], cloudflarePrefix) {
+			continue
+		}
+
+		mode := address.ManagedMode
+		switch block.Type() {
+		case "resource":
+		case "data":
+			mode = address.DataMode
+		default:
+			continue
+		}
+
+		entry := inv.entry(mode, labels[0])
+		for _, module := range modules {
+			entry.ConfigBlocks++
+			inv.Summary.ConfigBlocks++
+			entry.module(module).ConfigBlocks++
+		}
+
+		addr := address.Address{Mode: mode, Type: labels[0], Name: labels[1]}
+		for _, nested := range block.Body().Blocks() {
+			if nested.Type() != "dynamic" || !entry.Migrator {
+				continue
+			}
+			name := ""
+			if len(nested.Labels()) > 0 {
+				name = nested.Labels()[0]
+			}
+			inv.addFinding(Finding{
+				File:    path,
+				Line:    blockLine(file, nested),
+				Address: addr.String(),
+				Reason:  fmt.Sprintf("dynamic %q block may not be converted by the migrator", name),
+			})
+		}
+	}
+}
+
+// AddState adds the Cloudflare resource instances of a state file
+func (inv *Inventory) AddState(stateJSON string) error {
+	if !gjson.Valid(stateJSON) {
+		return fmt.Errorf("state file is not valid JSON")
+	}
+
+	gjson.Get(stateJSON, "resources").ForEach(func(_, resource gjson.Result) bool {
+		addr := address.FromState(resource, gjson.Result{})
+		if !strings.HasPrefix(addr.Type, cloudflarePrefix) {
+			return true
+		}
+
+		instances := len(resource.Get("instances").Array())
+		entry := inv.entry(addr.Mode, addr.Type)
+		entry.StateInstances += instances
+		entry.module(addr.ModulePath()).StateInstances += instances
+		inv.Summary.StateInstances += instances
+		return true
+	})
+	return nil
+}
+
+// AddDiagnostics adds diagnostics raised for a configuration file as manual work,
+// such as references to attributes that are removed in the target version
+func (inv *Inventory) AddDiagnostics(path string, diags hcl.Diagnostics) {
+	for _, diag := range diags {
+		finding := Finding{File: path, Reason: diag.Summary}
+		if diag.Detail != "" {
+			finding.Reason = diag.Detail
+		}
+		if diag.Subject != nil {
+			finding.Line = diag.Subject.Start.Line
+		}
+		inv.addFinding(finding)
+	}
+}
+
+// WriteJSON writes the inventory as indented JSON
+func (inv *Inventory) WriteJSON(w io.Writer) error {
+	inv.sort()
+	data, err := json.MarshalIndent(inv, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode inventory: %w", err)
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// WriteTable writes the inventory as human-readable tables
+func (inv *Inventory) WriteTable(w io.Writer) error {
+	inv.sort()
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "KIND\tTYPE\tMIGRATOR (%s → %s)\tCONFIG\tSTATE\n", inv.SourceVersion, inv.TargetVersion)
+	for _, entry := range inv.Types {
+		migrator := "no"
+		if entry.Migrator {
+			migrator = "yes"
+			if entry.NewType != "" {
+				migrator += " → " + entry.NewType
+			}
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%d\n", kind(entry.Mode), entry.Type, migrator, entry.ConfigBlocks, entry.StateInstances)
+	}
+
+	fmt.Fprintf(tw, "\nMODULE\tKIND\tTYPE\tCONFIG\tSTATE\n")
+	for _, row := range inv.moduleRows() {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%d\n", moduleName(row.module.Module), kind(row.mode), row.typ, row.module.ConfigBlocks, row.module.StateInstances)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if len(inv.ManualWork) > 0 {
+		fmt.Fprintf(w, "\nManual work (%d):\n", len(inv.ManualWork))
+		for _, finding := range inv.ManualWork {
+			fmt.Fprintf(w, "  %s\n", finding)
+		}
+	}
+
+	s := inv.Summary
+	_, err := fmt.Fprintf(w, "\n%d types (%d without a migrator), %d configuration blocks in %d files, %d state instances, %d manual items\n",
+		s.Types, s.TypesWithoutMigrator, s.ConfigBlocks, s.Files, s.StateInstances, s.ManualWork)
+	return err
+}
+
+// String renders a finding as file:line: address: reason
+func (f Finding) String() string {
+	var parts []string
+	if f.File != "" {
+		location := f.File
+		if f.Line > 0 {
+			location = fmt.Sprintf("%s:%d", f.File, f.Line)
+		}
+		parts = append(parts, location)
+	}
+	if f.Address != "" {
+		parts = append(parts, f.Address)
+	}
+	return strings.Join(append(parts, f.Reason), ": ")
+}
+
+// entry returns the entry of a type, adding it if needed
+func (inv *Inventory) entry(mode address.Mode, resourceType string) *TypeEntry {
+	for i := range inv.Types {
+		if inv.Types[i].Mode == mode && inv.Types[i].Type == resourceType {
+			return &inv.Types[i]
+		}
+	}
+
+	entry := TypeEntry{Mode: mode, Type: resourceType, Modules: []ModuleEntry{}}
+	newType := ""
+	if mode == address.DataMode {
+		if dataSources, ok := inv.providers.(transform.DataSourceProvider); ok {
+			if migrator := dataSources.GetDataSourceMigrator(resourceType, inv.SourceVersion, inv.TargetVersion); migrator != nil {
+				entry.Migrator = true
+				newType = migrator.GetDataSourceType()
+			}
+		}
+	} else if migrator := inv.providers.GetMigrator(resourceType, inv.SourceVersion, inv.TargetVersion); migrator != nil {
+		entry.Migrator = true
+		newType = migrator.GetResourceType()
+	}
+	if newType != resourceType {
+		entry.NewType = newType
+	}
+
+	inv.Types = append(inv.Types, entry)
+	inv.Summary.Types++
+	if !entry.Migrator {
+		inv.Summary.TypesWithoutMigrator++
+	}
+	return &inv.Types[len(inv.Types)-1]
+}
+
+// module returns the counts of the entry in a module, adding them if needed
+func (e *TypeEntry) module(module string) *ModuleEntry {
+	for i := range e.Modules {
+		if e.Modules[i].Module == module {
+			return &e.Modules[i]
+		}
+	}
+	e.Modules = append(e.Modules, ModuleEntry{Module: module})
+	return &e.Modules[len(e.Modules)-1]
+}
+
+func (inv *Inventory) addFinding(finding Finding) {
+	inv.ManualWork = append(inv.ManualWork, finding)
+	inv.Summary.ManualWork++
+}
+
+// sort orders types by mode and name, modules by path and findings by location
+func (inv *Inventory) sort() {
+	sort.Slice(inv.Types, func(i, j int) bool {
+		if inv.Types[i].Mode != inv.Types[j].Mode {
+			return inv.Types[i].Mode == address.ManagedMode
+		}
+		return inv.Types[i].Type < inv.Types[j].Type
+	})
+	for _, entry := range inv.Types {
+		sort.Slice(entry.Modules, func(i, j int) bool { return entry.Modules[i].Module < entry.Modules[j].Module })
+	}
+	sort.SliceStable(inv.ManualWork, func(i, j int) bool {
+		a, b := inv.ManualWork[i], inv.ManualWork[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Line < b.Line
+	})
+}
+
+type moduleRow struct {
+	module ModuleEntry
+	mode   address.Mode
+	typ    string
+}
+
+// moduleRows returns the per-module counts of every type, grouped by module
+func (inv *Inventory) moduleRows() []moduleRow {
+	var rows []moduleRow
+	for _, entry := range inv.Types {
+		for _, module := range entry.Modules {
+			rows = append(rows, moduleRow{module: module, mode: entry.Mode, typ: entry.Type})
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].module.Module < rows[j].module.Module })
+	return rows
+}
+
+func kind(mode address.Mode) string {
+	if mode == address.DataMode {
+		return "data"
+	}
+	return "resource"
+}
+
+func moduleName(module string) string {
+	if module == "" {
+		return "(root)"
+	}
+	return module
+}
+
+// blockLine returns the line of a block in a file, or 0 if it cannot be found
+func blockLine(file *hclwrite.File, block *hclwrite.Block) int {
+	blockTokens := block.BuildTokens(nil)
+	if len(blockTokens) == 0 {
+		return 0
+	}
+
+	line := 1
+	for _, token := range file.BuildTokens(nil) {
+		if token == blockTokens[0] {
+			return line
+		}
+		line += bytes.Count(token.Bytes, []byte("\n"))
+	}
+	return 0
+}
@@ -0,0 +1,131 @@
+package inventory
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/cloudflare/tf-migrate/internal/address"
+	"github.com/cloudflare/tf-migrate/internal/testhelpers"
+	"github.com/cloudflare/tf-migrate/internal/transform"
+)
+
+func newProviders() transform.MigrationProvider {
+	return testhelpers.NewMigrationProvider(
+		map[string]transform.ResourceTransformer{
+			"cloudflare_record": &testhelpers.TypeMigrator{NewType: "cloudflare_dns_record"},
+		},
+		map[string]transform.DataSourceTransformer{
+			"cloudflare_zones": &testhelpers.TypeDataSourceMigrator{NewType: "cloudflare_zones"},
+		},
+	)
+}
+
+func parse(t *testing.T, src string) *hclwrite.File {
+	t.Helper()
+	file, diags := hclwrite.ParseConfig([]byte(src), "main.tf", hcl.InitialPos)
+	require.False(t, diags.HasErrors(), diags.Error())
+	return file
+}
+
+func TestInventory(t *testing.T) {
+	inv := New("v4", "v5", newProviders())
+
+	inv.AddConfigFile("main.tf", []string{""}, parse(t, `
+data "cloudflare_zones" "all" {}
+
+resource "cloudflare_record" "www" {
+  zone_id = "z"
+
+  dynamic "data" {
+    for_each = var.srv
+    content {
+      port = data.value
+    }
+  }
+}
+
+resource "aws_instance" "ignored" {}
+`))
+	inv.AddConfigFile("modules/dns/main.tf", []string{"module.a", "module.b"}, parse(t, `
+resource "cloudflare_record" "api" {}
+resource "cloudflare_ruleset" "waf" {}
+`))
+	require.NoError(t, inv.AddState(`{
+  "version": 4,
+  "resources": [
+    {"mode": "managed", "type": "cloudflare_record", "name": "www", "instances": [{"attributes": {}}]},
+    {"module": "module.a[\"x\"]", "mode": "managed", "type": "cloudflare_record", "name": "api", "instances": [{"index_key": 0}, {"index_key": 1}]},
+    {"mode": "data", "type": "cloudflare_zones", "name": "all", "instances": [{"attributes": {}}]},
+    {"mode": "managed", "type": "aws_instance", "name": "ignored", "instances": [{"attributes": {}}]}
+  ]
+}`))
+	inv.AddDiagnostics("outputs.tf", hcl.Diagnostics{{
+		Severity: hcl.DiagError,
+		Summary:  "Reference to removed attribute",
+		Detail:   "cloudflare_dns_record.www.hostname was removed",
+		Subject:  &hcl.Range{Filename: "outputs.tf", Start: hcl.Pos{Line: 3}},
+	}})
+
+	var out bytes.Buffer
+	require.NoError(t, inv.WriteJSON(&out))
+	result := gjson.Parse(out.String())
+
+	types := result.Get("types").Array()
+	require.Len(t, types, 3)
+	assert.Equal(t, "cloudflare_record", types[0].Get("type").String())
+	assert.True(t, types[0].Get("migrator").Bool())
+	assert.Equal(t, "cloudflare_dns_record", types[0].Get("new_type").String())
+	assert.Equal(t, int64(3), types[0].Get("config_blocks").Int())
+	assert.Equal(t, int64(3), types[0].Get("state_instances").Int())
+	assert.JSONEq(t, `[
+  {"module": "", "config_blocks": 1, "state_instances": 1},
+  {"module": "module.a", "config_blocks": 1, "state_instances": 2},
+  {"module": "module.b", "config_blocks": 1, "state_instances": 0}
+]`, types[0].Get("modules").Raw)
+
+	assert.Equal(t, "cloudflare_ruleset", types[1].Get("type").String())
+	assert.False(t, types[1].Get("migrator").Bool())
+
+	assert.Equal(t, string(address.DataMode), types[2].Get("mode").String())
+	assert.True(t, types[2].Get("migrator").Bool())
+	assert.False(t, types[2].Get("new_type").Exists(), "data source is not renamed")
+
+	assert.JSONEq(t, `[
+  {"file": "main.tf", "line": 7, "address": "cloudflare_record.www", "reason": "dynamic \"data\" block may not be converted by the migrator"},
+  {"file": "outputs.tf", "line": 3, "reason": "cloudflare_dns_record.www.hostname was removed"}
+]`, result.Get("manual_work").Raw)
+
+	var summary Summary
+	require.NoError(t, json.Unmarshal([]byte(result.Get("summary").Raw), &summary))
+	assert.Equal(t, Summary{Files: 2, ConfigBlocks: 6, StateInstances: 4, Types: 3, TypesWithoutMigrator: 1, ManualWork: 2}, summary)
+}
+
+func TestWriteTable(t *testing.T) {
+	inv := New("v4", "v5", newProviders())
+	inv.AddConfigFile("main.tf", []string{""}, parse(t, `resource "cloudflare_record" "www" {}`))
+	require.NoError(t, inv.AddState(`{"resources": [{"module": "module.dns", "mode": "managed", "type": "cloudflare_record", "name": "www", "instances": [{}]}]}`))
+
+	var out bytes.Buffer
+	require.NoError(t, inv.WriteTable(&out))
+	assert.Equal(t, `KIND      TYPE               MIGRATOR (v4 → v5)           CONFIG  STATE
+resource  cloudflare_record  yes → cloudflare_dns_record  1       1
+
+MODULE      KIND      TYPE               CONFIG  STATE
+(root)      resource  cloudflare_record  1       0
+module.dns  resource  cloudflare_record  0       1
+
+1 types (0 without a migrator), 1 configuration blocks in 1 files, 1 state instances, 0 manual items
+`, out.String())
+}
+
+func TestAddStateRejectsInvalidJSON(t *testing.T) {
+	inv := New("v4", "v5", newProviders())
+	assert.Error(t, inv.AddState("{not json"))
+}
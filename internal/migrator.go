@@ -2,6 +2,7 @@ package internal
 
 import (
 	"fmt"
+	"sort"
 
 	"github.com/cloudflare/tf-migrate/internal/transform"
 )
@@ -57,3 +58,103 @@ func RegisterMigrator(sourceVersionResourceType string, sourceVersion string, ta
 		TargetVersion:    targetVersion,
 	}
 }
+
+// DataSourceMigrator holds information about a registered data source migrator
+type DataSourceMigrator struct {
+	DataSourceMigrator transform.DataSourceTransformer
+	SourceVersion      string
+	TargetVersion      string
+}
+
+// dataSourceMigrators is the map of all available data source migrators.
+// Data sources live in their own namespace, since a resource and a data source can share a type name.
+// Key format: "dataSourceType:sourceVersion:targetVersion"
+var dataSourceMigrators = make(map[string]*DataSourceMigrator)
+
+// GetDataSourceMigrator returns the migrator for the given data source type and versions
+func GetDataSourceMigrator(dataSourceType string, sourceVersion string, targetVersion string) transform.DataSourceTransformer {
+	key := fmt.Sprintf("%s:%s:%s", dataSourceType, sourceVersion, targetVersion)
+	if reg, ok := dataSourceMigrators[key]; ok {
+		return reg.DataSourceMigrator
+	}
+	return nil
+}
+
+// GetAllDataSourceMigrators returns all data source migrators for the specified versions
+func GetAllDataSourceMigrators(sourceVersion string, targetVersion string) []transform.DataSourceTransformer {
+	result := make([]transform.DataSourceTransformer, 0)
+	for _, reg := range dataSourceMigrators {
+		if reg.SourceVersion == sourceVersion && reg.TargetVersion == targetVersion {
+			result = append(result, reg.DataSourceMigrator)
+		}
+	}
+	return result
+}
+
+// RegisterDataSourceMigrator registers a data source migrator for a specific version transition
+func RegisterDataSourceMigrator(sourceVersionDataSourceType string, sourceVersion string, targetVersion string, dataSourceMigrator transform.DataSourceTransformer) {
+	key := fmt.Sprintf("%s:%s:%s", sourceVersionDataSourceType, sourceVersion, targetVersion)
+	dataSourceMigrators[key] = &DataSourceMigrator{
+		DataSourceMigrator: dataSourceMigrator,
+		SourceVersion:      sourceVersion,
+		TargetVersion:      targetVersion,
+	}
+}
+
+// VersionPath returns the shortest chain of provider versions from sourceVersion to
+// targetVersion, such as [v4 v5 v6], where every hop between two adjacent versions has
+// registered resource or data source migrators, or is one of the extra hops given as
+// {source, target} pairs. It returns nil if the target version cannot be reached.
+func VersionPath(sourceVersion, targetVersion string, extra ...[2]string) []string {
+	if sourceVersion == targetVersion {
+		return nil
+	}
+
+	// Versions form a graph with an edge for every version path with a migrator
+	edges := make(map[string]map[string]bool)
+	addEdge := func(source, target string) {
+		if edges[source] == nil {
+			edges[source] = make(map[string]bool)
+		}
+		edges[source][target] = true
+	}
+	for _, reg := range migrators {
+		addEdge(reg.SourceVersion, reg.TargetVersion)
+	}
+	for _, reg := range dataSourceMigrators {
+		addEdge(reg.SourceVersion, reg.TargetVersion)
+	}
+	for _, hop := range extra {
+		addEdge(hop[0], hop[1])
+	}
+
+	// Breadth-first search finds the path with the fewest hops, visiting the targets of a
+	// version in order so that the path does not depend on the order of registration
+	previous := map[string]string{sourceVersion: ""}
+	queue := []string{sourceVersion}
+	for len(queue) > 0 {
+		version := queue[0]
+		queue = queue[1:]
+
+		targets := make([]string, 0, len(edges[version]))
+		for target := range edges[version] {
+			targets = append(targets, target)
+		}
+		sort.Strings(targets)
+		for _, target := range targets {
+			if _, seen := previous[target]; seen {
+				continue
+			}
+			previous[target] = version
+			if target == targetVersion {
+				path := []string{target}
+				for v := version; v != ""; v = previous[v] {
+					path = append([]string{v}, path...)
+				}
+				return path
+			}
+			queue = append(queue, target)
+		}
+	}
+	return nil
+}
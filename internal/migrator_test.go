@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/hashicorp/hcl/v2/hclwrite"
@@ -86,3 +87,79 @@ func TestVersionBasedMigratorSelection(t *testing.T) {
 		t.Errorf("Expected 0 migrators for v3->v4, got %d", len(all))
 	}
 }
+
+func TestVersionPath(t *testing.T) {
+	migrators = make(map[string]*Migrator)
+	dataSourceMigrators = make(map[string]*DataSourceMigrator)
+
+	RegisterMigrator("test_resource", "v4", "v5", &mockMigrator{version: "4-5"})
+	RegisterMigrator("test_resource", "v5", "v6", &mockMigrator{version: "5-6"})
+	RegisterDataSourceMigrator("test_resource", "v6", "v7", &mockDataSourceMigrator{})
+	RegisterMigrator("test_resource", "v5", "v7", &mockMigrator{version: "5-7"})
+
+	tests := []struct {
+		name     string
+		source   string
+		target   string
+		extra    [][2]string
+		expected []string
+	}{
+		{name: "Single hop", source: "v4", target: "v5", expected: []string{"v4", "v5"}},
+		{name: "Two hops", source: "v4", target: "v6", expected: []string{"v4", "v5", "v6"}},
+		{name: "Shortest path", source: "v4", target: "v7", expected: []string{"v4", "v5", "v7"}},
+		{name: "Data source hop", source: "v6", target: "v7", expected: []string{"v6", "v7"}},
+		{name: "Extra hop", source: "v3", target: "v6", extra: [][2]string{{"v3", "v4"}}, expected: []string{"v3", "v4", "v5", "v6"}},
+		{name: "Backwards", source: "v6", target: "v4"},
+		{name: "Unknown version", source: "v3", target: "v5"},
+		{name: "Same version", source: "v5", target: "v5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := VersionPath(tt.source, tt.target, tt.extra...)
+			if fmt.Sprint(path) != fmt.Sprint(tt.expected) {
+				t.Errorf("Expected path %v, got %v", tt.expected, path)
+			}
+		})
+	}
+}
+
+type mockDataSourceMigrator struct{}
+
+func (m *mockDataSourceMigrator) CanHandle(dataSourceType string) bool {
+	return true
+}
+
+func (m *mockDataSourceMigrator) TransformConfig(ctx *transform.Context, block *hclwrite.Block) (*transform.TransformResult, error) {
+	return nil, nil
+}
+
+func (m *mockDataSourceMigrator) GetDataSourceType() string {
+	return "test_resource"
+}
+
+func TestDataSourceMigratorNamespace(t *testing.T) {
+	migrators = make(map[string]*Migrator)
+	dataSourceMigrators = make(map[string]*DataSourceMigrator)
+
+	RegisterMigrator("test_resource", "v4", "v5", &mockMigrator{version: "4-5"})
+	RegisterDataSourceMigrator("test_resource", "v4", "v5", &mockDataSourceMigrator{})
+
+	if GetDataSourceMigrator("test_resource", "v4", "v5") == nil {
+		t.Fatal("Expected data source migrator for v4->v5, got nil")
+	}
+	if GetDataSourceMigrator("test_resource", "v5", "v6") != nil {
+		t.Error("Expected nil for non-existent v5->v6 data source migration")
+	}
+
+	// Resource and data source registrations with the same type must not collide
+	if _, ok := GetMigrator("test_resource", "v4", "v5").(*mockMigrator); !ok {
+		t.Error("Expected the resource migrator to be unaffected by the data source registration")
+	}
+
+	if all := GetAllDataSourceMigrators("v4", "v5"); len(all) != 1 {
+		t.Errorf("Expected 1 data source migrator for v4->v5, got %d", len(all))
+	}
+	if all := GetAllMigrators("v4", "v5"); len(all) != 1 {
+		t.Errorf("Expected 1 resource migrator for v4->v5, got %d", len(all))
+	}
+}
@@ -0,0 +1,125 @@
+// Package moved generates Terraform moved blocks for resources whose type is
+// renamed by a migration, as an alternative to rewriting the state file.
+package moved
+
+import (
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/tidwall/gjson"
+
+	"github.com/cloudflare/tf-migrate/internal/address"
+	"github.com/cloudflare/tf-migrate/internal/hcl"
+	"github.com/cloudflare/tf-migrate/internal/transform"
+)
+
+// FileName is the name of the file the moved blocks are written to
+const FileName = "tf_migrate_moved.tf"
+
+// Move is a single moved block
+type Move struct {
+	From string
+	To   string
+}
+
+// FromChanges returns a move for every configuration resource renamed by a migrator.
+// renames maps old resource types to new ones, as reported by transform.ResourceRenamer.
+func FromChanges(changes []transform.ResourceChange, renames map[string]string) []Move {
+	var moves []Move
+	for _, change := range changes {
+		newType, ok := renames[change.OldType]
+		if !ok || change.NewType != newType || change.NewAddress == "" {
+			continue
+		}
+		moves = append(moves, Move{From: change.OldAddress, To: change.NewAddress})
+	}
+	return moves
+}
+
+// FromState returns a move for every instance of a renamed managed resource in the state.
+// Addresses include the module path and the count or for_each key of the instance.
+func FromState(state []byte, renames map[string]string) []Move {
+	var moves []Move
+
+	gjson.GetBytes(state, "resources").ForEach(func(_, resource gjson.Result) bool {
+		if resource.Get("mode").String() == "data" {
+			return true
+		}
+		oldType := resource.Get("type").String()
+		newType, ok := renames[oldType]
+		if !ok {
+			return true
+		}
+
+		resource.Get("instances").ForEach(func(_, instance gjson.Result) bool {
+			addr := address.FromState(resource, instance.Get("index_key"))
+			moves = append(moves, Move{
+				From: addr.String(),
+				To:   addr.WithType(newType).String(),
+			})
+			return true
+		})
+		return true
+	})
+
+	return moves
+}
+
+// Merge returns the moves derived from the configuration followed by the moves read from
+// the state. A configuration move is left out when the state has a move from the same
+// address, or from an instance of the same resource, since a resource is moved either as a
+// whole or instance by instance.
+func Merge(configMoves, stateMoves []Move) []Move {
+	stateResources := make(map[string]bool)
+	for _, move := range stateMoves {
+		stateResources[move.From] = true
+		if from, err := address.Parse(move.From); err == nil {
+			stateResources[from.Resource().String()] = true
+		}
+	}
+
+	var moves []Move
+	for _, move := range configMoves {
+		if !stateResources[move.From] {
+			moves = append(moves, move)
+		}
+	}
+	return append(moves, stateMoves...)
+}
+
+// InModule returns the module path of a move, without instance keys, and the move with
+// addresses local to that module, as written in a moved block inside the module
+func (m Move) InModule() (string, Move) {
+	from, err := address.Parse(m.From)
+	if err != nil {
+		return "", m
+	}
+	to, err := address.Parse(m.To)
+	if err != nil {
+		return "", m
+	}
+	modulePath := from.ModulePath()
+	from.Module, to.Module = "", ""
+	return modulePath, Move{From: from.String(), To: to.String()}
+}
+
+// Render returns the contents of a moved blocks file. Duplicate moves are written once.
+func Render(moves []Move) []byte {
+	file := hclwrite.NewEmptyFile()
+	body := file.Body()
+	body.AppendUnstructuredTokens(hclwrite.Tokens{
+		{Type: hclsyntax.TokenComment, Bytes: []byte("# Generated by tf-migrate. These blocks move resources to their renamed types\n")},
+		{Type: hclsyntax.TokenComment, Bytes: []byte("# and can be removed once the migration has been applied everywhere.\n")},
+	})
+
+	seen := make(map[Move]bool)
+	for _, move := range moves {
+		if seen[move] {
+			continue
+		}
+		seen[move] = true
+		body.AppendNewline()
+		body.AppendBlock(hcl.CreateMovedBlock(move.From, move.To))
+	}
+
+	return hclwrite.Format(file.Bytes())
+}
@@ -0,0 +1,106 @@
+package moved
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cloudflare/tf-migrate/internal/transform"
+)
+
+var renames = map[string]string{
+	"cloudflare_record":     "cloudflare_dns_record",
+	"cloudflare_teams_list": "cloudflare_zero_trust_list",
+}
+
+func TestFromChanges(t *testing.T) {
+	changes := []transform.ResourceChange{
+		{OldType: "cloudflare_record", NewType: "cloudflare_dns_record", OldAddress: "cloudflare_record.www", NewAddress: "cloudflare_dns_record.www"},
+		{OldType: "cloudflare_zone", NewType: "cloudflare_zone", OldAddress: "cloudflare_zone.z", NewAddress: "cloudflare_zone.z"},
+		{OldType: "cloudflare_teams_list", OldAddress: "cloudflare_teams_list.failed"},
+		{OldType: "cloudflare_teams_list", NewType: "cloudflare_zero_trust_list", OldAddress: "cloudflare_teams_list.l", NewAddress: "cloudflare_zero_trust_list.l"},
+	}
+
+	assert.Equal(t, []Move{
+		{From: "cloudflare_record.www", To: "cloudflare_dns_record.www"},
+		{From: "cloudflare_teams_list.l", To: "cloudflare_zero_trust_list.l"},
+	}, FromChanges(changes, renames))
+}
+
+func TestFromState(t *testing.T) {
+	state := `{"resources":[
+		{"mode":"data","type":"cloudflare_record","name":"lookup","instances":[{"attributes":{}}]},
+		{"mode":"managed","type":"cloudflare_zone","name":"z","instances":[{"attributes":{}}]},
+		{"mode":"managed","type":"cloudflare_record","name":"www","instances":[{"attributes":{}}]},
+		{"module":"module.dns[\"prod\"]","mode":"managed","type":"cloudflare_record","name":"api","instances":[
+			{"index_key":"a","attributes":{}},
+			{"index_key":"${b}","attributes":{}}]},
+		{"mode":"managed","type":"cloudflare_teams_list","name":"l","instances":[
+			{"index_key":0,"attributes":{}},
+			{"index_key":1,"attributes":{}}]}]}`
+
+	assert.Equal(t, []Move{
+		{From: "cloudflare_record.www", To: "cloudflare_dns_record.www"},
+		{From: `module.dns["prod"].cloudflare_record.api["a"]`, To: `module.dns["prod"].cloudflare_dns_record.api["a"]`},
+		{From: `module.dns["prod"].cloudflare_record.api["$${b}"]`, To: `module.dns["prod"].cloudflare_dns_record.api["$${b}"]`},
+		{From: "cloudflare_teams_list.l[0]", To: "cloudflare_zero_trust_list.l[0]"},
+		{From: "cloudflare_teams_list.l[1]", To: "cloudflare_zero_trust_list.l[1]"},
+	}, FromState([]byte(state), renames))
+}
+
+func TestInModule(t *testing.T) {
+	modulePath, local := Move{
+		From: `module.dns["prod"].module.records.cloudflare_record.api["a"]`,
+		To:   `module.dns["prod"].module.records.cloudflare_dns_record.api["a"]`,
+	}.InModule()
+	assert.Equal(t, "module.dns.module.records", modulePath)
+	assert.Equal(t, Move{From: `cloudflare_record.api["a"]`, To: `cloudflare_dns_record.api["a"]`}, local)
+
+	root := Move{From: "cloudflare_record.www", To: "cloudflare_dns_record.www"}
+	modulePath, local = root.InModule()
+	assert.Equal(t, "", modulePath)
+	assert.Equal(t, root, local)
+}
+
+func TestMerge(t *testing.T) {
+	configMoves := []Move{
+		{From: "cloudflare_record.www", To: "cloudflare_dns_record.www"},
+		{From: "cloudflare_record.api", To: "cloudflare_dns_record.api"},
+		{From: "cloudflare_record.new", To: "cloudflare_dns_record.new"},
+	}
+	stateMoves := []Move{
+		{From: "cloudflare_record.www", To: "cloudflare_dns_record.www"},
+		{From: "cloudflare_record.api[0]", To: "cloudflare_dns_record.api[0]"},
+	}
+
+	// Resources without instances in the state keep the move of their configuration
+	assert.Equal(t, []Move{
+		{From: "cloudflare_record.new", To: "cloudflare_dns_record.new"},
+		{From: "cloudflare_record.www", To: "cloudflare_dns_record.www"},
+		{From: "cloudflare_record.api[0]", To: "cloudflare_dns_record.api[0]"},
+	}, Merge(configMoves, stateMoves))
+	assert.Equal(t, stateMoves, Merge(nil, stateMoves))
+}
+
+func TestRender(t *testing.T) {
+	moves := []Move{
+		{From: "cloudflare_record.www", To: "cloudflare_dns_record.www"},
+		{From: `module.dns.cloudflare_record.api["a"]`, To: `module.dns.cloudflare_dns_record.api["a"]`},
+		{From: "cloudflare_record.www", To: "cloudflare_dns_record.www"},
+	}
+
+	expected := `# Generated by tf-migrate. These blocks move resources to their renamed types
+# and can be removed once the migration has been applied everywhere.
+
+moved {
+  from = cloudflare_record.www
+  to   = cloudflare_dns_record.www
+}
+
+moved {
+  from = module.dns.cloudflare_record.api["a"]
+  to   = module.dns.cloudflare_dns_record.api["a"]
+}
+`
+	assert.Equal(t, expected, string(Render(moves)))
+}
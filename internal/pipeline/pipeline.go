@@ -1,6 +1,9 @@
 package pipeline
 
 import (
+	"fmt"
+	"sync"
+
 	"github.com/hashicorp/go-hclog"
 
 	"github.com/cloudflare/tf-migrate/internal/handlers"
@@ -32,22 +35,45 @@ func BuildConfigPipeline(log hclog.Logger, providers transform.MigrationProvider
 }
 
 // BuildStatePipeline creates the standard pipeline for JSON state files
-// Pipeline: Transform → Format
+// Pipeline: Version → Transform → Format
 func BuildStatePipeline(log hclog.Logger, providers transform.MigrationProvider) *Pipeline {
+	version := handlers.NewStateVersionHandler(log)
 	stateTransformer := handlers.NewStateTransformHandler(log, providers)
 	format := handlers.NewStateFormatterHandler(log)
 
 	// Chain handlers
+	version.SetNext(stateTransformer)
 	stateTransformer.SetNext(format)
 
 	return &Pipeline{
-		handler: stateTransformer,
+		handler: version,
 		log:     log,
 	}
 }
 
-// Transform executes the pipeline on the given content
+// Transform executes the pipeline on the given content. With ctx.Hops, the pipeline runs
+// once per hop, each on the output of the previous one, starting with the hop of ctx; the
+// context of every hop is linked from the previous one with Next.
 func (p *Pipeline) Transform(ctx *transform.Context) ([]byte, error) {
+	for {
+		content, err := p.transformHop(ctx)
+		if err != nil {
+			if ctx.MultiHop() {
+				return nil, fmt.Errorf("%s-%s: %w", ctx.SourceVersion, ctx.TargetVersion, err)
+			}
+			return nil, err
+		}
+
+		ctx.Next = ctx.NextHop(content)
+		if ctx.Next == nil {
+			return content, nil
+		}
+		ctx = ctx.Next
+	}
+}
+
+// transformHop executes the pipeline for the versions of ctx
+func (p *Pipeline) transformHop(ctx *transform.Context) ([]byte, error) {
 	result, err := p.handler.Handle(ctx)
 	if err != nil {
 		return nil, err
@@ -59,3 +85,52 @@ func (p *Pipeline) Transform(ctx *transform.Context) ([]byte, error) {
 
 	return result.Content, nil
 }
+
+// TransformAll runs the pipeline on every context with up to parallelism workers. done is
+// called once per context, in the order of ctxs, as soon as that context and all contexts
+// before it are transformed, so output and progress do not depend on the parallelism.
+// Handlers and migrators are shared by the workers, so they must not keep state between calls.
+func (p *Pipeline) TransformAll(ctxs []*transform.Context, parallelism int, done func(i int, content []byte, err error)) {
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	parallelism = min(parallelism, len(ctxs))
+
+	type result struct {
+		content []byte
+		err     error
+	}
+	results := make([]result, len(ctxs))
+	finished := make([]chan struct{}, len(ctxs))
+	for i := range finished {
+		finished[i] = make(chan struct{})
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range parallelism {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				content, err := p.Transform(ctxs[i])
+				results[i] = result{content: content, err: err}
+				close(finished[i])
+			}
+		}()
+	}
+	go func() {
+		for i := range ctxs {
+			jobs <- i
+		}
+		close(jobs)
+	}()
+
+	for i := range ctxs {
+		<-finished[i]
+		done(i, results[i].content, results[i].err)
+		// Release the output once it has been handed over
+		results[i] = result{}
+	}
+	wg.Wait()
+}
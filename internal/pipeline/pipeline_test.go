@@ -1,18 +1,26 @@
 package pipeline_test
 
 import (
+	"bytes"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/hcl/v2/hclwrite"
 
 	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
 
 	"github.com/zclconf/go-cty/cty"
 
+	"github.com/cloudflare/tf-migrate/internal"
 	"github.com/cloudflare/tf-migrate/internal/pipeline"
+	"github.com/cloudflare/tf-migrate/internal/registry"
 	"github.com/cloudflare/tf-migrate/internal/transform"
 )
 
@@ -457,4 +465,345 @@ func TestStandardPipelines(t *testing.T) {
 			t.Errorf("State pipeline failed on valid JSON: %v", err)
 		}
 	})
+
+	t.Run("BuildStatePipeline refuses unsupported state versions", func(t *testing.T) {
+		p := pipeline.BuildStatePipeline(log, providers)
+
+		ctx := &transform.Context{
+			Content:       []byte(`{"version":3,"serial":1}`),
+			Filename:      "terraform.tfstate",
+			SourceVersion: sourceVersion,
+			TargetVersion: targetVersion,
+		}
+		_, err := p.Transform(ctx)
+		if err == nil || !strings.Contains(err.Error(), "unsupported state format version 3") {
+			t.Errorf("Expected unsupported version error, got %v", err)
+		}
+	})
+}
+
+// delayTransformer renames test_resource blocks after sleeping for the number of
+// milliseconds in their delay attribute. It keeps no state, so it can run concurrently.
+type delayTransformer struct{}
+
+func (delayTransformer) CanHandle(resourceType string) bool { return resourceType == "test_resource" }
+func (delayTransformer) GetResourceType() string            { return "test_resource" }
+func (delayTransformer) Preprocess(content string) string   { return content }
+
+func (delayTransformer) TransformConfig(ctx *transform.Context, block *hclwrite.Block) (*transform.TransformResult, error) {
+	if attr := block.Body().GetAttribute("delay"); attr != nil {
+		delay, _ := strconv.Atoi(strings.TrimSpace(string(attr.Expr().BuildTokens(nil).Bytes())))
+		time.Sleep(time.Duration(delay) * time.Millisecond)
+	}
+	block.SetLabels([]string{"new_resource", block.Labels()[1]})
+	return &transform.TransformResult{Blocks: []*hclwrite.Block{block}}, nil
+}
+
+func (delayTransformer) TransformState(ctx *transform.Context, stateJSON gjson.Result, resourcePath, resourceName string) (string, error) {
+	return stateJSON.String(), nil
+}
+
+func TestTransformAll(t *testing.T) {
+	providers := setupTestMigrators(t, delayTransformer{})
+	p := pipeline.BuildConfigPipeline(log, providers)
+
+	// Later files finish first, and one file cannot be parsed
+	const files = 20
+	ctxs := make([]*transform.Context, files)
+	for i := range ctxs {
+		content := fmt.Sprintf("resource \"test_resource\" \"r%d\" {\n  delay = %d\n}\n", i, (files-i)*2)
+		if i == 5 {
+			content = `resource "test_resource" {{{`
+		}
+		ctxs[i] = &transform.Context{
+			Content:       []byte(content),
+			Filename:      fmt.Sprintf("file%d.tf", i),
+			Metadata:      make(map[string]interface{}),
+			SourceVersion: sourceVersion,
+			TargetVersion: targetVersion,
+		}
+	}
+
+	for _, parallelism := range []int{0, 1, 4, files * 2} {
+		t.Run(fmt.Sprintf("parallelism %d", parallelism), func(t *testing.T) {
+			var order []int
+			p.TransformAll(ctxs, parallelism, func(i int, content []byte, err error) {
+				order = append(order, i)
+				if i == 5 {
+					if err == nil {
+						t.Errorf("Expected an error for file %d", i)
+					}
+					return
+				}
+				if err != nil {
+					t.Errorf("Unexpected error for file %d: %v", i, err)
+				}
+				if expected := fmt.Sprintf(`resource "new_resource" "r%d"`, i); !strings.Contains(string(content), expected) {
+					t.Errorf("File %d: expected %q in output, got:\n%s", i, expected, content)
+				}
+			})
+
+			if len(order) != files {
+				t.Fatalf("Expected %d results, got %d", files, len(order))
+			}
+			for i, index := range order {
+				if index != i {
+					t.Fatalf("Results were not handled in file order: %v", order)
+				}
+			}
+		})
+	}
+
+	// No files
+	p.TransformAll(nil, 4, func(i int, content []byte, err error) {
+		t.Errorf("Unexpected result for file %d", i)
+	})
+}
+
+// TestTransformAllRegisteredMigrators runs the registered migrators on the integration test
+// inputs concurrently, several times each, and checks the output matches a sequential run.
+// Run with -race to check the migrators are safe to use concurrently.
+func TestTransformAllRegisteredMigrators(t *testing.T) {
+	registry.RegisterAllMigrations()
+	providers := transform.NewMigrationProviderWithDataSources(
+		internal.GetMigrator, internal.GetAllMigrators,
+		internal.GetDataSourceMigrator, internal.GetAllDataSourceMigrators,
+	)
+
+	inputs, err := filepath.Glob("../../integration/v4_to_v5/testdata/*/input")
+	if err != nil || len(inputs) == 0 {
+		t.Fatalf("Failed to find the integration test inputs: %v", err)
+	}
+
+	newContexts := func(state bool) []*transform.Context {
+		var ctxs []*transform.Context
+		for range 3 {
+			for _, dir := range inputs {
+				stateJSON, _ := os.ReadFile(filepath.Join(dir, "terraform.tfstate"))
+				if state {
+					if len(stateJSON) > 0 {
+						ctxs = append(ctxs, &transform.Context{
+							Content:       stateJSON,
+							StateJSON:     string(stateJSON),
+							Filename:      "terraform.tfstate",
+							Metadata:      make(map[string]interface{}),
+							SourceVersion: "v4",
+							TargetVersion: "v5",
+						})
+					}
+					continue
+				}
+
+				files, _ := filepath.Glob(filepath.Join(dir, "*.tf"))
+				for _, file := range files {
+					content, err := os.ReadFile(file)
+					if err != nil {
+						t.Fatalf("Failed to read %s: %v", file, err)
+					}
+					ctxs = append(ctxs, &transform.Context{
+						Content:       content,
+						StateJSON:     string(stateJSON),
+						Filename:      filepath.Base(file),
+						Metadata:      make(map[string]interface{}),
+						SourceVersion: "v4",
+						TargetVersion: "v5",
+					})
+				}
+			}
+		}
+		return ctxs
+	}
+
+	run := func(p *pipeline.Pipeline, state bool, parallelism int) [][]byte {
+		ctxs := newContexts(state)
+		outputs := make([][]byte, len(ctxs))
+		p.TransformAll(ctxs, parallelism, func(i int, content []byte, err error) {
+			if err != nil {
+				t.Errorf("Failed to transform %s: %v", ctxs[i].Filename, err)
+			}
+			outputs[i] = content
+		})
+		return outputs
+	}
+
+	for name, p := range map[string]*pipeline.Pipeline{
+		"config": pipeline.BuildConfigPipeline(log, providers),
+		"state":  pipeline.BuildStatePipeline(log, providers),
+	} {
+		t.Run(name, func(t *testing.T) {
+			state := name == "state"
+			sequential := run(p, state, 1)
+			concurrent := run(p, state, 8)
+			for i := range sequential {
+				if !bytes.Equal(sequential[i], concurrent[i]) {
+					t.Errorf("Output %d differs between sequential and concurrent runs", i)
+				}
+			}
+		})
+	}
+}
+
+func TestTransformHops(t *testing.T) {
+	// renameTo returns a transformer renaming the resources it handles to newType
+	renameTo := func(resourceType, newType string) *MockResourceTransformer {
+		return &MockResourceTransformer{
+			resourceType: resourceType,
+			transformFunc: func(ctx *transform.Context, block *hclwrite.Block) (*transform.TransformResult, error) {
+				if newType == "" {
+					return nil, fmt.Errorf("cannot migrate %s", block.Labels()[1])
+				}
+				block.SetLabels([]string{newType, block.Labels()[1]})
+				return &transform.TransformResult{Blocks: []*hclwrite.Block{block}}, nil
+			},
+		}
+	}
+	hops := []transform.Hop{
+		{SourceVersion: "v100", TargetVersion: "v200"},
+		{SourceVersion: "v200", TargetVersion: "v300"},
+	}
+	provider := &MockProvider{mockProviders: map[string]transform.ResourceTransformer{
+		"test_resource:v100:v200": renameTo("test_resource", "mid_resource"),
+		"mid_resource:v200:v300":  renameTo("mid_resource", "new_resource"),
+		"fail_resource:v200:v300": renameTo("fail_resource", ""),
+	}}
+	p := pipeline.BuildConfigPipeline(log, provider)
+
+	t.Run("Every hop runs on the output of the previous one", func(t *testing.T) {
+		ctx := &transform.Context{
+			Content:       []byte(`resource "test_resource" "example" {}`),
+			Filename:      "test.tf",
+			Metadata:      make(map[string]interface{}),
+			SourceVersion: "v100",
+			TargetVersion: "v200",
+			Hops:          hops,
+		}
+		content, err := p.Transform(ctx)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !strings.Contains(string(content), `resource "new_resource" "example"`) {
+			t.Errorf("Expected the resource to be renamed by both hops, got:\n%s", content)
+		}
+
+		chain := ctx.Chain()
+		if len(chain) != 2 {
+			t.Fatalf("Expected 2 hop contexts, got %d", len(chain))
+		}
+		for i, hop := range hops {
+			if chain[i].SourceVersion != hop.SourceVersion || chain[i].TargetVersion != hop.TargetVersion {
+				t.Errorf("Hop %d ran from %s to %s, expected %s", i, chain[i].SourceVersion, chain[i].TargetVersion, hop)
+			}
+		}
+		if changes := chain[0].ResourceChanges(); len(changes) != 1 || changes[0].NewType != "mid_resource" {
+			t.Errorf("Expected the first hop to record the rename to mid_resource, got %+v", changes)
+		}
+		if changes := chain[1].ResourceChanges(); len(changes) != 1 || changes[0].OldType != "mid_resource" || changes[0].NewType != "new_resource" {
+			t.Errorf("Expected the second hop to record the rename to new_resource, got %+v", changes)
+		}
+	})
+
+	t.Run("The state serial is incremented once", func(t *testing.T) {
+		ctx := &transform.Context{
+			Content:       []byte(`{"version": 4, "serial": 7, "lineage": "abc", "resources": []}`),
+			Filename:      "terraform.tfstate",
+			Metadata:      make(map[string]interface{}),
+			SourceVersion: "v100",
+			TargetVersion: "v200",
+			Hops:          hops,
+		}
+		content, err := pipeline.BuildStatePipeline(log, provider).Transform(ctx)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if serial := gjson.GetBytes(content, "serial").Int(); serial != 8 {
+			t.Errorf("Expected serial 8 after two hops, got %d", serial)
+		}
+	})
+
+	t.Run("Errors name their hop", func(t *testing.T) {
+		ctx := &transform.Context{
+			Content:       []byte(`resource "fail_resource" "example" {}`),
+			Filename:      "test.tf",
+			Metadata:      make(map[string]interface{}),
+			SourceVersion: "v100",
+			TargetVersion: "v200",
+			Hops:          hops,
+		}
+		_, err := p.Transform(ctx)
+		if err == nil || !strings.HasPrefix(err.Error(), "v200-v300: ") {
+			t.Errorf("Expected an error of the second hop, got %v", err)
+		}
+	})
+}
+
+// syntheticState returns a state of about size bytes, made of copies of the resources of the
+// integration test states. Every copy gets a new name, so each resource type shows up as often
+// as in the test data.
+func syntheticState(tb testing.TB, size int) []byte {
+	files, err := filepath.Glob("../../integration/v4_to_v5/testdata/*/input/terraform.tfstate")
+	if err != nil || len(files) == 0 {
+		tb.Fatalf("Failed to find the integration test states: %v", err)
+	}
+	var resources []gjson.Result
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			tb.Fatalf("Failed to read %s: %v", file, err)
+		}
+		resources = append(resources, gjson.GetBytes(content, "resources").Array()...)
+	}
+
+	var b strings.Builder
+	b.Grow(size + size/10)
+	b.WriteString(`{"version":4,"terraform_version":"1.5.0","serial":1,"lineage":"00000000-0000-0000-0000-000000000000","outputs":{},"resources":[`)
+	for i := 0; b.Len() < size; i++ {
+		resource := resources[i%len(resources)]
+		renamed, err := sjson.Set(resource.Raw, "name", fmt.Sprintf("%s_%d", resource.Get("name").String(), i))
+		if err != nil {
+			tb.Fatal(err)
+		}
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(renamed)
+	}
+	b.WriteString(`],"check_results":null}`)
+	return []byte(b.String())
+}
+
+// BenchmarkStatePipeline measures the state pipeline the CLI runs, with every registered
+// migrator: parsing, every state handler, the serial bump and the formatting.
+// The 100MB state is only benchmarked with BENCH_LARGE_STATE=1.
+func BenchmarkStatePipeline(b *testing.B) {
+	registry.RegisterAllMigrations()
+	providers := transform.NewMigrationProviderWithDataSources(
+		internal.GetMigrator, internal.GetAllMigrators,
+		internal.GetDataSourceMigrator, internal.GetAllDataSourceMigrators,
+	)
+	p := pipeline.BuildStatePipeline(hclog.NewNullLogger(), providers)
+
+	sizes := []int{1 << 20, 10 << 20}
+	if os.Getenv("BENCH_LARGE_STATE") == "1" {
+		sizes = append(sizes, 100<<20)
+	}
+	for _, size := range sizes {
+		state := syntheticState(b, size)
+		b.Run(fmt.Sprintf("%dMB", size>>20), func(b *testing.B) {
+			b.SetBytes(int64(len(state)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				ctx := &transform.Context{
+					Content:       state,
+					StateJSON:     string(state),
+					Filename:      "terraform.tfstate",
+					Metadata:      make(map[string]interface{}),
+					SourceVersion: "v4",
+					TargetVersion: "v5",
+				}
+				if _, err := p.Transform(ctx); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
 }
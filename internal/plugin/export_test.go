@@ -0,0 +1,19 @@
+package plugin
+
+import "time"
+
+// SetHandshakeTimeout changes the handshake timeout for a test and returns a function
+// restoring it
+func SetHandshakeTimeout(timeout time.Duration) (restore func()) {
+	previous := handshakeTimeout
+	handshakeTimeout = timeout
+	return func() { handshakeTimeout = previous }
+}
+
+// SetCallTimeout changes the timeout of the requests other than the handshake for a test
+// and returns a function restoring it
+func SetCallTimeout(timeout time.Duration) (restore func()) {
+	previous := callTimeout
+	callTimeout = timeout
+	return func() { callTimeout = previous }
+}
@@ -0,0 +1,411 @@
+package plugin
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/tidwall/gjson"
+
+	"github.com/cloudflare/tf-migrate/internal/transform"
+)
+
+// closeTimeout is how long a plugin has to exit after its stdin is closed
+const closeTimeout = 5 * time.Second
+
+// handshakeTimeout is how long a plugin has to answer the handshake once started
+var handshakeTimeout = 10 * time.Second
+
+// callTimeout is how long a plugin has to answer any other request
+var callTimeout = time.Minute
+
+// Plugin is a migrator running as an external process. It implements
+// transform.ResourceTransformer for the resource type and version path given in the
+// handshake.
+type Plugin struct {
+	path string
+	info HandshakeResult
+	log  hclog.Logger
+
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *bufio.Reader
+
+	// mu serializes requests, which the pipeline sends from several goroutines
+	mu        sync.Mutex
+	lastID    int
+	canHandle map[string]bool
+	// killed is set once the plugin is killed for not answering in time, and returned by
+	// every later request
+	killed error
+}
+
+var (
+	_ transform.ResourceTransformer = (*Plugin)(nil)
+	_ transform.ResourceRenamer     = (*Plugin)(nil)
+)
+
+// Start starts the plugin executable at path with args and performs the handshake
+func Start(log hclog.Logger, path string, args ...string) (*Plugin, error) {
+	cmd := exec.Command(path, args...)
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start plugin %s: %w", path, err)
+	}
+
+	p := &Plugin{
+		path:      path,
+		log:       log,
+		cmd:       cmd,
+		stdin:     stdin,
+		stdout:    bufio.NewReader(stdout),
+		canHandle: make(map[string]bool),
+	}
+	if err := p.handshake(); err != nil {
+		return nil, err
+	}
+	if p.info.ProtocolVersion != ProtocolVersion {
+		p.Close()
+		return nil, fmt.Errorf("plugin %s speaks protocol version %d, expected %d", path, p.info.ProtocolVersion, ProtocolVersion)
+	}
+	if p.info.ResourceType == "" || p.info.SourceVersion == "" || p.info.TargetVersion == "" {
+		p.Close()
+		return nil, fmt.Errorf("plugin %s did not give its resource type and version path in the handshake", path)
+	}
+	if p.info.Name == "" {
+		p.info.Name = filepath.Base(path)
+	}
+
+	log.Debug("Started plugin", "plugin", p.info.Name, "path", path, "type", p.info.ResourceType,
+		"source", p.info.SourceVersion, "target", p.info.TargetVersion)
+	return p, nil
+}
+
+// handshake sends the handshake request. A plugin that does not answer in time is killed.
+func (p *Plugin) handshake() error {
+	if _, err := p.call(MethodHandshake, HandshakeParams{ProtocolVersion: ProtocolVersion}, &p.info); err != nil {
+		if p.killed != nil {
+			return err
+		}
+		p.Close()
+		return fmt.Errorf("handshake with plugin %s failed: %w", p.path, err)
+	}
+	return nil
+}
+
+// Discover starts every executable file in dir, in the order of their names. If a plugin
+// fails to start, the plugins already started are closed.
+func Discover(log hclog.Logger, dir string) ([]*Plugin, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plugins directory: %w", err)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+	var plugins []*Plugin
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || !info.Mode().IsRegular() || info.Mode().Perm()&0o111 == 0 {
+			continue
+		}
+		p, err := Start(log, filepath.Join(dir, entry.Name()))
+		if err != nil {
+			CloseAll(plugins)
+			return nil, err
+		}
+		plugins = append(plugins, p)
+	}
+	return plugins, nil
+}
+
+// CloseAll closes all plugins and returns the errors of those that did not exit cleanly
+func CloseAll(plugins []*Plugin) error {
+	var errs []error
+	for _, p := range plugins {
+		errs = append(errs, p.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// Name returns the name the plugin gave in the handshake, or the name of its executable
+func (p *Plugin) Name() string {
+	return p.info.Name
+}
+
+// SourceVersion returns the provider version the plugin migrates from
+func (p *Plugin) SourceVersion() string {
+	return p.info.SourceVersion
+}
+
+// TargetVersion returns the provider version the plugin migrates to
+func (p *Plugin) TargetVersion() string {
+	return p.info.TargetVersion
+}
+
+// Close closes the stdin of the plugin and waits for it to exit. A plugin that does not
+// exit in time is killed.
+func (p *Plugin) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.killed != nil {
+		// The error was returned by the request that timed out
+		return nil
+	}
+	p.stdin.Close()
+	done := make(chan error, 1)
+	go func() { done <- p.cmd.Wait() }()
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("plugin %s: %w", p.path, err)
+		}
+		return nil
+	case <-time.After(closeTimeout):
+		p.cmd.Process.Kill()
+		<-done
+		return fmt.Errorf("plugin %s did not exit after its input was closed", p.path)
+	}
+}
+
+// GetResourceType returns the resource type in the target version
+func (p *Plugin) GetResourceType() string {
+	return p.info.ResourceType
+}
+
+// GetResourceRename returns the resource type in the source and the target version
+func (p *Plugin) GetResourceRename() (string, string) {
+	if p.info.RenamedFrom == "" {
+		return p.info.ResourceType, p.info.ResourceType
+	}
+	return p.info.RenamedFrom, p.info.ResourceType
+}
+
+// CanHandle asks the plugin whether it migrates resourceType. Answers are cached, and a
+// plugin that fails to answer handles nothing.
+func (p *Plugin) CanHandle(resourceType string) bool {
+	p.mu.Lock()
+	canHandle, ok := p.canHandle[resourceType]
+	p.mu.Unlock()
+	if ok {
+		return canHandle
+	}
+
+	var result CanHandleResult
+	diags, err := p.call(MethodCanHandle, CanHandleParams{ResourceType: resourceType}, &result)
+	p.logDiagnostics(MethodCanHandle, diags)
+	if err != nil {
+		p.log.Warn("Plugin failed to answer can_handle", "plugin", p.info.Name, "type", resourceType, "error", err)
+	}
+
+	p.mu.Lock()
+	p.canHandle[resourceType] = result.CanHandle
+	p.mu.Unlock()
+	return result.CanHandle
+}
+
+// Preprocess sends the content of a configuration file to the plugin. The content is
+// left unchanged if the plugin fails; there is no context to report the error in, so it
+// is logged. The preprocess handler calls PreprocessContext instead.
+func (p *Plugin) Preprocess(content string) string {
+	var result PreprocessResult
+	diags, err := p.call(MethodPreprocess, PreprocessParams{Content: content}, &result)
+	p.logDiagnostics(MethodPreprocess, diags)
+	if err != nil {
+		p.log.Warn("Plugin failed to preprocess a configuration file", "plugin", p.info.Name, "error", err)
+		return content
+	}
+	return result.Content
+}
+
+// PreprocessContext sends the content of a configuration file to the plugin and adds the
+// diagnostics of the plugin to ctx. The content is left unchanged if the plugin fails,
+// and the failure is added to ctx as an error.
+func (p *Plugin) PreprocessContext(ctx *transform.Context, content string) string {
+	var result PreprocessResult
+	diags, err := p.call(MethodPreprocess, PreprocessParams{Content: content}, &result)
+	ctx.Diagnostics = append(ctx.Diagnostics, p.diagnostics(diags)...)
+	if err != nil {
+		ctx.Diagnostics = append(ctx.Diagnostics, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  fmt.Sprintf("Failed to preprocess %s (plugin %s)", ctx.Filename, p.info.Name),
+			Detail:   err.Error(),
+		})
+		return content
+	}
+	return result.Content
+}
+
+// TransformConfig sends a resource block to the plugin and returns the blocks it
+// answers with. A block changed in place is updated in the configuration file.
+func (p *Plugin) TransformConfig(ctx *transform.Context, block *hclwrite.Block) (*transform.TransformResult, error) {
+	var result TransformConfigResult
+	diags, err := p.call(MethodTransformConfig, TransformConfigParams{
+		Filename: ctx.Filename,
+		Block:    string(hclwrite.Format(block.BuildTokens(nil).Bytes())),
+	}, &result)
+	ctx.Diagnostics = append(ctx.Diagnostics, p.diagnostics(diags)...)
+	if err != nil {
+		return nil, err
+	}
+
+	file, parseDiags := hclwrite.ParseConfig([]byte(result.Config), ctx.Filename, hcl.InitialPos)
+	if parseDiags.HasErrors() {
+		return nil, fmt.Errorf("plugin %s returned invalid configuration: %s", p.info.Name, parseDiags.Error())
+	}
+	blocks := file.Body().Blocks()
+
+	if result.RemoveOriginal {
+		return &transform.TransformResult{Blocks: blocks, RemoveOriginal: true}, nil
+	}
+	if len(blocks) != 1 {
+		return nil, fmt.Errorf("plugin %s returned %d blocks for a block changed in place, expected 1", p.info.Name, len(blocks))
+	}
+	block.SetType(blocks[0].Type())
+	block.SetLabels(blocks[0].Labels())
+	block.Body().Clear()
+	block.Body().AppendUnstructuredTokens(blocks[0].Body().BuildTokens(nil))
+	return &transform.TransformResult{Blocks: []*hclwrite.Block{block}, RemoveOriginal: false}, nil
+}
+
+// TransformState sends a resource instance to the plugin and returns the instance it
+// answers with
+func (p *Plugin) TransformState(ctx *transform.Context, stateJSON gjson.Result, resourcePath, resourceName string) (string, error) {
+	var result TransformStateResult
+	diags, err := p.call(MethodTransformState, TransformStateParams{
+		Instance:     json.RawMessage(stateJSON.Raw),
+		ResourcePath: resourcePath,
+		ResourceName: resourceName,
+	}, &result)
+	ctx.Diagnostics = append(ctx.Diagnostics, p.diagnostics(diags)...)
+	if err != nil {
+		return "", err
+	}
+	if !gjson.ValidBytes(result.Instance) {
+		return "", fmt.Errorf("plugin %s returned an instance that is not valid JSON", p.info.Name)
+	}
+	return string(result.Instance), nil
+}
+
+// call sends a request to the plugin and decodes the result of its response into result.
+// A plugin that does not answer in time is killed, and fails every later request.
+func (p *Plugin) call(method string, params, result any) ([]Diagnostic, error) {
+	rawParams, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.killed != nil {
+		return nil, p.killed
+	}
+	p.lastID++
+	request, err := json.Marshal(Request{ID: p.lastID, Method: method, Params: rawParams})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.stdin.Write(append(request, '\n')); err != nil {
+		return nil, fmt.Errorf("failed to send %s to plugin %s: %w", method, p.path, err)
+	}
+
+	line, err := p.read(method)
+	if err != nil {
+		if p.killed != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to read the %s response of plugin %s: %w", method, p.path, err)
+	}
+	var response Response
+	if err := json.Unmarshal(line, &response); err != nil {
+		return nil, fmt.Errorf("plugin %s sent an invalid %s response: %w", p.path, method, err)
+	}
+	if response.ID != p.lastID {
+		return nil, fmt.Errorf("plugin %s answered request %d, expected %d", p.path, response.ID, p.lastID)
+	}
+	if response.Error != "" {
+		return response.Diagnostics, fmt.Errorf("plugin %s: %s", p.path, response.Error)
+	}
+	if len(response.Result) > 0 {
+		if err := json.Unmarshal(response.Result, result); err != nil {
+			return response.Diagnostics, fmt.Errorf("plugin %s sent an invalid %s result: %w", p.path, method, err)
+		}
+	}
+	return response.Diagnostics, nil
+}
+
+// read reads the response to a request. A plugin that does not answer in time is killed.
+func (p *Plugin) read(method string) ([]byte, error) {
+	timeout, request := callTimeout, method+" request"
+	if method == MethodHandshake {
+		timeout, request = handshakeTimeout, "handshake"
+	}
+
+	type response struct {
+		line []byte
+		err  error
+	}
+	done := make(chan response, 1)
+	go func() {
+		line, err := p.stdout.ReadBytes('\n')
+		done <- response{line, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.line, r.err
+	case <-time.After(timeout):
+		p.cmd.Process.Kill()
+		// Wait closes stdout, which ends the pending read even if a child of the plugin keeps it open
+		p.cmd.Wait()
+		<-done
+		p.stdin.Close()
+		p.killed = fmt.Errorf("plugin %s did not answer the %s within %s and was stopped", p.path, request, timeout)
+		return nil, p.killed
+	}
+}
+
+// diagnostics converts the diagnostics of a plugin, naming the plugin in their summary
+func (p *Plugin) diagnostics(diags []Diagnostic) hcl.Diagnostics {
+	var result hcl.Diagnostics
+	for _, diag := range diags {
+		severity := hcl.DiagWarning
+		if diag.Severity == "error" {
+			severity = hcl.DiagError
+		}
+		result = append(result, &hcl.Diagnostic{
+			Severity: severity,
+			Summary:  fmt.Sprintf("%s (plugin %s)", diag.Summary, p.info.Name),
+			Detail:   diag.Detail,
+		})
+	}
+	return result
+}
+
+// logDiagnostics logs the diagnostics of a call that has no context to add them to
+func (p *Plugin) logDiagnostics(method string, diags []Diagnostic) {
+	for _, diag := range diags {
+		p.log.Warn("Plugin diagnostic", "plugin", p.info.Name, "method", method,
+			"severity", diag.Severity, "summary", diag.Summary, "detail", diag.Detail)
+	}
+}
@@ -0,0 +1,302 @@
+package plugin_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/cloudflare/tf-migrate/internal/plugin"
+	"github.com/cloudflare/tf-migrate/internal/transform"
+)
+
+// testHandler migrates acme_zone resources, renaming zone_name to name
+type testHandler struct{}
+
+func (testHandler) CanHandle(resourceType string) bool {
+	return resourceType == "acme_zone"
+}
+
+func (testHandler) TransformConfig(params plugin.TransformConfigParams) (plugin.TransformConfigResult, []plugin.Diagnostic, error) {
+	if strings.Contains(params.Block, "fail") {
+		return plugin.TransformConfigResult{}, nil, errors.New("cannot migrate this block")
+	}
+	if strings.Contains(params.Block, "split") {
+		return plugin.TransformConfigResult{
+			Config: `resource "acme_zone" "a" {
+  name = "a"
+}
+resource "acme_zone" "b" {
+  name = "b"
+}
+`,
+			RemoveOriginal: true,
+		}, nil, nil
+	}
+	diags := []plugin.Diagnostic{{Severity: "warning", Summary: "Renamed zone_name", Detail: params.Filename}}
+	return plugin.TransformConfigResult{Config: strings.ReplaceAll(params.Block, "zone_name", "name")}, diags, nil
+}
+
+func (testHandler) TransformState(params plugin.TransformStateParams) (plugin.TransformStateResult, []plugin.Diagnostic, error) {
+	instance := strings.ReplaceAll(string(params.Instance), "zone_name", "name")
+	return plugin.TransformStateResult{Instance: json.RawMessage(instance)}, nil, nil
+}
+
+func (testHandler) Preprocess(content string) string {
+	return strings.ReplaceAll(content, "acme_old_zone", "acme_zone")
+}
+
+// TestHelperProcess is the plugin started by the tests, it is not a test itself
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
+		return
+	}
+	defer os.Exit(0)
+
+	if os.Getenv("PLUGIN_PROTOCOL_VERSION") == "0" {
+		// An old plugin answering the handshake with another protocol version
+		bufio.NewReader(os.Stdin).ReadBytes('\n')
+		fmt.Println(`{"id":1,"result":{"protocol_version":0,"resource_type":"acme_zone","source_version":"v1","target_version":"v2"}}`)
+		return
+	}
+	if os.Getenv("PLUGIN_HANG") == "1" {
+		// A plugin that never answers the handshake
+		bufio.NewReader(os.Stdin).ReadBytes('\n')
+		time.Sleep(time.Hour)
+		return
+	}
+	if os.Getenv("PLUGIN_HANG") == "after_handshake" {
+		// A plugin that stops answering once started
+		stdin := bufio.NewReader(os.Stdin)
+		stdin.ReadBytes('\n')
+		fmt.Println(`{"id":1,"result":{"protocol_version":1,"resource_type":"acme_zone","source_version":"v1","target_version":"v2"}}`)
+		stdin.ReadBytes('\n')
+		time.Sleep(time.Hour)
+		return
+	}
+
+	if mode := os.Getenv("PLUGIN_PREPROCESS"); mode != "" {
+		// A plugin answering preprocess with diagnostics, or with an error
+		stdin := bufio.NewReader(os.Stdin)
+		stdin.ReadBytes('\n')
+		fmt.Println(`{"id":1,"result":{"protocol_version":1,"resource_type":"acme_zone","source_version":"v1","target_version":"v2"}}`)
+		stdin.ReadBytes('\n')
+		if mode == "error" {
+			fmt.Println(`{"id":2,"error":"cannot read the file"}`)
+		} else {
+			fmt.Println(`{"id":2,"result":{"content":"# preprocessed"},"diagnostics":[{"severity":"error","summary":"Unsupported provider alias","detail":"acme.eu"}]}`)
+		}
+		stdin.ReadBytes('\n')
+		return
+	}
+
+	info := plugin.HandshakeResult{ResourceType: "acme_zone", RenamedFrom: "acme_old_zone", SourceVersion: "v1", TargetVersion: "v2"}
+	if err := plugin.Serve(info, testHandler{}, os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func startPlugin(t *testing.T) *plugin.Plugin {
+	t.Helper()
+	t.Setenv("GO_WANT_HELPER_PROCESS", "1")
+	p, err := plugin.Start(hclog.NewNullLogger(), os.Args[0], "-test.run=TestHelperProcess", "--")
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, p.Close()) })
+	return p
+}
+
+func parseBlock(t *testing.T, config string) (*hclwrite.File, *hclwrite.Block) {
+	t.Helper()
+	file, diags := hclwrite.ParseConfig([]byte(config), "main.tf", hcl.InitialPos)
+	require.False(t, diags.HasErrors(), diags.Error())
+	return file, file.Body().Blocks()[0]
+}
+
+func TestStart(t *testing.T) {
+	p := startPlugin(t)
+
+	assert.Equal(t, filepath.Base(os.Args[0]), p.Name())
+	assert.Equal(t, "acme_zone", p.GetResourceType())
+	assert.Equal(t, "v1", p.SourceVersion())
+	assert.Equal(t, "v2", p.TargetVersion())
+
+	oldType, newType := p.GetResourceRename()
+	assert.Equal(t, "acme_old_zone", oldType)
+	assert.Equal(t, "acme_zone", newType)
+}
+
+func TestStartProtocolVersionMismatch(t *testing.T) {
+	t.Setenv("GO_WANT_HELPER_PROCESS", "1")
+	t.Setenv("PLUGIN_PROTOCOL_VERSION", "0")
+
+	_, err := plugin.Start(hclog.NewNullLogger(), os.Args[0], "-test.run=TestHelperProcess", "--")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "speaks protocol version 0, expected 1")
+}
+
+func TestStartHandshakeTimeout(t *testing.T) {
+	t.Setenv("GO_WANT_HELPER_PROCESS", "1")
+	t.Setenv("PLUGIN_HANG", "1")
+	defer plugin.SetHandshakeTimeout(100 * time.Millisecond)()
+
+	started := time.Now()
+	_, err := plugin.Start(hclog.NewNullLogger(), os.Args[0], "-test.run=TestHelperProcess", "--")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "did not answer the handshake within 100ms")
+	assert.Less(t, time.Since(started), 10*time.Second)
+}
+
+func TestCallTimeout(t *testing.T) {
+	t.Setenv("PLUGIN_HANG", "after_handshake")
+	p := startPlugin(t)
+	defer plugin.SetCallTimeout(100 * time.Millisecond)()
+
+	started := time.Now()
+	instance := gjson.Parse(`{"attributes":{"id":"1"}}`)
+	_, err := p.TransformState(&transform.Context{}, instance, "resources.0.instances.0", "acme_zone.example")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "plugin "+os.Args[0]+" did not answer the transform_state request within 100ms")
+	assert.Less(t, time.Since(started), 10*time.Second)
+
+	// The plugin is stopped, so later requests fail at once
+	_, block := parseBlock(t, `resource "acme_zone" "example" {}`)
+	_, err = p.TransformConfig(&transform.Context{}, block)
+	assert.ErrorContains(t, err, "did not answer the transform_state request")
+	assert.False(t, p.CanHandle("acme_zone"))
+}
+
+func TestCanHandle(t *testing.T) {
+	p := startPlugin(t)
+
+	assert.True(t, p.CanHandle("acme_zone"))
+	assert.False(t, p.CanHandle("acme_record"))
+	// Cached answers
+	assert.True(t, p.CanHandle("acme_zone"))
+}
+
+func TestPreprocess(t *testing.T) {
+	p := startPlugin(t)
+
+	assert.Equal(t, `resource "acme_zone" "x" {}`, p.Preprocess(`resource "acme_old_zone" "x" {}`))
+
+	t.Run("Diagnostics are added to the context", func(t *testing.T) {
+		t.Setenv("PLUGIN_PREPROCESS", "diagnostics")
+		p := startPlugin(t)
+		ctx := &transform.Context{Filename: "main.tf"}
+
+		assert.Equal(t, "# preprocessed", p.PreprocessContext(ctx, `resource "acme_zone" "x" {}`))
+		require.Len(t, ctx.Diagnostics, 1)
+		assert.Equal(t, hcl.DiagError, ctx.Diagnostics[0].Severity)
+		assert.Equal(t, "Unsupported provider alias (plugin "+p.Name()+")", ctx.Diagnostics[0].Summary)
+		assert.Equal(t, "acme.eu", ctx.Diagnostics[0].Detail)
+	})
+
+	t.Run("A failure is added to the context", func(t *testing.T) {
+		t.Setenv("PLUGIN_PREPROCESS", "error")
+		p := startPlugin(t)
+		ctx := &transform.Context{Filename: "main.tf"}
+
+		assert.Equal(t, `resource "acme_zone" "x" {}`, p.PreprocessContext(ctx, `resource "acme_zone" "x" {}`))
+		require.Len(t, ctx.Diagnostics, 1)
+		assert.Equal(t, hcl.DiagError, ctx.Diagnostics[0].Severity)
+		assert.Equal(t, "Failed to preprocess main.tf (plugin "+p.Name()+")", ctx.Diagnostics[0].Summary)
+		assert.Contains(t, ctx.Diagnostics[0].Detail, "cannot read the file")
+	})
+}
+
+func TestTransformConfig(t *testing.T) {
+	t.Run("In place", func(t *testing.T) {
+		p := startPlugin(t)
+		file, block := parseBlock(t, `resource "acme_zone" "example" {
+  zone_name = "example.com"
+}
+
+output "zone" {
+  value = acme_zone.example.name
+}
+`)
+		ctx := &transform.Context{Filename: "main.tf"}
+
+		result, err := p.TransformConfig(ctx, block)
+		require.NoError(t, err)
+		assert.False(t, result.RemoveOriginal)
+		assert.Equal(t, []*hclwrite.Block{block}, result.Blocks)
+		assert.Equal(t, `resource "acme_zone" "example" {
+  name = "example.com"
+}
+
+output "zone" {
+  value = acme_zone.example.name
+}
+`, string(hclwrite.Format(file.Bytes())))
+
+		require.Len(t, ctx.Diagnostics, 1)
+		assert.Equal(t, hcl.DiagWarning, ctx.Diagnostics[0].Severity)
+		assert.Equal(t, fmt.Sprintf("Renamed zone_name (plugin %s)", p.Name()), ctx.Diagnostics[0].Summary)
+		assert.Equal(t, "main.tf", ctx.Diagnostics[0].Detail)
+	})
+
+	t.Run("Remove original", func(t *testing.T) {
+		p := startPlugin(t)
+		_, block := parseBlock(t, `resource "acme_zone" "split" {}`)
+
+		result, err := p.TransformConfig(&transform.Context{}, block)
+		require.NoError(t, err)
+		assert.True(t, result.RemoveOriginal)
+		require.Len(t, result.Blocks, 2)
+		assert.Equal(t, []string{"acme_zone", "a"}, result.Blocks[0].Labels())
+		assert.Equal(t, []string{"acme_zone", "b"}, result.Blocks[1].Labels())
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		p := startPlugin(t)
+		_, block := parseBlock(t, `resource "acme_zone" "fail" {}`)
+
+		_, err := p.TransformConfig(&transform.Context{}, block)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "cannot migrate this block")
+
+		// The plugin keeps answering after an error
+		assert.True(t, p.CanHandle("acme_zone"))
+	})
+}
+
+func TestTransformState(t *testing.T) {
+	p := startPlugin(t)
+
+	instance := gjson.Parse(`{"attributes":{"id":"1","zone_name":"example.com"}}`)
+	result, err := p.TransformState(&transform.Context{}, instance, "resources.0.instances.0", "acme_zone.example")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"attributes":{"id":"1","name":"example.com"}}`, result)
+}
+
+func TestDiscover(t *testing.T) {
+	t.Setenv("GO_WANT_HELPER_PROCESS", "1")
+	dir := t.TempDir()
+	script := fmt.Sprintf("#!/bin/sh\nexec %q -test.run=TestHelperProcess --\n", os.Args[0])
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b-zone"), []byte(script), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a-zone"), []byte(script), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("Not a plugin"), 0o644))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "lib"), 0o755))
+
+	plugins, err := plugin.Discover(hclog.NewNullLogger(), dir)
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, plugin.CloseAll(plugins)) }()
+
+	require.Len(t, plugins, 2)
+	assert.Equal(t, "a-zone", plugins[0].Name())
+	assert.Equal(t, "b-zone", plugins[1].Name())
+}
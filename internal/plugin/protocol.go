@@ -0,0 +1,128 @@
+// Package plugin runs migrators that are not compiled into tf-migrate as external
+// processes. A plugin is an executable that tf-migrate starts once per run and talks to
+// over stdin and stdout with the protocol below, so it can be written in any language.
+//
+// Every message is a single line of JSON. tf-migrate writes a Request and the plugin
+// answers with a Response carrying the same ID, one request at a time:
+//
+//	{"id":1,"method":"handshake","params":{"protocol_version":1}}
+//	{"id":1,"result":{"protocol_version":1,"name":"acme","resource_type":"acme_zone","source_version":"v4","target_version":"v5"}}
+//	{"id":2,"method":"can_handle","params":{"resource_type":"acme_zone"}}
+//	{"id":2,"result":{"can_handle":true}}
+//
+// The handshake comes first. A plugin answers it with the protocol version it speaks,
+// and tf-migrate stops it if that is not ProtocolVersion. The plugin then acts as the
+// migrator of resource_type for the version path it names. The other methods mirror
+// transform.ResourceTransformer; configuration goes over the wire as HCL text and state
+// as JSON. Diagnostics in a response are added to those of the file being migrated, and
+// a response with an error fails the call. tf-migrate closes stdin at the end of the
+// run; the plugin should exit then. Anything written to stderr is shown to the user.
+//
+// A plugin migrates a single resource type, named in the handshake together with the
+// type it was renamed from. Projects with several types ship one plugin per type. Only
+// resource blocks are sent to transform_config, so a plugin cannot rewrite module, data
+// or other blocks; preprocess sees the whole file as text.
+package plugin
+
+import "encoding/json"
+
+// ProtocolVersion is the version of the protocol spoken by this tf-migrate
+const ProtocolVersion = 1
+
+// Methods of the protocol
+const (
+	MethodHandshake       = "handshake"
+	MethodCanHandle       = "can_handle"
+	MethodTransformConfig = "transform_config"
+	MethodTransformState  = "transform_state"
+	MethodPreprocess      = "preprocess"
+)
+
+// Request is a call from tf-migrate to a plugin
+type Request struct {
+	ID     int             `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+// Response is the answer of a plugin to a request
+type Response struct {
+	ID          int             `json:"id"`
+	Result      json.RawMessage `json:"result,omitempty"`
+	Error       string          `json:"error,omitempty"`
+	Diagnostics []Diagnostic    `json:"diagnostics,omitempty"`
+}
+
+// Diagnostic is a warning or an error reported by a plugin
+type Diagnostic struct {
+	// Severity is "error" or "warning"
+	Severity string `json:"severity"`
+	Summary  string `json:"summary"`
+	Detail   string `json:"detail,omitempty"`
+}
+
+// HandshakeParams are the parameters of the handshake
+type HandshakeParams struct {
+	ProtocolVersion int `json:"protocol_version"`
+}
+
+// HandshakeResult describes the migrator a plugin implements
+type HandshakeResult struct {
+	ProtocolVersion int    `json:"protocol_version"`
+	Name            string `json:"name"`
+	// ResourceType is the resource type in the target version
+	ResourceType string `json:"resource_type"`
+	// RenamedFrom is the resource type in the source version, if the plugin renames it.
+	// References to it are updated across all configuration files.
+	RenamedFrom   string `json:"renamed_from,omitempty"`
+	SourceVersion string `json:"source_version"`
+	TargetVersion string `json:"target_version"`
+}
+
+// CanHandleParams are the parameters of can_handle
+type CanHandleParams struct {
+	ResourceType string `json:"resource_type"`
+}
+
+// CanHandleResult reports whether the plugin migrates a resource type
+type CanHandleResult struct {
+	CanHandle bool `json:"can_handle"`
+}
+
+// TransformConfigParams are the parameters of transform_config
+type TransformConfigParams struct {
+	Filename string `json:"filename"`
+	// Block is the HCL text of the resource block
+	Block string `json:"block"`
+}
+
+// TransformConfigResult is the migrated configuration of a resource block. Config holds
+// the HCL text of the resulting blocks. Unless RemoveOriginal is set, it must hold a
+// single block, which replaces the original in place.
+type TransformConfigResult struct {
+	Config         string `json:"config"`
+	RemoveOriginal bool   `json:"remove_original"`
+}
+
+// TransformStateParams are the parameters of transform_state
+type TransformStateParams struct {
+	// Instance is the JSON of the resource instance in the state
+	Instance     json.RawMessage `json:"instance"`
+	ResourcePath string          `json:"resource_path"`
+	ResourceName string          `json:"resource_name"`
+}
+
+// TransformStateResult is the migrated resource instance
+type TransformStateResult struct {
+	Instance json.RawMessage `json:"instance"`
+}
+
+// PreprocessParams are the parameters of preprocess
+type PreprocessParams struct {
+	Content string `json:"content"`
+}
+
+// PreprocessResult is the content of a configuration file after preprocessing
+type PreprocessResult struct {
+	Content string `json:"content"`
+}
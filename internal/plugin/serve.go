@@ -0,0 +1,94 @@
+package plugin
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Handler implements a plugin in Go, served with Serve
+type Handler interface {
+	CanHandle(resourceType string) bool
+	TransformConfig(params TransformConfigParams) (TransformConfigResult, []Diagnostic, error)
+	TransformState(params TransformStateParams) (TransformStateResult, []Diagnostic, error)
+	Preprocess(content string) string
+}
+
+// Serve answers the requests read from in with handler, until in is closed. info is the
+// answer to the handshake; its protocol version is set by Serve.
+//
+// A plugin written in Go serves its requests from main:
+//
+//	func main() {
+//		info := plugin.HandshakeResult{ResourceType: "acme_zone", SourceVersion: "v4", TargetVersion: "v5"}
+//		if err := plugin.Serve(info, handler{}, os.Stdin, os.Stdout); err != nil {
+//			fmt.Fprintln(os.Stderr, err)
+//			os.Exit(1)
+//		}
+//	}
+func Serve(info HandshakeResult, handler Handler, in io.Reader, out io.Writer) error {
+	info.ProtocolVersion = ProtocolVersion
+	reader := bufio.NewReader(in)
+	encoder := json.NewEncoder(out)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) && len(line) == 0 {
+			return nil
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+
+		var request Request
+		if err := json.Unmarshal(line, &request); err != nil {
+			return fmt.Errorf("invalid request: %w", err)
+		}
+		response := Response{ID: request.ID}
+		result, diags, err := dispatch(info, handler, request)
+		response.Diagnostics = diags
+		if err != nil {
+			response.Error = err.Error()
+		} else if response.Result, err = json.Marshal(result); err != nil {
+			return err
+		}
+		if err := encoder.Encode(response); err != nil {
+			return err
+		}
+	}
+}
+
+// dispatch calls the handler method of a request
+func dispatch(info HandshakeResult, handler Handler, request Request) (any, []Diagnostic, error) {
+	switch request.Method {
+	case MethodHandshake:
+		return info, nil, nil
+	case MethodCanHandle:
+		var params CanHandleParams
+		if err := json.Unmarshal(request.Params, &params); err != nil {
+			return nil, nil, err
+		}
+		return CanHandleResult{CanHandle: handler.CanHandle(params.ResourceType)}, nil, nil
+	case MethodTransformConfig:
+		var params TransformConfigParams
+		if err := json.Unmarshal(request.Params, &params); err != nil {
+			return nil, nil, err
+		}
+		return handler.TransformConfig(params)
+	case MethodTransformState:
+		var params TransformStateParams
+		if err := json.Unmarshal(request.Params, &params); err != nil {
+			return nil, nil, err
+		}
+		return handler.TransformState(params)
+	case MethodPreprocess:
+		var params PreprocessParams
+		if err := json.Unmarshal(request.Params, &params); err != nil {
+			return nil, nil, err
+		}
+		return PreprocessResult{Content: handler.Preprocess(params.Content)}, nil, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown method %q", request.Method)
+	}
+}
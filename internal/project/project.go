@@ -0,0 +1,415 @@
+// Package project loads the .tf-migrate.hcl project configuration file, which holds the
+// options of repeatable migration runs: version paths, resource filters, state and output
+// locations per directory, and options for individual migrators.
+package project
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+// FileName is the name of the project configuration file discovered in the working directory
+const FileName = ".tf-migrate.hcl"
+
+// versionPattern matches provider major versions such as v4
+var versionPattern = regexp.MustCompile(`^v[0-9]+$`)
+
+// Settings are the migration options that can be set at the top level of the file and in
+// directory blocks. Nil fields are not set. Paths are resolved relative to the file.
+type Settings struct {
+	SourceVersion    *string   `hcl:"source_version,optional"`
+	TargetVersion    *string   `hcl:"target_version,optional"`
+	Resources        *[]string `hcl:"resources,optional"`
+	ExcludeResources *[]string `hcl:"exclude_resources,optional"`
+	Recursive        *bool     `hcl:"recursive,optional"`
+	Backup           *bool     `hcl:"backup,optional"`
+	ContinueOnError  *bool     `hcl:"continue_on_error,optional"`
+	RenameStrategy   *string   `hcl:"rename_strategy,optional"`
+	TerraformVersion *string   `hcl:"terraform_version,optional"`
+	Parallelism      *int      `hcl:"parallelism,optional"`
+	StateFile        *string   `hcl:"state_file,optional"`
+	StateBackend     *string   `hcl:"state_backend,optional"`
+	BackendConfig    *string   `hcl:"backend_config,optional"`
+	OutputDir        *string   `hcl:"output_dir,optional"`
+	OutputState      *string   `hcl:"output_state,optional"`
+	Report           *string   `hcl:"report,optional"`
+	DiffOutput       *string   `hcl:"diff_output,optional"`
+	Schema           *string   `hcl:"schema,optional"`
+	PluginsDir       *string   `hcl:"plugins_dir,optional"`
+	RulesDir         *string   `hcl:"rules_dir,optional"`
+	// FailOnRemovedReferences fails the run when references to removed attributes remain
+	FailOnRemovedReferences *bool `hcl:"fail_on_removed_references,optional"`
+}
+
+// Merge returns the settings with the fields set in other taking precedence
+func (s Settings) Merge(other Settings) Settings {
+	merged := s
+	pick(&merged.SourceVersion, other.SourceVersion)
+	pick(&merged.TargetVersion, other.TargetVersion)
+	pick(&merged.Resources, other.Resources)
+	pick(&merged.ExcludeResources, other.ExcludeResources)
+	pick(&merged.Recursive, other.Recursive)
+	pick(&merged.Backup, other.Backup)
+	pick(&merged.ContinueOnError, other.ContinueOnError)
+	pick(&merged.RenameStrategy, other.RenameStrategy)
+	pick(&merged.TerraformVersion, other.TerraformVersion)
+	pick(&merged.Parallelism, other.Parallelism)
+	pick(&merged.StateFile, other.StateFile)
+	pick(&merged.StateBackend, other.StateBackend)
+	pick(&merged.BackendConfig, other.BackendConfig)
+	pick(&merged.OutputDir, other.OutputDir)
+	pick(&merged.OutputState, other.OutputState)
+	pick(&merged.Report, other.Report)
+	pick(&merged.DiffOutput, other.DiffOutput)
+	pick(&merged.Schema, other.Schema)
+	pick(&merged.PluginsDir, other.PluginsDir)
+	pick(&merged.RulesDir, other.RulesDir)
+	pick(&merged.FailOnRemovedReferences, other.FailOnRemovedReferences)
+	return merged
+}
+
+func pick[T any](field **T, value *T) {
+	if value != nil {
+		*field = value
+	}
+}
+
+// Directory is a directory block: a configuration directory migrated with its own settings
+type Directory struct {
+	// Path of the configuration directory, resolved relative to the file
+	Path     string
+	Settings Settings
+}
+
+// File is a parsed project configuration file
+type File struct {
+	Path        string
+	Settings    Settings
+	Directories []Directory
+	// Migrators holds the options of each migrator, keyed by source resource type and option name
+	Migrators map[string]map[string]cty.Value
+
+	migratorRanges map[string]hcl.Range
+	optionRanges   map[string]map[string]hcl.Range
+}
+
+// rawFile is the decoding schema of the file
+type rawFile struct {
+	Directories []rawDirectory `hcl:"directory,block"`
+	Migrators   []rawMigrator  `hcl:"migrator,block"`
+	Remain      hcl.Body       `hcl:",remain"`
+}
+
+type rawDirectory struct {
+	Path   string   `hcl:"path,label"`
+	Remain hcl.Body `hcl:",remain"`
+}
+
+type rawMigrator struct {
+	ResourceType string   `hcl:"resource_type,label"`
+	Remain       hcl.Body `hcl:",remain"`
+}
+
+// Find returns the path of the project file in dir, or an empty string if there is none
+func Find(dir string) (string, error) {
+	path := filepath.Join(dir, FileName)
+	_, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return path, nil
+}
+
+// Load reads and validates a project configuration file
+func Load(path string) (*File, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read project file %s: %w", path, err)
+	}
+	file, diags := Parse(path, src)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("invalid project file: %w", diags)
+	}
+	return file, nil
+}
+
+// Parse parses and validates a project configuration file. Relative paths in the file are
+// resolved against the directory of path.
+func Parse(path string, src []byte) (*File, hcl.Diagnostics) {
+	parsed, diags := hclparse.NewParser().ParseHCL(src, path)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	var raw rawFile
+	if diags := gohcl.DecodeBody(parsed.Body, nil, &raw); diags.HasErrors() {
+		return nil, diags
+	}
+
+	base := filepath.Dir(path)
+	file := &File{
+		Path:           path,
+		Migrators:      make(map[string]map[string]cty.Value),
+		migratorRanges: make(map[string]hcl.Range),
+		optionRanges:   make(map[string]map[string]hcl.Range),
+	}
+
+	file.Settings, diags = decodeSettings(raw.Remain, base)
+
+	directoryRanges := blockRanges(parsed.Body, "directory")
+	seen := make(map[string]hcl.Range)
+	for i, dir := range raw.Directories {
+		rng := directoryRanges[i]
+		settings, settingsDiags := decodeSettings(dir.Remain, base)
+		diags = append(diags, settingsDiags...)
+		// Plugins are started, and rules loaded, once for the whole run
+		for _, setting := range []struct {
+			name  string
+			value *string
+		}{
+			{"plugins_dir", settings.PluginsDir},
+			{"rules_dir", settings.RulesDir},
+		} {
+			if setting.value != nil {
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Unsupported setting in directory block",
+					Detail:   fmt.Sprintf("%s applies to the whole run and can only be set at the top level of the file.", setting.name),
+					Subject:  attributeRange(dir.Remain, setting.name),
+				})
+			}
+		}
+
+		dirPath := resolve(base, dir.Path)
+		if previous, ok := seen[filepath.Clean(dirPath)]; ok {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Duplicate directory block",
+				Detail:   fmt.Sprintf("Directory %q is already configured at %s.", dir.Path, previous),
+				Subject:  rng.Ptr(),
+			})
+			continue
+		}
+		seen[filepath.Clean(dirPath)] = rng
+		if info, err := os.Stat(dirPath); err != nil || !info.IsDir() {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Directory not found",
+				Detail:   fmt.Sprintf("The directory %q does not exist. Directory paths are relative to the project file.", dir.Path),
+				Subject:  rng.Ptr(),
+			})
+			continue
+		}
+		file.Directories = append(file.Directories, Directory{Path: dirPath, Settings: settings})
+	}
+
+	migratorRanges := blockRanges(parsed.Body, "migrator")
+	for i, migrator := range raw.Migrators {
+		rng := migratorRanges[i]
+		if previous, ok := file.migratorRanges[migrator.ResourceType]; ok {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Duplicate migrator block",
+				Detail:   fmt.Sprintf("Options for %q are already set at %s.", migrator.ResourceType, previous),
+				Subject:  rng.Ptr(),
+			})
+			continue
+		}
+		file.migratorRanges[migrator.ResourceType] = rng
+
+		attrs, attrDiags := migrator.Remain.JustAttributes()
+		diags = append(diags, attrDiags...)
+		options := make(map[string]cty.Value, len(attrs))
+		ranges := make(map[string]hcl.Range, len(attrs))
+		for name, attr := range attrs {
+			value, valueDiags := attr.Expr.Value(nil)
+			diags = append(diags, valueDiags...)
+			if valueDiags.HasErrors() {
+				continue
+			}
+			options[name] = value
+			ranges[name] = attr.Expr.Range()
+		}
+		file.Migrators[migrator.ResourceType] = options
+		file.optionRanges[migrator.ResourceType] = ranges
+	}
+
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	return file, diags
+}
+
+// SettingsFor returns the settings of a configuration directory: the top-level settings
+// overridden by the directory block for dir, if there is one
+func (f *File) SettingsFor(dir string) Settings {
+	for _, directory := range f.Directories {
+		if samePath(directory.Path, dir) {
+			return f.Settings.Merge(directory.Settings)
+		}
+	}
+	return f.Settings
+}
+
+// CheckMigratorOptions validates the migrator options against the options each migrator
+// accepts and converts them to the declared types. lookup returns the options accepted by
+// the migrator of a source resource type, and false if there is no such migrator.
+func (f *File) CheckMigratorOptions(lookup func(resourceType string) (map[string]cty.Type, bool)) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+
+	types := make([]string, 0, len(f.Migrators))
+	for resourceType := range f.Migrators {
+		types = append(types, resourceType)
+	}
+	sort.Strings(types)
+
+	for _, resourceType := range types {
+		accepted, ok := lookup(resourceType)
+		if !ok {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Unknown migrator",
+				Detail:   fmt.Sprintf("There is no migrator for the resource type %q in the configured version paths.", resourceType),
+				Subject:  f.migratorRanges[resourceType].Ptr(),
+			})
+			continue
+		}
+
+		options := f.Migrators[resourceType]
+		names := make([]string, 0, len(options))
+		for name := range options {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			rng := f.optionRanges[resourceType][name]
+			optionType, ok := accepted[name]
+			if !ok {
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Unsupported migrator option",
+					Detail:   fmt.Sprintf("The migrator for %q has no option %q. %s", resourceType, name, supportedOptions(accepted)),
+					Subject:  rng.Ptr(),
+				})
+				continue
+			}
+			value, err := convert.Convert(options[name], optionType)
+			if err != nil {
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Invalid migrator option value",
+					Detail:   fmt.Sprintf("Inappropriate value for option %q of the %q migrator: %s.", name, resourceType, err),
+					Subject:  rng.Ptr(),
+				})
+				continue
+			}
+			options[name] = value
+		}
+	}
+
+	return diags
+}
+
+// decodeSettings decodes and validates the settings in a body
+func decodeSettings(body hcl.Body, base string) (Settings, hcl.Diagnostics) {
+	var settings Settings
+	diags := gohcl.DecodeBody(body, nil, &settings)
+	if diags.HasErrors() {
+		return settings, diags
+	}
+
+	versions := []struct {
+		name  string
+		value *string
+	}{
+		{"source_version", settings.SourceVersion},
+		{"target_version", settings.TargetVersion},
+	}
+	for _, version := range versions {
+		name, value := version.name, version.value
+		if value != nil && !versionPattern.MatchString(*value) {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid provider version",
+				Detail:   fmt.Sprintf("The %s %q is not a provider major version such as \"v4\".", name, *value),
+				Subject:  attributeRange(body, name),
+			})
+		}
+	}
+
+	for _, path := range []*string{settings.StateFile, settings.BackendConfig, settings.OutputDir, settings.OutputState, settings.Report, settings.DiffOutput, settings.Schema, settings.PluginsDir, settings.RulesDir} {
+		if path != nil && *path != "" {
+			*path = resolve(base, *path)
+		}
+	}
+
+	return settings, diags
+}
+
+// resolve returns path relative to base, unless it is absolute
+func resolve(base, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(base, path)
+}
+
+// samePath reports whether two paths refer to the same directory
+func samePath(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	if errA != nil || errB != nil {
+		return filepath.Clean(a) == filepath.Clean(b)
+	}
+	return absA == absB
+}
+
+// attributeRange returns the range of an attribute in a native syntax body
+func attributeRange(body hcl.Body, name string) *hcl.Range {
+	syntaxBody, ok := body.(*hclsyntax.Body)
+	if !ok {
+		return nil
+	}
+	if attr, ok := syntaxBody.Attributes[name]; ok {
+		return attr.SrcRange.Ptr()
+	}
+	return nil
+}
+
+// blockRanges returns the definition ranges of the blocks of a type, in order
+func blockRanges(body hcl.Body, blockType string) []hcl.Range {
+	var ranges []hcl.Range
+	for _, block := range body.(*hclsyntax.Body).Blocks {
+		if block.Type == blockType {
+			ranges = append(ranges, block.DefRange())
+		}
+	}
+	return ranges
+}
+
+func supportedOptions(accepted map[string]cty.Type) string {
+	if len(accepted) == 0 {
+		return "It does not accept any options."
+	}
+	names := make([]string, 0, len(accepted))
+	for name := range accepted {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return "Supported options: " + strings.Join(names, ", ") + "."
+}
@@ -0,0 +1,190 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func writeProject(t *testing.T, src string) string {
+	t.Helper()
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "stacks", "dns"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "stacks", "waf"), 0755))
+	path := filepath.Join(dir, FileName)
+	require.NoError(t, os.WriteFile(path, []byte(src), 0644))
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	path := writeProject(t, `
+source_version    = "v4"
+target_version    = "v5"
+exclude_resources = ["cloudflare_ruleset"]
+backup            = false
+report            = "reports/migration.json"
+plugins_dir       = "plugins"
+rules_dir         = "rules"
+
+directory "stacks/dns" {
+  resources  = ["cloudflare_record"]
+  state_file = "stacks/dns/terraform.tfstate"
+  backup     = true
+}
+
+directory "stacks/waf" {
+  recursive = true
+}
+
+migrator "cloudflare_record" {
+  default_ttl = 300
+}
+`)
+	dir := filepath.Dir(path)
+
+	file, err := Load(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, "v4", *file.Settings.SourceVersion)
+	assert.Equal(t, []string{"cloudflare_ruleset"}, *file.Settings.ExcludeResources)
+	assert.Equal(t, filepath.Join(dir, "reports", "migration.json"), *file.Settings.Report)
+	assert.Equal(t, filepath.Join(dir, "plugins"), *file.Settings.PluginsDir)
+	assert.Equal(t, filepath.Join(dir, "rules"), *file.Settings.RulesDir)
+	assert.Nil(t, file.Settings.Resources)
+
+	require.Len(t, file.Directories, 2)
+	assert.Equal(t, filepath.Join(dir, "stacks", "dns"), file.Directories[0].Path)
+
+	dns := file.SettingsFor(filepath.Join(dir, "stacks", "dns"))
+	assert.Equal(t, []string{"cloudflare_record"}, *dns.Resources)
+	assert.Equal(t, filepath.Join(dir, "stacks", "dns", "terraform.tfstate"), *dns.StateFile)
+	assert.True(t, *dns.Backup, "directory settings override the top-level settings")
+	assert.Equal(t, "v5", *dns.TargetVersion, "top-level settings are inherited")
+
+	other := file.SettingsFor(dir)
+	assert.False(t, *other.Backup)
+	assert.Nil(t, other.Recursive)
+
+	require.Len(t, file.Migrators, 1)
+	assert.True(t, file.Migrators["cloudflare_record"]["default_ttl"].RawEquals(cty.NumberIntVal(300)))
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Syntax error",
+			input:    `source_version = `,
+			expected: ".tf-migrate.hcl:1,18",
+		},
+		{
+			name:     "Unknown setting",
+			input:    "source_version = \"v4\"\nresource = [\"cloudflare_record\"]",
+			expected: `.tf-migrate.hcl:2,1-9: Unsupported argument; An argument named "resource" is not expected here.`,
+		},
+		{
+			name:     "Wrong type",
+			input:    `backup = "yes"`,
+			expected: `Unsuitable value type`,
+		},
+		{
+			name:     "Invalid version",
+			input:    `target_version = "5.0"`,
+			expected: `.tf-migrate.hcl:1,1-23: Invalid provider version; The target_version "5.0" is not a provider major version such as "v4".`,
+		},
+		{
+			name:     "Missing directory",
+			input:    `directory "stacks/missing" {}`,
+			expected: `Directory not found; The directory "stacks/missing" does not exist.`,
+		},
+		{
+			name:     "Duplicate directory",
+			input:    "directory \"stacks/dns\" {}\ndirectory \"./stacks/dns\" {}",
+			expected: `.tf-migrate.hcl:2,1-25: Duplicate directory block; Directory "./stacks/dns" is already configured at`,
+		},
+		{
+			name:     "Unknown block in directory",
+			input:    "directory \"stacks/dns\" {\n  migrator \"cloudflare_record\" {}\n}",
+			expected: `Unsupported block type; Blocks of type "migrator" are not expected here.`,
+		},
+		{
+			name:     "Plugins directory in directory block",
+			input:    "directory \"stacks/dns\" {\n  plugins_dir = \"plugins\"\n}",
+			expected: `.tf-migrate.hcl:2,3-26: Unsupported setting in directory block; plugins_dir applies to the whole run`,
+		},
+		{
+			name:     "Option that is not a literal",
+			input:    "migrator \"cloudflare_record\" {\n  default_ttl = var.ttl\n}",
+			expected: `Variables not allowed`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(writeProject(t, tt.input))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.expected)
+		})
+	}
+}
+
+func TestCheckMigratorOptions(t *testing.T) {
+	file, err := Load(writeProject(t, `
+migrator "cloudflare_record" {
+  default_ttl = "300"
+}
+
+migrator "cloudflare_zone" {
+  plan = "free"
+}
+
+migrator "cloudflare_unknown" {}
+
+migrator "cloudflare_ruleset" {
+  default_ttl = true
+}
+`))
+	require.NoError(t, err)
+
+	lookup := func(resourceType string) (map[string]cty.Type, bool) {
+		switch resourceType {
+		case "cloudflare_record", "cloudflare_ruleset":
+			return map[string]cty.Type{"default_ttl": cty.Number}, true
+		case "cloudflare_zone":
+			return nil, true
+		}
+		return nil, false
+	}
+
+	var details []string
+	for _, diag := range file.CheckMigratorOptions(lookup) {
+		details = append(details, diag.Summary+": "+diag.Detail)
+	}
+	assert.Equal(t, []string{
+		`Invalid migrator option value: Inappropriate value for option "default_ttl" of the "cloudflare_ruleset" migrator: number required, but have bool.`,
+		`Unknown migrator: There is no migrator for the resource type "cloudflare_unknown" in the configured version paths.`,
+		`Unsupported migrator option: The migrator for "cloudflare_zone" has no option "plan". It does not accept any options.`,
+	}, details)
+
+	// Valid values are converted to the declared type
+	assert.True(t, file.Migrators["cloudflare_record"]["default_ttl"].RawEquals(cty.NumberIntVal(300)))
+}
+
+func TestFind(t *testing.T) {
+	path := writeProject(t, ``)
+
+	found, err := Find(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Equal(t, path, found)
+
+	found, err = Find(t.TempDir())
+	require.NoError(t, err)
+	assert.Empty(t, found)
+}
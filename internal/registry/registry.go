@@ -1,6 +1,10 @@
 package registry
 
 import (
+	ds_accounts "github.com/cloudflare/tf-migrate/internal/datasources/accounts"
+	ds_api_token_permission_groups_list "github.com/cloudflare/tf-migrate/internal/datasources/api_token_permission_groups_list"
+	ds_zero_trust_tunnel_cloudflared "github.com/cloudflare/tf-migrate/internal/datasources/zero_trust_tunnel_cloudflared"
+	ds_zones "github.com/cloudflare/tf-migrate/internal/datasources/zones"
 	"github.com/cloudflare/tf-migrate/internal/resources/account_member"
 	"github.com/cloudflare/tf-migrate/internal/resources/api_token"
 	"github.com/cloudflare/tf-migrate/internal/resources/dns_record"
@@ -39,4 +43,10 @@ func RegisterAllMigrations() {
 	zero_trust_list.NewV4ToV5Migrator()
 	zero_trust_tunnel_cloudflared.NewV4ToV5Migrator()
 	zero_trust_tunnel_cloudflared_route.NewV4ToV5Migrator()
+
+	// Data sources are registered in their own namespace
+	ds_accounts.NewV4ToV5Migrator()
+	ds_api_token_permission_groups_list.NewV4ToV5Migrator()
+	ds_zero_trust_tunnel_cloudflared.NewV4ToV5Migrator()
+	ds_zones.NewV4ToV5Migrator()
 }
@@ -0,0 +1,25 @@
+package registry
+
+import (
+	"testing"
+
+	"github.com/cloudflare/tf-migrate/internal"
+	"github.com/cloudflare/tf-migrate/internal/rules"
+)
+
+// TestRegisterAllMigrations checks that registration does not panic, which it would at the
+// start of every command if a migrator compiled from an embedded rule had no rule
+func TestRegisterAllMigrations(t *testing.T) {
+	RegisterAllMigrations()
+
+	migrators, err := rules.Builtin()
+	if err != nil {
+		t.Fatalf("Failed to load the embedded rules: %v", err)
+	}
+	for _, m := range migrators {
+		registered := internal.GetMigrator(m.SourceResourceType(), m.SourceVersion(), m.TargetVersion())
+		if registered != m {
+			t.Errorf("Embedded rule for %s from %s to %s is not registered", m.SourceResourceType(), m.SourceVersion(), m.TargetVersion())
+		}
+	}
+}
@@ -0,0 +1,208 @@
+// Package remotestate reads and writes Terraform state stored in an S3-compatible bucket,
+// such as Cloudflare R2, the way the Terraform s3 backend does. A migration fetches the
+// state, holds the backend's lock object while it runs, keeps a pre-migration copy of the
+// state in the bucket and uploads the migrated state.
+package remotestate
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+// Config is the location of a state object and the settings used to reach it
+type Config struct {
+	Bucket   string
+	Key      string
+	Region   string
+	Endpoint string
+	// UsePathStyle addresses the bucket in the URL path instead of the host name
+	UsePathStyle bool
+
+	AccessKey    string
+	SecretKey    string
+	SessionToken string
+}
+
+// ParseURL parses a state location such as s3://bucket/path/terraform.tfstate
+func ParseURL(raw string) (Config, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid state backend %q: %w", raw, err)
+	}
+	if u.Scheme != "s3" {
+		return Config{}, fmt.Errorf("invalid state backend %q: only s3://bucket/key locations are supported", raw)
+	}
+	key := strings.TrimPrefix(u.Path, "/")
+	if u.Host == "" || key == "" || strings.HasSuffix(key, "/") {
+		return Config{}, fmt.Errorf("invalid state backend %q: expected s3://bucket/key", raw)
+	}
+	return Config{Bucket: u.Host, Key: key}, nil
+}
+
+// LoadBackendConfig reads the settings of an s3 backend from a file in the format of
+// terraform init -backend-config, such as backend.hcl. Settings that do not affect how the
+// state is read, like skip_credentials_validation, are ignored.
+func LoadBackendConfig(path string) (Config, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to read backend config: %w", err)
+	}
+	cfg, diags := parseBackendConfig(path, src)
+	if diags.HasErrors() {
+		return Config{}, fmt.Errorf("invalid backend config: %w", diags)
+	}
+	return cfg, nil
+}
+
+func parseBackendConfig(path string, src []byte) (Config, hcl.Diagnostics) {
+	var cfg Config
+	file, diags := hclparse.NewParser().ParseHCL(src, path)
+	if diags.HasErrors() {
+		return cfg, diags
+	}
+	attrs, diags := file.Body.JustAttributes()
+	if diags.HasErrors() {
+		return cfg, diags
+	}
+
+	fields := map[string]*string{
+		"bucket":     &cfg.Bucket,
+		"key":        &cfg.Key,
+		"region":     &cfg.Region,
+		"endpoint":   &cfg.Endpoint,
+		"access_key": &cfg.AccessKey,
+		"secret_key": &cfg.SecretKey,
+		"token":      &cfg.SessionToken,
+	}
+	names := make([]string, 0, len(attrs))
+	for name := range attrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		attr := attrs[name]
+		switch name {
+		case "force_path_style", "use_path_style":
+			value, valueDiags := literal(attr, cty.Bool)
+			diags = append(diags, valueDiags...)
+			if !valueDiags.HasErrors() && value.True() {
+				cfg.UsePathStyle = true
+			}
+		case "endpoints":
+			// Terraform 1.6 and later take the endpoint of each service in an object
+			value, valueDiags := literal(attr, cty.Map(cty.String))
+			diags = append(diags, valueDiags...)
+			if !valueDiags.HasErrors() {
+				if s3, ok := value.AsValueMap()["s3"]; ok {
+					cfg.Endpoint = s3.AsString()
+				}
+			}
+		default:
+			field, ok := fields[name]
+			if !ok {
+				continue
+			}
+			value, valueDiags := literal(attr, cty.String)
+			diags = append(diags, valueDiags...)
+			if !valueDiags.HasErrors() {
+				*field = value.AsString()
+			}
+		}
+	}
+	return cfg, diags
+}
+
+// literal evaluates a backend setting, which cannot refer to variables, and converts it to
+// the given type
+func literal(attr *hcl.Attribute, want cty.Type) (cty.Value, hcl.Diagnostics) {
+	value, diags := attr.Expr.Value(nil)
+	if diags.HasErrors() {
+		return cty.NilVal, diags
+	}
+	converted, err := convert.Convert(value, want)
+	if err != nil || converted.IsNull() {
+		detail := fmt.Sprintf("The %s setting must be a %s.", attr.Name, want.FriendlyName())
+		if err != nil {
+			detail = fmt.Sprintf("Inappropriate value for %s: %s.", attr.Name, err)
+		}
+		return cty.NilVal, hcl.Diagnostics{{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid backend setting",
+			Detail:   detail,
+			Subject:  attr.Expr.Range().Ptr(),
+		}}
+	}
+	return converted, nil
+}
+
+// Merge returns the config with the fields set in other taking precedence
+func (c Config) Merge(other Config) Config {
+	merged := c
+	set := func(field *string, value string) {
+		if value != "" {
+			*field = value
+		}
+	}
+	set(&merged.Bucket, other.Bucket)
+	set(&merged.Key, other.Key)
+	set(&merged.Region, other.Region)
+	set(&merged.Endpoint, other.Endpoint)
+	set(&merged.AccessKey, other.AccessKey)
+	set(&merged.SecretKey, other.SecretKey)
+	set(&merged.SessionToken, other.SessionToken)
+	merged.UsePathStyle = c.UsePathStyle || other.UsePathStyle
+	return merged
+}
+
+// WithEnvironment fills the settings that are not set from the environment variables the
+// AWS SDK reads, as the Terraform s3 backend does
+func (c Config) WithEnvironment(getenv func(string) string) Config {
+	fallback := func(field *string, names ...string) {
+		for _, name := range names {
+			if *field == "" {
+				*field = getenv(name)
+			}
+		}
+	}
+	fallback(&c.Region, "AWS_REGION", "AWS_DEFAULT_REGION")
+	fallback(&c.Endpoint, "AWS_ENDPOINT_URL_S3", "AWS_ENDPOINT_URL")
+	fallback(&c.AccessKey, "AWS_ACCESS_KEY_ID")
+	fallback(&c.SecretKey, "AWS_SECRET_ACCESS_KEY")
+	fallback(&c.SessionToken, "AWS_SESSION_TOKEN")
+	return c
+}
+
+// Validate checks that the config has everything needed to reach the state
+func (c Config) Validate() error {
+	var missing []string
+	if c.Bucket == "" {
+		missing = append(missing, "bucket")
+	}
+	if c.Key == "" {
+		missing = append(missing, "key")
+	}
+	if c.Region == "" {
+		missing = append(missing, "region (or AWS_REGION)")
+	}
+	if c.AccessKey == "" || c.SecretKey == "" {
+		missing = append(missing, "credentials (AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY)")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("incomplete state backend settings: missing %s", strings.Join(missing, ", "))
+	}
+	if c.Endpoint != "" {
+		u, err := url.Parse(c.Endpoint)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return fmt.Errorf("invalid state backend endpoint %q: expected an http or https URL", c.Endpoint)
+		}
+	}
+	return nil
+}
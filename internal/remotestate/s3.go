@@ -0,0 +1,263 @@
+package remotestate
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned when an object does not exist
+	ErrNotFound = errors.New("object not found")
+	// ErrPreconditionFailed is returned when a conditional request is refused, because the
+	// object already exists or was changed since it was read
+	ErrPreconditionFailed = errors.New("precondition failed")
+)
+
+// Client is a minimal S3 client for the objects of a single bucket. Requests are signed
+// with AWS Signature Version 4, which S3-compatible services such as R2 accept.
+type Client struct {
+	cfg        Config
+	httpClient *http.Client
+	now        func() time.Time
+}
+
+// NewClient returns a client for the bucket of the config
+func NewClient(cfg Config) (*Client, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &Client{
+		cfg:        cfg,
+		httpClient: &http.Client{Timeout: 5 * time.Minute},
+		now:        time.Now,
+	}, nil
+}
+
+// Object is the content of an object and its entity tag
+type Object struct {
+	Body []byte
+	ETag string
+}
+
+// PutOptions are the conditions of a PUT request
+type PutOptions struct {
+	// IfNoneMatch only writes the object if it does not exist yet
+	IfNoneMatch bool
+	// IfMatch only writes the object if its entity tag is unchanged
+	IfMatch string
+}
+
+// Get reads an object
+func (c *Client) Get(ctx context.Context, key string) (*Object, error) {
+	resp, err := c.do(ctx, http.MethodGet, key, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if err := checkResponse(resp, key); err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", key, err)
+	}
+	return &Object{Body: body, ETag: resp.Header.Get("ETag")}, nil
+}
+
+// Put writes an object and returns its new entity tag
+func (c *Client) Put(ctx context.Context, key string, body []byte, opts PutOptions) (string, error) {
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	if opts.IfNoneMatch {
+		header.Set("If-None-Match", "*")
+	}
+	if opts.IfMatch != "" {
+		header.Set("If-Match", opts.IfMatch)
+	}
+	resp, err := c.do(ctx, http.MethodPut, key, header, body)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if err := checkResponse(resp, key); err != nil {
+		return "", err
+	}
+	return resp.Header.Get("ETag"), nil
+}
+
+// Delete removes an object. Deleting an object that does not exist is not an error.
+func (c *Client) Delete(ctx context.Context, key string) error {
+	resp, err := c.do(ctx, http.MethodDelete, key, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	return checkResponse(resp, key)
+}
+
+// do sends a signed request for an object of the bucket
+func (c *Client) do(ctx context.Context, method, key string, header http.Header, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.objectURL(key), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	c.sign(req, body, c.now().UTC())
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to %s %s: %w", strings.ToLower(method), c.cfg.Bucket+"/"+key, err)
+	}
+	return resp, nil
+}
+
+// objectURL returns the URL of an object, with the bucket in the host name unless path
+// style addressing is used
+func (c *Client) objectURL(key string) string {
+	endpoint := c.cfg.Endpoint
+	if endpoint == "" {
+		endpoint = "https://s3." + c.cfg.Region + ".amazonaws.com"
+	}
+	u, _ := url.Parse(strings.TrimSuffix(endpoint, "/"))
+	if c.cfg.UsePathStyle {
+		u.Path += "/" + c.cfg.Bucket + "/" + key
+	} else {
+		u.Host = c.cfg.Bucket + "." + u.Host
+		u.Path += "/" + key
+	}
+	u.RawPath = escapePath(u.Path)
+	return u.String()
+}
+
+// checkResponse turns an error response into an error
+func checkResponse(resp *http.Response, key string) error {
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusNotFound:
+		return fmt.Errorf("%s: %w", key, ErrNotFound)
+	case resp.StatusCode == http.StatusPreconditionFailed || resp.StatusCode == http.StatusConflict:
+		return fmt.Errorf("%s: %w", key, ErrPreconditionFailed)
+	}
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	return fmt.Errorf("%s: unexpected response %s: %s", key, resp.Status, strings.TrimSpace(string(message)))
+}
+
+// sign adds an AWS Signature Version 4 Authorization header to a request. Every header
+// of the request is signed.
+func (c *Client) sign(req *http.Request, body []byte, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if c.cfg.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", c.cfg.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.Join(values, ",")
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(headers[name]) + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + c.cfg.Region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+c.cfg.SecretKey), date)
+	key = hmacSHA256(key, c.cfg.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		c.cfg.AccessKey, scope, signedHeaders, signature))
+}
+
+// canonicalQuery encodes query parameters sorted by name, as Signature Version 4 requires
+func canonicalQuery(query url.Values) string {
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var parts []string
+	for _, name := range names {
+		values := append([]string(nil), query[name]...)
+		sort.Strings(values)
+		for _, value := range values {
+			parts = append(parts, escape(name)+"="+escape(value))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// escapePath percent-encodes every byte of a path except the unreserved characters and
+// the slashes between segments
+func escapePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = escape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// escape percent-encodes every byte except the unreserved characters of RFC 3986
+func escape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,150 @@
+package remotestate
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"time"
+)
+
+// LockInfo is the content of a lock object, in the format Terraform writes, so a lock held
+// by tf-migrate is reported by terraform and can be released with terraform force-unlock
+type LockInfo struct {
+	ID        string
+	Operation string
+	Info      string
+	Who       string
+	Version   string
+	Created   time.Time
+	Path      string
+}
+
+// NewLockInfo returns the lock information of an operation run by the current user
+func NewLockInfo(operation, version string, now time.Time) LockInfo {
+	who := "unknown"
+	if u, err := user.Current(); err == nil {
+		who = u.Username
+	}
+	if host, err := os.Hostname(); err == nil {
+		who += "@" + host
+	}
+	return LockInfo{
+		ID:        newLockID(),
+		Operation: operation,
+		Who:       who,
+		Version:   version,
+		Created:   now.UTC(),
+	}
+}
+
+// newLockID returns a random ID in the format of a UUID, like Terraform's lock IDs
+func newLockID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// LockedError is returned when the state is locked by another operation
+type LockedError struct {
+	Key string
+	// Info describes the operation holding the lock, if the lock object could be read
+	Info *LockInfo
+}
+
+func (e *LockedError) Error() string {
+	if e.Info == nil {
+		return fmt.Sprintf("state is locked: %s exists", e.Key)
+	}
+	return fmt.Sprintf("state is locked by %s (operation %q by %s, created %s, lock ID %s)",
+		e.Key, e.Info.Operation, e.Info.Who, e.Info.Created.Format(time.RFC3339), e.Info.ID)
+}
+
+// LockKey returns the key of the lock object of a state, the one the s3 backend uses with
+// use_lockfile
+func LockKey(key string) string {
+	return key + ".tflock"
+}
+
+// BackupKey returns the key of the pre-migration copy of a state. Every migration writes
+// a new copy, so earlier ones are never overwritten.
+func BackupKey(key string, now time.Time) string {
+	return key + ".pre-migration-" + now.UTC().Format("20060102T150405Z")
+}
+
+// Key returns the key of the state object
+func (c *Client) Key() string {
+	return c.cfg.Key
+}
+
+// URL returns the s3:// location of an object of the bucket
+func (c *Client) URL(key string) string {
+	return "s3://" + c.cfg.Bucket + "/" + key
+}
+
+// Lock creates the lock object of the state. The request fails if the object exists, so
+// only one operation holds the lock.
+func (c *Client) Lock(ctx context.Context, info LockInfo) error {
+	info.Path = c.cfg.Bucket + "/" + c.cfg.Key
+	body, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+
+	lockKey := LockKey(c.cfg.Key)
+	_, err = c.Put(ctx, lockKey, body, PutOptions{IfNoneMatch: true})
+	if errors.Is(err, ErrPreconditionFailed) {
+		locked := &LockedError{Key: lockKey}
+		if current, getErr := c.Get(ctx, lockKey); getErr == nil {
+			var holder LockInfo
+			if json.Unmarshal(current.Body, &holder) == nil {
+				locked.Info = &holder
+			}
+		}
+		return locked
+	}
+	if err != nil {
+		return fmt.Errorf("failed to lock state: %w", err)
+	}
+	return nil
+}
+
+// Unlock removes the lock object of the state, if it is still the one created with info
+func (c *Client) Unlock(ctx context.Context, info LockInfo) error {
+	lockKey := LockKey(c.cfg.Key)
+	current, err := c.Get(ctx, lockKey)
+	if err != nil {
+		return fmt.Errorf("failed to unlock state: %w", err)
+	}
+	var holder LockInfo
+	if err := json.Unmarshal(current.Body, &holder); err != nil || holder.ID != info.ID {
+		return fmt.Errorf("failed to unlock state: %s is no longer held by lock ID %s", lockKey, info.ID)
+	}
+	if err := c.Delete(ctx, lockKey); err != nil {
+		return fmt.Errorf("failed to unlock state: %w", err)
+	}
+	return nil
+}
+
+// Upload copies the original state to a new backup key and replaces the state with
+// migrated, unless the state was changed by someone else since original was read. The
+// backup key is returned once the copy is written, also when the upload then fails.
+func (c *Client) Upload(ctx context.Context, original *Object, migrated []byte, now time.Time) (string, error) {
+	backupKey := BackupKey(c.cfg.Key, now)
+	if _, err := c.Put(ctx, backupKey, original.Body, PutOptions{IfNoneMatch: true}); err != nil {
+		return "", fmt.Errorf("failed to write pre-migration copy of the state: %w", err)
+	}
+
+	if _, err := c.Put(ctx, c.cfg.Key, migrated, PutOptions{IfMatch: original.ETag}); err != nil {
+		if errors.Is(err, ErrPreconditionFailed) {
+			return backupKey, fmt.Errorf("failed to upload migrated state: the state was changed by another operation while it was locked")
+		}
+		return backupKey, fmt.Errorf("failed to upload migrated state: %w", err)
+	}
+	return backupKey, nil
+}
@@ -0,0 +1,280 @@
+// Package report builds a machine-readable summary of a migration run from the
+// transform.Context objects produced by the configuration and state pipelines.
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/hashicorp/hcl/v2"
+
+	"github.com/cloudflare/tf-migrate/internal/transform"
+)
+
+// Report is the top-level migration report
+type Report struct {
+	SourceVersion string `json:"source_version"`
+	TargetVersion string `json:"target_version"`
+	// VersionPath lists the versions of a migration across several provider versions,
+	// such as v4, v5 and v6
+	VersionPath []string     `json:"version_path,omitempty"`
+	Files       []FileReport `json:"files"`
+	State       *FileReport  `json:"state,omitempty"`
+	FollowUp    []FollowUp   `json:"follow_up"`
+	Summary     Summary      `json:"summary"`
+}
+
+// FileReport describes a single configuration or state file
+type FileReport struct {
+	Path      string           `json:"path"`
+	Resources []ResourceReport `json:"resources"`
+	// Diagnostics that could not be attributed to a single resource
+	Diagnostics []Diagnostic `json:"diagnostics,omitempty"`
+}
+
+// ResourceReport describes a single resource (or state instance) touched by the migration
+type ResourceReport struct {
+	OldType    string `json:"old_type"`
+	NewType    string `json:"new_type,omitempty"`
+	OldAddress string `json:"old_address"`
+	NewAddress string `json:"new_address,omitempty"`
+	Migrator   string `json:"migrator,omitempty"`
+	// Hop is the version path of the hop that changed the resource, e.g. v5-v6, in a
+	// migration across several provider versions
+	Hop      string       `json:"hop,omitempty"`
+	Warnings []Diagnostic `json:"warnings,omitempty"`
+	Errors   []Diagnostic `json:"errors,omitempty"`
+}
+
+// Diagnostic is a JSON-friendly form of hcl.Diagnostic
+type Diagnostic struct {
+	Severity string `json:"severity"`
+	Summary  string `json:"summary"`
+	Detail   string `json:"detail,omitempty"`
+	Range    string `json:"range,omitempty"`
+}
+
+// FollowUp lists a resource or reference that needs attention after the migration
+type FollowUp struct {
+	File string `json:"file"`
+	// Address is the address of the resource, empty for a reference
+	Address string `json:"address,omitempty"`
+	// Range is the location of a reference, e.g. outputs.tf:2,37-45
+	Range  string `json:"range,omitempty"`
+	Kind   string `json:"kind"`
+	Reason string `json:"reason"`
+	Hop    string `json:"hop,omitempty"`
+}
+
+// Summary holds totals across the whole run
+type Summary struct {
+	FilesProcessed          int `json:"files_processed"`
+	ResourcesTransformed    int `json:"resources_transformed"`
+	StateInstancesProcessed int `json:"state_instances_processed"`
+	DataSourcesRemoved      int `json:"data_sources_removed"`
+	Warnings                int `json:"warnings"`
+	Errors                  int `json:"errors"`
+}
+
+// New creates an empty report for the given version path
+func New(sourceVersion, targetVersion string) *Report {
+	return &Report{
+		SourceVersion: sourceVersion,
+		TargetVersion: targetVersion,
+		Files:         []FileReport{},
+		FollowUp:      []FollowUp{},
+	}
+}
+
+// AddConfigFile adds the result of running the config pipeline on a file
+func (r *Report) AddConfigFile(path string, ctx *transform.Context) {
+	fr, followUps := buildFileReport(path, ctx)
+	r.Files = append(r.Files, fr)
+	r.FollowUp = append(r.FollowUp, followUps...)
+
+	r.Summary.FilesProcessed++
+	for _, hop := range ctx.Chain() {
+		for _, change := range hop.ResourceChanges() {
+			if change.Changed {
+				r.Summary.ResourcesTransformed++
+			}
+		}
+	}
+	r.addTotals(fr)
+}
+
+// SetState adds the result of running the state pipeline
+func (r *Report) SetState(path string, ctx *transform.Context) {
+	fr, followUps := buildFileReport(path, ctx)
+	r.State = &fr
+	r.FollowUp = append(r.FollowUp, followUps...)
+
+	// Every hop processes the same instances, which are counted once
+	r.Summary.StateInstancesProcessed = 0
+	r.Summary.DataSourcesRemoved = 0
+	for _, hop := range ctx.Chain() {
+		if n := len(hop.ResourceChanges()); n > r.Summary.StateInstancesProcessed {
+			r.Summary.StateInstancesProcessed = n
+		}
+		if removed, ok := hop.Metadata["datasources_removed"].(int); ok {
+			r.Summary.DataSourcesRemoved += removed
+		}
+	}
+	r.addTotals(fr)
+}
+
+// AddDiagnostics adds diagnostics raised for a configuration file outside of the
+// pipeline, such as references that could not be updated
+func (r *Report) AddDiagnostics(path string, diags hcl.Diagnostics) {
+	var fr *FileReport
+	for i := range r.Files {
+		if r.Files[i].Path == path {
+			fr = &r.Files[i]
+			break
+		}
+	}
+	if fr == nil {
+		r.Files = append(r.Files, FileReport{Path: path, Resources: []ResourceReport{}})
+		r.AddDiagnostics(path, diags)
+		return
+	}
+
+	added := FileReport{}
+	for _, diag := range diags {
+		added.Diagnostics = append(added.Diagnostics, convertDiagnostic(diag))
+	}
+	fr.Diagnostics = append(fr.Diagnostics, added.Diagnostics...)
+	r.addTotals(added)
+}
+
+// AddUnresolvedReferences adds references that could not be updated automatically, such
+// as references to removed attributes, as diagnostics and follow-up items of a file
+func (r *Report) AddUnresolvedReferences(path string, diags hcl.Diagnostics) {
+	r.AddDiagnostics(path, diags)
+	for _, diag := range diags {
+		r.FollowUp = append(r.FollowUp, FollowUp{
+			File:   path,
+			Range:  convertDiagnostic(diag).Range,
+			Kind:   string(transform.FollowUpFor(diag)),
+			Reason: diag.Summary,
+		})
+	}
+}
+
+// WriteFile writes the report as indented JSON, with the files sorted by path
+func (r *Report) WriteFile(path string) error {
+	sort.SliceStable(r.Files, func(i, j int) bool { return r.Files[i].Path < r.Files[j].Path })
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode report: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write report %s: %w", path, err)
+	}
+	return nil
+}
+
+// addTotals updates the warning and error totals from a file report
+func (r *Report) addTotals(fr FileReport) {
+	for _, res := range fr.Resources {
+		r.Summary.Warnings += len(res.Warnings)
+		r.Summary.Errors += len(res.Errors)
+	}
+	for _, d := range fr.Diagnostics {
+		switch d.Severity {
+		case severityError:
+			r.Summary.Errors++
+		case severityWarning:
+			r.Summary.Warnings++
+		}
+	}
+}
+
+// buildFileReport converts the changes and diagnostics recorded on a context and the
+// contexts of the hops that followed it. Every resource with a warning or error is also
+// returned as a follow-up item.
+func buildFileReport(path string, ctx *transform.Context) (FileReport, []FollowUp) {
+	fr := FileReport{
+		Path:      path,
+		Resources: []ResourceReport{},
+	}
+	var followUps []FollowUp
+	for _, hop := range ctx.Chain() {
+		followUps = append(followUps, addHop(&fr, path, hop)...)
+	}
+	return fr, followUps
+}
+
+// addHop adds the changes and diagnostics recorded on the context of a hop to a file report
+func addHop(fr *FileReport, path string, ctx *transform.Context) []FollowUp {
+	var followUps []FollowUp
+	var hop string
+	if ctx.MultiHop() {
+		hop = transform.Hop{SourceVersion: ctx.SourceVersion, TargetVersion: ctx.TargetVersion}.String()
+	}
+
+	attributed := make(map[*hcl.Diagnostic]bool)
+	for _, change := range ctx.ResourceChanges() {
+		rr := ResourceReport{
+			OldType:    change.OldType,
+			NewType:    change.NewType,
+			OldAddress: change.OldAddress,
+			NewAddress: change.NewAddress,
+			Migrator:   change.Migrator,
+			Hop:        hop,
+		}
+		address := change.NewAddress
+		if address == "" {
+			address = change.OldAddress
+		}
+		for _, diag := range change.Diagnostics {
+			attributed[diag] = true
+			if diag.Severity == hcl.DiagError || diag.Severity == hcl.DiagWarning {
+				followUps = append(followUps, FollowUp{
+					File:    path,
+					Address: address,
+					Kind:    string(transform.FollowUpFor(diag)),
+					Reason:  diag.Summary,
+					Hop:     hop,
+				})
+			}
+			switch diag.Severity {
+			case hcl.DiagError:
+				rr.Errors = append(rr.Errors, convertDiagnostic(diag))
+			case hcl.DiagWarning:
+				rr.Warnings = append(rr.Warnings, convertDiagnostic(diag))
+			}
+		}
+		fr.Resources = append(fr.Resources, rr)
+	}
+
+	for _, diag := range ctx.Diagnostics {
+		if !attributed[diag] {
+			fr.Diagnostics = append(fr.Diagnostics, convertDiagnostic(diag))
+		}
+	}
+
+	return followUps
+}
+
+const (
+	severityError   = "error"
+	severityWarning = "warning"
+)
+
+func convertDiagnostic(diag *hcl.Diagnostic) Diagnostic {
+	d := Diagnostic{
+		Severity: severityWarning,
+		Summary:  diag.Summary,
+		Detail:   diag.Detail,
+	}
+	if diag.Severity == hcl.DiagError {
+		d.Severity = severityError
+	}
+	if diag.Subject != nil {
+		d.Range = diag.Subject.String()
+	}
+	return d
+}
@@ -0,0 +1,199 @@
+package report
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/tf-migrate/internal/transform"
+)
+
+func TestReport(t *testing.T) {
+	refresh := &hcl.Diagnostic{
+		Severity: hcl.DiagWarning,
+		Summary:  "Tunnel route ID could not be resolved from the API",
+		Extra:    transform.FollowUpRefresh,
+	}
+	failed := &hcl.Diagnostic{
+		Severity: hcl.DiagError,
+		Summary:  "Failed to transform cloudflare_load_balancer resource",
+		Detail:   "bad pool",
+	}
+	unattributed := &hcl.Diagnostic{
+		Severity: hcl.DiagWarning,
+		Summary:  "Unrelated warning",
+	}
+
+	mainCtx := &transform.Context{Metadata: map[string]interface{}{}}
+	mainCtx.Diagnostics = hcl.Diagnostics{refresh, failed, unattributed}
+	mainCtx.RecordResourceChange(transform.ResourceChange{
+		OldType:     "cloudflare_tunnel_route",
+		NewType:     "cloudflare_zero_trust_tunnel_cloudflared_route",
+		OldAddress:  "cloudflare_tunnel_route.r",
+		NewAddress:  "cloudflare_zero_trust_tunnel_cloudflared_route.r",
+		Migrator:    "*v4_to_v5.V4ToV5Migrator",
+		Changed:     true,
+		Diagnostics: hcl.Diagnostics{refresh},
+	})
+	mainCtx.RecordResourceChange(transform.ResourceChange{
+		OldType:     "cloudflare_load_balancer",
+		OldAddress:  "cloudflare_load_balancer.lb",
+		Diagnostics: hcl.Diagnostics{failed},
+	})
+
+	dnsCtx := &transform.Context{Metadata: map[string]interface{}{}}
+	dnsCtx.RecordResourceChange(transform.ResourceChange{
+		OldType:    "cloudflare_record",
+		NewType:    "cloudflare_dns_record",
+		OldAddress: "cloudflare_record.www",
+		NewAddress: "cloudflare_dns_record.www",
+		Changed:    true,
+	})
+	dnsCtx.RecordResourceChange(transform.ResourceChange{
+		OldType:    "cloudflare_zone",
+		NewType:    "cloudflare_zone",
+		OldAddress: "cloudflare_zone.example",
+		NewAddress: "cloudflare_zone.example",
+	})
+
+	stateCtx := &transform.Context{Metadata: map[string]interface{}{"datasources_removed": 1}}
+	stateCtx.RecordResourceChange(transform.ResourceChange{
+		OldType:    "cloudflare_record",
+		NewType:    "cloudflare_dns_record",
+		OldAddress: "cloudflare_record.www",
+		NewAddress: "cloudflare_dns_record.www",
+	})
+
+	r := New("v4", "v5")
+	r.AddConfigFile("main.tf", mainCtx)
+	r.AddConfigFile("dns.tf", dnsCtx)
+	r.SetState("terraform.tfstate", stateCtx)
+
+	require.Len(t, r.Files, 2)
+	assert.Len(t, r.Files[1].Resources, 2)
+
+	mainReport := r.Files[0]
+	require.Len(t, mainReport.Resources, 2)
+	assert.Equal(t, []Diagnostic{{Severity: "warning", Summary: refresh.Summary}}, mainReport.Resources[0].Warnings)
+	assert.Equal(t, []Diagnostic{{Severity: "error", Summary: failed.Summary, Detail: "bad pool"}}, mainReport.Resources[1].Errors)
+	assert.Equal(t, []Diagnostic{{Severity: "warning", Summary: "Unrelated warning"}}, mainReport.Diagnostics)
+
+	assert.Equal(t, []FollowUp{
+		{File: "main.tf", Address: "cloudflare_zero_trust_tunnel_cloudflared_route.r", Kind: "refresh", Reason: refresh.Summary},
+		{File: "main.tf", Address: "cloudflare_load_balancer.lb", Kind: "manual", Reason: failed.Summary},
+	}, r.FollowUp)
+
+	assert.Equal(t, Summary{
+		FilesProcessed:          2,
+		ResourcesTransformed:    2,
+		StateInstancesProcessed: 1,
+		DataSourcesRemoved:      1,
+		Warnings:                2,
+		Errors:                  1,
+	}, r.Summary)
+
+	path := filepath.Join(t.TempDir(), "report.json")
+	require.NoError(t, r.WriteFile(path))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var decoded Report
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, r.Summary, decoded.Summary)
+	assert.Equal(t, "terraform.tfstate", decoded.State.Path)
+	require.Len(t, decoded.Files, 2)
+	assert.Equal(t, "dns.tf", decoded.Files[0].Path)
+	assert.Equal(t, "main.tf", decoded.Files[1].Path)
+}
+
+func TestReportAddDiagnostics(t *testing.T) {
+	r := New("v4", "v5")
+	r.AddConfigFile("main.tf", &transform.Context{Metadata: map[string]interface{}{}})
+
+	removed := &hcl.Diagnostic{
+		Severity: hcl.DiagError,
+		Summary:  "Reference to removed attribute",
+		Subject:  &hcl.Range{Filename: "outputs.tf", Start: hcl.Pos{Line: 2, Column: 37}, End: hcl.Pos{Line: 2, Column: 45}},
+	}
+	r.AddDiagnostics("outputs.tf", hcl.Diagnostics{removed})
+	r.AddDiagnostics("main.tf", hcl.Diagnostics{removed})
+
+	require.Len(t, r.Files, 2)
+	assert.Equal(t, "main.tf", r.Files[0].Path)
+	assert.Equal(t, "outputs.tf", r.Files[1].Path)
+	assert.Equal(t, []Diagnostic{{Severity: "error", Summary: removed.Summary, Range: "outputs.tf:2,37-45"}}, r.Files[1].Diagnostics)
+	assert.Len(t, r.Files[0].Diagnostics, 1)
+	assert.Equal(t, 2, r.Summary.Errors)
+	assert.Empty(t, r.FollowUp)
+}
+
+func TestReportAddUnresolvedReferences(t *testing.T) {
+	r := New("v4", "v5")
+	removed := &hcl.Diagnostic{
+		Severity: hcl.DiagError,
+		Summary:  "Reference to removed attribute",
+		Subject:  &hcl.Range{Filename: "outputs.tf", Start: hcl.Pos{Line: 2, Column: 37}, End: hcl.Pos{Line: 2, Column: 45}},
+	}
+	r.AddUnresolvedReferences("outputs.tf", hcl.Diagnostics{removed})
+
+	require.Len(t, r.Files, 1)
+	assert.Len(t, r.Files[0].Diagnostics, 1)
+	assert.Equal(t, []FollowUp{
+		{File: "outputs.tf", Range: "outputs.tf:2,37-45", Kind: "manual", Reason: removed.Summary},
+	}, r.FollowUp)
+	assert.Equal(t, 1, r.Summary.Errors)
+}
+
+func TestReportHops(t *testing.T) {
+	hops := []transform.Hop{
+		{SourceVersion: "v4", TargetVersion: "v5"},
+		{SourceVersion: "v5", TargetVersion: "v6"},
+	}
+	warning := &hcl.Diagnostic{Severity: hcl.DiagWarning, Summary: "Check the new defaults"}
+
+	ctx := &transform.Context{SourceVersion: "v4", TargetVersion: "v5", Hops: hops, Metadata: map[string]interface{}{}}
+	ctx.RecordResourceChange(transform.ResourceChange{
+		OldType:    "cloudflare_record",
+		NewType:    "cloudflare_dns_record",
+		OldAddress: "cloudflare_record.www",
+		NewAddress: "cloudflare_dns_record.www",
+		Changed:    true,
+	})
+	ctx.Next = ctx.NextHop(nil)
+	require.NotNil(t, ctx.Next)
+	ctx.Next.Diagnostics = hcl.Diagnostics{warning}
+	ctx.Next.RecordResourceChange(transform.ResourceChange{
+		OldType:     "cloudflare_dns_record",
+		NewType:     "cloudflare_dns_record",
+		OldAddress:  "cloudflare_dns_record.www",
+		NewAddress:  "cloudflare_dns_record.www",
+		Changed:     true,
+		Diagnostics: hcl.Diagnostics{warning},
+	})
+	assert.Nil(t, ctx.Next.NextHop(nil))
+
+	r := New("v4", "v6")
+	r.VersionPath = []string{"v4", "v5", "v6"}
+	r.AddConfigFile("main.tf", ctx)
+
+	require.Len(t, r.Files, 1)
+	require.Len(t, r.Files[0].Resources, 2)
+	assert.Equal(t, "v4-v5", r.Files[0].Resources[0].Hop)
+	assert.Equal(t, "cloudflare_dns_record", r.Files[0].Resources[0].NewType)
+	assert.Equal(t, "v5-v6", r.Files[0].Resources[1].Hop)
+	assert.Equal(t, []Diagnostic{{Severity: "warning", Summary: warning.Summary}}, r.Files[0].Resources[1].Warnings)
+	assert.Equal(t, []FollowUp{
+		{File: "main.tf", Address: "cloudflare_dns_record.www", Kind: "manual", Reason: warning.Summary, Hop: "v5-v6"},
+	}, r.FollowUp)
+	assert.Equal(t, 2, r.Summary.ResourcesTransformed)
+	assert.Equal(t, 1, r.Summary.Warnings)
+
+	r.SetState("terraform.tfstate", ctx)
+	require.Len(t, r.State.Resources, 2)
+	assert.Equal(t, 1, r.Summary.StateInstancesProcessed)
+}
@@ -2,7 +2,6 @@ package api_token
 
 import (
 	"fmt"
-	"regexp"
 
 	"github.com/cloudflare/tf-migrate/internal"
 	"github.com/cloudflare/tf-migrate/internal/hcl"
@@ -34,11 +33,7 @@ func (m *V4ToV5Migrator) CanHandle(resourceType string) bool {
 }
 
 func (m *V4ToV5Migrator) Preprocess(content string) string {
-	// Remove deprecated data source cloudflare_api_token_permission_groups
-	// It was replaced by cloudflare_api_token_permission_groups_list in v5
-	// Remove just the data source line, preserving any comments
-	re := regexp.MustCompile(`(?m)^data\s+"cloudflare_api_token_permission_groups"\s+"[^"]+"\s*\{\s*\}\s*\n`)
-	content = re.ReplaceAllString(content, "")
+	// The cloudflare_api_token_permission_groups data source is migrated by its own data source migrator
 	return content
 }
 
@@ -57,6 +52,7 @@ func (m *V4ToV5Migrator) TransformConfig(ctx *transform.Context, block *hclwrite
 
 	m.transformPolicyBlocks(body)
 	m.transformConditionBlock(body)
+	ctx.Diagnostics = append(ctx.Diagnostics, tfhcl.UnconvertibleDynamicBlocks(body, "policy", "condition", "request_ip")...)
 	return &transform.TransformResult{
 		Blocks:         []*hclwrite.Block{block},
 		RemoveOriginal: false,
@@ -64,13 +60,9 @@ func (m *V4ToV5Migrator) TransformConfig(ctx *transform.Context, block *hclwrite
 }
 
 func (m *V4ToV5Migrator) transformPolicyBlocks(body *hclwrite.Body) {
-	policyBlocks := tfhcl.FindBlocksByType(body, "policy")
-	if len(policyBlocks) == 0 {
-		return
-	}
-
-	var policyObjects []hclwrite.Tokens
-	for _, policyBlock := range policyBlocks {
+	// Comments on the policy blocks are kept on their objects, and dynamic policy
+	// blocks become for expressions
+	tfhcl.ConvertBlocksToList(body, "policy", "policies", func(policyBlock *hclwrite.Block) {
 		policyBody := policyBlock.Body()
 
 		// Ensure effect attribute exists (required in v5, optional in v4)
@@ -81,15 +73,7 @@ func (m *V4ToV5Migrator) transformPolicyBlocks(body *hclwrite.Body) {
 
 		// Transform permission_groups from list of strings to list of objects with id field
 		m.transformPermissionGroups(policyBody)
-
-		objTokens := hcl.BuildObjectFromBlock(policyBlock)
-		policyObjects = append(policyObjects, objTokens)
-	}
-
-	listTokens := hclwrite.TokensForTuple(policyObjects)
-	body.SetAttributeRaw("policies", listTokens)
-
-	tfhcl.RemoveBlocksByType(body, "policy")
+	})
 }
 
 // transformPermissionGroups converts permission_groups from list of strings to list of objects
@@ -139,29 +123,27 @@ func (m *V4ToV5Migrator) transformPermissionGroups(body *hclwrite.Body) {
 		body.RemoveAttribute("permission_groups")
 		listTokens := hclwrite.TokensForTuple(permObjects)
 		body.SetAttributeRaw("permission_groups", listTokens)
-	}
-}
-
-func (m *V4ToV5Migrator) transformConditionBlock(body *hclwrite.Body) {
-	conditionBlock := tfhcl.FindBlockByType(body, "condition")
-	if conditionBlock == nil {
 		return
 	}
 
-	var conditionAttrs []hclwrite.ObjectAttrTokens
-	for _, nestedBlock := range conditionBlock.Body().Blocks() {
-		if nestedBlock.Type() == "request_ip" {
-			requestIPTokens := hcl.BuildObjectFromBlock(nestedBlock)
-			conditionAttrs = append(conditionAttrs, hclwrite.ObjectAttrTokens{
-				Name:  hclwrite.TokensForIdentifier("request_ip"),
-				Value: requestIPTokens,
-			})
-		}
+	// Other expressions, like the element of a dynamic policy block, are converted
+	// with a for expression: [for id in expr : { id = id }]
+	if len(exprTokens) > 0 && exprTokens[0].Type != hclsyntax.TokenOBrack {
+		idObject := hclwrite.TokensForObject([]hclwrite.ObjectAttrTokens{{
+			Name:  hclwrite.TokensForIdentifier("id"),
+			Value: hclwrite.TokensForIdentifier("id"),
+		}})
+		body.SetAttributeRaw("permission_groups", hcl.BuildForExpression("", "id", exprTokens, idObject))
 	}
+}
 
-	conditionTokens := hclwrite.TokensForObject(conditionAttrs)
-	body.SetAttributeRaw("condition", conditionTokens)
-	body.RemoveBlock(conditionBlock)
+// transformConditionBlock converts the condition block and its request_ip block to
+// objects. A dynamic condition becomes a for expression over its content block.
+func (m *V4ToV5Migrator) transformConditionBlock(body *hclwrite.Body) {
+	for _, conditionBlock := range tfhcl.FindBlocksByTypeWithDynamic(body, "condition") {
+		tfhcl.ConvertSingleBlockToAttribute(conditionBlock.Body(), "request_ip", "request_ip")
+	}
+	tfhcl.ConvertSingleBlockToAttribute(body, "condition", "condition")
 }
 
 func (m *V4ToV5Migrator) TransformState(ctx *transform.Context, stateJSON gjson.Result, resourcePath, resourceName string) (string, error) {
@@ -515,6 +515,83 @@ resource "cloudflare_api_token" "api_token_create" {
       not_in = ["198.51.100.1/32"]
     }
   }
+}`,
+		},
+		{
+			Name: "api token with static and dynamic policies",
+			Input: `
+resource "cloudflare_api_token" "example" {
+  name = "example"
+
+  policy {
+    permission_groups = ["c8fed203ed3043cba015a93ad1616f1f"]
+    resources = {
+      "com.cloudflare.api.account.zone.*" = "*"
+    }
+  }
+
+  dynamic "policy" {
+    for_each = var.zone_policies
+    iterator = zone_policy
+    content {
+      effect            = zone_policy.value.effect
+      permission_groups = zone_policy.value.permission_group_ids
+      resources = {
+        "com.cloudflare.api.account.zone.${zone_policy.key}" = "*"
+      }
+    }
+  }
+}`,
+			Expected: `
+resource "cloudflare_api_token" "example" {
+  name = "example"
+
+  policies = concat(
+    [{
+      resources = {
+        "com.cloudflare.api.account.zone.*" = "*"
+      }
+      effect = "allow"
+      permission_groups = [{
+        id = "c8fed203ed3043cba015a93ad1616f1f"
+      }]
+    }],
+    [for zone_policy_key, zone_policy in var.zone_policies : {
+      effect = zone_policy.effect
+      permission_groups = [for id in zone_policy.permission_group_ids : {
+        id = id
+      }]
+      resources = {
+        "com.cloudflare.api.account.zone.${zone_policy_key}" = "*"
+      }
+    }],
+  )
+}`,
+		},
+		{
+			Name: "api token with dynamic condition block",
+			Input: `
+resource "cloudflare_api_token" "example" {
+  name = "example"
+
+  dynamic "condition" {
+    for_each = var.allowed_ips == null ? [] : [var.allowed_ips]
+    content {
+      request_ip {
+        in = condition.value
+      }
+    }
+  }
+}`,
+			Expected: `
+resource "cloudflare_api_token" "example" {
+  name = "example"
+
+  condition = one([for condition in var.allowed_ips == null ? [] : [var.allowed_ips] : {
+    request_ip = {
+      in = condition
+    }
+  }])
 }`,
 		},
 	}
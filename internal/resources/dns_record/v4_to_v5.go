@@ -8,6 +8,7 @@ import (
 	"github.com/hashicorp/hcl/v2/hclwrite"
 	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
+	"github.com/zclconf/go-cty/cty"
 
 	"github.com/cloudflare/tf-migrate/internal"
 
@@ -49,6 +50,32 @@ func (m *V4ToV5Migrator) GetResourceRename() (string, string) {
 	return "cloudflare_record", "cloudflare_dns_record"
 }
 
+// GetOptions implements the ConfigurableMigrator interface
+func (m *V4ToV5Migrator) GetOptions() map[string]cty.Type {
+	return map[string]cty.Type{
+		// default_ttl is set on records without a ttl instead of 1 (automatic)
+		"default_ttl": cty.Number,
+	}
+}
+
+// GetAttributeRenames implements the AttributeRenamer interface
+func (m *V4ToV5Migrator) GetAttributeRenames() map[string]string {
+	return map[string]string{"value": "content"}
+}
+
+// GetRemovedAttributes implements the AttributeRemover interface
+func (m *V4ToV5Migrator) GetRemovedAttributes() []string {
+	return []string{"hostname", "allow_overwrite", "metadata"}
+}
+
+// RenamesAttributesOf implements the AttributeRenameFilter interface.
+// Records that use the data field have no content to rename value to, so references
+// to their value are left for the user to update.
+func (m *V4ToV5Migrator) RenamesAttributesOf(block *hclwrite.Block) bool {
+	body := block.Body()
+	return body.GetAttribute("data") == nil && len(tfhcl.FindBlocksByTypeWithDynamic(body, "data")) == 0
+}
+
 func (m *V4ToV5Migrator) TransformConfig(ctx *transform.Context, block *hclwrite.Block) (*transform.TransformResult, error) {
 	// Rename cloudflare_record to cloudflare_dns_record
 	tfhcl.RenameResourceType(block, "cloudflare_record", "cloudflare_dns_record")
@@ -56,6 +83,9 @@ func (m *V4ToV5Migrator) TransformConfig(ctx *transform.Context, block *hclwrite
 	body := block.Body()
 
 	// Ensure TTL is present for v5 (required field)
+	if ttl, ok := ctx.Option("cloudflare_record", "default_ttl"); ok && body.GetAttribute("ttl") == nil {
+		body.SetAttributeValue("ttl", ttl)
+	}
 	tfhcl.EnsureAttribute(body, "ttl", 1)
 
 	// Get the record type
@@ -66,26 +96,16 @@ func (m *V4ToV5Migrator) TransformConfig(ctx *transform.Context, block *hclwrite
 		recordType = tfhcl.ExtractStringFromAttribute(typeAttr)
 	}
 
-	// Complex record types that use the data field instead of content/value
-	complexDataTypes := map[string]bool{
-		"SRV": true, "CAA": true, "CERT": true, "DNSKEY": true, "DS": true,
-		"LOC": true, "NAPTR": true, "SMIMEA": true, "SSHFP": true, "SVCB": true,
-		"HTTPS": true, "TLSA": true, "URI": true,
-	}
-
-	// Rename value to content for all record types EXCEPT those that use data field
-	// This handles simple types (A, AAAA, CNAME, etc.) as well as dynamic type expressions
-	// where we can't determine the type statically
-	if !complexDataTypes[recordType] {
-		// Rename value to content
-		if valueAttr := body.GetAttribute("value"); valueAttr != nil {
-			// Get the expression from value attribute
-			tokens := valueAttr.Expr().BuildTokens(nil)
-			// Set content with the same expression
-			body.SetAttributeRaw("content", tokens)
-			// Remove the old value attribute
-			body.RemoveAttribute("value")
-		}
+	// Rename value to content for all record types, as v5 has no top-level value attribute.
+	// Record types that use the data field normally leave value unset. References to value
+	// are renamed the same way, see GetAttributeRenames.
+	if valueAttr := body.GetAttribute("value"); valueAttr != nil {
+		// Get the expression from value attribute
+		tokens := valueAttr.Expr().BuildTokens(nil)
+		// Set content with the same expression
+		body.SetAttributeRaw("content", tokens)
+		// Remove the old value attribute
+		body.RemoveAttribute("value")
 	}
 
 	// Remove deprecated attributes
@@ -96,6 +116,7 @@ func (m *V4ToV5Migrator) TransformConfig(ctx *transform.Context, block *hclwrite
 
 	// Process data attribute for CAA records
 	m.processDataAttribute(block, recordType)
+	ctx.Diagnostics = append(ctx.Diagnostics, tfhcl.UnconvertibleDynamicBlocks(body, "data")...)
 
 	return &transform.TransformResult{
 		Blocks:         []*hclwrite.Block{block},
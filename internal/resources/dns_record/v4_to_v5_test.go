@@ -3,6 +3,8 @@ package dns_record
 import (
 	"testing"
 
+	"github.com/zclconf/go-cty/cty"
+
 	"github.com/cloudflare/tf-migrate/internal/testhelpers"
 )
 
@@ -179,6 +181,42 @@ resource "cloudflare_record" "mx_test" {
   content  = "mx.sendgrid.net"
   priority = 10
   ttl      = 1
+}`,
+			},
+			{
+				Name: "DNS record without TTL - default_ttl option",
+				Input: `
+resource "cloudflare_record" "a_default_ttl" {
+  zone_id = "0da42c8d2132a9ddaf714f9e7c920711"
+  name    = "test.example.com"
+  type    = "A"
+  content = "192.168.1.1"
+}
+
+resource "cloudflare_record" "a_explicit_ttl" {
+  zone_id = "0da42c8d2132a9ddaf714f9e7c920711"
+  name    = "test.example.com"
+  type    = "A"
+  ttl     = 60
+  content = "192.168.1.1"
+}`,
+				Options: map[string]map[string]cty.Value{
+					"cloudflare_record": {"default_ttl": cty.NumberIntVal(300)},
+				},
+				Expected: `resource "cloudflare_dns_record" "a_default_ttl" {
+  zone_id = "0da42c8d2132a9ddaf714f9e7c920711"
+  name    = "test.example.com"
+  type    = "A"
+  content = "192.168.1.1"
+  ttl     = 300
+}
+
+resource "cloudflare_dns_record" "a_explicit_ttl" {
+  zone_id = "0da42c8d2132a9ddaf714f9e7c920711"
+  name    = "test.example.com"
+  type    = "A"
+  ttl     = 60
+  content = "192.168.1.1"
 }`,
 			},
 			{
@@ -262,6 +300,23 @@ resource "cloudflare_record" "a_test" {
   type    = "A"
   ttl     = 1
   content = "192.168.1.1"
+}`,
+			},
+			{
+				Name: "CAA record with value field should rename to content",
+				Input: `
+resource "cloudflare_record" "caa_test" {
+  zone_id = "0da42c8d2132a9ddaf714f9e7c920711"
+  name    = "example.com"
+  type    = "CAA"
+  value   = "0 issue \"letsencrypt.org\""
+}`,
+				Expected: `resource "cloudflare_dns_record" "caa_test" {
+  zone_id = "0da42c8d2132a9ddaf714f9e7c920711"
+  name    = "example.com"
+  type    = "CAA"
+  ttl     = 1
+  content = "0 issue \"letsencrypt.org\""
 }`,
 			},
 			// Additional test cases for better coverage
@@ -2,64 +2,14 @@ package logpull_retention
 
 import (
 	"github.com/cloudflare/tf-migrate/internal"
+	"github.com/cloudflare/tf-migrate/internal/rules"
 	"github.com/cloudflare/tf-migrate/internal/transform"
-	tfhcl "github.com/cloudflare/tf-migrate/internal/transform/hcl"
-	"github.com/cloudflare/tf-migrate/internal/transform/state"
-	"github.com/hashicorp/hcl/v2/hclwrite"
-	"github.com/tidwall/gjson"
 )
 
-type V4ToV5Migrator struct {
-}
-
+// NewV4ToV5Migrator registers the migrator compiled from the embedded rule file
+// internal/rules/builtin/logpull_retention.hcl
 func NewV4ToV5Migrator() transform.ResourceTransformer {
-	migrator := &V4ToV5Migrator{}
+	migrator := rules.MustBuiltin("cloudflare_logpull_retention", "v4", "v5")
 	internal.RegisterMigrator("cloudflare_logpull_retention", "v4", "v5", migrator)
 	return migrator
 }
-
-func (m *V4ToV5Migrator) GetResourceType() string {
-	return "cloudflare_logpull_retention"
-}
-
-func (m *V4ToV5Migrator) CanHandle(resourceType string) bool {
-	return resourceType == "cloudflare_logpull_retention"
-}
-
-func (m *V4ToV5Migrator) Preprocess(content string) string {
-	// No preprocessing needed for this simple migration
-	return content
-}
-
-// GetResourceRename implements the ResourceRenamer interface
-// This resource does not rename, so we return the same name for both old and new
-func (m *V4ToV5Migrator) GetResourceRename() (string, string) {
-	return "cloudflare_logpull_retention", "cloudflare_logpull_retention"
-}
-
-func (m *V4ToV5Migrator) TransformConfig(ctx *transform.Context, block *hclwrite.Block) (*transform.TransformResult, error) {
-	body := block.Body()
-
-	// Rename enabled → flag
-	tfhcl.RenameAttribute(body, "enabled", "flag")
-
-	return &transform.TransformResult{
-		Blocks:         []*hclwrite.Block{block},
-		RemoveOriginal: false,
-	}, nil
-}
-
-func (m *V4ToV5Migrator) TransformState(ctx *transform.Context, stateJSON gjson.Result, resourcePath, resourceName string) (string, error) {
-	result := stateJSON.String()
-
-	if !stateJSON.Exists() || !stateJSON.Get("attributes").Exists() {
-		return result, nil
-	}
-
-	attrs := stateJSON.Get("attributes")
-
-	// Rename enabled → flag
-	result = state.RenameField(result, "attributes", attrs, "enabled", "flag")
-
-	return result, nil
-}
@@ -43,6 +43,11 @@ func (m *V4ToV5Migrator) GetResourceRename() (string, string) {
 	return "cloudflare_access_service_token", "cloudflare_zero_trust_access_service_token"
 }
 
+// GetRemovedAttributes implements the AttributeRemover interface
+func (m *V4ToV5Migrator) GetRemovedAttributes() []string {
+	return []string{"min_days_for_renewal"}
+}
+
 func (m *V4ToV5Migrator) TransformConfig(ctx *transform.Context, block *hclwrite.Block) (*transform.TransformResult, error) {
 	resourceType := tfhcl.GetResourceType(block)
 	if resourceType == "cloudflare_access_service_token" {
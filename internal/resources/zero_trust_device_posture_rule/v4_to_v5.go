@@ -50,7 +50,7 @@ func (m *V4ToV5Migrator) TransformConfig(ctx *transform.Context, block *hclwrite
 	body := block.Body()
 
 	// Convert optional input block to attribute, handling nested locations attribute
-	if inputBlock := tfhcl.FindBlockByType(body, "input"); inputBlock != nil {
+	if inputBlocks := tfhcl.FindBlocksByTypeWithDynamic(body, "input"); len(inputBlocks) > 0 {
 		tfhcl.ConvertBlocksToAttribute(body, "input", "input", func(inputBlock *hclwrite.Block) {
 			tfhcl.RemoveAttributes(inputBlock.Body(), "running")
 			tfhcl.ConvertBlocksToAttribute(inputBlock.Body(), "locations", "locations", func(locationsBlock *hclwrite.Block) {})
@@ -58,24 +58,29 @@ func (m *V4ToV5Migrator) TransformConfig(ctx *transform.Context, block *hclwrite
 	}
 
 	// Convert optional match blocks to attribute array
-	if matchBlocks := tfhcl.FindBlocksByType(body, "match"); len(matchBlocks) > 0 {
+	if matchBlocks := tfhcl.FindBlocksByTypeWithDynamic(body, "match"); len(matchBlocks) > 0 {
 		tfhcl.MergeAttributeAndBlocksToObjectArray(body, "", "match", "match", "platform", []string{}, true)
 	}
+	ctx.Diagnostics = append(ctx.Diagnostics, tfhcl.UnconvertibleDynamicBlocks(body, "input", "locations", "match")...)
 
 	// IF there is no name attribute, check in state for a name value and if so, use it
 	if !tfhcl.HasAttribute(body, "name") {
-		if ctx.StateJSON != "" {
-			labels := block.Labels()
-			resourceName := labels[1]
-			gjson.Parse(ctx.StateJSON).Get("resources").ForEach(func(key, resource gjson.Result) bool {
-				if m.CanHandle(resource.Get("type").String()) && resource.Get("name").String() == resourceName {
-					if resource.Get("instances.0.attributes.name").Exists() {
-						body.SetAttributeValue("name", cty.StringVal(resource.Get("instances.0.attributes.name").String()))
-					}
-					return false
-				}
-				return true
+		resourceName := block.Labels()[1]
+		for _, resourceType := range []string{"cloudflare_device_posture_rule", "cloudflare_zero_trust_device_posture_rule"} {
+			instances := ctx.FindStateInstances(resourceType, resourceName)
+			if len(instances) == 0 {
+				continue
+			}
+			tokens, diag := transform.InstanceValueTokens("name", instances, func(attrs gjson.Result) (cty.Value, bool) {
+				name := attrs.Get("name")
+				return cty.StringVal(name.String()), name.Exists()
 			})
+			if diag != nil {
+				ctx.Diagnostics = append(ctx.Diagnostics, diag)
+			} else if tokens != nil {
+				body.SetAttributeRaw("name", tokens)
+			}
+			break
 		}
 	}
 
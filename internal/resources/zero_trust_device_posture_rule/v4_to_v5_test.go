@@ -297,6 +297,28 @@ resource "cloudflare_zero_trust_device_posture_rule" "test" {
     platform = "linux"
   }]
 }
+`,
+		},
+		{
+			Name: "name from state - count instances",
+			Input: `
+resource "cloudflare_device_posture_rule" "test" {
+  count      = 2
+  account_id = "f037e56e89293a057740de681ac9abbe"
+  type       = "serial_number"
+}
+`,
+			State: testhelpers.MultiInstanceState("cloudflare_device_posture_rule", "test",
+				testhelpers.StateInstanceFixture{IndexKey: 0, Attributes: map[string]interface{}{"name": "first"}},
+				testhelpers.StateInstanceFixture{IndexKey: 1, Attributes: map[string]interface{}{"name": "second"}},
+			),
+			Expected: `
+resource "cloudflare_zero_trust_device_posture_rule" "test" {
+  count      = 2
+  account_id = "f037e56e89293a057740de681ac9abbe"
+  type       = "serial_number"
+  name       = ["first", "second"][count.index]
+}
 `,
 		},
 	}
@@ -9,7 +9,6 @@ import (
 	"github.com/tidwall/sjson"
 
 	"github.com/cloudflare/tf-migrate/internal"
-	"github.com/cloudflare/tf-migrate/internal/hcl"
 	"github.com/cloudflare/tf-migrate/internal/transform"
 	tfhcl "github.com/cloudflare/tf-migrate/internal/transform/hcl"
 	"github.com/cloudflare/tf-migrate/internal/transform/state"
@@ -67,6 +66,7 @@ func (m *V4ToV5Migrator) TransformConfig(ctx *transform.Context, block *hclwrite
 		}
 		tfhcl.RemoveAttributes(body, "type")
 		m.transformCustomEntryBlocks(body)
+		ctx.Diagnostics = append(ctx.Diagnostics, tfhcl.UnconvertibleDynamicBlocks(body, "entry", "pattern")...)
 
 	case "predefined":
 		tfhcl.RenameResourceType(block, currentType, "cloudflare_zero_trust_dlp_predefined_profile")
@@ -87,47 +87,17 @@ func (m *V4ToV5Migrator) TransformConfig(ctx *transform.Context, block *hclwrite
 }
 
 func (m *V4ToV5Migrator) transformCustomEntryBlocks(body *hclwrite.Body) {
-	var entryBlocks []*hclwrite.Block
-	for _, block := range body.Blocks() {
-		if block.Type() == "entry" {
-			entryBlocks = append(entryBlocks, block)
-		}
-	}
-
-	if len(entryBlocks) == 0 {
-		return
-	}
-
-	var entryObjects []hclwrite.Tokens
-	for _, entryBlock := range entryBlocks {
+	// Comments on the entry blocks are kept on their objects, and dynamic entry
+	// blocks become for expressions
+	tfhcl.ConvertBlocksToList(body, "entry", "entries", func(entryBlock *hclwrite.Block) {
 		entryBody := entryBlock.Body()
 		tfhcl.RemoveAttributes(entryBody, "id")
 		m.transformPatternBlock(entryBody)
-		objTokens := hcl.BuildObjectFromBlock(entryBlock)
-		entryObjects = append(entryObjects, objTokens)
-	}
-
-	arrayTokens := hclwrite.TokensForTuple(entryObjects)
-	body.SetAttributeRaw("entries", arrayTokens)
-	tfhcl.RemoveBlocksByType(body, "entry")
+	})
 }
 
 func (m *V4ToV5Migrator) transformPatternBlock(entryBody *hclwrite.Body) {
-	var patternBlock *hclwrite.Block
-	for _, block := range entryBody.Blocks() {
-		if block.Type() == "pattern" {
-			patternBlock = block
-			break
-		}
-	}
-
-	if patternBlock == nil {
-		return
-	}
-
-	objTokens := hcl.BuildObjectFromBlock(patternBlock)
-	entryBody.SetAttributeRaw("pattern", objTokens)
-	entryBody.RemoveBlock(patternBlock)
+	tfhcl.ConvertSingleBlockToAttribute(entryBody, "pattern", "pattern")
 }
 
 func (m *V4ToV5Migrator) transformPredefinedEntryBlocks(body *hclwrite.Body) {
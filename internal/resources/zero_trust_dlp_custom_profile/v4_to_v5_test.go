@@ -91,6 +91,42 @@ func TestV4ToV5Transformation(t *testing.T) {
       validation = "luhn"
     }
   }]
+}`,
+			},
+			{
+				Name: "Dynamic entries",
+				Input: `resource "cloudflare_dlp_profile" "dynamic" {
+  account_id          = "123456789"
+  name                = "Dynamic Pattern Profile"
+  type                = "custom"
+  allowed_match_count = 0
+
+  dynamic "entry" {
+    for_each = var.patterns
+    content {
+      name    = entry.key
+      enabled = true
+      dynamic "pattern" {
+        for_each = [entry.value]
+        content {
+          regex = pattern.value
+        }
+      }
+    }
+  }
+}`,
+				Expected: `resource "cloudflare_zero_trust_dlp_custom_profile" "dynamic" {
+  account_id          = "123456789"
+  name                = "Dynamic Pattern Profile"
+  allowed_match_count = 0
+
+  entries = [for entry_key, entry in var.patterns : {
+    name    = entry_key
+    enabled = true
+    pattern = one([for pattern in [entry] : {
+      regex = pattern
+    }])
+  }]
 }`,
 			},
 			{
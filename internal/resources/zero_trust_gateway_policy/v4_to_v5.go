@@ -48,14 +48,15 @@ func (m *V4ToV5Migrator) TransformConfig(ctx *transform.Context, block *hclwrite
 
 	body := block.Body()
 
-	// Process rule_settings if it exists
-	if ruleSettingsBlock := tfhcl.FindBlockByType(body, "rule_settings"); ruleSettingsBlock != nil {
+	// Process rule_settings if it exists, a dynamic one through its content block
+	for _, ruleSettingsBlock := range tfhcl.FindBlocksByTypeWithDynamic(body, "rule_settings") {
 		m.processRuleSettingsBlock(ruleSettingsBlock)
 	}
 
 	// Convert rule_settings block to attribute syntax
 	// This must be done AFTER processing nested blocks
 	tfhcl.ConvertSingleBlockToAttribute(body, "rule_settings", "rule_settings")
+	ctx.Diagnostics = append(ctx.Diagnostics, tfhcl.UnconvertibleDynamicBlocks(body, append([]string{"rule_settings"}, ruleSettingsBlocks...)...)...)
 
 	return &transform.TransformResult{
 		Blocks:         []*hclwrite.Block{block},
@@ -63,6 +64,20 @@ func (m *V4ToV5Migrator) TransformConfig(ctx *transform.Context, block *hclwrite
 	}, nil
 }
 
+// ruleSettingsBlocks are the MaxItems:1 blocks nested in rule_settings
+var ruleSettingsBlocks = []string{
+	"audit_ssh",
+	"l4override",
+	"biso_admin_controls",
+	"check_session",
+	"egress",
+	"untrusted_cert",
+	"payload_log",
+	"notification_settings",
+	"dns_resolvers",
+	"resolve_dns_internally",
+}
+
 // processRuleSettingsBlock processes all nested structures within rule_settings
 func (m *V4ToV5Migrator) processRuleSettingsBlock(ruleSettingsBlock *hclwrite.Block) {
 	ruleSettingsBody := ruleSettingsBlock.Body()
@@ -72,23 +87,10 @@ func (m *V4ToV5Migrator) processRuleSettingsBlock(ruleSettingsBlock *hclwrite.Bl
 
 	// Convert all nested MaxItems:1 blocks to attributes
 	// These blocks need to be converted to attribute syntax with =
-	nestedBlocks := []string{
-		"audit_ssh",
-		"l4override",
-		"biso_admin_controls",
-		"check_session",
-		"egress",
-		"untrusted_cert",
-		"payload_log",
-		"notification_settings",
-		"dns_resolvers",
-		"resolve_dns_internally",
-	}
-
-	for _, blockName := range nestedBlocks {
+	for _, blockName := range ruleSettingsBlocks {
 		// For notification_settings, rename message → msg BEFORE converting
 		if blockName == "notification_settings" {
-			if notifBlock := tfhcl.FindBlockByType(ruleSettingsBody, "notification_settings"); notifBlock != nil {
+			for _, notifBlock := range tfhcl.FindBlocksByTypeWithDynamic(ruleSettingsBody, "notification_settings") {
 				tfhcl.RenameAttribute(notifBlock.Body(), "message", "msg")
 			}
 		}
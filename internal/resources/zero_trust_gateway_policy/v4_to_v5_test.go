@@ -88,6 +88,71 @@ func TestV4ToV5Transformation(t *testing.T) {
       msg     = "Custom notification"
     }
   }
+}`,
+			},
+			{
+				Name: "Gateway policy keeps the comments of renamed attributes",
+				Input: `resource "cloudflare_teams_rule" "with_comments" {
+  account_id = "f037e56e89293a057740de681ac9abbe"
+  name       = "Test"
+  action     = "block"
+
+  rule_settings {
+    # block reason note
+    block_page_reason = "Blocked" # inline note
+    notification_settings {
+      enabled = true
+      # message comment
+      message = "Custom notification"
+    }
+  }
+}`,
+				Expected: `resource "cloudflare_zero_trust_gateway_policy" "with_comments" {
+  account_id = "f037e56e89293a057740de681ac9abbe"
+  name       = "Test"
+  action     = "block"
+
+  rule_settings = {
+    # block reason note
+    block_reason = "Blocked" # inline note
+    notification_settings = {
+      enabled = true
+      # message comment
+      msg = "Custom notification"
+    }
+  }
+}`,
+			},
+			{
+				Name: "Gateway policy with dynamic rule_settings",
+				Input: `resource "cloudflare_teams_rule" "with_dynamic" {
+  account_id = "f037e56e89293a057740de681ac9abbe"
+  name       = "Test"
+  action     = "block"
+
+  dynamic "rule_settings" {
+    for_each = var.settings == null ? [] : [var.settings]
+    content {
+      block_page_reason = rule_settings.value.reason
+      notification_settings {
+        enabled = true
+        message = rule_settings.value.message
+      }
+    }
+  }
+}`,
+				Expected: `resource "cloudflare_zero_trust_gateway_policy" "with_dynamic" {
+  account_id = "f037e56e89293a057740de681ac9abbe"
+  name       = "Test"
+  action     = "block"
+
+  rule_settings = one([for rule_settings in var.settings == null ? [] : [var.settings] : {
+    block_reason = rule_settings.reason
+    notification_settings = {
+      enabled = true
+      msg     = rule_settings.message
+    }
+  }])
 }`,
 			},
 		}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/cloudflare/cloudflare-go/v6"
 	"github.com/cloudflare/cloudflare-go/v6/zero_trust"
+	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hclwrite"
 	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
@@ -73,6 +74,7 @@ func (m *V4ToV5Migrator) TransformState(ctx *transform.Context, stateJSON gjson.
 	// In v4, the ID was the network CIDR (or a checksum of it)
 	// In v5, the ID is a UUID from the API
 	attrs := stateJSON.Get("attributes")
+	resolved := false
 	if attrs.Exists() && ctx.APIClient != nil {
 		accountID := attrs.Get("account_id").String()
 		tunnelID := attrs.Get("tunnel_id").String()
@@ -96,10 +98,21 @@ func (m *V4ToV5Migrator) TransformState(ctx *transform.Context, stateJSON gjson.
 			if route.Network == network {
 				// Update the ID to the UUID from the API
 				result, _ = sjson.Set(result, "attributes.id", route.ID)
+				resolved = true
 				break
 			}
 		}
 	}
 
+	// Without the UUID the provider has to look the route up again
+	if attrs.Exists() && !resolved {
+		ctx.Diagnostics = append(ctx.Diagnostics, &hcl.Diagnostic{
+			Severity: hcl.DiagWarning,
+			Summary:  "Tunnel route ID could not be resolved from the API",
+			Detail:   "The v4 ID is the network CIDR; run terraform refresh after migration to update it to the v5 UUID",
+			Extra:    transform.FollowUpRefresh,
+		})
+	}
+
 	return result, nil
 }
@@ -68,33 +68,17 @@ func (m *V4ToV5Migrator) TransformConfig(ctx *transform.Context, block *hclwrite
 	}
 
 	// Get the resource name from the block labels (e.g., "example" in resource "cloudflare_zone_dnssec" "example")
+	// and find every instance of this resource in the state, in the module of the configuration file.
+	// Instances of a resource with count or for_each may have different statuses.
 	labels := block.Labels()
 	if len(labels) >= 2 {
-		resourceName := labels[1]
-
-		// Parse state JSON and find this specific resource
-		state := gjson.Parse(ctx.StateJSON)
-		state.Get("resources").ForEach(func(key, resource gjson.Result) bool {
-			// Match by resource type and name
-			if resource.Get("type").String() == "cloudflare_zone_dnssec" &&
-				resource.Get("name").String() == resourceName {
-				// Get status from the first instance
-				status := resource.Get("instances.0.attributes.status")
-				statusValue := status.String()
-				// Only add status if it's a valid v5 value ("active" or "disabled")
-				// The v5 schema only accepts these two values, not "pending" or other intermediate states
-				if status.Exists() && status.Type != gjson.Null && statusValue != "" {
-					if statusValue == "active" || statusValue == "pending" {
-						// Add status attribute to config using the value from state
-						body.SetAttributeValue("status", cty.StringVal("active"))
-					} else if statusValue == "disabled" || statusValue == "pending-disabled" {
-						body.SetAttributeValue("status", cty.StringVal("disabled"))
-					}
-				}
-				return false // stop iterating
-			}
-			return true // continue iterating
-		})
+		instances := ctx.FindStateInstances("cloudflare_zone_dnssec", labels[1])
+		tokens, diag := transform.InstanceValueTokens("status", instances, statusFromState)
+		if diag != nil {
+			ctx.Diagnostics = append(ctx.Diagnostics, diag)
+		} else if tokens != nil {
+			body.SetAttributeRaw("status", tokens)
+		}
 	}
 
 	return &transform.TransformResult{
@@ -103,6 +87,18 @@ func (m *V4ToV5Migrator) TransformConfig(ctx *transform.Context, block *hclwrite
 	}, nil
 }
 
+// statusFromState returns the v5 status for the status of a state instance.
+// The v5 schema only accepts "active" or "disabled", not "pending" or other intermediate states.
+func statusFromState(attrs gjson.Result) (cty.Value, bool) {
+	switch attrs.Get("status").String() {
+	case "active", "pending":
+		return cty.StringVal("active"), true
+	case "disabled", "pending-disabled":
+		return cty.StringVal("disabled"), true
+	}
+	return cty.NilVal, false
+}
+
 // TransformState handles state file transformations.
 // This function receives a single resource instance and returns the transformed instance JSON.
 // Converts flags and key_tag from TypeInt (v4) to Float64 (v5).
@@ -1,9 +1,16 @@
 package zone_dnssec
 
 import (
+	"strings"
 	"testing"
 
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
 	"github.com/cloudflare/tf-migrate/internal/testhelpers"
+	"github.com/cloudflare/tf-migrate/internal/transform"
 )
 
 func TestV4ToV5Transformation(t *testing.T) {
@@ -315,3 +322,179 @@ resource "cloudflare_zone_dnssec" "example" {
 		testhelpers.RunStateTransformTests(t, tests, migrator)
 	})
 }
+
+func TestStatusFromStateIsModuleAware(t *testing.T) {
+	// Two modules each contain cloudflare_zone_dnssec.main with a different status
+	stateJSON := `{
+  "version": 4,
+  "resources": [
+    {
+      "module": "module.prod",
+      "mode": "managed",
+      "type": "cloudflare_zone_dnssec",
+      "name": "main",
+      "instances": [{"attributes": {"zone_id": "prod", "status": "active"}}]
+    },
+    {
+      "module": "module.staging[\"eu\"]",
+      "mode": "managed",
+      "type": "cloudflare_zone_dnssec",
+      "name": "main",
+      "instances": [{"attributes": {"zone_id": "staging", "status": "disabled"}}]
+    }
+  ]
+}`
+
+	tests := []struct {
+		name     string
+		modules  []string
+		expected string
+	}{
+		{name: "Prod module", modules: []string{"module.prod"}, expected: `"active"`},
+		{name: "Staging module with for_each key", modules: []string{"module.staging"}, expected: `"disabled"`},
+		{name: "Root module", modules: []string{""}, expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, diags := hclwrite.ParseConfig([]byte(`resource "cloudflare_zone_dnssec" "main" {
+  zone_id = var.zone_id
+}
+`), "main.tf", hcl.InitialPos)
+			require.False(t, diags.HasErrors())
+
+			ctx := &transform.Context{CFGFile: file, StateJSON: stateJSON, Modules: tt.modules}
+			block := file.Body().Blocks()[0]
+			_, err := NewV4ToV5Migrator().TransformConfig(ctx, block)
+			require.NoError(t, err)
+
+			status := block.Body().GetAttribute("status")
+			if tt.expected == "" {
+				assert.Nil(t, status)
+				return
+			}
+			require.NotNil(t, status)
+			assert.Equal(t, tt.expected, strings.TrimSpace(string(status.Expr().BuildTokens(nil).Bytes())))
+		})
+	}
+}
+
+func TestStatusFromMultipleInstances(t *testing.T) {
+	status := func(s string) map[string]interface{} {
+		return map[string]interface{}{"zone_id": "abc123", "status": s}
+	}
+
+	tests := []testhelpers.ConfigTestCase{
+		{
+			Name: "Instances agree",
+			Input: `
+resource "cloudflare_zone_dnssec" "this" {
+  for_each = var.zones
+  zone_id  = each.value
+}`,
+			State: testhelpers.MultiInstanceState("cloudflare_zone_dnssec", "this",
+				testhelpers.StateInstanceFixture{IndexKey: "eu", Attributes: status("active")},
+				testhelpers.StateInstanceFixture{IndexKey: "us", Attributes: status("pending")},
+			),
+			Expected: `resource "cloudflare_zone_dnssec" "this" {
+  for_each = var.zones
+  zone_id  = each.value
+  status   = "active"
+}`,
+		},
+		{
+			Name: "for_each instances disagree",
+			Input: `
+resource "cloudflare_zone_dnssec" "this" {
+  for_each = var.zones
+  zone_id  = each.value
+}`,
+			State: testhelpers.MultiInstanceState("cloudflare_zone_dnssec", "this",
+				testhelpers.StateInstanceFixture{IndexKey: "us", Attributes: status("disabled")},
+				testhelpers.StateInstanceFixture{IndexKey: "eu", Attributes: status("active")},
+			),
+			Expected: `resource "cloudflare_zone_dnssec" "this" {
+  for_each = var.zones
+  zone_id  = each.value
+  status = {
+    "eu" = "active"
+    "us" = "disabled"
+  }[each.key]
+}`,
+		},
+		{
+			Name: "count instances disagree",
+			Input: `
+resource "cloudflare_zone_dnssec" "this" {
+  count   = 2
+  zone_id = var.zone_ids[count.index]
+}`,
+			State: testhelpers.MultiInstanceState("cloudflare_zone_dnssec", "this",
+				testhelpers.StateInstanceFixture{IndexKey: 0, Attributes: status("pending-disabled")},
+				testhelpers.StateInstanceFixture{IndexKey: 1, Attributes: status("active")},
+			),
+			Expected: `resource "cloudflare_zone_dnssec" "this" {
+  count   = 2
+  zone_id = var.zone_ids[count.index]
+  status  = ["disabled", "active"][count.index]
+}`,
+		},
+		{
+			Name: "count instances with a gap",
+			Input: `
+resource "cloudflare_zone_dnssec" "this" {
+  count   = 3
+  zone_id = var.zone_ids[count.index]
+}`,
+			State: testhelpers.MultiInstanceState("cloudflare_zone_dnssec", "this",
+				testhelpers.StateInstanceFixture{IndexKey: 0, Attributes: status("active")},
+				testhelpers.StateInstanceFixture{IndexKey: 2, Attributes: status("disabled")},
+			),
+			Expected: `resource "cloudflare_zone_dnssec" "this" {
+  count   = 3
+  zone_id = var.zone_ids[count.index]
+}`,
+			Diagnostics: []string{
+				`Instance indexes are not contiguous: cloudflare_zone_dnssec.this[0] = "active", cloudflare_zone_dnssec.this[2] = "disabled"`,
+			},
+		},
+		{
+			Name: "Module instances disagree",
+			Input: `
+resource "cloudflare_zone_dnssec" "this" {
+  zone_id = var.zone_id
+}`,
+			State: testhelpers.MultiInstanceState("cloudflare_zone_dnssec", "this",
+				testhelpers.StateInstanceFixture{Module: `module.zone["a"]`, Attributes: status("active")},
+				testhelpers.StateInstanceFixture{Module: `module.zone["b"]`, Attributes: status("disabled")},
+			),
+			Expected: `resource "cloudflare_zone_dnssec" "this" {
+  zone_id = var.zone_id
+}`,
+			Diagnostics: []string{
+				`Conflicting status values across instances: Instances of different module instances disagree: module.zone["a"].cloudflare_zone_dnssec.this = "active", module.zone["b"].cloudflare_zone_dnssec.this = "disabled"`,
+			},
+		},
+		{
+			Name: "Instance without a v5 status",
+			Input: `
+resource "cloudflare_zone_dnssec" "this" {
+  for_each = var.zones
+  zone_id  = each.value
+}`,
+			State: testhelpers.MultiInstanceState("cloudflare_zone_dnssec", "this",
+				testhelpers.StateInstanceFixture{IndexKey: "eu", Attributes: status("active")},
+				testhelpers.StateInstanceFixture{IndexKey: "us", Attributes: status("error")},
+			),
+			Expected: `resource "cloudflare_zone_dnssec" "this" {
+  for_each = var.zones
+  zone_id  = each.value
+}`,
+			Diagnostics: []string{
+				`cloudflare_zone_dnssec.this["us"] has no value`,
+			},
+		},
+	}
+
+	testhelpers.RunConfigTransformTests(t, tests, NewV4ToV5Migrator())
+}
@@ -0,0 +1,9 @@
+rule "cloudflare_account_member" {
+  source_version = "v4"
+  target_version = "v5"
+
+  rename_attributes = {
+    email_address = "email"
+    role_ids      = "roles"
+  }
+}
@@ -0,0 +1,8 @@
+rule "cloudflare_logpull_retention" {
+  source_version = "v4"
+  target_version = "v5"
+
+  rename_attributes = {
+    enabled = "flag"
+  }
+}
@@ -0,0 +1,10 @@
+rule "cloudflare_workers_kv" {
+  source_version = "v4"
+  target_version = "v5"
+
+  rename_attributes = {
+    key = "key_name"
+  }
+
+  state_schema_version = 0
+}
@@ -0,0 +1,14 @@
+rule "cloudflare_tunnel" {
+  source_version = "v4"
+  target_version = "v5"
+  rename_to      = "cloudflare_zero_trust_tunnel_cloudflared"
+  move_to_end    = true
+
+  rename_attributes = {
+    secret = "tunnel_secret"
+  }
+  # Computed in v4, gone in v5
+  remove_attributes = ["cname", "tunnel_token"]
+
+  state_schema_version = 0
+}
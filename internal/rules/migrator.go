@@ -0,0 +1,210 @@
+package rules
+
+import (
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/cloudflare/tf-migrate/internal/transform"
+	tfhcl "github.com/cloudflare/tf-migrate/internal/transform/hcl"
+	"github.com/cloudflare/tf-migrate/internal/transform/state"
+)
+
+// Migrator is the migrator compiled from a rule
+type Migrator struct {
+	resourceType  string
+	sourceVersion string
+	targetVersion string
+	renameTo      string
+	moveToEnd     bool
+
+	blocksToAttributes []blockToAttribute
+	renames            []attributeRename
+	removed            []string
+	numeric            []string
+	defaults           []attributeDefault
+	stateSchemaVersion *int
+
+	declRange hcl.Range
+}
+
+type blockToAttribute struct {
+	blockType string
+	attribute string
+	list      bool
+}
+
+type attributeRename struct {
+	from, to string
+}
+
+type attributeDefault struct {
+	attribute  string
+	value      cty.Value
+	stateValue interface{}
+}
+
+var (
+	_ transform.ResourceTransformer = (*Migrator)(nil)
+	_ transform.ResourceRenamer     = (*Migrator)(nil)
+	_ transform.AttributeRenamer    = (*Migrator)(nil)
+	_ transform.AttributeRemover    = (*Migrator)(nil)
+)
+
+// SourceResourceType returns the resource type in the source version, the label of the rule
+func (m *Migrator) SourceResourceType() string {
+	return m.resourceType
+}
+
+// SourceVersion returns the provider version the rule migrates from
+func (m *Migrator) SourceVersion() string {
+	return m.sourceVersion
+}
+
+// TargetVersion returns the provider version the rule migrates to
+func (m *Migrator) TargetVersion() string {
+	return m.targetVersion
+}
+
+// GetResourceType returns the resource type in the target version
+func (m *Migrator) GetResourceType() string {
+	if m.renameTo != "" {
+		return m.renameTo
+	}
+	return m.resourceType
+}
+
+func (m *Migrator) CanHandle(resourceType string) bool {
+	return resourceType == m.resourceType
+}
+
+func (m *Migrator) Preprocess(content string) string {
+	return content
+}
+
+// GetResourceRename implements the ResourceRenamer interface
+func (m *Migrator) GetResourceRename() (string, string) {
+	return m.resourceType, m.GetResourceType()
+}
+
+// GetAttributeRenames implements the AttributeRenamer interface
+func (m *Migrator) GetAttributeRenames() map[string]string {
+	if len(m.renames) == 0 {
+		return nil
+	}
+	renames := make(map[string]string, len(m.renames))
+	for _, rename := range m.renames {
+		renames[rename.from] = rename.to
+	}
+	return renames
+}
+
+// GetRemovedAttributes implements the AttributeRemover interface
+func (m *Migrator) GetRemovedAttributes() []string {
+	return m.removed
+}
+
+func (m *Migrator) TransformConfig(ctx *transform.Context, block *hclwrite.Block) (*transform.TransformResult, error) {
+	if m.renameTo != "" {
+		tfhcl.RenameResourceType(block, m.resourceType, m.renameTo)
+	}
+
+	body := block.Body()
+	blockTypes := make([]string, 0, len(m.blocksToAttributes))
+	for _, conversion := range m.blocksToAttributes {
+		if conversion.list {
+			tfhcl.ConvertBlocksToList(body, conversion.blockType, conversion.attribute, nil)
+		} else {
+			tfhcl.ConvertSingleBlockToAttribute(body, conversion.blockType, conversion.attribute)
+		}
+		blockTypes = append(blockTypes, conversion.blockType)
+	}
+	ctx.Diagnostics = append(ctx.Diagnostics, tfhcl.UnconvertibleDynamicBlocks(body, blockTypes...)...)
+	for _, rename := range m.renames {
+		tfhcl.RenameAttribute(body, rename.from, rename.to)
+	}
+	tfhcl.RemoveAttributes(body, m.removed...)
+	for _, name := range m.numeric {
+		coerceNumber(body, name)
+	}
+	for _, d := range m.defaults {
+		if body.GetAttribute(d.attribute) == nil {
+			body.SetAttributeValue(d.attribute, d.value)
+		}
+	}
+
+	return &transform.TransformResult{
+		Blocks:         []*hclwrite.Block{block},
+		RemoveOriginal: m.moveToEnd,
+	}, nil
+}
+
+func (m *Migrator) TransformState(ctx *transform.Context, instance gjson.Result, resourcePath, resourceName string) (string, error) {
+	result := instance.String()
+	if !instance.Get("attributes").Exists() {
+		return result, nil
+	}
+	// Every operation sees the attributes changed by the previous ones
+	attributes := func() gjson.Result {
+		return gjson.Get(result, "attributes")
+	}
+
+	for _, conversion := range m.blocksToAttributes {
+		field := attributes().Get(conversion.blockType)
+		if !conversion.list && field.IsArray() {
+			// Blocks are stored as a list; the first one is kept, as in the configuration
+			var value interface{}
+			if elements := field.Array(); len(elements) > 0 {
+				value = elements[0].Value()
+			}
+			result, _ = sjson.Set(result, "attributes."+conversion.blockType, value)
+		}
+		if conversion.attribute != conversion.blockType {
+			result = state.RenameField(result, "attributes", attributes(), conversion.blockType, conversion.attribute)
+		}
+	}
+	for _, rename := range m.renames {
+		result = state.RenameField(result, "attributes", attributes(), rename.from, rename.to)
+	}
+	result = state.RemoveFields(result, "attributes", attributes(), m.removed...)
+	for _, name := range m.numeric {
+		if field := attributes().Get(name); field.Exists() {
+			result, _ = sjson.Set(result, "attributes."+name, state.ConvertToFloat64(field))
+		}
+	}
+	for _, d := range m.defaults {
+		result = state.EnsureField(result, "attributes", attributes(), d.attribute, d.stateValue)
+	}
+
+	if m.stateSchemaVersion != nil {
+		result, _ = sjson.Set(result, "schema_version", *m.stateSchemaVersion)
+	}
+	// Instances given with their resource's type, as in the migrator tests, get the new type
+	if m.renameTo != "" && instance.Get("type").Exists() {
+		result, _ = sjson.Set(result, "type", m.renameTo)
+	}
+
+	return result, nil
+}
+
+// coerceNumber replaces the value of an attribute that is a quoted number, such as "300",
+// with the number
+func coerceNumber(body *hclwrite.Body, name string) {
+	attr := body.GetAttribute(name)
+	if attr == nil {
+		return
+	}
+	tokens := attr.Expr().BuildTokens(nil)
+	if len(tokens) != 3 || tokens[0].Type != hclsyntax.TokenOQuote ||
+		tokens[1].Type != hclsyntax.TokenQuotedLit || tokens[2].Type != hclsyntax.TokenCQuote {
+		return
+	}
+	number, err := cty.ParseNumberVal(string(tokens[1].Bytes))
+	if err != nil {
+		return
+	}
+	body.SetAttributeValue(name, number)
+}
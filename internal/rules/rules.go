@@ -0,0 +1,335 @@
+// Package rules compiles declarative rule files into migrators. A rule describes the
+// migration of a resource type between two provider versions with the operations most
+// migrators are made of, applied to both the configuration and the state:
+//
+//	rule "example_record" {
+//	  source_version = "v4"
+//	  target_version = "v5"
+//
+//	  # Resource type in the target version
+//	  rename_to = "example_dns_record"
+//	  # The migrated block is moved to the end of its file instead of staying in place
+//	  move_to_end = false
+//
+//	  # Applied in order, before the attributes below are looked up
+//	  rename_attributes = {
+//	    value = "content"
+//	  }
+//	  remove_attributes = ["allow_overwrite"]
+//
+//	  # A single block becomes an object attribute, or all blocks a list with list = true
+//	  block_to_attribute "data" {
+//	    attribute = "settings"
+//	  }
+//
+//	  # Set when the attribute is missing
+//	  defaults = {
+//	    ttl = 1
+//	  }
+//	  # Quoted numbers such as "300" become numbers
+//	  numeric_attributes = ["ttl", "priority"]
+//
+//	  state_schema_version = 0
+//	}
+//
+// Rule files are loaded from the rules embedded in the binary, or from a directory.
+package rules
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sync"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// FileExtension is the extension of rule files
+const FileExtension = ".hcl"
+
+// builtinFiles are the rule files embedded in the binary
+//
+//go:embed builtin/*.hcl
+var builtinFiles embed.FS
+
+// versionPattern matches provider major versions such as v4
+var versionPattern = regexp.MustCompile(`^v[0-9]+$`)
+
+// rawFile is the decoding schema of a rule file
+type rawFile struct {
+	Rules []rawRule `hcl:"rule,block"`
+}
+
+type rawRule struct {
+	ResourceType       string                `hcl:"resource_type,label"`
+	SourceVersion      string                `hcl:"source_version"`
+	TargetVersion      string                `hcl:"target_version"`
+	RenameTo           *string               `hcl:"rename_to,optional"`
+	MoveToEnd          *bool                 `hcl:"move_to_end,optional"`
+	RenameAttributes   hcl.Expression        `hcl:"rename_attributes,optional"`
+	RemoveAttributes   []string              `hcl:"remove_attributes,optional"`
+	BlocksToAttributes []rawBlockToAttribute `hcl:"block_to_attribute,block"`
+	Defaults           hcl.Expression        `hcl:"defaults,optional"`
+	NumericAttributes  []string              `hcl:"numeric_attributes,optional"`
+	StateSchemaVersion *int                  `hcl:"state_schema_version,optional"`
+	DeclRange          hcl.Range             `hcl:",def_range"`
+}
+
+type rawBlockToAttribute struct {
+	BlockType string  `hcl:"block_type,label"`
+	Attribute *string `hcl:"attribute,optional"`
+	List      *bool   `hcl:"list,optional"`
+}
+
+// Load reads the rules of a rule file
+func Load(path string) ([]*Migrator, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rule file %s: %w", path, err)
+	}
+	migrators, diags := Parse(path, src)
+	diags = append(diags, checkDuplicates(migrators)...)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("invalid rule file: %w", diags)
+	}
+	return migrators, nil
+}
+
+// LoadDir reads the rules of every rule file in dir, in the order of their names
+func LoadDir(dir string) ([]*Migrator, error) {
+	return loadFS(os.DirFS(dir), func(name string) string { return filepath.Join(dir, name) })
+}
+
+// builtin loads the embedded rule files once
+var builtin = sync.OnceValues(func() ([]*Migrator, error) {
+	files, err := fs.Sub(builtinFiles, "builtin")
+	if err != nil {
+		return nil, err
+	}
+	return loadFS(files, func(name string) string { return path.Join("builtin", name) })
+})
+
+// Builtin returns the migrators of the rule files embedded in the binary
+func Builtin() ([]*Migrator, error) {
+	return builtin()
+}
+
+// MustBuiltin returns the embedded migrator of a resource type and version path. It panics
+// if the embedded rule files are invalid or have no such rule, which the tests of the
+// registry check for every migrator compiled from a rule.
+func MustBuiltin(resourceType, sourceVersion, targetVersion string) *Migrator {
+	migrators, err := Builtin()
+	if err != nil {
+		panic(fmt.Sprintf("cannot load the embedded rule for %s from %s to %s: %v", resourceType, sourceVersion, targetVersion, err))
+	}
+	for _, m := range migrators {
+		if m.resourceType == resourceType && m.sourceVersion == sourceVersion && m.targetVersion == targetVersion {
+			return m
+		}
+	}
+	panic(fmt.Sprintf("no embedded rule for %s from %s to %s", resourceType, sourceVersion, targetVersion))
+}
+
+// loadFS reads the rule files of fsys, naming them in diagnostics with name
+func loadFS(fsys fs.FS, name func(string) string) ([]*Migrator, error) {
+	paths, err := fs.Glob(fsys, "*"+FileExtension)
+	if err != nil {
+		return nil, err
+	}
+
+	var migrators []*Migrator
+	var diags hcl.Diagnostics
+	for _, p := range paths {
+		src, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read rule file %s: %w", name(p), err)
+		}
+		fileMigrators, fileDiags := Parse(name(p), src)
+		migrators = append(migrators, fileMigrators...)
+		diags = append(diags, fileDiags...)
+	}
+	diags = append(diags, checkDuplicates(migrators)...)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("invalid rule files: %w", diags)
+	}
+	return migrators, nil
+}
+
+// Parse parses the rules of a rule file and compiles them into migrators
+func Parse(filename string, src []byte) ([]*Migrator, hcl.Diagnostics) {
+	parsed, diags := hclparse.NewParser().ParseHCL(src, filename)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	var raw rawFile
+	if diags := gohcl.DecodeBody(parsed.Body, nil, &raw); diags.HasErrors() {
+		return nil, diags
+	}
+
+	var migrators []*Migrator
+	for _, rule := range raw.Rules {
+		m, ruleDiags := compile(rule)
+		diags = append(diags, ruleDiags...)
+		if !ruleDiags.HasErrors() {
+			migrators = append(migrators, m)
+		}
+	}
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	return migrators, diags
+}
+
+// compile validates a rule and compiles it into a migrator
+func compile(rule rawRule) (*Migrator, hcl.Diagnostics) {
+	var diags hcl.Diagnostics
+	m := &Migrator{
+		resourceType:       rule.ResourceType,
+		sourceVersion:      rule.SourceVersion,
+		targetVersion:      rule.TargetVersion,
+		removed:            rule.RemoveAttributes,
+		numeric:            rule.NumericAttributes,
+		stateSchemaVersion: rule.StateSchemaVersion,
+		declRange:          rule.DeclRange,
+	}
+	if rule.RenameTo != nil {
+		m.renameTo = *rule.RenameTo
+	}
+	if rule.MoveToEnd != nil {
+		m.moveToEnd = *rule.MoveToEnd
+	}
+
+	for _, version := range []struct{ name, value string }{
+		{"source_version", rule.SourceVersion},
+		{"target_version", rule.TargetVersion},
+	} {
+		if !versionPattern.MatchString(version.value) {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid provider version",
+				Detail:   fmt.Sprintf("The %s %q is not a provider major version such as \"v4\".", version.name, version.value),
+				Subject:  rule.DeclRange.Ptr(),
+			})
+		}
+	}
+
+	for _, b := range rule.BlocksToAttributes {
+		conversion := blockToAttribute{blockType: b.BlockType, attribute: b.BlockType}
+		if b.Attribute != nil {
+			conversion.attribute = *b.Attribute
+		}
+		if b.List != nil {
+			conversion.list = *b.List
+		}
+		m.blocksToAttributes = append(m.blocksToAttributes, conversion)
+	}
+
+	renames, renameDiags := decodeMap(rule.RenameAttributes)
+	diags = append(diags, renameDiags...)
+	for _, item := range renames {
+		if item.value.Type() != cty.String || item.value.IsNull() {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid attribute rename",
+				Detail:   fmt.Sprintf("The new name of %q must be a string.", item.key),
+				Subject:  item.rng.Ptr(),
+			})
+			continue
+		}
+		m.renames = append(m.renames, attributeRename{from: item.key, to: item.value.AsString()})
+	}
+
+	defaults, defaultDiags := decodeMap(rule.Defaults)
+	diags = append(diags, defaultDiags...)
+	for _, item := range defaults {
+		// State values are kept as the JSON terraform would write
+		raw, err := ctyjson.Marshal(item.value, item.value.Type())
+		var stateValue interface{}
+		if err == nil {
+			err = json.Unmarshal(raw, &stateValue)
+		}
+		if err != nil {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid default",
+				Detail:   fmt.Sprintf("The default of %q cannot be written to the state: %s.", item.key, err),
+				Subject:  item.rng.Ptr(),
+			})
+			continue
+		}
+		m.defaults = append(m.defaults, attributeDefault{attribute: item.key, value: item.value, stateValue: stateValue})
+	}
+
+	return m, diags
+}
+
+// mapItem is an item of an object expression
+type mapItem struct {
+	key   string
+	value cty.Value
+	rng   hcl.Range
+}
+
+// decodeMap returns the items of an object expression in the order they are written. An
+// attribute that is not set is an empty object.
+func decodeMap(expr hcl.Expression) ([]mapItem, hcl.Diagnostics) {
+	if value, diags := expr.Value(nil); diags.HasErrors() || value.IsNull() {
+		return nil, diags
+	}
+
+	pairs, diags := hcl.ExprMap(expr)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	items := make([]mapItem, 0, len(pairs))
+	for _, pair := range pairs {
+		key, keyDiags := pair.Key.Value(nil)
+		diags = append(diags, keyDiags...)
+		value, valueDiags := pair.Value.Value(nil)
+		diags = append(diags, valueDiags...)
+		if keyDiags.HasErrors() || valueDiags.HasErrors() {
+			continue
+		}
+		if key.Type() != cty.String || key.IsNull() {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid attribute name",
+				Detail:   "Attribute names must be strings.",
+				Subject:  pair.Key.Range().Ptr(),
+			})
+			continue
+		}
+		items = append(items, mapItem{key: key.AsString(), value: value, rng: pair.Value.Range()})
+	}
+	return items, diags
+}
+
+// checkDuplicates reports rules for the same resource type and version path
+func checkDuplicates(migrators []*Migrator) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	seen := make(map[[3]string]hcl.Range)
+	for _, m := range migrators {
+		key := [3]string{m.resourceType, m.sourceVersion, m.targetVersion}
+		if previous, ok := seen[key]; ok {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Duplicate rule",
+				Detail:   fmt.Sprintf("A rule for %q from %s to %s is already defined at %s.", m.resourceType, m.sourceVersion, m.targetVersion, previous),
+				Subject:  m.declRange.Ptr(),
+			})
+			continue
+		}
+		seen[key] = m.declRange
+	}
+	return diags
+}
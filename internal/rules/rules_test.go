@@ -0,0 +1,291 @@
+package rules
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/tf-migrate/internal/testhelpers"
+	"github.com/cloudflare/tf-migrate/internal/transform"
+)
+
+const exampleRule = `
+rule "example_record" {
+  source_version = "v4"
+  target_version = "v5"
+  rename_to      = "example_dns_record"
+
+  rename_attributes = {
+    value    = "content"
+    hostname = "name"
+  }
+  remove_attributes = ["allow_overwrite"]
+
+  block_to_attribute "data" {}
+  block_to_attribute "rule" {
+    attribute = "rules"
+    list      = true
+  }
+
+  defaults = {
+    ttl     = 1
+    proxied = false
+  }
+  numeric_attributes = ["ttl", "priority"]
+
+  state_schema_version = 0
+}
+`
+
+func parseRule(t *testing.T, src string) *Migrator {
+	t.Helper()
+	migrators, diags := Parse("rules.hcl", []byte(src))
+	require.False(t, diags.HasErrors(), diags.Error())
+	require.Len(t, migrators, 1)
+	return migrators[0]
+}
+
+func TestParse(t *testing.T) {
+	m := parseRule(t, exampleRule)
+
+	assert.Equal(t, "example_record", m.SourceResourceType())
+	assert.Equal(t, "v4", m.SourceVersion())
+	assert.Equal(t, "v5", m.TargetVersion())
+	assert.Equal(t, "example_dns_record", m.GetResourceType())
+	assert.True(t, m.CanHandle("example_record"))
+	assert.False(t, m.CanHandle("example_dns_record"))
+
+	oldType, newType := m.GetResourceRename()
+	assert.Equal(t, "example_record", oldType)
+	assert.Equal(t, "example_dns_record", newType)
+	assert.Equal(t, map[string]string{"value": "content", "hostname": "name"}, m.GetAttributeRenames())
+	assert.Equal(t, []string{"allow_overwrite"}, m.GetRemovedAttributes())
+	assert.Equal(t, []attributeRename{{"value", "content"}, {"hostname", "name"}}, m.renames, "renames keep the order of the file")
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Missing version",
+			input:    "rule \"example_record\" {\n  source_version = \"v4\"\n}",
+			expected: `Missing required argument; The argument "target_version" is required`,
+		},
+		{
+			name:     "Invalid version",
+			input:    "rule \"example_record\" {\n  source_version = \"4\"\n  target_version = \"v5\"\n}",
+			expected: `rules.hcl:1,1-22: Invalid provider version; The source_version "4" is not a provider major version`,
+		},
+		{
+			name:     "Rename that is not a string",
+			input:    "rule \"example_record\" {\n  source_version = \"v4\"\n  target_version = \"v5\"\n  rename_attributes = {\n    value = 1\n  }\n}",
+			expected: `rules.hcl:5,13-14: Invalid attribute rename; The new name of "value" must be a string.`,
+		},
+		{
+			name:     "Default that is not a literal",
+			input:    "rule \"example_record\" {\n  source_version = \"v4\"\n  target_version = \"v5\"\n  defaults = {\n    ttl = var.ttl\n  }\n}",
+			expected: `Variables not allowed`,
+		},
+		{
+			name:     "Unknown setting",
+			input:    "rule \"example_record\" {\n  source_version = \"v4\"\n  target_version = \"v5\"\n  rename = \"x\"\n}",
+			expected: `An argument named "rename" is not expected here.`,
+		},
+		{
+			name:     "Duplicate rule",
+			input:    exampleRule + exampleRule,
+			expected: `Duplicate rule; A rule for "example_record" from v4 to v5 is already defined at rules.hcl:2,1-22.`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "rules.hcl")
+			require.NoError(t, os.WriteFile(path, []byte(tt.input), 0o644))
+			t.Chdir(filepath.Dir(path))
+
+			_, err := Load("rules.hcl")
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.expected)
+		})
+	}
+}
+
+func TestTransformConfig(t *testing.T) {
+	tests := []testhelpers.ConfigTestCase{
+		{
+			Name: "All operations",
+			Input: `
+resource "example_record" "www" {
+  zone_id         = "abc"
+  hostname        = "www"
+  value           = "192.0.2.1"
+  allow_overwrite = true # Gone in v5
+  priority        = "10"
+
+  data {
+    flags = 0
+  }
+
+  rule {
+    action = "allow"
+  }
+
+  rule {
+    action = "deny"
+  }
+}`,
+			Expected: `
+resource "example_dns_record" "www" {
+  zone_id  = "abc"
+  priority = 10
+
+  data = {
+    flags = 0
+  }
+  rules = [{
+    action = "allow"
+    }, {
+    action = "deny"
+  }]
+  content = "192.0.2.1"
+  name    = "www"
+  ttl     = 1
+  proxied = false
+}`,
+		},
+		{
+			Name: "Existing values are kept",
+			Input: `
+resource "example_record" "www" {
+  ttl     = var.ttl
+  proxied = true
+}`,
+			Expected: `
+resource "example_dns_record" "www" {
+  ttl     = var.ttl
+  proxied = true
+}`,
+		},
+	}
+
+	testhelpers.RunConfigTransformTests(t, tests, parseRule(t, exampleRule))
+}
+
+func TestMoveToEnd(t *testing.T) {
+	file, diags := hclwrite.ParseConfig([]byte(`resource "example_zone" "main" {}`), "main.tf", hcl.InitialPos)
+	require.False(t, diags.HasErrors())
+	block := file.Body().Blocks()[0]
+
+	result, err := parseRule(t, exampleRule).TransformConfig(&transform.Context{}, block)
+	require.NoError(t, err)
+	assert.False(t, result.RemoveOriginal)
+
+	m := parseRule(t, `
+rule "example_zone" {
+  source_version = "v4"
+  target_version = "v5"
+  move_to_end    = true
+}`)
+	result, err = m.TransformConfig(&transform.Context{}, block)
+	require.NoError(t, err)
+	assert.True(t, result.RemoveOriginal)
+}
+
+func TestTransformState(t *testing.T) {
+	tests := []testhelpers.StateTestCase{
+		{
+			Name: "All operations",
+			Input: `{
+  "type": "example_record",
+  "schema_version": 3,
+  "attributes": {
+    "zone_id": "abc",
+    "hostname": "www",
+    "value": "192.0.2.1",
+    "allow_overwrite": true,
+    "priority": "10",
+    "data": [{"flags": 0}],
+    "rule": [{"action": "allow"}, {"action": "deny"}]
+  }
+}`,
+			Expected: `{
+  "type": "example_dns_record",
+  "schema_version": 0,
+  "attributes": {
+    "zone_id": "abc",
+    "name": "www",
+    "content": "192.0.2.1",
+    "priority": 10,
+    "data": {"flags": 0},
+    "rules": [{"action": "allow"}, {"action": "deny"}],
+    "ttl": 1,
+    "proxied": false
+  }
+}`,
+		},
+		{
+			Name: "No blocks",
+			Input: `{
+  "attributes": {
+    "data": [],
+    "ttl": 300
+  }
+}`,
+			Expected: `{
+  "schema_version": 0,
+  "attributes": {
+    "data": null,
+    "ttl": 300,
+    "proxied": false
+  }
+}`,
+		},
+	}
+
+	testhelpers.RunStateTransformTests(t, tests, parseRule(t, exampleRule))
+}
+
+func TestLoadDir(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b.hcl"), []byte(exampleRule), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.hcl"), []byte(`
+rule "example_zone" {
+  source_version = "v5"
+  target_version = "v6"
+}
+`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("Not a rule file"), 0o644))
+
+	migrators, err := LoadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, migrators, 2)
+	assert.Equal(t, "example_zone", migrators[0].SourceResourceType())
+	assert.Equal(t, "example_record", migrators[1].SourceResourceType())
+
+	// The same rule in another file
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "c.hcl"), []byte(exampleRule), 0o644))
+	_, err = LoadDir(dir)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), filepath.Join(dir, "c.hcl")+":2,1-22: Duplicate rule")
+}
+
+func TestBuiltin(t *testing.T) {
+	migrators, err := Builtin()
+	require.NoError(t, err)
+	require.NotEmpty(t, migrators)
+
+	m := MustBuiltin("cloudflare_tunnel", "v4", "v5")
+	assert.Equal(t, "cloudflare_zero_trust_tunnel_cloudflared", m.GetResourceType())
+	assert.PanicsWithValue(t, "no embedded rule for cloudflare_tunnel from v5 to v6", func() {
+		MustBuiltin("cloudflare_tunnel", "v5", "v6")
+	})
+}
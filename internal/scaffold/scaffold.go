@@ -0,0 +1,297 @@
+// Package scaffold writes starter migrator packages from the schema diff of a resource.
+// The generated migrator applies the changes that have a standard migration, such as
+// removed attributes and blocks that became attributes, and leaves a TODO for the others.
+// The generated tests migrate the required attributes and pass as generated.
+package scaffold
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+
+	"github.com/cloudflare/tf-migrate/internal/schema"
+)
+
+//go:embed templates/*.tmpl
+var templateFiles embed.FS
+
+var templates = template.Must(template.ParseFS(templateFiles, "templates/*.tmpl"))
+
+// versionPattern matches provider major versions such as v4
+var versionPattern = regexp.MustCompile(`^v[0-9]+$`)
+
+// Options are the settings of a generated migrator package
+type Options struct {
+	// Dir is the directory of the resource packages, e.g. internal/resources
+	Dir           string
+	SourceVersion string
+	TargetVersion string
+}
+
+// templateData is the data of the templates
+type templateData struct {
+	Package             string
+	Migrator            string
+	VersionPath         string
+	SourceType          string
+	TargetType          string
+	SourceVersion       string
+	TargetVersion       string
+	Renamed             bool
+	TargetSchemaVersion int
+	SetSchemaVersion    bool
+
+	// Top-level attributes and blocks with a standard migration
+	Removed []string
+	// RemovedBlocks are the nested blocks of Removed
+	RemovedBlocks []string
+	SingleBlocks  []schema.BlockChange
+	ListBlocks    []schema.BlockChange
+	// Todos are the changes left to the developer
+	Todos []string
+
+	ConfigInput    string
+	ConfigExpected string
+	StateInput     string
+	StateExpected  string
+}
+
+func (d templateData) UsesBody() bool {
+	return len(d.Removed) > 0 || len(d.SingleBlocks) > 0 || len(d.ListBlocks) > 0
+}
+
+func (d templateData) UsesHCL() bool {
+	return d.Renamed || d.UsesBody()
+}
+
+func (d templateData) UsesState() bool {
+	return len(d.Removed) > 0 || len(d.SingleBlocks) > 0
+}
+
+// PackageName returns the name of the package of a resource type: the type without its
+// provider prefix, e.g. dns_record for cloudflare_dns_record
+func PackageName(resourceType string) string {
+	if _, name, ok := strings.Cut(resourceType, "_"); ok {
+		return name
+	}
+	return resourceType
+}
+
+// Generate writes the migrator package of a resource diff, named after the target type,
+// and returns the paths of the files written. from and to are the resource schemas the
+// diff was made of. Existing files are never overwritten.
+func Generate(diff *schema.ResourceDiff, from, to *schema.Resource, opts Options) ([]string, error) {
+	data, err := newTemplateData(diff, from, to, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Join(opts.Dir, data.Package)
+	base := opts.SourceVersion + "_to_" + opts.TargetVersion
+	files := []struct {
+		path     string
+		template string
+	}{
+		{filepath.Join(dir, base+".go"), "migrator.go.tmpl"},
+		{filepath.Join(dir, base+"_test.go"), "migrator_test.go.tmpl"},
+	}
+
+	sources := make([][]byte, len(files))
+	for i, file := range files {
+		if _, err := os.Stat(file.path); err == nil {
+			return nil, fmt.Errorf("%s already exists", file.path)
+		}
+		var buf bytes.Buffer
+		if err := templates.ExecuteTemplate(&buf, file.template, data); err != nil {
+			return nil, fmt.Errorf("failed to generate %s: %w", file.path, err)
+		}
+		src, err := format.Source(buf.Bytes())
+		if err != nil {
+			return nil, fmt.Errorf("failed to format %s: %w", file.path, err)
+		}
+		sources[i] = src
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	paths := make([]string, len(files))
+	for i, file := range files {
+		if err := os.WriteFile(file.path, sources[i], 0o644); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", file.path, err)
+		}
+		paths[i] = file.path
+	}
+	return paths, nil
+}
+
+func newTemplateData(diff *schema.ResourceDiff, from, to *schema.Resource, opts Options) (templateData, error) {
+	if !versionPattern.MatchString(opts.SourceVersion) || !versionPattern.MatchString(opts.TargetVersion) {
+		return templateData{}, fmt.Errorf("invalid version path %q to %q: versions are provider major versions such as v4", opts.SourceVersion, opts.TargetVersion)
+	}
+
+	data := templateData{
+		Package:             PackageName(diff.TargetType),
+		VersionPath:         "V" + opts.SourceVersion[1:] + "ToV" + opts.TargetVersion[1:],
+		SourceType:          diff.SourceType,
+		TargetType:          diff.TargetType,
+		SourceVersion:       opts.SourceVersion,
+		TargetVersion:       opts.TargetVersion,
+		Renamed:             diff.Renamed(),
+		TargetSchemaVersion: diff.TargetVersion,
+		SetSchemaVersion:    diff.SourceVersion != diff.TargetVersion,
+	}
+	data.Migrator = data.VersionPath + "Migrator"
+
+	for _, path := range diff.Removed {
+		if strings.Contains(path, ".") {
+			data.Todos = append(data.Todos, fmt.Sprintf("remove %s, which does not exist in %s", path, opts.TargetVersion))
+		} else {
+			data.Removed = append(data.Removed, path)
+			if from.Block != nil && from.Block.BlockTypes[path] != nil {
+				data.RemovedBlocks = append(data.RemovedBlocks, path)
+			}
+		}
+	}
+	for _, c := range diff.BlocksToAttributes {
+		switch {
+		case strings.Contains(c.Path, "."):
+			data.Todos = append(data.Todos, fmt.Sprintf("convert the nested %s block %s to a %s attribute", c.NestingMode, c.Path, describeNesting(c.TargetNestingMode)))
+		case c.TargetNestingMode == schema.NestingSingle && (c.NestingMode == schema.NestingSingle || c.MaxItems == 1):
+			data.SingleBlocks = append(data.SingleBlocks, c)
+		case c.TargetNestingMode == schema.NestingList || c.TargetNestingMode == schema.NestingSet:
+			data.ListBlocks = append(data.ListBlocks, c)
+		default:
+			data.Todos = append(data.Todos, fmt.Sprintf("convert the %s block %s to a %s attribute", c.NestingMode, c.Path, describeNesting(c.TargetNestingMode)))
+		}
+	}
+	for _, c := range diff.TypeChanges {
+		data.Todos = append(data.Todos, fmt.Sprintf("convert %s from %s to %s", c.Path, c.From.FriendlyName(), c.To.FriendlyName()))
+	}
+	for _, field := range diff.Added {
+		if field.Required {
+			data.Todos = append(data.Todos, fmt.Sprintf("set %s, which is required in %s", field.Path, opts.TargetVersion))
+		}
+	}
+
+	values := fixtureValues(from, to)
+	data.ConfigInput = configFixture(diff.SourceType, values)
+	data.ConfigExpected = configFixture(diff.TargetType, values)
+	var err error
+	if data.StateInput, err = stateFixture(diff.SourceVersion, values); err != nil {
+		return templateData{}, err
+	}
+	if data.StateExpected, err = stateFixture(diff.TargetVersion, values); err != nil {
+		return templateData{}, err
+	}
+	return data, nil
+}
+
+func describeNesting(nestingMode string) string {
+	if nestingMode == schema.NestingSingle {
+		return "object"
+	}
+	return nestingMode
+}
+
+// fixtureValue is an attribute value of the test fixtures
+type fixtureValue struct {
+	name  string
+	value cty.Value
+}
+
+// fixtureValues returns example values of the required attributes that keep their type,
+// in the order of their names
+func fixtureValues(from, to *schema.Resource) []fixtureValue {
+	var values []fixtureValue
+	if from.Block == nil || to.Block == nil {
+		return nil
+	}
+	names := make([]string, 0, len(from.Block.Attributes))
+	for name := range from.Block.Attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		source, target := from.Block.Attributes[name], to.Block.Attributes[name]
+		if !source.Required || target == nil || !source.ImpliedType().Equals(target.ImpliedType()) {
+			continue
+		}
+		if value, ok := exampleValue(source.ImpliedType()); ok {
+			values = append(values, fixtureValue{name: name, value: value})
+		}
+	}
+	return values
+}
+
+// exampleValue returns a value of a primitive type or a collection of primitives
+func exampleValue(ty cty.Type) (cty.Value, bool) {
+	switch {
+	case ty == cty.String:
+		return cty.StringVal("example"), true
+	case ty == cty.Number:
+		return cty.NumberIntVal(1), true
+	case ty == cty.Bool:
+		return cty.True, true
+	case ty.IsListType() || ty.IsSetType() || ty.IsMapType():
+		element, ok := exampleValue(ty.ElementType())
+		if !ok {
+			return cty.NilVal, false
+		}
+		switch {
+		case ty.IsListType():
+			return cty.ListVal([]cty.Value{element}), true
+		case ty.IsSetType():
+			return cty.SetVal([]cty.Value{element}), true
+		default:
+			return cty.MapVal(map[string]cty.Value{"key": element}), true
+		}
+	default:
+		return cty.NilVal, false
+	}
+}
+
+// configFixture returns a resource block setting the fixture values
+func configFixture(resourceType string, values []fixtureValue) string {
+	file := hclwrite.NewEmptyFile()
+	body := file.Body().AppendNewBlock("resource", []string{resourceType, "example"}).Body()
+	for _, v := range values {
+		body.SetAttributeValue(v.name, v.value)
+	}
+	return strings.TrimSuffix(string(hclwrite.Format(file.Bytes())), "\n")
+}
+
+// stateFixture returns a state instance holding the fixture values
+func stateFixture(schemaVersion int, values []fixtureValue) (string, error) {
+	attributes := make(map[string]json.RawMessage, len(values))
+	for _, v := range values {
+		raw, err := ctyjson.Marshal(v.value, v.value.Type())
+		if err != nil {
+			return "", fmt.Errorf("failed to encode the example value of %s: %w", v.name, err)
+		}
+		attributes[v.name] = raw
+	}
+	instance := struct {
+		SchemaVersion int                        `json:"schema_version"`
+		Attributes    map[string]json.RawMessage `json:"attributes"`
+	}{schemaVersion, attributes}
+
+	data, err := json.MarshalIndent(instance, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode state fixture: %w", err)
+	}
+	return string(data), nil
+}
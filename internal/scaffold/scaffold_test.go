@@ -0,0 +1,168 @@
+package scaffold
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/tf-migrate/internal/schema"
+)
+
+const sourceSchema = `{
+  "format_version": "1.0",
+  "provider_schemas": {
+    "registry.terraform.io/cloudflare/cloudflare": {
+      "resource_schemas": {
+        "cloudflare_record": {
+          "version": 2,
+          "block": {
+            "attributes": {
+              "id":       {"type": "string", "computed": true},
+              "zone_id":  {"type": "string", "required": true},
+              "name":     {"type": "string", "required": true},
+              "hostname": {"type": "string", "computed": true},
+              "ttl":      {"type": "string", "optional": true}
+            },
+            "block_types": {
+              "data": {
+                "nesting_mode": "list",
+                "block": {"attributes": {"flags": {"type": "string", "optional": true}}},
+                "max_items": 1
+              },
+              "rule": {
+                "nesting_mode": "list",
+                "block": {"attributes": {"action": {"type": "string", "required": true}}}
+              },
+              "legacy": {
+                "nesting_mode": "list",
+                "block": {"attributes": {"value": {"type": "string", "optional": true}}}
+              }
+            }
+          }
+        }
+      }
+    }
+  }
+}`
+
+const targetSchema = `{
+  "format_version": "1.0",
+  "provider_schemas": {
+    "registry.terraform.io/cloudflare/cloudflare": {
+      "resource_schemas": {
+        "cloudflare_dns_record": {
+          "version": 0,
+          "block": {
+            "attributes": {
+              "id":      {"type": "string", "computed": true},
+              "zone_id": {"type": "string", "required": true},
+              "name":    {"type": "string", "required": true},
+              "type":    {"type": "string", "required": true},
+              "ttl":     {"type": "number", "optional": true},
+              "data": {
+                "nested_type": {"nesting_mode": "single", "attributes": {"flags": {"type": "string", "optional": true}}},
+                "optional": true
+              },
+              "rule": {
+                "nested_type": {"nesting_mode": "list", "attributes": {"action": {"type": "string", "required": true}}},
+                "optional": true
+              }
+            }
+          }
+        }
+      }
+    }
+  }
+}`
+
+func generate(t *testing.T, dir string) []string {
+	t.Helper()
+	from, err := schema.Parse([]byte(sourceSchema))
+	require.NoError(t, err)
+	to, err := schema.Parse([]byte(targetSchema))
+	require.NoError(t, err)
+	diff := schema.Compare(from, to, map[string]string{"cloudflare_record": "cloudflare_dns_record"})
+	require.Len(t, diff.Changed, 1)
+
+	paths, err := Generate(diff.Changed[0], from.Resources["cloudflare_record"], to.Resources["cloudflare_dns_record"],
+		Options{Dir: dir, SourceVersion: "v4", TargetVersion: "v5"})
+	require.NoError(t, err)
+	return paths
+}
+
+func TestGenerate(t *testing.T) {
+	dir := t.TempDir()
+	paths := generate(t, dir)
+	require.Equal(t, []string{
+		filepath.Join(dir, "dns_record", "v4_to_v5.go"),
+		filepath.Join(dir, "dns_record", "v4_to_v5_test.go"),
+	}, paths)
+
+	migrator, err := os.ReadFile(paths[0])
+	require.NoError(t, err)
+	for _, expected := range []string{
+		"package dns_record",
+		`internal.RegisterMigrator("cloudflare_record", "v4", "v5", migrator)`,
+		`tfhcl.RenameResourceType(block, "cloudflare_record", "cloudflare_dns_record")`,
+		`tfhcl.ConvertSingleBlockToAttribute(body, "data", "data")`,
+		`tfhcl.ConvertBlocksToList(body, "rule", "rule", nil)`,
+		`tfhcl.RemoveBlocksByType(body, "legacy")`,
+		`result = state.FlattenArrayField(result, "attributes.data", attrs.Get("data"))`,
+		`result = state.RemoveFields(result, "attributes", attrs, removedAttributes...)`,
+		"// TODO: convert ttl from string to number",
+		"// TODO: set type, which is required in v5",
+		`result, _ = sjson.Set(result, "schema_version", 0)`,
+	} {
+		assert.Contains(t, string(migrator), expected)
+	}
+
+	test, err := os.ReadFile(paths[1])
+	require.NoError(t, err)
+	assert.Contains(t, string(test), "func TestV4ToV5Migration(t *testing.T) {")
+	assert.Contains(t, string(test), `
+resource "cloudflare_record" "example" {
+  name    = "example"
+  zone_id = "example"
+}`)
+	assert.Contains(t, string(test), `
+resource "cloudflare_dns_record" "example" {
+  name    = "example"
+  zone_id = "example"
+}`)
+	assert.Contains(t, string(test), `"schema_version": 2,`)
+	assert.Contains(t, string(test), `"schema_version": 0,`)
+}
+
+func TestGenerateKeepsExistingFiles(t *testing.T) {
+	dir := t.TempDir()
+	paths := generate(t, dir)
+	require.NoError(t, os.WriteFile(paths[0], []byte("package dns_record\n"), 0o644))
+
+	from, err := schema.Parse([]byte(sourceSchema))
+	require.NoError(t, err)
+	diff := &schema.ResourceDiff{SourceType: "cloudflare_record", TargetType: "cloudflare_dns_record"}
+	_, err = Generate(diff, from.Resources["cloudflare_record"], from.Resources["cloudflare_record"],
+		Options{Dir: dir, SourceVersion: "v4", TargetVersion: "v5"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "v4_to_v5.go already exists")
+
+	content, err := os.ReadFile(paths[0])
+	require.NoError(t, err)
+	assert.Equal(t, "package dns_record\n", string(content))
+}
+
+func TestGenerateInvalidVersions(t *testing.T) {
+	diff := &schema.ResourceDiff{SourceType: "cloudflare_record", TargetType: "cloudflare_record"}
+	_, err := Generate(diff, &schema.Resource{}, &schema.Resource{}, Options{Dir: t.TempDir(), SourceVersion: "4", TargetVersion: "v5"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid version path")
+}
+
+func TestPackageName(t *testing.T) {
+	assert.Equal(t, "dns_record", PackageName("cloudflare_dns_record"))
+	assert.Equal(t, "zero_trust_list", PackageName("cloudflare_zero_trust_list"))
+	assert.Equal(t, "record", PackageName("record"))
+}
@@ -0,0 +1,266 @@
+package schema
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Diff lists the resource schema changes between two provider versions
+type Diff struct {
+	// Resources that exist in both versions and changed
+	Changed []*ResourceDiff
+	// Resource types of the source version without a counterpart in the target version
+	Removed []string
+	// Resource types of the target version without a counterpart in the source version
+	Added []string
+}
+
+// ResourceDiff lists the changes of a resource schema. Paths name nested attributes and
+// blocks with dots, e.g. data.priority.
+type ResourceDiff struct {
+	SourceType    string
+	TargetType    string
+	SourceVersion int
+	TargetVersion int
+
+	Removed            []string
+	Added              []AddedField
+	BlocksToAttributes []BlockChange
+	TypeChanges        []TypeChange
+}
+
+// AddedField is an attribute or block of the target version only
+type AddedField struct {
+	Path     string
+	Required bool
+}
+
+// BlockChange is a nested block that became an attribute
+type BlockChange struct {
+	Path string
+	// NestingMode and MaxItems of the block in the source version
+	NestingMode string
+	MaxItems    int
+	// TargetNestingMode of the attribute: single for an object, or list, set or map
+	TargetNestingMode string
+}
+
+// TypeChange is an attribute whose type changed
+type TypeChange struct {
+	Path     string
+	From, To cty.Type
+}
+
+// Renamed reports whether the resource type changed
+func (d *ResourceDiff) Renamed() bool {
+	return d.SourceType != d.TargetType
+}
+
+// Empty reports whether the resource schema is unchanged
+func (d *ResourceDiff) Empty() bool {
+	return !d.Renamed() && d.SourceVersion == d.TargetVersion && len(d.Removed) == 0 &&
+		len(d.Added) == 0 && len(d.BlocksToAttributes) == 0 && len(d.TypeChanges) == 0
+}
+
+// MaxItemsOne reports whether a list block of at most one item became an object
+func (c BlockChange) MaxItemsOne() bool {
+	return c.MaxItems == 1 && c.TargetNestingMode == NestingSingle
+}
+
+// Compare compares the resource schemas of two provider versions. renames maps source
+// resource types to their type in the target version; other types keep their name.
+func Compare(from, to *Provider, renames map[string]string) *Diff {
+	diff := &Diff{}
+	matched := make(map[string]bool)
+
+	for _, sourceType := range sortedKeys(from.Resources) {
+		targetType := sourceType
+		if renamed, ok := renames[sourceType]; ok {
+			targetType = renamed
+		}
+		target, ok := to.Resources[targetType]
+		if !ok {
+			diff.Removed = append(diff.Removed, sourceType)
+			continue
+		}
+		matched[targetType] = true
+
+		source := from.Resources[sourceType]
+		d := &ResourceDiff{
+			SourceType:    sourceType,
+			TargetType:    targetType,
+			SourceVersion: source.Version,
+			TargetVersion: target.Version,
+		}
+		d.compareBlocks("", source.Block, target.Block)
+		if !d.Empty() {
+			diff.Changed = append(diff.Changed, d)
+		}
+	}
+
+	for _, targetType := range sortedKeys(to.Resources) {
+		if !matched[targetType] {
+			diff.Added = append(diff.Added, targetType)
+		}
+	}
+	return diff
+}
+
+// compareBlocks compares the attributes and nested blocks of two block bodies
+func (d *ResourceDiff) compareBlocks(prefix string, from, to *Block) {
+	if from == nil {
+		from = &Block{}
+	}
+	if to == nil {
+		to = &Block{}
+	}
+
+	names := make(map[string]bool)
+	for name := range from.Attributes {
+		names[name] = true
+	}
+	for name := range from.BlockTypes {
+		names[name] = true
+	}
+	for name := range to.Attributes {
+		names[name] = true
+	}
+	for name := range to.BlockTypes {
+		names[name] = true
+	}
+
+	for _, name := range sortedKeys(names) {
+		path := prefix + name
+		fromAttr, fromBlock := from.Attributes[name], from.BlockTypes[name]
+		toAttr, toBlock := to.Attributes[name], to.BlockTypes[name]
+
+		switch {
+		case fromAttr == nil && fromBlock == nil:
+			required := (toAttr != nil && toAttr.Required) || (toBlock != nil && toBlock.MinItems > 0)
+			d.Added = append(d.Added, AddedField{Path: path, Required: required})
+		case toAttr == nil && toBlock == nil:
+			d.Removed = append(d.Removed, path)
+		case fromBlock != nil && toBlock != nil:
+			if fromBlock.NestingMode != toBlock.NestingMode {
+				d.TypeChanges = append(d.TypeChanges, TypeChange{
+					Path: path,
+					From: wrap(fromBlock.NestingMode, fromBlock.Block.ImpliedType()),
+					To:   wrap(toBlock.NestingMode, toBlock.Block.ImpliedType()),
+				})
+			}
+			d.compareBlocks(path+".", fromBlock.Block, toBlock.Block)
+		case fromBlock != nil:
+			d.BlocksToAttributes = append(d.BlocksToAttributes, BlockChange{
+				Path:              path,
+				NestingMode:       fromBlock.NestingMode,
+				MaxItems:          fromBlock.MaxItems,
+				TargetNestingMode: nestingModeOf(toAttr),
+			})
+			if toAttr.NestedType != nil {
+				d.compareBlocks(path+".", fromBlock.Block, &Block{Attributes: toAttr.NestedType.Attributes})
+			}
+		case toBlock != nil:
+			d.TypeChanges = append(d.TypeChanges, TypeChange{
+				Path: path,
+				From: fromAttr.ImpliedType(),
+				To:   wrap(toBlock.NestingMode, toBlock.Block.ImpliedType()),
+			})
+		case fromAttr.NestedType != nil && toAttr.NestedType != nil:
+			if fromAttr.NestedType.NestingMode != toAttr.NestedType.NestingMode {
+				d.TypeChanges = append(d.TypeChanges, TypeChange{Path: path, From: fromAttr.ImpliedType(), To: toAttr.ImpliedType()})
+			}
+			d.compareBlocks(path+".", &Block{Attributes: fromAttr.NestedType.Attributes}, &Block{Attributes: toAttr.NestedType.Attributes})
+		default:
+			if fromType, toType := fromAttr.ImpliedType(), toAttr.ImpliedType(); !fromType.Equals(toType) {
+				d.TypeChanges = append(d.TypeChanges, TypeChange{Path: path, From: fromType, To: toType})
+			}
+		}
+	}
+}
+
+// WriteText writes the diff as a human-readable report
+func (d *Diff) WriteText(w io.Writer) error {
+	var b strings.Builder
+	for _, r := range d.Changed {
+		if r.Renamed() {
+			fmt.Fprintf(&b, "%s → %s\n", r.SourceType, r.TargetType)
+		} else {
+			fmt.Fprintf(&b, "%s\n", r.SourceType)
+		}
+		if r.SourceVersion != r.TargetVersion {
+			fmt.Fprintf(&b, "  schema version     %d → %d\n", r.SourceVersion, r.TargetVersion)
+		}
+		for _, path := range r.Removed {
+			fmt.Fprintf(&b, "  removed            %s\n", path)
+		}
+		for _, field := range r.Added {
+			if field.Required {
+				fmt.Fprintf(&b, "  added              %s (required)\n", field.Path)
+			} else {
+				fmt.Fprintf(&b, "  added              %s\n", field.Path)
+			}
+		}
+		for _, c := range r.BlocksToAttributes {
+			block := c.NestingMode + " block"
+			if c.MaxItems > 0 {
+				block += fmt.Sprintf(" (max %d)", c.MaxItems)
+			}
+			attribute := c.TargetNestingMode + " attribute"
+			if c.TargetNestingMode == NestingSingle {
+				attribute = "object attribute"
+			}
+			fmt.Fprintf(&b, "  block → attribute  %s: %s → %s\n", c.Path, block, attribute)
+		}
+		for _, c := range r.TypeChanges {
+			fmt.Fprintf(&b, "  type               %s: %s → %s\n", c.Path, c.From.FriendlyName(), c.To.FriendlyName())
+		}
+		b.WriteString("\n")
+	}
+	if len(d.Removed) > 0 {
+		fmt.Fprintf(&b, "Removed resource types (%d):\n", len(d.Removed))
+		for _, resourceType := range d.Removed {
+			fmt.Fprintf(&b, "  %s\n", resourceType)
+		}
+		b.WriteString("\n")
+	}
+	if len(d.Added) > 0 {
+		fmt.Fprintf(&b, "Added resource types (%d):\n", len(d.Added))
+		for _, resourceType := range d.Added {
+			fmt.Fprintf(&b, "  %s\n", resourceType)
+		}
+		b.WriteString("\n")
+	}
+	fmt.Fprintf(&b, "%d changed, %d removed, %d added resource types\n", len(d.Changed), len(d.Removed), len(d.Added))
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// nestingModeOf returns the nesting mode of an attribute holding objects
+func nestingModeOf(attr *Attribute) string {
+	if attr.NestedType != nil {
+		return attr.NestedType.NestingMode
+	}
+	switch {
+	case attr.Type.IsListType():
+		return NestingList
+	case attr.Type.IsSetType():
+		return NestingSet
+	case attr.Type.IsMapType():
+		return NestingMap
+	default:
+		return NestingSingle
+	}
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
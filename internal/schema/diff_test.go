@@ -0,0 +1,127 @@
+package schema
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// testSourceSchema is an older version of testSchema
+const testSourceSchema = `{
+  "format_version": "1.0",
+  "provider_schemas": {
+    "registry.terraform.io/cloudflare/cloudflare": {
+      "resource_schemas": {
+        "cloudflare_record": {
+          "version": 2,
+          "block": {
+            "attributes": {
+              "id":       {"type": "string", "computed": true},
+              "zone_id":  {"type": "string", "required": true},
+              "name":     {"type": "string", "required": true},
+              "value":    {"type": "string", "optional": true},
+              "hostname": {"type": "string", "computed": true},
+              "ttl":      {"type": "string", "optional": true},
+              "tags":     {"type": ["set", "string"], "optional": true}
+            },
+            "block_types": {
+              "data": {
+                "nesting_mode": "list",
+                "block": {
+                  "attributes": {
+                    "flags":    {"type": "string", "optional": true},
+                    "priority": {"type": "number", "optional": true},
+                    "target":   {"type": "string", "optional": true}
+                  }
+                },
+                "max_items": 1
+              },
+              "settings": {
+                "nesting_mode": "list",
+                "block": {"attributes": {"key": {"type": "string", "required": true}}}
+              },
+              "timeouts": {
+                "nesting_mode": "single",
+                "block": {"attributes": {"create": {"type": "string", "optional": true}}},
+                "max_items": 1
+              }
+            }
+          }
+        },
+        "cloudflare_old_thing": {
+          "version": 0,
+          "block": {"attributes": {"id": {"type": "string", "computed": true}}}
+        }
+      }
+    }
+  }
+}`
+
+func TestCompare(t *testing.T) {
+	from, err := Parse([]byte(testSourceSchema))
+	require.NoError(t, err)
+	to, err := Parse([]byte(testSchema))
+	require.NoError(t, err)
+
+	diff := Compare(from, to, map[string]string{"cloudflare_record": "cloudflare_dns_record"})
+
+	assert.Equal(t, []string{"cloudflare_old_thing"}, diff.Removed)
+	assert.Empty(t, diff.Added)
+	require.Len(t, diff.Changed, 1)
+
+	record := diff.Changed[0]
+	assert.Equal(t, "cloudflare_record", record.SourceType)
+	assert.Equal(t, "cloudflare_dns_record", record.TargetType)
+	assert.True(t, record.Renamed())
+	assert.Equal(t, 2, record.SourceVersion)
+	assert.Equal(t, 1, record.TargetVersion)
+	assert.Equal(t, []string{"data.flags", "hostname", "value"}, record.Removed)
+	assert.Equal(t, []AddedField{{Path: "proxied"}}, record.Added)
+	assert.Equal(t, []BlockChange{
+		{Path: "data", NestingMode: NestingList, MaxItems: 1, TargetNestingMode: NestingSingle},
+		{Path: "settings", NestingMode: NestingList, TargetNestingMode: NestingList},
+	}, record.BlocksToAttributes)
+	assert.True(t, record.BlocksToAttributes[0].MaxItemsOne())
+	assert.False(t, record.BlocksToAttributes[1].MaxItemsOne())
+	require.Len(t, record.TypeChanges, 1)
+	assert.Equal(t, "ttl", record.TypeChanges[0].Path)
+	assert.True(t, record.TypeChanges[0].From.Equals(cty.String))
+	assert.True(t, record.TypeChanges[0].To.Equals(cty.Number))
+
+	// Without the rename hint the types do not match
+	diff = Compare(from, to, nil)
+	assert.Empty(t, diff.Changed)
+	assert.Equal(t, []string{"cloudflare_old_thing", "cloudflare_record"}, diff.Removed)
+	assert.Equal(t, []string{"cloudflare_dns_record"}, diff.Added)
+
+	// A provider compared with itself
+	assert.Empty(t, Compare(to, to, nil).Changed)
+}
+
+func TestDiffWriteText(t *testing.T) {
+	from, err := Parse([]byte(testSourceSchema))
+	require.NoError(t, err)
+	to, err := Parse([]byte(testSchema))
+	require.NoError(t, err)
+
+	var out strings.Builder
+	require.NoError(t, Compare(from, to, map[string]string{"cloudflare_record": "cloudflare_dns_record"}).WriteText(&out))
+	assert.Equal(t, `cloudflare_record → cloudflare_dns_record
+  schema version     2 → 1
+  removed            data.flags
+  removed            hostname
+  removed            value
+  added              proxied
+  block → attribute  data: list block (max 1) → object attribute
+  block → attribute  settings: list block → list attribute
+  type               ttl: string → number
+
+Removed resource types (1):
+  cloudflare_old_thing
+
+1 changed, 1 removed, 0 added resource types
+`, out.String())
+}
@@ -0,0 +1,195 @@
+// Package schema loads provider schemas produced by `terraform providers schema -json`
+// and validates migrated configuration and state files against them, so leftover
+// attributes and wrong block syntax are found without running terraform plan.
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// cloudflareProvider is the suffix of the Cloudflare provider source address
+const cloudflareProvider = "/cloudflare/cloudflare"
+
+// Nesting modes of nested blocks and nested attribute types
+const (
+	NestingSingle = "single"
+	NestingGroup  = "group"
+	NestingList   = "list"
+	NestingSet    = "set"
+	NestingMap    = "map"
+)
+
+// Provider holds the resource and data source schemas of a provider
+type Provider struct {
+	Resources   map[string]*Resource `json:"resource_schemas"`
+	DataSources map[string]*Resource `json:"data_source_schemas"`
+}
+
+// Resource is the schema of a resource or data source
+type Resource struct {
+	Version int    `json:"version"`
+	Block   *Block `json:"block"`
+}
+
+// Block is the schema of a resource body or nested block body
+type Block struct {
+	Attributes map[string]*Attribute   `json:"attributes"`
+	BlockTypes map[string]*NestedBlock `json:"block_types"`
+}
+
+// Attribute is the schema of an attribute. Attributes have either a Type or a NestedType.
+type Attribute struct {
+	Type       cty.Type
+	NestedType *NestedType
+	Required   bool
+	Optional   bool
+	Computed   bool
+	Deprecated bool
+}
+
+// UnmarshalJSON decodes an attribute and its cty type
+func (a *Attribute) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Type       json.RawMessage `json:"type"`
+		NestedType *NestedType     `json:"nested_type"`
+		Required   bool            `json:"required"`
+		Optional   bool            `json:"optional"`
+		Computed   bool            `json:"computed"`
+		Deprecated bool            `json:"deprecated"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*a = Attribute{
+		NestedType: raw.NestedType,
+		Required:   raw.Required,
+		Optional:   raw.Optional,
+		Computed:   raw.Computed,
+		Deprecated: raw.Deprecated,
+	}
+	if raw.NestedType != nil {
+		return nil
+	}
+	if len(raw.Type) == 0 {
+		return fmt.Errorf("attribute has neither a type nor a nested type")
+	}
+	t, err := ctyjson.UnmarshalType(raw.Type)
+	if err != nil {
+		return fmt.Errorf("invalid attribute type %s: %w", raw.Type, err)
+	}
+	a.Type = t
+	return nil
+}
+
+// NestedType is the schema of an attribute holding objects, as used by the v5 provider
+// for what were nested blocks in v4
+type NestedType struct {
+	Attributes  map[string]*Attribute `json:"attributes"`
+	NestingMode string                `json:"nesting_mode"`
+}
+
+// NestedBlock is the schema of a nested block type
+type NestedBlock struct {
+	NestingMode string `json:"nesting_mode"`
+	Block       *Block `json:"block"`
+	MinItems    int    `json:"min_items"`
+	MaxItems    int    `json:"max_items"`
+}
+
+// Load reads the Cloudflare provider schema from a `terraform providers schema -json` document
+func Load(path string) (*Provider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema %s: %w", path, err)
+	}
+	provider, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load schema %s: %w", path, err)
+	}
+	return provider, nil
+}
+
+// Parse parses a `terraform providers schema -json` document and returns the schema of
+// the Cloudflare provider. A document with a single provider is accepted whatever its source.
+func Parse(data []byte) (*Provider, error) {
+	var doc struct {
+		FormatVersion   string               `json:"format_version"`
+		ProviderSchemas map[string]*Provider `json:"provider_schemas"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid schema document: %w", err)
+	}
+	if doc.FormatVersion == "" || !strings.HasPrefix(doc.FormatVersion, "1.") {
+		return nil, fmt.Errorf("unsupported schema format version %q: expected the output of `terraform providers schema -json`", doc.FormatVersion)
+	}
+
+	var provider *Provider
+	var sources []string
+	for source, p := range doc.ProviderSchemas {
+		sources = append(sources, source)
+		if strings.HasSuffix(source, cloudflareProvider) {
+			provider = p
+		}
+	}
+	if provider == nil && len(doc.ProviderSchemas) == 1 {
+		provider = doc.ProviderSchemas[sources[0]]
+	}
+	if provider == nil {
+		sort.Strings(sources)
+		return nil, fmt.Errorf("no Cloudflare provider schema found (providers: %s)", strings.Join(sources, ", "))
+	}
+
+	return provider, nil
+}
+
+// ReadOnly reports whether the attribute is computed and cannot be set in the configuration
+func (a *Attribute) ReadOnly() bool {
+	return a.Computed && !a.Optional && !a.Required
+}
+
+// ImpliedType returns the type of the attribute value
+func (a *Attribute) ImpliedType() cty.Type {
+	if a.NestedType == nil {
+		return a.Type
+	}
+
+	attrTypes := make(map[string]cty.Type, len(a.NestedType.Attributes))
+	for name, attr := range a.NestedType.Attributes {
+		attrTypes[name] = attr.ImpliedType()
+	}
+	return wrap(a.NestedType.NestingMode, cty.Object(attrTypes))
+}
+
+// ImpliedType returns the type of the block value
+func (b *Block) ImpliedType() cty.Type {
+	attrTypes := make(map[string]cty.Type, len(b.Attributes)+len(b.BlockTypes))
+	for name, attr := range b.Attributes {
+		attrTypes[name] = attr.ImpliedType()
+	}
+	for name, nested := range b.BlockTypes {
+		attrTypes[name] = wrap(nested.NestingMode, nested.Block.ImpliedType())
+	}
+	return cty.Object(attrTypes)
+}
+
+// wrap returns the collection type of a nesting mode for an element type
+func wrap(nestingMode string, element cty.Type) cty.Type {
+	switch nestingMode {
+	case NestingList:
+		return cty.List(element)
+	case NestingSet:
+		return cty.Set(element)
+	case NestingMap:
+		return cty.Map(element)
+	default:
+		return element
+	}
+}
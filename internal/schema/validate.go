@@ -0,0 +1,461 @@
+package schema
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/tidwall/gjson"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+
+	"github.com/cloudflare/tf-migrate/internal/address"
+)
+
+// cloudflarePrefix is the type prefix of every resource and data source of the provider
+const cloudflarePrefix = "cloudflare_"
+
+// metaArguments are the arguments Terraform accepts in every resource and data block
+var metaArguments = map[string]bool{
+	"count":      true,
+	"for_each":   true,
+	"provider":   true,
+	"depends_on": true,
+}
+
+// metaBlocks are the nested blocks Terraform accepts in every resource and data block
+var metaBlocks = map[string]bool{
+	"lifecycle":   true,
+	"provisioner": true,
+	"connection":  true,
+}
+
+// Validator checks configuration and state files against a provider schema
+type Validator struct {
+	provider *Provider
+}
+
+// NewValidator creates a validator for the given provider schema
+func NewValidator(provider *Provider) *Validator {
+	return &Validator{provider: provider}
+}
+
+// ValidateConfig checks the attributes and nested blocks of every Cloudflare resource and
+// data block in a configuration file. Expressions that refer to variables or call functions
+// are not evaluated, so only literal values are type checked.
+func (v *Validator) ValidateConfig(filename string, src []byte) hcl.Diagnostics {
+	file, diags := hclsyntax.ParseConfig(src, filename, hcl.InitialPos)
+	if diags.HasErrors() {
+		return diags
+	}
+	body, ok := file.Body.(*hclsyntax.Body)
+	if !ok {
+		return nil
+	}
+
+	var result hcl.Diagnostics
+	for _, block := range body.Blocks {
+		if len(block.Labels) != 2 || !strings.HasPrefix(block.Labels[0], cloudflarePrefix) {
+			continue
+		}
+
+		var schemas map[string]*Resource
+		kind := "resource"
+		switch block.Type {
+		case "resource":
+			schemas = v.provider.Resources
+		case "data":
+			schemas = v.provider.DataSources
+			kind = "data source"
+		default:
+			continue
+		}
+
+		resource, ok := schemas[block.Labels[0]]
+		if !ok {
+			labelRange := block.LabelRanges[0]
+			result = append(result, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Unknown " + kind + " type",
+				Detail:   fmt.Sprintf("The target provider schema has no %s type %q.", kind, block.Labels[0]),
+				Subject:  &labelRange,
+			})
+			continue
+		}
+		result = append(result, validateBody(block.Body, resource.Block, true)...)
+	}
+	sortDiagnostics(result)
+	return result
+}
+
+// validateBody checks a block body against a block schema
+func validateBody(body *hclsyntax.Body, schema *Block, topLevel bool) hcl.Diagnostics {
+	if schema == nil {
+		schema = &Block{}
+	}
+
+	var diags hcl.Diagnostics
+	for name, attr := range body.Attributes {
+		if topLevel && metaArguments[name] {
+			continue
+		}
+		attrSchema, ok := schema.Attributes[name]
+		if !ok {
+			detail := fmt.Sprintf("An argument named %q is not expected here.", name)
+			if _, isBlock := schema.BlockTypes[name]; isBlock {
+				detail = fmt.Sprintf("%q is a block in the target schema: use %s { ... } instead of an argument.", name, name)
+			}
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Unsupported argument",
+				Detail:   detail,
+				Subject:  attr.NameRange.Ptr(),
+			})
+			continue
+		}
+		diags = append(diags, validateAttribute(name, attr.Expr, attrSchema, attr.NameRange)...)
+	}
+
+	counts := make(map[string]int)
+	dynamic := make(map[string]bool)
+	for _, block := range body.Blocks {
+		if topLevel && metaBlocks[block.Type] {
+			continue
+		}
+
+		name, typeRange := block.Type, block.TypeRange
+		content := block.Body
+		if block.Type == "dynamic" && len(block.Labels) == 1 {
+			name, typeRange = block.Labels[0], block.LabelRanges[0]
+			dynamic[name] = true
+			content = nil
+			for _, nested := range block.Body.Blocks {
+				if nested.Type == "content" {
+					content = nested.Body
+				}
+			}
+		}
+
+		nestedSchema, ok := schema.BlockTypes[name]
+		if !ok {
+			detail := fmt.Sprintf("Blocks of type %q are not expected here.", name)
+			if _, isAttr := schema.Attributes[name]; isAttr {
+				detail = fmt.Sprintf("%q is an attribute in the target schema: use %s = ... instead of a block.", name, name)
+			}
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Unsupported block type",
+				Detail:   detail,
+				Subject:  typeRange.Ptr(),
+			})
+			continue
+		}
+		counts[name]++
+		if content != nil {
+			diags = append(diags, validateBody(content, nestedSchema.Block, false)...)
+		}
+	}
+
+	for name, attrSchema := range schema.Attributes {
+		if _, ok := body.Attributes[name]; !ok && attrSchema.Required {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Missing required argument",
+				Detail:   fmt.Sprintf("The argument %q is required, but no definition was found.", name),
+				Subject:  openBraceRange(body),
+			})
+		}
+	}
+	for name, nested := range schema.BlockTypes {
+		if dynamic[name] {
+			continue
+		}
+		switch {
+		case counts[name] < nested.MinItems:
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Insufficient " + name + " blocks",
+				Detail:   fmt.Sprintf("At least %d %q blocks are required.", nested.MinItems, name),
+				Subject:  openBraceRange(body),
+			})
+		case nested.MaxItems > 0 && counts[name] > nested.MaxItems:
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Too many " + name + " blocks",
+				Detail:   fmt.Sprintf("No more than %d %q blocks are allowed.", nested.MaxItems, name),
+				Subject:  openBraceRange(body),
+			})
+		}
+	}
+	return diags
+}
+
+// validateAttribute checks an attribute expression against its schema
+func validateAttribute(name string, expr hclsyntax.Expression, schema *Attribute, nameRange hcl.Range) hcl.Diagnostics {
+	if schema.ReadOnly() {
+		return hcl.Diagnostics{{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid argument",
+			Detail:   fmt.Sprintf("The argument %q is read-only in the target schema and cannot be set.", name),
+			Subject:  nameRange.Ptr(),
+		}}
+	}
+
+	if schema.NestedType != nil {
+		return validateNestedExpr(expr, schema.NestedType)
+	}
+
+	// Only literal values can be checked without an evaluation context
+	if len(expr.Variables()) > 0 {
+		return nil
+	}
+	value, diags := expr.Value(nil)
+	if diags.HasErrors() || !value.IsWhollyKnown() {
+		return nil
+	}
+	if _, err := convert.Convert(value, schema.Type); err != nil {
+		return hcl.Diagnostics{{
+			Severity: hcl.DiagError,
+			Summary:  "Incorrect attribute value type",
+			Detail:   fmt.Sprintf("Inappropriate value for attribute %q: %s.", name, err),
+			Subject:  expr.Range().Ptr(),
+		}}
+	}
+	return nil
+}
+
+// validateNestedExpr checks an object, list of objects or map of objects written as a literal.
+// Other expressions, such as for expressions and references, are not checked.
+func validateNestedExpr(expr hclsyntax.Expression, schema *NestedType) hcl.Diagnostics {
+	switch schema.NestingMode {
+	case NestingList, NestingSet:
+		tuple, ok := expr.(*hclsyntax.TupleConsExpr)
+		if !ok {
+			return nil
+		}
+		var diags hcl.Diagnostics
+		for _, item := range tuple.Exprs {
+			diags = append(diags, validateObjectExpr(item, schema.Attributes)...)
+		}
+		return diags
+	case NestingMap:
+		object, ok := expr.(*hclsyntax.ObjectConsExpr)
+		if !ok {
+			return nil
+		}
+		var diags hcl.Diagnostics
+		for _, item := range object.Items {
+			diags = append(diags, validateObjectExpr(item.ValueExpr, schema.Attributes)...)
+		}
+		return diags
+	default:
+		return validateObjectExpr(expr, schema.Attributes)
+	}
+}
+
+// validateObjectExpr checks the keys and values of an object literal against nested attributes
+func validateObjectExpr(expr hclsyntax.Expression, attributes map[string]*Attribute) hcl.Diagnostics {
+	object, ok := expr.(*hclsyntax.ObjectConsExpr)
+	if !ok {
+		return nil
+	}
+
+	var diags hcl.Diagnostics
+	seen := make(map[string]bool)
+	for _, item := range object.Items {
+		name, ok := objectKey(item.KeyExpr)
+		if !ok {
+			// Computed keys may set any attribute, so required attributes cannot be checked
+			return diags
+		}
+		seen[name] = true
+
+		attrSchema, ok := attributes[name]
+		if !ok {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Unsupported attribute",
+				Detail:   fmt.Sprintf("An attribute named %q is not expected here.", name),
+				Subject:  item.KeyExpr.Range().Ptr(),
+			})
+			continue
+		}
+		diags = append(diags, validateAttribute(name, item.ValueExpr, attrSchema, item.KeyExpr.Range())...)
+	}
+
+	for name, attrSchema := range attributes {
+		if attrSchema.Required && !seen[name] {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Missing required attribute",
+				Detail:   fmt.Sprintf("The attribute %q is required, but no definition was found.", name),
+				Subject:  object.OpenRange.Ptr(),
+			})
+		}
+	}
+	return diags
+}
+
+// objectKey returns the name of an object key written as an identifier or a string literal
+func objectKey(expr hclsyntax.Expression) (string, bool) {
+	if keyword := hcl.ExprAsKeyword(expr); keyword != "" {
+		return keyword, true
+	}
+	if len(expr.Variables()) > 0 {
+		return "", false
+	}
+	value, diags := expr.Value(nil)
+	if diags.HasErrors() || !value.IsKnown() || value.IsNull() || !value.Type().Equals(cty.String) {
+		return "", false
+	}
+	return value.AsString(), true
+}
+
+// openBraceRange returns the range of the opening brace of a block body
+func openBraceRange(body *hclsyntax.Body) *hcl.Range {
+	r := body.SrcRange
+	r.End = r.Start
+	return &r
+}
+
+// ValidateState checks the attributes object of every Cloudflare resource instance in a
+// state file: attributes unknown to the schema, missing required attributes and values
+// that do not match the attribute type.
+func (v *Validator) ValidateState(filename string, stateJSON []byte) hcl.Diagnostics {
+	if !gjson.ValidBytes(stateJSON) {
+		return hcl.Diagnostics{{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid state file",
+			Detail:   "The state file is not valid JSON.",
+			Subject:  &hcl.Range{Filename: filename, Start: hcl.InitialPos, End: hcl.InitialPos},
+		}}
+	}
+
+	var diags hcl.Diagnostics
+	subject := func(offset int) *hcl.Range {
+		pos := hcl.Pos{Line: bytes.Count(stateJSON[:offset], []byte("\n")) + 1, Column: 1, Byte: offset}
+		return &hcl.Range{Filename: filename, Start: pos, End: pos}
+	}
+
+	gjson.GetBytes(stateJSON, "resources").ForEach(func(_, resource gjson.Result) bool {
+		addr := address.FromState(resource, gjson.Result{})
+		if !strings.HasPrefix(addr.Type, cloudflarePrefix) {
+			return true
+		}
+
+		schemas, kind := v.provider.Resources, "resource"
+		if addr.Mode == address.DataMode {
+			schemas, kind = v.provider.DataSources, "data source"
+		}
+		resourceSchema, ok := schemas[addr.Type]
+		if !ok {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Unknown " + kind + " type",
+				Detail:   fmt.Sprintf("%s: the target provider schema has no %s type %q.", addr, kind, addr.Type),
+				Subject:  subject(resource.Index),
+			})
+			return true
+		}
+		block := resourceSchema.Block
+		if block == nil {
+			block = &Block{}
+		}
+		impliedType := block.ImpliedType()
+
+		resource.Get("instances").ForEach(func(_, instance gjson.Result) bool {
+			instanceAddr := address.FromState(resource, instance.Get("index_key")).String()
+
+			if version := instance.Get("schema_version"); version.Exists() && int(version.Int()) > resourceSchema.Version {
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Unsupported schema version",
+					Detail:   fmt.Sprintf("%s: schema_version %d is newer than the target schema version %d.", instanceAddr, version.Int(), resourceSchema.Version),
+					Subject:  subject(version.Index),
+				})
+			}
+
+			attributes := instance.Get("attributes")
+			if !attributes.IsObject() {
+				return true
+			}
+			attributes.ForEach(func(key, value gjson.Result) bool {
+				name := key.String()
+				if !impliedType.HasAttribute(name) {
+					diags = append(diags, &hcl.Diagnostic{
+						Severity: hcl.DiagError,
+						Summary:  "Unsupported attribute in state",
+						Detail:   fmt.Sprintf("%s: the attribute %q is not in the target schema.", instanceAddr, name),
+						Subject:  subject(key.Index),
+					})
+					return true
+				}
+				if _, err := ctyjson.Unmarshal([]byte(value.Raw), impliedType.AttributeType(name)); err != nil {
+					diags = append(diags, &hcl.Diagnostic{
+						Severity: hcl.DiagError,
+						Summary:  "Incorrect attribute type in state",
+						Detail:   fmt.Sprintf("%s: attribute %q%s: %s.", instanceAddr, name, errorPath(err), err),
+						Subject:  subject(value.Index),
+					})
+				}
+				return true
+			})
+
+			for name, attrSchema := range block.Attributes {
+				if value := attributes.Get(name); attrSchema.Required && (!value.Exists() || value.Type == gjson.Null) {
+					diags = append(diags, &hcl.Diagnostic{
+						Severity: hcl.DiagError,
+						Summary:  "Missing required attribute in state",
+						Detail:   fmt.Sprintf("%s: the attribute %q is required but has no value.", instanceAddr, name),
+						Subject:  subject(attributes.Index),
+					})
+				}
+			}
+			return true
+		})
+		return true
+	})
+	sortDiagnostics(diags)
+	return diags
+}
+
+// errorPath renders the path of a cty error, e.g. .rules[0].action, or "" if it has none
+func errorPath(err error) string {
+	pathErr, ok := err.(cty.PathError)
+	if !ok {
+		return ""
+	}
+
+	var sb strings.Builder
+	for _, step := range pathErr.Path {
+		switch s := step.(type) {
+		case cty.GetAttrStep:
+			sb.WriteString("." + s.Name)
+		case cty.IndexStep:
+			if s.Key.Type() == cty.String {
+				sb.WriteString(fmt.Sprintf("[%q]", s.Key.AsString()))
+			} else if s.Key.Type() == cty.Number {
+				sb.WriteString("[" + s.Key.AsBigFloat().String() + "]")
+			}
+		}
+	}
+	return sb.String()
+}
+
+// sortDiagnostics orders diagnostics by position, since schemas are walked in map order
+func sortDiagnostics(diags hcl.Diagnostics) {
+	sort.SliceStable(diags, func(i, j int) bool {
+		a, b := diags[i].Subject, diags[j].Subject
+		if a == nil || b == nil {
+			return b != nil
+		}
+		if a.Start.Byte != b.Start.Byte {
+			return a.Start.Byte < b.Start.Byte
+		}
+		return diags[i].Detail < diags[j].Detail
+	})
+}
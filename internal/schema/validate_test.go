@@ -0,0 +1,257 @@
+package schema
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+)
+
+const testSchema = `{
+  "format_version": "1.0",
+  "provider_schemas": {
+    "registry.terraform.io/hashicorp/random": {
+      "resource_schemas": {}
+    },
+    "registry.terraform.io/cloudflare/cloudflare": {
+      "resource_schemas": {
+        "cloudflare_dns_record": {
+          "version": 1,
+          "block": {
+            "attributes": {
+              "id":      {"type": "string", "computed": true},
+              "zone_id": {"type": "string", "required": true},
+              "name":    {"type": "string", "required": true},
+              "ttl":     {"type": "number", "required": true},
+              "proxied": {"type": "bool", "optional": true},
+              "tags":    {"type": ["set", "string"], "optional": true},
+              "data": {
+                "nested_type": {
+                  "nesting_mode": "single",
+                  "attributes": {
+                    "priority": {"type": "number", "optional": true},
+                    "target":   {"type": "string", "required": true}
+                  }
+                },
+                "optional": true
+              },
+              "settings": {
+                "nested_type": {
+                  "nesting_mode": "list",
+                  "attributes": {
+                    "key": {"type": "string", "required": true}
+                  }
+                },
+                "optional": true
+              }
+            },
+            "block_types": {
+              "timeouts": {
+                "nesting_mode": "single",
+                "block": {"attributes": {"create": {"type": "string", "optional": true}}},
+                "max_items": 1
+              }
+            }
+          }
+        }
+      },
+      "data_source_schemas": {
+        "cloudflare_zones": {
+          "version": 0,
+          "block": {"attributes": {"name": {"type": "string", "optional": true}}}
+        }
+      }
+    }
+  }
+}`
+
+func loadTestSchema(t *testing.T) *Validator {
+	t.Helper()
+	provider, err := Parse([]byte(testSchema))
+	require.NoError(t, err)
+	return NewValidator(provider)
+}
+
+// details returns "line: summary: detail" for each diagnostic
+func details(diags hcl.Diagnostics) []string {
+	var result []string
+	for _, diag := range diags {
+		line := 0
+		if diag.Subject != nil {
+			line = diag.Subject.Start.Line
+		}
+		result = append(result, fmt.Sprintf("%d: %s: %s", line, diag.Summary, diag.Detail))
+	}
+	return result
+}
+
+func TestParse(t *testing.T) {
+	provider, err := Parse([]byte(testSchema))
+	require.NoError(t, err)
+
+	record := provider.Resources["cloudflare_dns_record"]
+	require.NotNil(t, record)
+	assert.Equal(t, 1, record.Version)
+	assert.Equal(t, cty.Set(cty.String), record.Block.Attributes["tags"].Type)
+	assert.True(t, record.Block.Attributes["id"].ReadOnly())
+	assert.Equal(t, cty.Object(map[string]cty.Type{"priority": cty.Number, "target": cty.String}), record.Block.Attributes["data"].ImpliedType())
+
+	_, err = Parse([]byte(`{"format_version": "1.0", "provider_schemas": {"registry.terraform.io/hashicorp/a": {}, "registry.terraform.io/hashicorp/b": {}}}`))
+	assert.ErrorContains(t, err, "no Cloudflare provider schema found")
+
+	_, err = Parse([]byte(`{"resource_schemas": {}}`))
+	assert.ErrorContains(t, err, "unsupported schema format version")
+}
+
+func TestValidateConfig(t *testing.T) {
+	v := loadTestSchema(t)
+
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{
+			name: "Valid resource",
+			input: `resource "cloudflare_dns_record" "www" {
+  count    = 2
+  zone_id  = var.zone_id
+  name     = "www"
+  ttl      = "300"
+  tags     = ["a"]
+  data     = { target = "example.com", priority = 10 }
+  settings = [{ key = "a" }, { key = "b" }]
+  timeouts {
+    create = "1m"
+  }
+  lifecycle {
+    ignore_changes = [ttl]
+  }
+}
+
+resource "random_id" "ignored" {
+  unknown = true
+}
+
+data "cloudflare_zones" "all" {
+  name = "example.com"
+}`,
+		},
+		{
+			name: "Leftover v4 attributes and blocks",
+			input: `resource "cloudflare_dns_record" "www" {
+  zone_id  = "z"
+  name     = "www"
+  ttl      = 1
+  value    = "1.2.3.4"
+  id       = "abc"
+  timeouts = {}
+  data {
+    target = "example.com"
+  }
+}`,
+			expected: []string{
+				`5: Unsupported argument: An argument named "value" is not expected here.`,
+				`6: Invalid argument: The argument "id" is read-only in the target schema and cannot be set.`,
+				`7: Unsupported argument: "timeouts" is a block in the target schema: use timeouts { ... } instead of an argument.`,
+				`8: Unsupported block type: "data" is an attribute in the target schema: use data = ... instead of a block.`,
+			},
+		},
+		{
+			name: "Missing required arguments and wrong types",
+			input: `resource "cloudflare_dns_record" "www" {
+  zone_id  = "z"
+  proxied  = "maybe"
+  data     = { priority = "high", weight = 1 }
+  settings = [{}]
+}
+
+resource "cloudflare_record" "old" {}`,
+			expected: []string{
+				`1: Missing required argument: The argument "name" is required, but no definition was found.`,
+				`1: Missing required argument: The argument "ttl" is required, but no definition was found.`,
+				`3: Incorrect attribute value type: Inappropriate value for attribute "proxied": a bool is required.`,
+				`4: Missing required attribute: The attribute "target" is required, but no definition was found.`,
+				`4: Incorrect attribute value type: Inappropriate value for attribute "priority": a number is required.`,
+				`4: Unsupported attribute: An attribute named "weight" is not expected here.`,
+				`5: Missing required attribute: The attribute "key" is required, but no definition was found.`,
+				`8: Unknown resource type: The target provider schema has no resource type "cloudflare_record".`,
+			},
+		},
+		{
+			name: "Dynamic blocks",
+			input: `resource "cloudflare_dns_record" "www" {
+  zone_id = "z"
+  name    = "www"
+  ttl     = 1
+  dynamic "timeouts" {
+    for_each = var.timeouts
+    content {
+      delete = timeouts.value
+    }
+  }
+  dynamic "settings" {
+    for_each = var.settings
+    content {
+      key = settings.value
+    }
+  }
+}`,
+			expected: []string{
+				`8: Unsupported argument: An argument named "delete" is not expected here.`,
+				`11: Unsupported block type: "settings" is an attribute in the target schema: use settings = ... instead of a block.`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, details(v.ValidateConfig("main.tf", []byte(tt.input))))
+		})
+	}
+}
+
+func TestValidateState(t *testing.T) {
+	v := loadTestSchema(t)
+
+	state := `{
+  "version": 4,
+  "resources": [
+    {
+      "mode": "managed",
+      "type": "cloudflare_dns_record",
+      "name": "www",
+      "instances": [
+        {
+          "index_key": "a",
+          "schema_version": 2,
+          "attributes": {
+            "id": "abc", "zone_id": "z", "name": "www", "ttl": 300, "proxied": null,
+            "value": "1.2.3.4",
+            "data": {"target": "example.com", "priority": "high"},
+            "settings": null, "tags": null, "timeouts": null
+          }
+        },
+        {
+          "index_key": "b",
+          "attributes": {"id": "abc", "zone_id": "z", "name": null, "ttl": 1, "tags": ["x"]}
+        }
+      ]
+    },
+    {"mode": "data", "type": "cloudflare_zones", "name": "all", "instances": [{"attributes": {"name": "example.com"}}]},
+    {"mode": "managed", "type": "random_id", "name": "ignored", "instances": [{"attributes": {"x": 1}}]},
+    {"mode": "managed", "type": "cloudflare_record", "name": "old", "instances": []}
+  ]
+}`
+
+	assert.Equal(t, []string{
+		`11: Unsupported schema version: cloudflare_dns_record.www["a"]: schema_version 2 is newer than the target schema version 1.`,
+		`14: Unsupported attribute in state: cloudflare_dns_record.www["a"]: the attribute "value" is not in the target schema.`,
+		`15: Incorrect attribute type in state: cloudflare_dns_record.www["a"]: attribute "data".priority: a number is required.`,
+		`21: Missing required attribute in state: cloudflare_dns_record.www["b"]: the attribute "name" is required but has no value.`,
+		`27: Unknown resource type: cloudflare_record.old: the target provider schema has no resource type "cloudflare_record".`,
+	}, details(v.ValidateState("terraform.tfstate", []byte(state))))
+}
@@ -0,0 +1,253 @@
+// Package staging writes the outputs of a migration as a single change. Outputs are first
+// written to a staging directory, and only moved over the files they replace once all of
+// them are ready, so a run that fails leaves every file as it was.
+package staging
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// rename moves a file over another, replaced in tests to simulate failures
+var rename = os.Rename
+
+// Transaction holds the staged outputs of a run
+type Transaction struct {
+	dir   string
+	files []*file
+	index map[string]*file
+}
+
+// file is a staged output and the file it replaces
+type file struct {
+	target string
+	staged string
+
+	// Set while committing: the new content next to the target, and a copy of the
+	// original content to restore if the commit fails
+	next     string
+	original string
+}
+
+// New creates a transaction with an empty staging directory in the system temporary directory
+func New() (*Transaction, error) {
+	dir, err := os.MkdirTemp("", "tf-migrate-staging-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	return &Transaction{dir: dir, index: make(map[string]*file)}, nil
+}
+
+// Write stages the new content of a file. Staging the same file again replaces its
+// content.
+func (t *Transaction) Write(path string, content []byte) error {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	f, ok := t.index[path]
+	if !ok {
+		f = &file{
+			target: path,
+			staged: filepath.Join(t.dir, fmt.Sprintf("%03d-%s", len(t.files)+1, filepath.Base(path))),
+		}
+	}
+	if err := os.WriteFile(f.staged, content, 0600); err != nil {
+		return fmt.Errorf("failed to stage %s: %w", path, err)
+	}
+	if !ok {
+		t.index[path] = f
+		t.files = append(t.files, f)
+	}
+	return nil
+}
+
+// ReadFile returns the staged content of a file, or its current content if it is not staged
+func (t *Transaction) ReadFile(path string) ([]byte, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	if f, ok := t.index[abs]; ok {
+		return os.ReadFile(f.staged)
+	}
+	return os.ReadFile(path)
+}
+
+// Paths returns the paths of the staged files, in the order they were first staged
+func (t *Transaction) Paths() []string {
+	paths := make([]string, len(t.files))
+	for i, f := range t.files {
+		paths[i] = f.target
+	}
+	return paths
+}
+
+// Commit replaces every target with its staged content. It runs in two steps: the new
+// content and a copy of the original are first written next to each target, calling
+// before for every target, and then each new file is renamed over its target. Each rename
+// is atomic, and if one fails the files already replaced are restored, so either every
+// target is replaced or none is. The staging directory is removed afterwards.
+func (t *Transaction) Commit(before func(path string) error) (err error) {
+	var created []string
+	committed := false
+	defer func() {
+		err = errors.Join(err, t.removeSiblings())
+		if !committed {
+			err = errors.Join(err, removeDirs(created))
+		}
+		err = errors.Join(err, t.Discard())
+	}()
+
+	for _, f := range t.files {
+		dirs, err := mkdirAll(filepath.Dir(f.target))
+		created = append(created, dirs...)
+		if err == nil {
+			err = t.prepare(f)
+		}
+		if err == nil && before != nil {
+			err = before(f.target)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	for i, f := range t.files {
+		if err := rename(f.next, f.target); err != nil {
+			err = fmt.Errorf("failed to replace %s: %w", f.target, err)
+			return errors.Join(err, t.restore(t.files[:i]))
+		}
+		f.next = ""
+	}
+	committed = true
+	return nil
+}
+
+// prepare writes the new content of a file next to its target, with the permissions of
+// the target, and keeps a copy of the original content
+func (t *Transaction) prepare(f *file) error {
+	content, err := os.ReadFile(f.staged)
+	if err != nil {
+		return fmt.Errorf("failed to read staged %s: %w", f.target, err)
+	}
+	mode := fs.FileMode(0644)
+	original, err := os.ReadFile(f.target)
+	switch {
+	case err == nil:
+		info, statErr := os.Stat(f.target)
+		if statErr != nil {
+			return statErr
+		}
+		mode = info.Mode().Perm()
+		if f.original, err = writeSibling(f.target, ".orig", original, mode); err != nil {
+			return err
+		}
+	case !errors.Is(err, fs.ErrNotExist):
+		return fmt.Errorf("failed to read %s: %w", f.target, err)
+	}
+	f.next, err = writeSibling(f.target, ".new", content, mode)
+	return err
+}
+
+// restore puts back the original content of files already replaced
+func (t *Transaction) restore(files []*file) error {
+	var errs []error
+	for i := len(files) - 1; i >= 0; i-- {
+		f := files[i]
+		var err error
+		if f.original != "" {
+			err = rename(f.original, f.target)
+			f.original = ""
+		} else {
+			err = os.Remove(f.target)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to restore %s: %w", f.target, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// removeSiblings removes the files written next to the targets that were not renamed
+func (t *Transaction) removeSiblings() error {
+	var errs []error
+	for _, f := range t.files {
+		for _, path := range []string{f.next, f.original} {
+			if path == "" {
+				continue
+			}
+			if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				errs = append(errs, err)
+			}
+		}
+		f.next, f.original = "", ""
+	}
+	return errors.Join(errs...)
+}
+
+// Discard removes the staging directory without changing any target
+func (t *Transaction) Discard() error {
+	if err := os.RemoveAll(t.dir); err != nil {
+		return fmt.Errorf("failed to remove staging directory: %w", err)
+	}
+	return nil
+}
+
+// writeSibling writes content to a new hidden file in the directory of path, so it can
+// be renamed over path atomically
+func writeSibling(path, suffix string, content []byte, mode fs.FileMode) (string, error) {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*"+suffix)
+	if err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	name := f.Name()
+	_, err = f.Write(content)
+	if err == nil {
+		err = f.Chmod(mode)
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(name)
+		return "", fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return name, nil
+}
+
+// mkdirAll creates a directory and its missing parents, and returns the directories it
+// created, outermost first
+func mkdirAll(dir string) ([]string, error) {
+	var missing []string
+	for d := dir; ; d = filepath.Dir(d) {
+		if _, err := os.Stat(d); err == nil {
+			break
+		}
+		missing = append([]string{d}, missing...)
+		if filepath.Dir(d) == d {
+			break
+		}
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+	return missing, nil
+}
+
+// removeDirs removes directories created for a commit that failed, innermost first
+func removeDirs(dirs []string) error {
+	var errs []error
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if err := os.Remove(dirs[i]); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
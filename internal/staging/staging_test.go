@@ -0,0 +1,133 @@
+package staging
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/tf-migrate/internal/testhelpers"
+)
+
+// listFiles returns the paths of all files under dir, relative to it
+func listFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	var files []string
+	require.NoError(t, filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		files = append(files, filepath.ToSlash(rel))
+		return err
+	}))
+	return files
+}
+
+// stage creates a transaction that rewrites main.tf and the state and creates a file in
+// a new directory
+func stage(t *testing.T, dir string) *Transaction {
+	t.Helper()
+	testhelpers.WriteFile(t, filepath.Join(dir, "main.tf"), "original\n")
+	testhelpers.WriteFile(t, filepath.Join(dir, "terraform.tfstate"), "{}\n")
+	require.NoError(t, os.Chmod(filepath.Join(dir, "terraform.tfstate"), 0600))
+
+	tx, err := New()
+	require.NoError(t, err)
+	require.NoError(t, tx.Write(filepath.Join(dir, "main.tf"), []byte("first\n")))
+	require.NoError(t, tx.Write(filepath.Join(dir, "terraform.tfstate"), []byte("{\"serial\": 2}\n")))
+	require.NoError(t, tx.Write(filepath.Join(dir, "modules", "dns", "moved.tf"), []byte("moved {}\n")))
+	require.NoError(t, tx.Write(filepath.Join(dir, "main.tf"), []byte("migrated\n")))
+	return tx
+}
+
+func TestCommit(t *testing.T) {
+	dir := t.TempDir()
+	tx := stage(t, dir)
+
+	content, err := tx.ReadFile(filepath.Join(dir, "main.tf"))
+	require.NoError(t, err)
+	assert.Equal(t, "migrated\n", string(content), "staged content is read back")
+	assert.Equal(t, "original\n", testhelpers.ReadFile(t, filepath.Join(dir, "main.tf")), "nothing is written before the commit")
+	assert.Equal(t, []string{
+		filepath.Join(dir, "main.tf"),
+		filepath.Join(dir, "terraform.tfstate"),
+		filepath.Join(dir, "modules", "dns", "moved.tf"),
+	}, tx.Paths())
+
+	var before []string
+	require.NoError(t, tx.Commit(func(path string) error {
+		before = append(before, path)
+		return nil
+	}))
+	assert.Equal(t, tx.Paths(), before)
+
+	assert.Equal(t, "migrated\n", testhelpers.ReadFile(t, filepath.Join(dir, "main.tf")))
+	assert.Equal(t, "{\"serial\": 2}\n", testhelpers.ReadFile(t, filepath.Join(dir, "terraform.tfstate")))
+	assert.Equal(t, "moved {}\n", testhelpers.ReadFile(t, filepath.Join(dir, "modules", "dns", "moved.tf")))
+	assert.Equal(t, []string{"main.tf", "modules/dns/moved.tf", "terraform.tfstate"}, listFiles(t, dir))
+
+	info, err := os.Stat(filepath.Join(dir, "terraform.tfstate"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm(), "permissions of replaced files are kept")
+	assert.NoDirExists(t, tx.dir)
+}
+
+func TestCommitFailureLeavesFilesUntouched(t *testing.T) {
+	tests := []struct {
+		name   string
+		before func(path string) error
+		rename func(from, to string) error
+	}{
+		{
+			name: "Preparing a file fails",
+			before: func(path string) error {
+				if filepath.Base(path) == "moved.tf" {
+					return errors.New("disk full")
+				}
+				return nil
+			},
+		},
+		{
+			name: "Replacing a file fails",
+			rename: func(from, to string) error {
+				if filepath.Base(to) == "moved.tf" {
+					return errors.New("disk full")
+				}
+				return os.Rename(from, to)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.rename != nil {
+				rename = tt.rename
+				t.Cleanup(func() { rename = os.Rename })
+			}
+			dir := t.TempDir()
+			tx := stage(t, dir)
+
+			err := tx.Commit(tt.before)
+			assert.ErrorContains(t, err, "disk full")
+
+			assert.Equal(t, "original\n", testhelpers.ReadFile(t, filepath.Join(dir, "main.tf")))
+			assert.Equal(t, "{}\n", testhelpers.ReadFile(t, filepath.Join(dir, "terraform.tfstate")))
+			assert.Equal(t, []string{"main.tf", "terraform.tfstate"}, listFiles(t, dir), "no file is left behind")
+			assert.NoDirExists(t, filepath.Join(dir, "modules"))
+			assert.NoDirExists(t, tx.dir)
+		})
+	}
+}
+
+func TestDiscard(t *testing.T) {
+	dir := t.TempDir()
+	tx := stage(t, dir)
+
+	require.NoError(t, tx.Discard())
+	assert.Equal(t, "original\n", testhelpers.ReadFile(t, filepath.Join(dir, "main.tf")))
+	assert.Equal(t, []string{"main.tf", "terraform.tfstate"}, listFiles(t, dir))
+	assert.NoDirExists(t, tx.dir)
+}
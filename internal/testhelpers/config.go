@@ -8,6 +8,7 @@ import (
 	"github.com/hashicorp/hcl/v2/hclwrite"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
 
 	"github.com/cloudflare/tf-migrate/internal/transform"
 )
@@ -17,6 +18,12 @@ type ConfigTestCase struct {
 	Name     string
 	Input    string
 	Expected string
+	// State is the state JSON seen by migrators that read values from the state, see MultiInstanceState
+	State string
+	// Diagnostics lists text expected in the summary or detail of the diagnostics raised, in order
+	Diagnostics []string
+	// Options are the migrator options from the project configuration file, see transform.Context.Options
+	Options map[string]map[string]cty.Value
 }
 
 // runConfigTransformTest runs a single configuration transformation test
@@ -33,9 +40,11 @@ func runConfigTransformTest(t *testing.T, tt ConfigTestCase, migrator transform.
 
 	// Step 3: Create context with preprocessed content
 	ctx := &transform.Context{
-		Content:  []byte(processedContent),
-		Filename: "test.tf",
-		CFGFile:  file,
+		Content:   []byte(processedContent),
+		Filename:  "test.tf",
+		CFGFile:   file,
+		StateJSON: tt.State,
+		Options:   tt.Options,
 	}
 
 	// Step 4: Transform using HCL CFGFile
@@ -73,6 +82,7 @@ func runConfigTransformTest(t *testing.T, tt ConfigTestCase, migrator transform.
 	expectedOutput = strings.TrimSpace(expectedOutput)
 
 	assert.Equal(t, expectedOutput, output)
+	assertDiagnostics(t, tt.Diagnostics, ctx.Diagnostics)
 }
 
 // RunConfigTransformTests runs multiple configuration transformation tests
@@ -83,3 +93,61 @@ func RunConfigTransformTests(t *testing.T, tests []ConfigTestCase, migrator tran
 		})
 	}
 }
+
+// runDataSourceConfigTransformTest runs a single data source configuration transformation test
+func runDataSourceConfigTransformTest(t *testing.T, tt ConfigTestCase, migrator transform.DataSourceTransformer) {
+	t.Helper()
+
+	file, diags := hclwrite.ParseConfig([]byte(tt.Input), "test.tf", hcl.InitialPos)
+	require.False(t, diags.HasErrors(), "Failed to parse HCL: %v", diags)
+
+	ctx := &transform.Context{
+		Content:   []byte(tt.Input),
+		Filename:  "test.tf",
+		CFGFile:   file,
+		StateJSON: tt.State,
+	}
+
+	body := file.Body()
+	for _, block := range body.Blocks() {
+		if block.Type() == "data" && len(block.Labels()) >= 2 && migrator.CanHandle(block.Labels()[0]) {
+			result, err := migrator.TransformConfig(ctx, block)
+			assert.NoError(t, err, "Failed to transform data source")
+
+			if result != nil && result.RemoveOriginal {
+				body.RemoveBlock(block)
+				for _, newBlock := range result.Blocks {
+					body.AppendBlock(newBlock)
+				}
+			}
+		}
+	}
+
+	output := strings.TrimSpace(NormalizeHCLWhitespace(string(hclwrite.Format(file.Bytes()))))
+
+	expectedFile, diags := hclwrite.ParseConfig([]byte(tt.Expected), "expected.tf", hcl.InitialPos)
+	require.False(t, diags.HasErrors(), "Failed to parse expected HCL: %v", diags)
+	expectedOutput := strings.TrimSpace(NormalizeHCLWhitespace(string(hclwrite.Format(expectedFile.Bytes()))))
+
+	assert.Equal(t, expectedOutput, output)
+	assertDiagnostics(t, tt.Diagnostics, ctx.Diagnostics)
+}
+
+// RunDataSourceConfigTransformTests runs multiple data source configuration transformation tests
+func RunDataSourceConfigTransformTests(t *testing.T, tests []ConfigTestCase, migrator transform.DataSourceTransformer) {
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			runDataSourceConfigTransformTest(t, tt, migrator)
+		})
+	}
+}
+
+// assertDiagnostics checks that each diagnostic mentions the expected text
+func assertDiagnostics(t *testing.T, expected []string, diags hcl.Diagnostics) {
+	t.Helper()
+
+	require.Len(t, diags, len(expected), "Unexpected diagnostics: %v", diags)
+	for i, text := range expected {
+		assert.Contains(t, diags[i].Summary+": "+diags[i].Detail, text)
+	}
+}
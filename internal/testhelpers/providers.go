@@ -0,0 +1,60 @@
+package testhelpers
+
+import (
+	"sort"
+
+	"github.com/cloudflare/tf-migrate/internal/transform"
+)
+
+// TypeMigrator is a resource migrator that only reports the type it migrates to
+type TypeMigrator struct {
+	transform.ResourceTransformer
+	NewType string
+}
+
+func (m *TypeMigrator) GetResourceType() string { return m.NewType }
+
+// TypeDataSourceMigrator is a data source migrator that only reports the type it migrates to
+type TypeDataSourceMigrator struct {
+	transform.DataSourceTransformer
+	NewType string
+}
+
+func (m *TypeDataSourceMigrator) GetDataSourceType() string { return m.NewType }
+
+// NewMigrationProvider returns a provider of the given v4 to v5 migrators, keyed by the
+// resource or data source type they migrate from. GetAllMigrators returns the resource
+// migrators sorted by that type.
+func NewMigrationProvider(resources map[string]transform.ResourceTransformer, dataSources map[string]transform.DataSourceTransformer) transform.MigrationProvider {
+	oldTypes := make([]string, 0, len(resources))
+	for oldType := range resources {
+		oldTypes = append(oldTypes, oldType)
+	}
+	sort.Strings(oldTypes)
+	all := make([]transform.ResourceTransformer, 0, len(oldTypes))
+	for _, oldType := range oldTypes {
+		all = append(all, resources[oldType])
+	}
+
+	return transform.NewMigrationProviderWithDataSources(
+		func(resourceType, source, target string) transform.ResourceTransformer {
+			if source == "v4" && target == "v5" {
+				return resources[resourceType]
+			}
+			return nil
+		},
+		func(source, target string, _ ...string) []transform.ResourceTransformer {
+			if source == "v4" && target == "v5" {
+				return all
+			}
+			return nil
+		},
+		func(dataSourceType, source, target string) transform.DataSourceTransformer {
+			if source == "v4" && target == "v5" {
+				return dataSources[dataSourceType]
+			}
+			return nil
+		},
+		nil,
+	)
+}
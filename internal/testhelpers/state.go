@@ -137,3 +137,59 @@ func RunStateTransformTests(t *testing.T, tests []StateTestCase, migrator transf
 		})
 	}
 }
+
+// StateInstanceFixture is a resource instance of a multi-instance state fixture
+type StateInstanceFixture struct {
+	// Module is the module instance path, e.g. module.dns["prod"]. Empty for the root module.
+	Module string
+	// IndexKey is the count index (int) or for_each key (string), nil for a single instance
+	IndexKey interface{}
+	// Attributes are the instance attributes
+	Attributes map[string]interface{}
+}
+
+// MultiInstanceState builds a state file with one managed resource of the given type and
+// name per module instance, holding the given instances:
+//
+//	testhelpers.MultiInstanceState("cloudflare_zone_dnssec", "this",
+//		testhelpers.StateInstanceFixture{IndexKey: "eu", Attributes: map[string]interface{}{"status": "active"}},
+//		testhelpers.StateInstanceFixture{IndexKey: "us", Attributes: map[string]interface{}{"status": "disabled"}},
+//	)
+func MultiInstanceState(resourceType, name string, instances ...StateInstanceFixture) string {
+	var resources []map[string]interface{}
+	byModule := make(map[string]map[string]interface{})
+	for _, instance := range instances {
+		resource, ok := byModule[instance.Module]
+		if !ok {
+			resource = map[string]interface{}{
+				"mode":      "managed",
+				"type":      resourceType,
+				"name":      name,
+				"provider":  `provider["registry.terraform.io/cloudflare/cloudflare"]`,
+				"instances": []interface{}{},
+			}
+			if instance.Module != "" {
+				resource["module"] = instance.Module
+			}
+			byModule[instance.Module] = resource
+			resources = append(resources, resource)
+		}
+
+		entry := map[string]interface{}{
+			"schema_version": 0,
+			"attributes":     instance.Attributes,
+		}
+		if instance.IndexKey != nil {
+			entry["index_key"] = instance.IndexKey
+		}
+		resource["instances"] = append(resource["instances"].([]interface{}), entry)
+	}
+
+	state, _ := json.Marshal(map[string]interface{}{
+		"version":   4,
+		"serial":    1,
+		"lineage":   "00000000-0000-0000-0000-000000000000",
+		"resources": resources,
+	})
+	return string(state)
+}
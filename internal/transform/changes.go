@@ -0,0 +1,59 @@
+package transform
+
+import (
+	"github.com/hashicorp/hcl/v2"
+)
+
+// MetadataResourceChanges is the Metadata key under which handlers record
+// the resources they touched, as a []ResourceChange
+const MetadataResourceChanges = "resource_changes"
+
+// FollowUp classifies the work a user has to do after a migration.
+// Migrators attach it to a diagnostic's Extra field.
+type FollowUp string
+
+const (
+	// FollowUpRefresh means the resource will be corrected by `terraform refresh`
+	FollowUpRefresh FollowUp = "refresh"
+	// FollowUpManual means the resource needs to be fixed by hand
+	FollowUpManual FollowUp = "manual"
+)
+
+// ResourceChange records a single resource (or resource instance for state)
+// touched by a transformation handler
+type ResourceChange struct {
+	OldType    string
+	NewType    string
+	OldAddress string
+	// NewAddress is empty when the resource was removed
+	NewAddress string
+	// Migrator is the migrator implementation used, empty if none was registered
+	Migrator string
+	// Changed reports whether the migrator changed the configuration block
+	Changed     bool
+	Diagnostics hcl.Diagnostics
+}
+
+// RecordResourceChange appends a change to the context metadata
+func (c *Context) RecordResourceChange(change ResourceChange) {
+	if c.Metadata == nil {
+		c.Metadata = make(map[string]interface{})
+	}
+	changes, _ := c.Metadata[MetadataResourceChanges].([]ResourceChange)
+	c.Metadata[MetadataResourceChanges] = append(changes, change)
+}
+
+// ResourceChanges returns the changes recorded on the context
+func (c *Context) ResourceChanges() []ResourceChange {
+	changes, _ := c.Metadata[MetadataResourceChanges].([]ResourceChange)
+	return changes
+}
+
+// FollowUpFor returns the follow-up classification of a diagnostic.
+// Warnings and errors without an explicit classification need manual work.
+func FollowUpFor(diag *hcl.Diagnostic) FollowUp {
+	if followUp, ok := diag.Extra.(FollowUp); ok {
+		return followUp
+	}
+	return FollowUpManual
+}
@@ -2,11 +2,14 @@
 package hcl
 
 import (
+	"sort"
 	"strings"
 
 	"github.com/hashicorp/hcl/v2/hclsyntax"
 	"github.com/hashicorp/hcl/v2/hclwrite"
 	"github.com/zclconf/go-cty/cty"
+
+	"github.com/cloudflare/tf-migrate/internal/hcl"
 )
 
 // ArrayElement represents a parsed element from an array
@@ -220,6 +223,55 @@ func extractStringFieldFromBlock(block *hclwrite.Block, fieldName string) (strin
 	return "", false
 }
 
+// commentedBlockItem builds the object of a block item with the comments of the block and
+// of its attributes. Fields are sorted by name, as in objects written from cty values.
+func commentedBlockItem(block *hclwrite.Block, itemAttrs map[string]cty.Value) hcl.CommentedTupleElem {
+	names := make([]string, 0, len(itemAttrs))
+	for name := range itemAttrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	attrs := make([]hcl.CommentedObjectAttr, len(names))
+	for i, name := range names {
+		attrs[i].Name = hclwrite.TokensForIdentifier(name)
+		attrs[i].Value = hclwrite.TokensForValue(itemAttrs[name])
+		attrs[i].LeadComments, attrs[i].LineComments = hcl.AttributeComments(block.Body(), name)
+	}
+	return hcl.CommentedTupleElem{
+		Value:        hcl.TokensForCommentedObject(attrs),
+		LeadComments: hcl.LeadComments(block),
+	}
+}
+
+// dynamicBlockItem builds the object of the items of a dynamic block from the expressions
+// in its content block. Fields the content block does not set are null.
+func dynamicBlockItem(content *hclwrite.Block, fields []string) hclwrite.Tokens {
+	names := append([]string(nil), fields...)
+	sort.Strings(names)
+
+	attrs := make([]hcl.CommentedObjectAttr, len(names))
+	for i, name := range names {
+		attrs[i].Name = hclwrite.TokensForIdentifier(name)
+		attrs[i].Value = hclwrite.TokensForValue(cty.NullVal(cty.String))
+		if attr := content.Body().GetAttribute(name); attr != nil {
+			attrs[i].Value = attr.Expr().BuildTokens(nil)
+			attrs[i].LeadComments, attrs[i].LineComments = hcl.AttributeComments(content.Body(), name)
+		}
+	}
+	return hcl.TokensForCommentedObject(attrs)
+}
+
+// hasComments reports whether any attribute of a block item has comments
+func hasComments(block *hclwrite.Block, itemAttrs map[string]cty.Value) bool {
+	for name := range itemAttrs {
+		if lead, line := hcl.AttributeComments(block.Body(), name); len(lead) > 0 || len(line) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
 // extractStringFieldFromArrayElement extracts a string value from an array element
 func extractStringFieldFromArrayElement(elem ArrayElement) (string, bool) {
 	if elem.Type == "string" {
@@ -249,6 +301,9 @@ func extractStringFieldFromArrayElement(elem ArrayElement) (string, bool) {
 //   - optionalFields: Names of optional fields that should be null if not present (e.g., ["description"])
 //   - blocksFirst: If true, blocks are processed first (to match API order), otherwise array elements first
 //
+// Dynamic blocks of blockType become for expressions building their objects from the
+// expressions in their content blocks, joined to the other items with concat.
+//
 // # Returns true if any modifications were made
 //
 // Example usage for Zero Trust List migration:
@@ -301,12 +356,29 @@ func MergeAttributeAndBlocksToObjectArray(
 	modified := false
 	var allItems []cty.Value
 
-	// Collect items from blocks
+	// Collect items from blocks, with their comments
 	var blocksToRemove []*hclwrite.Block
 	var blockItems []cty.Value
+	var blockElems []hcl.CommentedTupleElem
+	var blockListItems []listItem
+	commented := false
+	dynamic := false
 
 	for _, block := range body.Blocks() {
 		if block.Type() != blockType {
+			if d, ok := asDynamicBlock(block); ok && d.blockType == blockType {
+				fields := append([]string{primaryField}, optionalFields...)
+				blockListItems = append(blockListItems, listItem{
+					elem: hcl.CommentedTupleElem{
+						Value:        d.list(dynamicBlockItem(d.content, fields)),
+						LeadComments: hcl.LeadComments(block),
+					},
+					dynamic: block,
+				})
+				blocksToRemove = append(blocksToRemove, block)
+				dynamic = true
+				modified = true
+			}
 			continue
 		}
 
@@ -327,7 +399,11 @@ func MergeAttributeAndBlocksToObjectArray(
 		}
 
 		if len(itemAttrs) > 0 {
+			elem := commentedBlockItem(block, itemAttrs)
+			commented = commented || len(elem.LeadComments) > 0 || hasComments(block, itemAttrs)
 			blockItems = append(blockItems, cty.ObjectVal(itemAttrs))
+			blockElems = append(blockElems, elem)
+			blockListItems = append(blockListItems, listItem{elem: elem})
 			modified = true
 		}
 		blocksToRemove = append(blocksToRemove, block)
@@ -370,6 +446,34 @@ func MergeAttributeAndBlocksToObjectArray(
 		allItems = append(allItems, blockItems...)
 	}
 
+	// Dynamic blocks are written as for expressions next to the other items
+	if dynamic {
+		arrayListItems := make([]listItem, len(arrayItems))
+		for i, item := range arrayItems {
+			arrayListItems[i] = listItem{elem: hcl.CommentedTupleElem{Value: hclwrite.TokensForValue(item)}}
+		}
+		items := append(blockListItems, arrayListItems...)
+		if !blocksFirst {
+			items = append(arrayListItems, blockListItems...)
+		}
+		setList(body, outputAttrName, items)
+		return modified
+	}
+
+	// Comments of the blocks are written into the objects built from them
+	if commented {
+		arrayElems := make([]hcl.CommentedTupleElem, len(arrayItems))
+		for i, item := range arrayItems {
+			arrayElems[i] = hcl.CommentedTupleElem{Value: hclwrite.TokensForValue(item)}
+		}
+		elems := append(blockElems, arrayElems...)
+		if !blocksFirst {
+			elems = append(arrayElems, blockElems...)
+		}
+		body.SetAttributeRaw(outputAttrName, hcl.TokensForCommentedTuple(elems))
+		return modified
+	}
+
 	// Create the output attribute with all items as objects
 	// Use TupleVal to preserve order (SetVal would reorder elements)
 	if len(allItems) > 0 {
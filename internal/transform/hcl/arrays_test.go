@@ -403,6 +403,91 @@ resource "test" "example" {
     value       = "val2"
   }]
 }
+`,
+			expectModified: true,
+		},
+		{
+			name: "Keep comments of blocks",
+			input: `
+resource "test" "example" {
+  items = ["val1"]
+
+  # Office network
+  items_with_description {
+    value       = "10.0.0.0/8" # all floors
+    # Ticket NET-12
+    description = "Office"
+  }
+  items_with_description {
+    value = "172.16.0.0/12"
+  }
+}`,
+			arrayAttrName:  "items",
+			blockType:      "items_with_description",
+			outputAttrName: "items",
+			primaryField:   "value",
+			optionalFields: []string{"description"},
+			blocksFirst:    true,
+			expectedOutput: `resource "test" "example" {
+  items = [
+    # Office network
+    {
+      # Ticket NET-12
+      description = "Office"
+      value       = "10.0.0.0/8" # all floors
+    },
+    {
+      description = null
+      value       = "172.16.0.0/12"
+    },
+    {
+      description = null
+      value       = "val1"
+    },
+  ]
+}
+`,
+			expectModified: true,
+		},
+		{
+			name: "Dynamic blocks",
+			input: `
+resource "test" "example" {
+  items = ["val1"]
+  items_with_description {
+    value       = "10.0.0.0/8"
+    description = "Office"
+  }
+  dynamic "items_with_description" {
+    for_each = var.networks
+    iterator = network
+    content {
+      value = network.value.cidr
+    }
+  }
+}`,
+			arrayAttrName:  "items",
+			blockType:      "items_with_description",
+			outputAttrName: "items",
+			primaryField:   "value",
+			optionalFields: []string{"description"},
+			blocksFirst:    true,
+			expectedOutput: `resource "test" "example" {
+  items = concat(
+    [{
+      description = "Office"
+      value       = "10.0.0.0/8"
+    }],
+    [for network in var.networks : {
+      description = null
+      value       = network.cidr
+    }],
+    [{
+      description = null
+      value       = "val1"
+    }],
+  )
+}
 `,
 			expectModified: true,
 		},
@@ -45,7 +45,7 @@ func EnsureAttribute(body *hclwrite.Body, attrName string, defaultValue interfac
 	}
 }
 
-// RenameAttribute renames an attribute from oldName to newName.
+// RenameAttribute renames an attribute from oldName to newName, keeping its comments.
 // Returns true if the attribute was found and renamed, false otherwise.
 //
 // Example - Renaming 'value' to 'content' for DNS records:
@@ -56,7 +56,7 @@ func EnsureAttribute(body *hclwrite.Body, attrName string, defaultValue interfac
 //	  zone_id = "abc123"
 //	  name    = "test"
 //	  type    = "A"
-//	  value   = "192.0.2.1"  # Old field name
+//	  value   = "192.0.2.1" # Origin address
 //	}
 //
 // After calling RenameAttribute(body, "value", "content"):
@@ -65,13 +65,14 @@ func EnsureAttribute(body *hclwrite.Body, attrName string, defaultValue interfac
 //	  zone_id = "abc123"
 //	  name    = "test"
 //	  type    = "A"
-//	  content = "192.0.2.1"  # New field name
+//	  content = "192.0.2.1" # Origin address
 //	}
 func RenameAttribute(body *hclwrite.Body, oldName, newName string) bool {
 	if attr := body.GetAttribute(oldName); attr != nil {
 		tokens := attr.Expr().BuildTokens(nil)
-		body.SetAttributeRaw(newName, tokens)
+		lead, line := hcl.OwnAttributeComments(body, oldName)
 		body.RemoveAttribute(oldName)
+		hcl.SetAttributeWithComments(body, newName, tokens, lead, line)
 		return true
 	}
 	return false
@@ -87,6 +87,22 @@ resource "test" "example" {
 			contains:    "content = \"192.0.2.1\"",
 			notContains: "value",
 		},
+		{
+			name: "Keep the comments of the attribute",
+			input: `
+resource "test" "example" {
+  # Origin address
+  value = "192.0.2.1" # inline note
+  name  = "test"
+}`,
+			oldName:  "value",
+			newName:  "content",
+			expected: true,
+			contains: `  name = "test"
+  # Origin address
+  content = "192.0.2.1" # inline note
+}`,
+		},
 		{
 			name: "Return false for non-existent attribute",
 			input: `
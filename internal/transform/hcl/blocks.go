@@ -64,6 +64,8 @@ func GetResourceName(block *hclwrite.Block) string {
 
 // ConvertBlocksToAttribute converts all blocks of a certain type to an object attribute.
 // The preProcess function is called on each block before conversion (can be nil).
+// Comments above the block and on its attributes are kept. A dynamic block is
+// converted to a for expression over its content block, see ConvertSingleBlockToAttribute.
 //
 // Example - Converting data blocks to attribute for CAA records:
 //
@@ -92,10 +94,18 @@ func GetResourceName(block *hclwrite.Block) string {
 //     }
 //   }
 func ConvertBlocksToAttribute(body *hclwrite.Body, blockType, attrName string, preProcess func(*hclwrite.Block)) {
-	var blocksToRemove []*hclwrite.Block
-	
 	for _, block := range body.Blocks() {
 		if block.Type() != blockType {
+			if d, ok := asDynamicBlock(block); ok && d.blockType == blockType {
+				if preProcess != nil {
+					preProcess(d.content)
+				}
+				if !d.convertible() {
+					continue
+				}
+				objTokens := d.object(hcl.BuildObjectFromBlock(d.content))
+				hcl.SetAttributeFromBlock(body, d.block, attrName, objTokens)
+			}
 			continue
 		}
 		
@@ -104,16 +114,61 @@ func ConvertBlocksToAttribute(body *hclwrite.Body, blockType, attrName string, p
 			preProcess(block)
 		}
 		
-		// Convert block to object tokens
+		// Convert block to object tokens, keeping its comments
 		objTokens := hcl.BuildObjectFromBlock(block)
-		body.SetAttributeRaw(attrName, objTokens)
-		blocksToRemove = append(blocksToRemove, block)
+		hcl.SetAttributeFromBlock(body, block, attrName, objTokens)
 	}
-	
-	// Remove the converted blocks
-	for _, block := range blocksToRemove {
-		body.RemoveBlock(block)
+}
+
+// ConvertBlocksToList converts all blocks of a certain type to a list attribute with an
+// object for each block. The preProcess function is called on each block before
+// conversion (can be nil). Comments above the blocks and on their attributes are kept.
+//
+// A dynamic block becomes a for expression over its content block, and a for expression
+// mixed with static blocks is joined to them with concat.
+//
+// Example - Converting policy blocks of an API token:
+//
+// Before:
+//   resource "cloudflare_api_token" "example" {
+//     policy {
+//       effect = "allow"
+//     }
+//
+//     dynamic "policy" {
+//       for_each = var.policies
+//       content {
+//         effect = policy.value.effect
+//       }
+//     }
+//   }
+//
+// After calling ConvertBlocksToList(body, "policy", "policies", nil):
+//   resource "cloudflare_api_token" "example" {
+//     policies = concat([{
+//       effect = "allow"
+//     }], [for policy in var.policies : {
+//       effect = policy.effect
+//     }])
+//   }
+func ConvertBlocksToList(body *hclwrite.Body, blockType, attrName string, preProcess func(*hclwrite.Block)) {
+	var items []listItem
+	for _, block := range FindBlocksByTypeWithDynamic(body, blockType) {
+		if preProcess != nil {
+			preProcess(block)
+		}
 	}
+	for _, block := range body.Blocks() {
+		if item, ok := blockListItem(block, blockType); ok {
+			items = append(items, item)
+		}
+	}
+	if len(items) == 0 {
+		return
+	}
+
+	setList(body, attrName, items)
+	RemoveBlocksByType(body, blockType)
 }
 
 // HoistAttributeFromBlock copies an attribute from a nested block to the parent body.
@@ -196,13 +251,45 @@ func FindBlocksByType(body *hclwrite.Body, blockType string) []*hclwrite.Block {
 	return blocks
 }
 
-// RemoveBlocksByType removes all blocks of a given type
+// FindBlocksByTypeWithDynamic finds all blocks of a given type, along with the content
+// blocks of the dynamic blocks generating them. Edits to a content block only end up in
+// the configuration when the dynamic block is then converted by one of the helpers of
+// this package, which rewrite its iterator references.
+func FindBlocksByTypeWithDynamic(body *hclwrite.Body, blockType string) []*hclwrite.Block {
+	var blocks []*hclwrite.Block
+	for _, block := range body.Blocks() {
+		if block.Type() == blockType {
+			blocks = append(blocks, block)
+		} else if d, ok := asDynamicBlock(block); ok && d.blockType == blockType {
+			blocks = append(blocks, d.content)
+		}
+	}
+	return blocks
+}
+
+// DynamicBlockOf returns the dynamic block of a body that block is the content block of,
+// or nil if block is a static block
+func DynamicBlockOf(body *hclwrite.Body, block *hclwrite.Block) *hclwrite.Block {
+	for _, b := range body.Blocks() {
+		if d, ok := asDynamicBlock(b); ok && d.content == block {
+			return b
+		}
+	}
+	return nil
+}
+
+// RemoveBlocksByType removes all blocks of a given type, and the dynamic blocks
+// generating them
 func RemoveBlocksByType(body *hclwrite.Body, blockType string) int {
-	blocks := FindBlocksByType(body, blockType)
-	for _, block := range blocks {
-		body.RemoveBlock(block)
+	removed := 0
+	for _, block := range body.Blocks() {
+		d, ok := asDynamicBlock(block)
+		if block.Type() == blockType || (ok && d.blockType == blockType && d.convertible()) {
+			body.RemoveBlock(block)
+			removed++
+		}
 	}
-	return len(blocks)
+	return removed
 }
 
 // ProcessBlocksOfType applies a function to all blocks of a given type
@@ -219,14 +306,33 @@ func ProcessBlocksOfType(body *hclwrite.Body, blockType string, processor func(*
 
 // ConvertSingleBlockToAttribute converts the first block of a type to an attribute
 // This is useful when a resource changes from having a single block to an attribute
+// Comments above the block and on its attributes are kept.
+//
+// A dynamic block becomes the object for the only element of its collection, or null
+// if it is empty:
+//   dynamic "notification_settings" {
+//     for_each = var.notify ? [var.message] : []
+//     iterator = message
+//     content {
+//       msg = message.value
+//     }
+//   }
+// becomes
+//   notification_settings = one([for message in var.notify ? [var.message] : [] : {
+//     msg = message
+//   }])
 func ConvertSingleBlockToAttribute(body *hclwrite.Body, blockType, attrName string) bool {
-	block := FindBlockByType(body, blockType)
-	if block == nil {
-		return false
-	}
-	
-	objTokens := hcl.BuildObjectFromBlock(block)
-	body.SetAttributeRaw(attrName, objTokens)
-	body.RemoveBlock(block)
-	return true
+	for _, block := range body.Blocks() {
+		if block.Type() == blockType {
+			objTokens := hcl.BuildObjectFromBlock(block)
+			hcl.SetAttributeFromBlock(body, block, attrName, objTokens)
+			return true
+		}
+		if d, ok := asDynamicBlock(block); ok && d.blockType == blockType && d.convertible() {
+			objTokens := d.object(hcl.BuildObjectFromBlock(d.content))
+			hcl.SetAttributeFromBlock(body, d.block, attrName, objTokens)
+			return true
+		}
+	}
+	return false
 }
\ No newline at end of file
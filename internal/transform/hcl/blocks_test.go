@@ -352,6 +352,13 @@ resource "test" "example" {
   item {
     value = "third"
   }
+
+  dynamic "item" {
+    for_each = var.items
+    content {
+      value = item.value
+    }
+  }
 }`
 
 	file, diags := hclwrite.ParseConfig([]byte(input), "", hcl.InitialPos)
@@ -364,6 +371,14 @@ resource "test" "example" {
 		assert.Equal(t, 3, len(blocks))
 	})
 
+	t.Run("Find content block of dynamic block", func(t *testing.T) {
+		blocks := FindBlocksByTypeWithDynamic(body, "item")
+		require.Len(t, blocks, 4)
+		assert.Equal(t, "content", blocks[3].Type())
+		assert.NotNil(t, DynamicBlockOf(body, blocks[3]))
+		assert.Nil(t, DynamicBlockOf(body, blocks[0]))
+	})
+
 	t.Run("Find single block", func(t *testing.T) {
 		blocks := FindBlocksByType(body, "settings")
 		assert.Equal(t, 1, len(blocks))
@@ -391,6 +406,13 @@ resource "test" "example" {
   deprecated {
     value = "also_old"
   }
+
+  dynamic "deprecated" {
+    for_each = var.old
+    content {
+      value = deprecated.value
+    }
+  }
 }`
 
 	file, diags := hclwrite.ParseConfig([]byte(input), "", hcl.InitialPos)
@@ -399,11 +421,12 @@ resource "test" "example" {
 	body := file.Body().Blocks()[0].Body()
 	count := RemoveBlocksByType(body, "deprecated")
 
-	assert.Equal(t, 2, count)
+	assert.Equal(t, 3, count)
 
 	output := string(file.Bytes())
 	assert.Contains(t, output, "keep {")
 	assert.NotContains(t, output, "deprecated {")
+	assert.NotContains(t, output, "dynamic")
 }
 
 func TestProcessBlocksOfType(t *testing.T) {
@@ -514,3 +537,387 @@ resource "test" "example" {
 		})
 	}
 }
+
+func TestConvertBlocksKeepComments(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		convert  func(body *hclwrite.Body)
+		expected string
+	}{
+		{
+			name: "Nested blocks converted with ConvertBlocksToAttribute",
+			input: `resource "cloudflare_device_posture_rule" "example" {
+  # Only managed devices in the office
+  input {
+    # Checked every five minutes
+    enabled = true # required by IT
+    locations {
+      paths = ["/opt/agent"] // installed by MDM
+      /* see runbook */ trust_stores = ["system"]
+    }
+  }
+}
+`,
+			convert: func(body *hclwrite.Body) {
+				ConvertBlocksToAttribute(body, "input", "input", func(input *hclwrite.Block) {
+					ConvertBlocksToAttribute(input.Body(), "locations", "locations", nil)
+				})
+			},
+			expected: `resource "cloudflare_device_posture_rule" "example" {
+  # Only managed devices in the office
+  input = {
+    # Checked every five minutes
+    enabled = true # required by IT
+    locations = {
+      paths                          = ["/opt/agent"] // installed by MDM
+      /* see runbook */ trust_stores = ["system"]
+    }
+  }
+}
+`,
+		},
+		{
+			name: "Nested blocks converted with ConvertSingleBlockToAttribute",
+			input: `resource "cloudflare_teams_rule" "example" {
+  name = "block"
+
+  # Settings agreed with the security team
+  rule_settings {
+    block_page_enabled = true # shown to users
+
+    # Notify on every block
+    notification_settings {
+      enabled = true
+      # Shown in the WARP client
+      message = "Blocked"
+    }
+  }
+}
+`,
+			convert: func(body *hclwrite.Body) {
+				settings := FindBlockByType(body, "rule_settings")
+				ConvertSingleBlockToAttribute(settings.Body(), "notification_settings", "notification_settings")
+				ConvertSingleBlockToAttribute(body, "rule_settings", "rule_settings")
+			},
+			expected: `resource "cloudflare_teams_rule" "example" {
+  name = "block"
+
+  # Settings agreed with the security team
+  rule_settings = {
+    block_page_enabled = true # shown to users
+    # Notify on every block
+    notification_settings = {
+      enabled = true
+      # Shown in the WARP client
+      message = "Blocked"
+    }
+  }
+}
+`,
+		},
+		{
+			name: "Values with comments inside",
+			input: `resource "cloudflare_dns_record" "caa" {
+  data {
+    tags = [
+      "a", # first
+      "b",
+    ]
+  }
+}
+`,
+			convert: func(body *hclwrite.Body) {
+				ConvertSingleBlockToAttribute(body, "data", "data")
+			},
+			expected: `resource "cloudflare_dns_record" "caa" {
+  data = {
+    tags = [
+      "a", # first
+      "b",
+    ]
+  }
+}
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, diags := hclwrite.ParseConfig([]byte(tt.input), "", hcl.InitialPos)
+			require.False(t, diags.HasErrors())
+
+			tt.convert(file.Body().Blocks()[0].Body())
+
+			output := string(hclwrite.Format(file.Bytes()))
+			assert.Equal(t, tt.expected, output)
+			_, diags = hclwrite.ParseConfig([]byte(output), "", hcl.InitialPos)
+			assert.False(t, diags.HasErrors(), diags.Error())
+		})
+	}
+}
+
+func TestUnconvertibleDynamicBlocks(t *testing.T) {
+	file, diags := hclwrite.ParseConfig([]byte(`resource "cloudflare_teams_rule" "example" {
+  dynamic "rule_settings" {
+    for_each = var.settings
+    content {
+      enabled = rule_settings.value.enabled
+      dynamic "notification_settings" {
+        for_each = rule_settings.value.notifications
+        iterator = n
+        content {
+          msg = jsonencode(n)
+        }
+      }
+    }
+  }
+  dynamic "precedence" {
+    for_each = var.precedence
+    content {
+      value = precedence["value"]
+    }
+  }
+  dynamic "rule" {
+    for_each = var.rules
+    content {
+      name = rule.value.name
+    }
+  }
+}
+`), "main.tf", hcl.InitialPos)
+	require.False(t, diags.HasErrors())
+
+	diags = UnconvertibleDynamicBlocks(file.Body().Blocks()[0].Body(), "rule_settings", "notification_settings", "rule")
+	require.Len(t, diags, 2)
+	assert.Equal(t, "Dynamic rule_settings block is not migrated", diags[0].Summary)
+	assert.Contains(t, diags[0].Detail, `The content of the dynamic "rule_settings" block holds dynamic blocks`)
+	assert.Equal(t, "Dynamic notification_settings block is not migrated", diags[1].Summary)
+	assert.Contains(t, diags[1].Detail, "uses n other than as n.value or n.key")
+	assert.Equal(t, hcl.DiagWarning, diags[1].Severity)
+}
+
+func TestConvertDynamicBlocks(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		convert  func(body *hclwrite.Body)
+		expected string
+	}{
+		{
+			name: "Dynamic blocks with ConvertBlocksToList",
+			input: `resource "cloudflare_api_token" "example" {
+  # Generated from the module input
+  dynamic "policy" {
+    for_each = var.policies
+    content {
+      effect = policy.value.effect
+    }
+  }
+}
+`,
+			convert: func(body *hclwrite.Body) {
+				ConvertBlocksToList(body, "policy", "policies", nil)
+			},
+			expected: `resource "cloudflare_api_token" "example" {
+  # Generated from the module input
+  policies = [for policy in var.policies : {
+    effect = policy.effect
+  }]
+}
+`,
+		},
+		{
+			name: "Static and dynamic blocks with ConvertBlocksToList",
+			input: `resource "cloudflare_api_token" "example" {
+  policy {
+    effect = "allow"
+  }
+  # One policy per zone
+  dynamic "policy" {
+    for_each = var.zones
+    iterator = zone
+    content {
+      effect    = "deny"
+      resources = { (zone.key) = zone.value.id }
+    }
+  }
+  policy {
+    effect = "deny"
+  }
+}
+`,
+			convert: func(body *hclwrite.Body) {
+				ConvertBlocksToList(body, "policy", "policies", func(policy *hclwrite.Block) {
+					RenameAttribute(policy.Body(), "effect", "action")
+				})
+			},
+			expected: `resource "cloudflare_api_token" "example" {
+  policies = concat(
+    [{
+      action = "allow"
+    }],
+    # One policy per zone
+    [for zone_key, zone in var.zones : {
+      resources = { (zone_key) = zone.id }
+      action    = "deny"
+    }],
+    [{
+      action = "deny"
+    }],
+  )
+}
+`,
+		},
+		{
+			name: "Nested dynamic blocks with ConvertBlocksToAttribute",
+			input: `resource "cloudflare_device_posture_rule" "example" {
+  dynamic "input" {
+    for_each = var.input == null ? [] : [var.input]
+    content {
+      enabled = input.value.enabled
+      dynamic "locations" {
+        for_each = input.value.locations
+        iterator = location
+        content {
+          paths = location.value.paths
+        }
+      }
+    }
+  }
+}
+`,
+			convert: func(body *hclwrite.Body) {
+				ConvertBlocksToAttribute(body, "input", "input", func(input *hclwrite.Block) {
+					ConvertBlocksToAttribute(input.Body(), "locations", "locations", nil)
+				})
+			},
+			expected: `resource "cloudflare_device_posture_rule" "example" {
+  input = one([for input in var.input == null ? [] : [var.input] : {
+    enabled = input.enabled
+    locations = one([for location in input.locations : {
+      paths = location.paths
+    }])
+  }])
+}
+`,
+		},
+		{
+			name: "Dynamic block with ConvertSingleBlockToAttribute",
+			input: `resource "cloudflare_teams_rule" "example" {
+  dynamic "rule_settings" {
+    for_each = var.settings[*]
+    content {
+      block_page_enabled = rule_settings.value.block_page_enabled
+    }
+  }
+}
+`,
+			convert: func(body *hclwrite.Body) {
+				ConvertSingleBlockToAttribute(body, "rule_settings", "rule_settings")
+			},
+			expected: `resource "cloudflare_teams_rule" "example" {
+  rule_settings = one([for rule_settings in var.settings[*] : {
+    block_page_enabled = rule_settings.block_page_enabled
+  }])
+}
+`,
+		},
+		{
+			name: "Dynamic blocks using the iterator directly are left in place",
+			input: `resource "cloudflare_api_token" "example" {
+  policy {
+    effect = "allow"
+  }
+  dynamic "policy" {
+    for_each = var.policies
+    content {
+      effect = policy["value"].effect
+    }
+  }
+  dynamic "policy" {
+    for_each = var.policies
+    iterator = p
+    content {
+      effect = lookup(p, "effect")
+    }
+  }
+}
+`,
+			convert: func(body *hclwrite.Body) {
+				ConvertBlocksToList(body, "policy", "policies", func(policy *hclwrite.Block) {
+					RenameAttribute(policy.Body(), "effect", "action")
+				})
+			},
+			expected: `resource "cloudflare_api_token" "example" {
+  dynamic "policy" {
+    for_each = var.policies
+    content {
+      effect = policy["value"].effect
+    }
+  }
+  dynamic "policy" {
+    for_each = var.policies
+    iterator = p
+    content {
+      effect = lookup(p, "effect")
+    }
+  }
+  policies = [{
+    action = "allow"
+  }]
+}
+`,
+		},
+		{
+			name: "Dynamic block holding unconverted dynamic blocks is left in place",
+			input: `resource "cloudflare_device_posture_rule" "example" {
+  dynamic "input" {
+    for_each = var.input == null ? [] : [var.input]
+    content {
+      enabled = input.value.enabled
+      dynamic "locations" {
+        for_each = input.value.locations
+        content {
+          paths = locations.value.paths
+        }
+      }
+    }
+  }
+}
+`,
+			convert: func(body *hclwrite.Body) {
+				ConvertSingleBlockToAttribute(body, "input", "input")
+			},
+			expected: `resource "cloudflare_device_posture_rule" "example" {
+  dynamic "input" {
+    for_each = var.input == null ? [] : [var.input]
+    content {
+      enabled = input.value.enabled
+      dynamic "locations" {
+        for_each = input.value.locations
+        content {
+          paths = locations.value.paths
+        }
+      }
+    }
+  }
+}
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, diags := hclwrite.ParseConfig([]byte(tt.input), "", hcl.InitialPos)
+			require.False(t, diags.HasErrors())
+
+			tt.convert(file.Body().Blocks()[0].Body())
+
+			output := string(hclwrite.Format(file.Bytes()))
+			assert.Equal(t, tt.expected, output)
+			_, diags = hclwrite.ParseConfig([]byte(output), "", hcl.InitialPos)
+			assert.False(t, diags.HasErrors(), diags.Error())
+		})
+	}
+}
@@ -0,0 +1,253 @@
+// Package hcl provides utilities for converting dynamic blocks along with the
+// blocks they generate
+package hcl
+
+import (
+	"fmt"
+	"strings"
+
+	hcl2 "github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+
+	"github.com/cloudflare/tf-migrate/internal/hcl"
+)
+
+// dynamicBlock is a dynamic block generating nested blocks of one type:
+//
+//	dynamic "policy" {
+//	  for_each = var.policies
+//	  iterator = p
+//	  content {
+//	    effect = p.value.effect
+//	  }
+//	}
+type dynamicBlock struct {
+	// block is the dynamic block itself
+	block *hclwrite.Block
+	// content holds the attributes of each generated block
+	content *hclwrite.Block
+	// blockType is the type of the generated blocks
+	blockType string
+	// forEach is the collection the blocks are generated from
+	forEach hclwrite.Tokens
+	// iterator names the element in content, the block type unless set
+	iterator string
+}
+
+// asDynamicBlock returns the dynamic block if block is one that can be converted: it has a
+// content block whose attributes only use the iterator as iterator.value and iterator.key.
+// Other dynamic blocks are left as they are by every helper.
+func asDynamicBlock(block *hclwrite.Block) (dynamicBlock, bool) {
+	d, ok := parseDynamicBlock(block)
+	if !ok || usesIteratorDirectly(d.content.Body(), d.iterator) {
+		return dynamicBlock{}, false
+	}
+	return d, true
+}
+
+// parseDynamicBlock returns the dynamic block if block is one, with a content block
+func parseDynamicBlock(block *hclwrite.Block) (dynamicBlock, bool) {
+	labels := block.Labels()
+	if block.Type() != "dynamic" || len(labels) != 1 {
+		return dynamicBlock{}, false
+	}
+	body := block.Body()
+	forEach := body.GetAttribute("for_each")
+	content := body.FirstMatchingBlock("content", nil)
+	if forEach == nil || content == nil {
+		return dynamicBlock{}, false
+	}
+
+	iterator := labels[0]
+	if attr := body.GetAttribute("iterator"); attr != nil {
+		iterator = strings.TrimSpace(string(attr.Expr().BuildTokens(nil).Bytes()))
+	}
+	return dynamicBlock{
+		block:     block,
+		content:   content,
+		blockType: labels[0],
+		forEach:   forEach.Expr().BuildTokens(nil),
+		iterator:  iterator,
+	}, true
+}
+
+// convertible reports whether the content block can be built into an object, which drops
+// nested blocks. Nested dynamic blocks must have been converted first, for example by the
+// preProcess function of a conversion.
+func (d dynamicBlock) convertible() bool {
+	for _, block := range d.content.Body().Blocks() {
+		if block.Type() == "dynamic" {
+			return false
+		}
+	}
+	return true
+}
+
+// list returns a for expression building a list with value for each element of the
+// collection. References to the iterator in value become the variables of the for
+// expression: p.value becomes p and p.key becomes p_key.
+func (d dynamicBlock) list(value hclwrite.Tokens) hclwrite.Tokens {
+	value, usesKey := replaceIteratorReferences(value, d.iterator)
+	key := ""
+	if usesKey {
+		key = d.iterator + "_key"
+	}
+	return hcl.BuildForExpression(key, d.iterator, d.forEach, value)
+}
+
+// object returns value for the only element of the collection, or null if it is empty,
+// for blocks that become a single object
+func (d dynamicBlock) object(value hclwrite.Tokens) hclwrite.Tokens {
+	return hclwrite.TokensForFunctionCall("one", d.list(value))
+}
+
+// usesIteratorDirectly reports whether an attribute expression of body or of its nested
+// blocks uses the iterator other than as iterator.value or iterator.key, for example as
+// iterator["value"] or as a function argument
+func usesIteratorDirectly(body *hclwrite.Body, iterator string) bool {
+	for _, attr := range body.Attributes() {
+		tokens := attr.Expr().BuildTokens(nil)
+		for i, token := range tokens {
+			if token.Type != hclsyntax.TokenIdent || string(token.Bytes) != iterator ||
+				(i > 0 && tokens[i-1].Type == hclsyntax.TokenDot) {
+				continue
+			}
+			isReference := i+2 < len(tokens) && tokens[i+1].Type == hclsyntax.TokenDot &&
+				(string(tokens[i+2].Bytes) == "value" || string(tokens[i+2].Bytes) == "key")
+			if !isReference {
+				return true
+			}
+		}
+	}
+	for _, block := range body.Blocks() {
+		if usesIteratorDirectly(block.Body(), iterator) {
+			return true
+		}
+	}
+	return false
+}
+
+// UnconvertibleDynamicBlocks returns a warning for every dynamic block of body, or of its
+// nested blocks, generating blocks of one of blockTypes that the conversions leave in place:
+// those using their iterator other than as iterator.value or iterator.key, and those whose
+// content still holds dynamic blocks. Call it once the blocks are converted.
+func UnconvertibleDynamicBlocks(body *hclwrite.Body, blockTypes ...string) hcl2.Diagnostics {
+	var diags hcl2.Diagnostics
+	for _, block := range body.Blocks() {
+		if d, ok := parseDynamicBlock(block); ok {
+			reason := ""
+			switch {
+			case usesIteratorDirectly(d.content.Body(), d.iterator):
+				reason = fmt.Sprintf("uses %s other than as %s.value or %s.key", d.iterator, d.iterator, d.iterator)
+			case !d.convertible():
+				reason = "holds dynamic blocks"
+			}
+			for _, blockType := range blockTypes {
+				if reason != "" && d.blockType == blockType {
+					diags = append(diags, &hcl2.Diagnostic{
+						Severity: hcl2.DiagWarning,
+						Summary:  fmt.Sprintf("Dynamic %s block is not migrated", blockType),
+						Detail:   fmt.Sprintf("The content of the dynamic %q block %s, so it cannot be rewritten to a for expression and was left in place. Convert it by hand.", blockType, reason),
+					})
+				}
+			}
+		}
+		diags = append(diags, UnconvertibleDynamicBlocks(block.Body(), blockTypes...)...)
+	}
+	return diags
+}
+
+// replaceIteratorReferences replaces iterator.value with iterator and iterator.key with
+// iterator_key, and reports whether the key is used
+func replaceIteratorReferences(tokens hclwrite.Tokens, iterator string) (hclwrite.Tokens, bool) {
+	var replaced hclwrite.Tokens
+	usesKey := false
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		isReference := token.Type == hclsyntax.TokenIdent && string(token.Bytes) == iterator &&
+			(i == 0 || tokens[i-1].Type != hclsyntax.TokenDot) &&
+			i+2 < len(tokens) && tokens[i+1].Type == hclsyntax.TokenDot && tokens[i+2].Type == hclsyntax.TokenIdent
+		if isReference {
+			name := ""
+			switch string(tokens[i+2].Bytes) {
+			case "value":
+				name = iterator
+			case "key":
+				name = iterator + "_key"
+				usesKey = true
+			}
+			if name != "" {
+				replaced = append(replaced, &hclwrite.Token{
+					Type:         hclsyntax.TokenIdent,
+					Bytes:        []byte(name),
+					SpacesBefore: token.SpacesBefore,
+				})
+				i += 2
+				continue
+			}
+		}
+		replaced = append(replaced, token)
+	}
+	return replaced, usesKey
+}
+
+// listItem is an object of a list built from blocks, or the for expression of a dynamic
+// block when dynamic is set
+type listItem struct {
+	elem    hcl.CommentedTupleElem
+	dynamic *hclwrite.Block
+}
+
+// blockListItem returns the list item of a static or dynamic block of blockType
+func blockListItem(block *hclwrite.Block, blockType string) (listItem, bool) {
+	if block.Type() == blockType {
+		return listItem{elem: hcl.CommentedTupleElem{
+			Value:        hcl.BuildObjectFromBlock(block),
+			LeadComments: hcl.LeadComments(block),
+		}}, true
+	}
+	if d, ok := asDynamicBlock(block); ok && d.blockType == blockType && d.convertible() {
+		return listItem{
+			elem: hcl.CommentedTupleElem{
+				Value:        d.list(hcl.BuildObjectFromBlock(d.content)),
+				LeadComments: hcl.LeadComments(d.block),
+			},
+			dynamic: d.block,
+		}, true
+	}
+	return listItem{}, false
+}
+
+// setList sets an attribute to the list of the items. Objects next to each other are
+// written as a tuple, and a tuple mixed with for expressions is joined with concat.
+func setList(body *hclwrite.Body, attrName string, items []listItem) {
+	var lists, elems []hcl.CommentedTupleElem
+	var dynamics []*hclwrite.Block
+	flush := func() {
+		if len(elems) > 0 {
+			lists = append(lists, hcl.CommentedTupleElem{Value: hcl.TokensForCommentedTuple(elems)})
+			elems = nil
+		}
+	}
+	for _, item := range items {
+		if item.dynamic == nil {
+			elems = append(elems, item.elem)
+			continue
+		}
+		flush()
+		lists = append(lists, item.elem)
+		dynamics = append(dynamics, item.dynamic)
+	}
+	flush()
+
+	switch {
+	case len(lists) == 1 && len(dynamics) == 1:
+		// The comments of a lone dynamic block go above the attribute
+		hcl.SetAttributeFromBlock(body, dynamics[0], attrName, lists[0].Value)
+	case len(lists) == 1:
+		body.SetAttributeRaw(attrName, lists[0].Value)
+	case len(lists) > 1:
+		body.SetAttributeRaw(attrName, hcl.TokensForCommentedFunctionCall("concat", lists))
+	}
+}
@@ -0,0 +1,329 @@
+package hcl
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	hcl2 "github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+// ReferenceRewrite records a single reference rewritten in a configuration file
+type ReferenceRewrite struct {
+	Range hcl2.Range
+	From  string
+	To    string
+}
+
+// ReferenceOptions controls which references RenameResourceReferences rewrites
+type ReferenceOptions struct {
+	// KeepMovedFrom leaves the from address of moved blocks unchanged. Use it when
+	// the state still holds the old resource types.
+	KeepMovedFrom bool
+}
+
+// RenameResourceReferences rewrites references to renamed resource types in a
+// configuration file. Data source types are keyed as data.<type>. Only traversal expressions are rewritten: string literals,
+// comments, block labels and longer names that share a prefix are left untouched.
+// This covers attributes, depends_on, count and for_each, outputs, locals,
+// template interpolations and moved blocks.
+//
+// Example - Renaming cloudflare_record to cloudflare_dns_record:
+//
+// Before:
+//
+//	output "www" {
+//	  # cloudflare_record.www is the main record
+//	  value = cloudflare_record.www.id
+//	}
+//
+// After calling RenameResourceReferences(src, "outputs.tf", map[string]string{"cloudflare_record": "cloudflare_dns_record"}, ReferenceOptions{}):
+//
+//	output "www" {
+//	  # cloudflare_record.www is the main record
+//	  value = cloudflare_dns_record.www.id
+//	}
+func RenameResourceReferences(src []byte, filename string, renames map[string]string, opts ReferenceOptions) ([]byte, []ReferenceRewrite, error) {
+	if len(renames) == 0 {
+		return src, nil, nil
+	}
+
+	file, diags := hclsyntax.ParseConfig(src, filename, hcl2.InitialPos)
+	if diags.HasErrors() {
+		return src, nil, fmt.Errorf("failed to parse %s: %s", filename, diags.Error())
+	}
+
+	body, ok := file.Body.(*hclsyntax.Body)
+	if !ok {
+		return src, nil, nil
+	}
+
+	var rewrites []ReferenceRewrite
+	for _, traversal := range bodyTraversals(body, "", opts) {
+		oldType, typeRange, _, ok := referenceType(traversal)
+		if !ok {
+			continue
+		}
+		newType, ok := renames[oldType]
+		if !ok {
+			continue
+		}
+		rewrites = append(rewrites, ReferenceRewrite{
+			Range: typeRange,
+			From:  strings.TrimPrefix(oldType, dataPrefix),
+			To:    strings.TrimPrefix(newType, dataPrefix),
+		})
+	}
+
+	result, rewrites := applyRewrites(src, rewrites)
+	return result, rewrites, nil
+}
+
+// bodyTraversals returns every traversal referenced by the expressions in a body and its nested blocks
+func bodyTraversals(body *hclsyntax.Body, blockType string, opts ReferenceOptions) []hcl2.Traversal {
+	var traversals []hcl2.Traversal
+
+	for name, attr := range body.Attributes {
+		if opts.KeepMovedFrom && blockType == "moved" && name == "from" {
+			continue
+		}
+		traversals = append(traversals, attr.Expr.Variables()...)
+	}
+	for _, block := range body.Blocks {
+		traversals = append(traversals, bodyTraversals(block.Body, block.Type, opts)...)
+	}
+
+	return traversals
+}
+
+// applyRewrites replaces the source ranges of the rewrites, starting from the end of the file.
+// It returns the new source and the rewrites that were applied, in source order.
+func applyRewrites(src []byte, rewrites []ReferenceRewrite) ([]byte, []ReferenceRewrite) {
+	if len(rewrites) == 0 {
+		return src, nil
+	}
+
+	sort.Slice(rewrites, func(i, j int) bool {
+		return rewrites[i].Range.Start.Byte < rewrites[j].Range.Start.Byte
+	})
+
+	result := append([]byte(nil), src...)
+	applied := make([]ReferenceRewrite, 0, len(rewrites))
+	for i := len(rewrites) - 1; i >= 0; i-- {
+		rng := rewrites[i].Range
+		if string(result[rng.Start.Byte:rng.End.Byte]) != rewrites[i].From {
+			continue
+		}
+		result = append(result[:rng.Start.Byte], append([]byte(rewrites[i].To), result[rng.End.Byte:]...)...)
+		applied = append([]ReferenceRewrite{rewrites[i]}, applied...)
+	}
+
+	return result, applied
+}
+
+// AttributeChanges describes the attribute renames and removals of a resource type.
+// Attribute paths are dot separated, e.g. "value" or "settings.ttl".
+type AttributeChanges struct {
+	Renames map[string]string
+	Removed []string
+	// Except holds the names of resources the renames do not apply to. References to their
+	// renamed attributes are reported like references to removed attributes.
+	Except map[string]bool
+}
+
+// RenameAttributeReferences rewrites references to renamed attributes of the given
+// resource types, or data source types keyed as data.<type>, in a configuration file, and reports references to removed
+// attributes as error diagnostics pointing at the reference.
+//
+// Example - Renaming value to content on cloudflare_dns_record:
+//
+// Before:
+//
+//	output "www" {
+//	  value = cloudflare_dns_record.www[0].value
+//	}
+//
+// After calling RenameAttributeReferences(src, "outputs.tf", map[string]AttributeChanges{"cloudflare_dns_record": {Renames: map[string]string{"value": "content"}}}):
+//
+//	output "www" {
+//	  value = cloudflare_dns_record.www[0].content
+//	}
+func RenameAttributeReferences(src []byte, filename string, changes map[string]AttributeChanges) ([]byte, []ReferenceRewrite, hcl2.Diagnostics, error) {
+	if len(changes) == 0 {
+		return src, nil, nil, nil
+	}
+
+	file, diags := hclsyntax.ParseConfig(src, filename, hcl2.InitialPos)
+	if diags.HasErrors() {
+		return src, nil, nil, fmt.Errorf("failed to parse %s: %s", filename, diags.Error())
+	}
+
+	body, ok := file.Body.(*hclsyntax.Body)
+	if !ok {
+		return src, nil, nil, nil
+	}
+
+	var rewrites []ReferenceRewrite
+	var removed hcl2.Diagnostics
+	traversals := append(bodyTraversals(body, "", ReferenceOptions{}), splatTraversals(body)...)
+	for _, traversal := range traversals {
+		resourceType, _, rest, ok := referenceType(traversal)
+		if !ok {
+			continue
+		}
+		resourceChanges, ok := changes[resourceType]
+		if !ok {
+			continue
+		}
+
+		steps := attributeSteps(rest)
+		if len(steps) == 0 {
+			continue
+		}
+
+		if path, ok := matchAttributePath(steps, resourceChanges.Removed); ok {
+			rng := attributeRange(steps[:len(strings.Split(path, "."))])
+			removed = append(removed, &hcl2.Diagnostic{
+				Severity: hcl2.DiagError,
+				Summary:  "Reference to removed attribute",
+				Detail:   fmt.Sprintf("The attribute %q was removed from %s and this reference must be updated by hand.", path, resourceType),
+				Subject:  &rng,
+			})
+			continue
+		}
+
+		oldPaths := make([]string, 0, len(resourceChanges.Renames))
+		for oldPath := range resourceChanges.Renames {
+			oldPaths = append(oldPaths, oldPath)
+		}
+		path, ok := matchAttributePath(steps, oldPaths)
+		if !ok {
+			continue
+		}
+		rng := attributeRange(steps[:len(strings.Split(path, "."))])
+		if name, isName := rest[0].(hcl2.TraverseAttr); isName && resourceChanges.Except[name.Name] {
+			removed = append(removed, &hcl2.Diagnostic{
+				Severity: hcl2.DiagError,
+				Summary:  "Reference to removed attribute",
+				Detail:   fmt.Sprintf("The attribute %q of %s.%s has no equivalent and this reference must be updated by hand.", path, resourceType, name.Name),
+				Subject:  &rng,
+			})
+			continue
+		}
+		rewrites = append(rewrites, ReferenceRewrite{
+			Range: rng,
+			From:  path,
+			To:    resourceChanges.Renames[path],
+		})
+	}
+
+	result, rewrites := applyRewrites(src, rewrites)
+	return result, rewrites, removed, nil
+}
+
+// splatTraversals returns the references made through splat expressions, such as
+// cloudflare_dns_record.www[*].value, as the traversal of the splat source followed by
+// the attributes read from each element. Variables only returns the splat source.
+func splatTraversals(body *hclsyntax.Body) []hcl2.Traversal {
+	var traversals []hcl2.Traversal
+	hclsyntax.VisitAll(body, func(node hclsyntax.Node) hcl2.Diagnostics {
+		splat, ok := node.(*hclsyntax.SplatExpr)
+		if !ok {
+			return nil
+		}
+		source, ok := splat.Source.(*hclsyntax.ScopeTraversalExpr)
+		if !ok {
+			return nil
+		}
+		each, ok := splat.Each.(*hclsyntax.RelativeTraversalExpr)
+		if !ok {
+			return nil
+		}
+		if _, ok := each.Source.(*hclsyntax.AnonSymbolExpr); !ok {
+			return nil
+		}
+		traversal := append(append(hcl2.Traversal{}, source.Traversal...), each.Traversal...)
+		traversals = append(traversals, traversal)
+		return nil
+	})
+	return traversals
+}
+
+// dataPrefix is the key prefix of data source types
+const dataPrefix = "data."
+
+// referenceType returns the type of a resource or data source reference, keyed as
+// <type> or data.<type>, the source range of the type name and the remaining steps,
+// starting at the name. It returns false if the traversal is too short to be a reference.
+func referenceType(traversal hcl2.Traversal) (string, hcl2.Range, hcl2.Traversal, bool) {
+	if traversal.RootName() == "data" {
+		if len(traversal) < 3 {
+			return "", hcl2.Range{}, nil, false
+		}
+		typeStep, ok := traversal[1].(hcl2.TraverseAttr)
+		if !ok {
+			return "", hcl2.Range{}, nil, false
+		}
+		return dataPrefix + typeStep.Name, attributeRange([]hcl2.TraverseAttr{typeStep}), traversal[2:], true
+	}
+
+	// A resource reference has at least a type and a name
+	if len(traversal) < 2 {
+		return "", hcl2.Range{}, nil, false
+	}
+	return traversal.RootName(), traversal[0].SourceRange(), traversal[1:], true
+}
+
+// attributeSteps returns the attribute steps of a reference, i.e. everything after
+// the name and an optional instance key
+func attributeSteps(reference hcl2.Traversal) []hcl2.TraverseAttr {
+	if len(reference) < 2 {
+		return nil
+	}
+
+	rest := reference[1:]
+	if _, ok := rest[0].(hcl2.TraverseIndex); ok {
+		rest = rest[1:]
+	}
+
+	var steps []hcl2.TraverseAttr
+	for _, step := range rest {
+		attr, ok := step.(hcl2.TraverseAttr)
+		if !ok {
+			break
+		}
+		steps = append(steps, attr)
+	}
+	return steps
+}
+
+// matchAttributePath returns the longest of the given paths that prefixes the attribute steps
+func matchAttributePath(steps []hcl2.TraverseAttr, paths []string) (string, bool) {
+	best := ""
+	for _, path := range paths {
+		segments := strings.Split(path, ".")
+		if len(segments) > len(steps) || (best != "" && len(segments) <= strings.Count(best, ".")+1) {
+			continue
+		}
+		matches := true
+		for i, segment := range segments {
+			if steps[i].Name != segment {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			best = path
+		}
+	}
+	return best, best != ""
+}
+
+// attributeRange returns the source range of attribute steps, without the leading dot
+func attributeRange(steps []hcl2.TraverseAttr) hcl2.Range {
+	rng := hcl2.RangeBetween(steps[0].SrcRange, steps[len(steps)-1].SrcRange)
+	rng.Start.Byte++
+	rng.Start.Column++
+	return rng
+}
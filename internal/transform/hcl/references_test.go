@@ -0,0 +1,374 @@
+package hcl
+
+import (
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenameResourceReferences(t *testing.T) {
+	renames := map[string]string{
+		"cloudflare_record":     "cloudflare_dns_record",
+		"cloudflare_teams_list": "cloudflare_zero_trust_list",
+	}
+
+	tests := []struct {
+		name             string
+		input            string
+		opts             ReferenceOptions
+		expected         string
+		expectedRewrites int
+	}{
+		{
+			name: "Attributes, depends_on, count and for_each",
+			input: `resource "cloudflare_page_rule" "example" {
+  count      = length(cloudflare_record.www)
+  target     = cloudflare_record.www[0].hostname
+  depends_on = [cloudflare_record.www, cloudflare_teams_list.blocked]
+}
+
+resource "cloudflare_zero_trust_gateway_policy" "example" {
+  for_each = { for item in cloudflare_teams_list.blocked.items : item => item }
+  traffic  = "any(dns.domains[*] in ${cloudflare_teams_list.blocked.id})"
+}
+`,
+			expected: `resource "cloudflare_page_rule" "example" {
+  count      = length(cloudflare_dns_record.www)
+  target     = cloudflare_dns_record.www[0].hostname
+  depends_on = [cloudflare_dns_record.www, cloudflare_zero_trust_list.blocked]
+}
+
+resource "cloudflare_zero_trust_gateway_policy" "example" {
+  for_each = { for item in cloudflare_zero_trust_list.blocked.items : item => item }
+  traffic  = "any(dns.domains[*] in ${cloudflare_zero_trust_list.blocked.id})"
+}
+`,
+			expectedRewrites: 6,
+		},
+		{
+			name: "Outputs, locals and nested blocks",
+			input: `locals {
+  ids = [for r in cloudflare_record.www : r.id]
+}
+
+output "www" {
+  value = cloudflare_record.www.id
+}
+
+resource "cloudflare_load_balancer" "example" {
+  rules {
+    name = cloudflare_record.www.name
+  }
+}
+`,
+			expected: `locals {
+  ids = [for r in cloudflare_dns_record.www : r.id]
+}
+
+output "www" {
+  value = cloudflare_dns_record.www.id
+}
+
+resource "cloudflare_load_balancer" "example" {
+  rules {
+    name = cloudflare_dns_record.www.name
+  }
+}
+`,
+			expectedRewrites: 3,
+		},
+		{
+			name: "Strings, comments, labels and longer names are left alone",
+			input: `# cloudflare_record.www is the main record
+resource "cloudflare_record" "www" {
+  name    = "cloudflare_record.www"
+  comment = my_cloudflare_record.www.id
+  zone_id = data.cloudflare_record.lookup.zone_id
+  value   = module.cloudflare_record.value
+}
+`,
+			expected: `# cloudflare_record.www is the main record
+resource "cloudflare_record" "www" {
+  name    = "cloudflare_record.www"
+  comment = my_cloudflare_record.www.id
+  zone_id = data.cloudflare_record.lookup.zone_id
+  value   = module.cloudflare_record.value
+}
+`,
+			expectedRewrites: 0,
+		},
+		{
+			name: "Moved blocks",
+			input: `moved {
+  from = cloudflare_record.old
+  to   = cloudflare_record.new
+}
+`,
+			expected: `moved {
+  from = cloudflare_dns_record.old
+  to   = cloudflare_dns_record.new
+}
+`,
+			expectedRewrites: 2,
+		},
+		{
+			name: "Moved blocks keep their from address",
+			input: `moved {
+  from = cloudflare_record.www
+  to   = cloudflare_record.new
+}
+`,
+			opts: ReferenceOptions{KeepMovedFrom: true},
+			expected: `moved {
+  from = cloudflare_record.www
+  to   = cloudflare_dns_record.new
+}
+`,
+			expectedRewrites: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, rewrites, err := RenameResourceReferences([]byte(tt.input), "main.tf", renames, tt.opts)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, string(result))
+			assert.Len(t, rewrites, tt.expectedRewrites)
+		})
+	}
+}
+
+func TestRenameResourceReferencesPositions(t *testing.T) {
+	input := `output "a" {
+  value = cloudflare_record.a.id
+}
+
+output "b" {
+  value = [cloudflare_teams_list.b.id, cloudflare_record.b.id]
+}
+`
+	renames := map[string]string{
+		"cloudflare_record":     "cloudflare_dns_record",
+		"cloudflare_teams_list": "cloudflare_zero_trust_list",
+	}
+
+	_, rewrites, err := RenameResourceReferences([]byte(input), "outputs.tf", renames, ReferenceOptions{})
+	require.NoError(t, err)
+	require.Len(t, rewrites, 3)
+
+	assert.Equal(t, "outputs.tf", rewrites[0].Range.Filename)
+	assert.Equal(t, 2, rewrites[0].Range.Start.Line)
+	assert.Equal(t, 11, rewrites[0].Range.Start.Column)
+	assert.Equal(t, "cloudflare_record", rewrites[0].From)
+
+	assert.Equal(t, 6, rewrites[1].Range.Start.Line)
+	assert.Equal(t, 12, rewrites[1].Range.Start.Column)
+	assert.Equal(t, "cloudflare_zero_trust_list", rewrites[1].To)
+
+	assert.Equal(t, 6, rewrites[2].Range.Start.Line)
+	assert.Equal(t, 40, rewrites[2].Range.Start.Column)
+}
+
+func TestRenameResourceReferencesInvalidHCL(t *testing.T) {
+	input := []byte(`resource "cloudflare_record" "www" {`)
+	result, rewrites, err := RenameResourceReferences(input, "broken.tf", map[string]string{"cloudflare_record": "cloudflare_dns_record"}, ReferenceOptions{})
+	assert.Error(t, err)
+	assert.Empty(t, rewrites)
+	assert.Equal(t, input, result)
+}
+
+func TestRenameAttributeReferences(t *testing.T) {
+	changes := map[string]AttributeChanges{
+		"cloudflare_dns_record": {
+			Renames: map[string]string{"value": "content"},
+			Removed: []string{"hostname", "allow_overwrite"},
+			Except:  map[string]bool{"srv": true},
+		},
+		"cloudflare_workers_kv": {
+			Renames: map[string]string{"key": "key_name"},
+		},
+		"cloudflare_example": {
+			Renames: map[string]string{"settings": "config", "settings.ttl": "config.ttl_seconds"},
+		},
+	}
+
+	tests := []struct {
+		name            string
+		input           string
+		expected        string
+		expectedRemoved []string
+	}{
+		{
+			name: "Rename attribute references",
+			input: `output "www" {
+  value = cloudflare_dns_record.www.value
+}
+
+locals {
+  record = cloudflare_dns_record.api[0].value
+  keys   = [cloudflare_workers_kv.kv["a"].key, "${cloudflare_workers_kv.kv["b"].key}"]
+  ttl    = cloudflare_example.e.settings.ttl
+  all    = cloudflare_example.e.settings
+}
+`,
+			expected: `output "www" {
+  value = cloudflare_dns_record.www.content
+}
+
+locals {
+  record = cloudflare_dns_record.api[0].content
+  keys   = [cloudflare_workers_kv.kv["a"].key_name, "${cloudflare_workers_kv.kv["b"].key_name}"]
+  ttl    = cloudflare_example.e.config.ttl_seconds
+  all    = cloudflare_example.e.config
+}
+`,
+		},
+		{
+			name: "Rename attribute references through splat expressions",
+			input: `output "values" {
+  value = cloudflare_dns_record.www[*].value
+}
+
+output "keys" {
+  value = [cloudflare_workers_kv.kv.*.key, cloudflare_example.e[*].settings.ttl]
+}
+`,
+			expected: `output "values" {
+  value = cloudflare_dns_record.www[*].content
+}
+
+output "keys" {
+  value = [cloudflare_workers_kv.kv.*.key_name, cloudflare_example.e[*].config.ttl_seconds]
+}
+`,
+		},
+		{
+			name: "Unrelated references are left alone",
+			input: `output "www" {
+  value = [cloudflare_dns_record.www.content, cloudflare_dns_record.www, other_resource.x.value, data.cloudflare_dns_record.x.value]
+}
+`,
+			expected: `output "www" {
+  value = [cloudflare_dns_record.www.content, cloudflare_dns_record.www, other_resource.x.value, data.cloudflare_dns_record.x.value]
+}
+`,
+		},
+		{
+			name: "Renamed attributes of excepted resources are reported",
+			input: `output "srv" {
+  value = [cloudflare_dns_record.srv.value, cloudflare_dns_record.srv.data, cloudflare_dns_record.www.value]
+}
+`,
+			expected: `output "srv" {
+  value = [cloudflare_dns_record.srv.value, cloudflare_dns_record.srv.data, cloudflare_dns_record.www.content]
+}
+`,
+			expectedRemoved: []string{"outputs.tf:2,38-43: Reference to removed attribute"},
+		},
+		{
+			name: "Report removed attribute references",
+			input: `output "hostname" {
+  value = cloudflare_dns_record.www.hostname
+}
+
+output "overwrite" {
+  value = cloudflare_dns_record.www[0].allow_overwrite
+}
+
+output "hostnames" {
+  value = cloudflare_dns_record.www[*].hostname
+}
+`,
+			expected: `output "hostname" {
+  value = cloudflare_dns_record.www.hostname
+}
+
+output "overwrite" {
+  value = cloudflare_dns_record.www[0].allow_overwrite
+}
+
+output "hostnames" {
+  value = cloudflare_dns_record.www[*].hostname
+}
+`,
+			expectedRemoved: []string{
+				"outputs.tf:2,37-45: Reference to removed attribute",
+				"outputs.tf:6,40-55: Reference to removed attribute",
+				"outputs.tf:10,40-48: Reference to removed attribute",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, _, diags, err := RenameAttributeReferences([]byte(tt.input), "outputs.tf", changes)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, string(result))
+
+			var removed []string
+			for _, diag := range diags {
+				assert.Equal(t, hcl.DiagError, diag.Severity)
+				removed = append(removed, diag.Subject.String()+": "+diag.Summary)
+			}
+			assert.ElementsMatch(t, tt.expectedRemoved, removed)
+		})
+	}
+}
+
+func TestDataSourceReferences(t *testing.T) {
+	input := `data "cloudflare_tunnel" "t" {
+  name = "my-tunnel"
+}
+
+output "tunnel" {
+  value = [data.cloudflare_tunnel.t.id, data.cloudflare_tunnel.t.tunnel_type, cloudflare_tunnel.t.id]
+}
+
+output "zones" {
+  value = data.cloudflare_zones.all.zones[0].id
+}
+
+output "config" {
+  value = data.cloudflare_tunnel.t.remote_config
+}
+`
+	renames := map[string]string{"data.cloudflare_tunnel": "data.cloudflare_zero_trust_tunnel_cloudflared"}
+	changes := map[string]AttributeChanges{
+		"data.cloudflare_zero_trust_tunnel_cloudflared": {
+			Renames: map[string]string{"tunnel_type": "tun_type"},
+			Removed: []string{"remote_config"},
+		},
+		"data.cloudflare_zones": {
+			Renames: map[string]string{"zones": "result"},
+		},
+	}
+
+	result, rewrites, err := RenameResourceReferences([]byte(input), "main.tf", renames, ReferenceOptions{})
+	require.NoError(t, err)
+	assert.Len(t, rewrites, 3)
+
+	result, _, diags, err := RenameAttributeReferences(result, "main.tf", changes)
+	require.NoError(t, err)
+
+	assert.Equal(t, `data "cloudflare_tunnel" "t" {
+  name = "my-tunnel"
+}
+
+output "tunnel" {
+  value = [data.cloudflare_zero_trust_tunnel_cloudflared.t.id, data.cloudflare_zero_trust_tunnel_cloudflared.t.tun_type, cloudflare_tunnel.t.id]
+}
+
+output "zones" {
+  value = data.cloudflare_zones.all.result[0].id
+}
+
+output "config" {
+  value = data.cloudflare_zero_trust_tunnel_cloudflared.t.remote_config
+}
+`, string(result))
+
+	require.Len(t, diags, 1)
+	assert.Equal(t, "main.tf:14,59-72", diags[0].Subject.String())
+}
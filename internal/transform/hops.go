@@ -0,0 +1,71 @@
+package transform
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/hcl/v2"
+)
+
+// Hop is one step of a migration across several provider versions, such as v5 to v6 in a
+// migration from v4 to v6
+type Hop struct {
+	SourceVersion string
+	TargetVersion string
+	// Resources are the resource types to migrate, named as in the source version of the
+	// hop. Empty migrates all resources.
+	Resources []string
+}
+
+// String returns the version path of the hop, e.g. v5-v6
+func (h Hop) String() string {
+	return fmt.Sprintf("%s-%s", h.SourceVersion, h.TargetVersion)
+}
+
+// NextHop returns the context of the hop that follows ctx in ctx.Hops, to run on the
+// content produced by ctx, or nil if ctx is the last hop. The settings of ctx are kept,
+// while diagnostics, metadata and the parsed file start over.
+func (ctx *Context) NextHop(content []byte) *Context {
+	for i, hop := range ctx.Hops {
+		if hop.SourceVersion != ctx.SourceVersion || hop.TargetVersion != ctx.TargetVersion {
+			continue
+		}
+		if i+1 == len(ctx.Hops) {
+			return nil
+		}
+		next := *ctx
+		next.Content = content
+		next.CFGFile = nil
+		next.Diagnostics = make(hcl.Diagnostics, 0)
+		next.Metadata = make(map[string]interface{})
+		next.SourceVersion = ctx.Hops[i+1].SourceVersion
+		next.TargetVersion = ctx.Hops[i+1].TargetVersion
+		next.Resources = ctx.Hops[i+1].Resources
+		next.Next = nil
+		return &next
+	}
+	return nil
+}
+
+// Chain returns ctx followed by the contexts of the hops that ran after it
+func (ctx *Context) Chain() []*Context {
+	var chain []*Context
+	for c := ctx; c != nil; c = c.Next {
+		chain = append(chain, c)
+	}
+	return chain
+}
+
+// LastHop reports whether ctx is the last hop of a migration across several provider
+// versions, or a migration with a single hop
+func (ctx *Context) LastHop() bool {
+	if len(ctx.Hops) == 0 {
+		return true
+	}
+	last := ctx.Hops[len(ctx.Hops)-1]
+	return last.SourceVersion == ctx.SourceVersion && last.TargetVersion == ctx.TargetVersion
+}
+
+// MultiHop reports whether ctx is part of a migration across several provider versions
+func (ctx *Context) MultiHop() bool {
+	return len(ctx.Hops) > 1
+}
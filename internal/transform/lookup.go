@@ -0,0 +1,209 @@
+package transform
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/tidwall/gjson"
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/cloudflare/tf-migrate/internal/address"
+)
+
+// StateInstance is a single resource instance found in the state
+type StateInstance struct {
+	Address    address.Address
+	Attributes gjson.Result
+}
+
+// FindStateResource returns the first managed resource in StateJSON with the given type
+// and name that belongs to one of the modules of the configuration file being transformed.
+// Module instance keys are ignored, so every instance of a module called with count or
+// for_each matches.
+func (ctx *Context) FindStateResource(resourceType, name string) (gjson.Result, bool) {
+	if ctx.StateJSON == "" {
+		return gjson.Result{}, false
+	}
+
+	var found gjson.Result
+	gjson.Get(ctx.StateJSON, "resources").ForEach(func(_, resource gjson.Result) bool {
+		addr := address.FromState(resource, gjson.Result{})
+		if addr.Mode != address.ManagedMode || addr.Type != resourceType || addr.Name != name || !ctx.inModules(addr.ModulePath()) {
+			return true
+		}
+		found = resource
+		return false
+	})
+	return found, found.Exists()
+}
+
+// FindStateInstances returns every instance of the managed resources in StateJSON with
+// the given type and name, in the modules of the configuration file being transformed.
+// A resource in a module called with count or for_each has instances in each module instance.
+func (ctx *Context) FindStateInstances(resourceType, name string) []StateInstance {
+	if ctx.StateJSON == "" {
+		return nil
+	}
+
+	var instances []StateInstance
+	gjson.Get(ctx.StateJSON, "resources").ForEach(func(_, resource gjson.Result) bool {
+		addr := address.FromState(resource, gjson.Result{})
+		if addr.Mode != address.ManagedMode || addr.Type != resourceType || addr.Name != name || !ctx.inModules(addr.ModulePath()) {
+			return true
+		}
+		resource.Get("instances").ForEach(func(_, instance gjson.Result) bool {
+			instances = append(instances, StateInstance{
+				Address:    address.FromState(resource, instance.Get("index_key")),
+				Attributes: instance.Get("attributes"),
+			})
+			return true
+		})
+		return true
+	})
+	return instances
+}
+
+// inModules reports whether a module path is one of the context's modules
+func (ctx *Context) inModules(modulePath string) bool {
+	if ctx.Modules == nil {
+		return true
+	}
+	for _, m := range ctx.Modules {
+		if m == modulePath {
+			return true
+		}
+	}
+	return false
+}
+
+// InstanceValueTokens builds the configuration expression for an attribute whose value is
+// read from the state instances of a resource. value returns the attribute value of an
+// instance, or false if the instance has none.
+//
+// When every instance agrees the value is written as a literal. When they disagree, the
+// value is looked up by instance key:
+//
+//	status = { "eu" = "active", "us" = "disabled" }[each.key]   # for_each
+//	status = ["active", "disabled"][count.index]                 # count
+//
+// Instances that cannot be expressed this way, for example when some instances have no
+// value or instances of different module instances disagree, produce a warning diagnostic
+// naming the conflicting instances, and no tokens.
+func InstanceValueTokens(attribute string, instances []StateInstance, value func(gjson.Result) (cty.Value, bool)) (hclwrite.Tokens, *hcl.Diagnostic) {
+	type keyedValue struct {
+		addr  address.Address
+		value cty.Value
+	}
+
+	var values []keyedValue
+	var missing []string
+	for _, instance := range instances {
+		v, ok := value(instance.Attributes)
+		if !ok {
+			missing = append(missing, instance.Address.String())
+			continue
+		}
+		values = append(values, keyedValue{addr: instance.Address, value: v})
+	}
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	agree := true
+	for _, v := range values[1:] {
+		if !v.value.RawEquals(values[0].value) {
+			agree = false
+			break
+		}
+	}
+	if agree && len(missing) == 0 {
+		return hclwrite.TokensForValue(values[0].value), nil
+	}
+
+	describe := func() string {
+		lines := make([]string, 0, len(values)+len(missing))
+		for _, v := range values {
+			lines = append(lines, fmt.Sprintf("%s = %s", v.addr, strings.TrimSpace(string(hclwrite.TokensForValue(v.value).Bytes()))))
+		}
+		for _, m := range missing {
+			lines = append(lines, fmt.Sprintf("%s has no value", m))
+		}
+		return strings.Join(lines, ", ")
+	}
+	conflict := func(reason string) *hcl.Diagnostic {
+		return &hcl.Diagnostic{
+			Severity: hcl.DiagWarning,
+			Summary:  fmt.Sprintf("Conflicting %s values across instances", attribute),
+			Detail:   fmt.Sprintf("%s: %s. Set %s in the configuration by hand.", reason, describe(), attribute),
+			Extra:    FollowUpManual,
+		}
+	}
+
+	if len(missing) > 0 {
+		return nil, conflict("Some instances have no value")
+	}
+
+	// Each resource instance key must map to a single value, whatever the module instance
+	byKey := make(map[string]cty.Value)
+	keys := make(map[string]interface{})
+	for _, v := range values {
+		k := address.FormatKey(v.addr.Key)
+		if existing, ok := byKey[k]; ok && !existing.RawEquals(v.value) {
+			return nil, conflict("Instances of different module instances disagree")
+		}
+		byKey[k] = v.value
+		keys[k] = v.addr.Key
+	}
+
+	var stringKeys []string
+	var intKeys []int
+	for _, key := range keys {
+		switch k := key.(type) {
+		case string:
+			stringKeys = append(stringKeys, k)
+		case int:
+			intKeys = append(intKeys, k)
+		}
+	}
+
+	switch {
+	case len(stringKeys) == len(keys):
+		sort.Strings(stringKeys)
+		items := make([]hclwrite.ObjectAttrTokens, 0, len(stringKeys))
+		for _, k := range stringKeys {
+			items = append(items, hclwrite.ObjectAttrTokens{
+				Name:  hclwrite.TokensForValue(cty.StringVal(k)),
+				Value: hclwrite.TokensForValue(byKey[address.FormatKey(k)]),
+			})
+		}
+		return indexTokens(hclwrite.TokensForObject(items), "each", "key"), nil
+
+	case len(intKeys) == len(keys):
+		sort.Ints(intKeys)
+		items := make([]hclwrite.Tokens, 0, len(intKeys))
+		for i, k := range intKeys {
+			if k != i {
+				return nil, conflict("Instance indexes are not contiguous")
+			}
+			items = append(items, hclwrite.TokensForValue(byKey[address.FormatKey(k)]))
+		}
+		return indexTokens(hclwrite.TokensForTuple(items), "count", "index"), nil
+	}
+
+	return nil, conflict("Instances have no count or for_each key")
+}
+
+// indexTokens appends an index such as [each.key] to a collection expression
+func indexTokens(collection hclwrite.Tokens, root, attr string) hclwrite.Tokens {
+	tokens := append(hclwrite.Tokens{}, collection...)
+	tokens = append(tokens, &hclwrite.Token{Type: hclsyntax.TokenOBrack, Bytes: []byte("[")})
+	tokens = append(tokens, hclwrite.TokensForTraversal(hcl.Traversal{
+		hcl.TraverseRoot{Name: root},
+		hcl.TraverseAttr{Name: attr},
+	})...)
+	return append(tokens, &hclwrite.Token{Type: hclsyntax.TokenCBrack, Bytes: []byte("]")})
+}
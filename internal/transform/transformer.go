@@ -5,6 +5,7 @@ import (
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hclwrite"
 	"github.com/tidwall/gjson"
+	"github.com/zclconf/go-cty/cty"
 )
 
 // Context carries data through the transformation pipeline
@@ -20,6 +21,26 @@ type Context struct {
 	SourceVersion string             // Source provider version (e.g., "v4")
 	TargetVersion string             // Target provider version (e.g., "v5")
 	APIClient     *cloudflare.Client // Optional: Cloudflare API client for migrations that need to query the API
+	// Optional: terraform_version to stamp on the migrated state, empty keeps the existing value
+	TerraformVersion string
+	// Module paths of the configuration file's directory, e.g. module.dns, or "" for the root module.
+	// Nil when unknown, in which case state lookups match resources in any module.
+	Modules []string
+	// Options set for migrators in the project configuration file, keyed by the source
+	// resource type and the option name
+	Options map[string]map[string]cty.Value
+	// Hops of a migration across several provider versions, such as v4 to v5 and v5 to v6.
+	// The pipeline runs once per hop, SourceVersion and TargetVersion being those of the
+	// current hop. Empty for a single hop.
+	Hops []Hop
+	// Next is the context of the following hop, which ran on the output of this one
+	Next *Context
+}
+
+// Option returns the value of a migrator option from the project configuration file
+func (ctx *Context) Option(resourceType, name string) (cty.Value, bool) {
+	value, ok := ctx.Options[resourceType][name]
+	return value, ok
 }
 
 // TransformResult represents the result of a resource transformation
@@ -52,6 +73,63 @@ type ResourceRenamer interface {
 	GetResourceRename() (oldType string, newType string)
 }
 
+// AttributeRenamer is an optional interface that migrators can implement
+// to expose attribute renames. This enables global updates of references
+// such as cloudflare_record.www.value in other resources and outputs.
+type AttributeRenamer interface {
+	// GetAttributeRenames returns old attribute paths mapped to new attribute paths
+	GetAttributeRenames() map[string]string
+}
+
+// AttributeRemover is an optional interface that migrators can implement
+// to expose removed attributes, so references to them are reported
+type AttributeRemover interface {
+	// GetRemovedAttributes returns attribute paths that no longer exist in the target version
+	GetRemovedAttributes() []string
+}
+
+// AttributeRenameFilter is an optional interface that AttributeRenamers can implement when
+// their renames do not apply to every resource of their type. References to the renamed
+// attributes of other resources are reported like references to removed attributes.
+type AttributeRenameFilter interface {
+	// RenamesAttributesOf reports whether the attribute renames apply to a resource, given
+	// its migrated configuration block
+	RenamesAttributesOf(block *hclwrite.Block) bool
+}
+
+// ContextPreprocessor is an optional interface that migrators can implement when their
+// preprocessing reports diagnostics. The preprocess handler calls PreprocessContext
+// instead of Preprocess.
+type ContextPreprocessor interface {
+	// PreprocessContext does what Preprocess does, and adds its diagnostics to ctx
+	PreprocessContext(ctx *Context, content string) string
+}
+
+// ConfigurableMigrator is an optional interface that migrators can implement to accept
+// options from the project configuration file. Options are read with Context.Option.
+type ConfigurableMigrator interface {
+	// GetOptions returns the type of each option the migrator accepts
+	GetOptions() map[string]cty.Type
+}
+
+// DataSourceTransformer defines the interface for data source specific transformations.
+// Data sources are read again by Terraform after the migration, so only their
+// configuration is transformed; their state entries are dropped.
+type DataSourceTransformer interface {
+	CanHandle(dataSourceType string) bool
+	// TransformConfig transforms a data block, with the same semantics as ResourceTransformer.TransformConfig
+	TransformConfig(ctx *Context, block *hclwrite.Block) (*TransformResult, error)
+	// GetDataSourceType returns the data source type in the target version
+	GetDataSourceType() string
+}
+
+// DataSourceProvider is an optional interface that a MigrationProvider can implement
+// to provide migrators for data sources
+type DataSourceProvider interface {
+	GetDataSourceMigrator(dataSourceType string, sourceVersion string, targetVersion string) DataSourceTransformer
+	GetAllDataSourceMigrators(sourceVersion string, targetVersion string) []DataSourceTransformer
+}
+
 // MigrationProvider specifies the interface for a migrator provider
 // This is used to provide a way to get migrators for a given resource type
 // a migrator defines the strategy which a resource uses to migrate the resource
@@ -62,8 +140,10 @@ type MigrationProvider interface {
 }
 
 type DefaultMigratorProvider struct {
-	getFunc    func(string, string, string) ResourceTransformer
-	getAllFunc func(string, string, ...string) []ResourceTransformer
+	getFunc              func(string, string, string) ResourceTransformer
+	getAllFunc           func(string, string, ...string) []ResourceTransformer
+	getDataSourceFunc    func(string, string, string) DataSourceTransformer
+	getAllDataSourceFunc func(string, string) []DataSourceTransformer
 }
 
 func NewMigrationProvider(
@@ -76,6 +156,21 @@ func NewMigrationProvider(
 	}
 }
 
+// NewMigrationProviderWithDataSources creates a provider for both resource and data source migrators
+func NewMigrationProviderWithDataSources(
+	getFunc func(string, string, string) ResourceTransformer,
+	getAllFunc func(string, string, ...string) []ResourceTransformer,
+	getDataSourceFunc func(string, string, string) DataSourceTransformer,
+	getAllDataSourceFunc func(string, string) []DataSourceTransformer,
+) MigrationProvider {
+	return &DefaultMigratorProvider{
+		getFunc:              getFunc,
+		getAllFunc:           getAllFunc,
+		getDataSourceFunc:    getDataSourceFunc,
+		getAllDataSourceFunc: getAllDataSourceFunc,
+	}
+}
+
 func (p *DefaultMigratorProvider) GetMigrator(resourceType string, sourceVersion string, targetVersion string) ResourceTransformer {
 	if p.getFunc != nil {
 		return p.getFunc(resourceType, sourceVersion, targetVersion)
@@ -89,3 +184,17 @@ func (p *DefaultMigratorProvider) GetAllMigrators(sourceVersion string, targetVe
 	}
 	return []ResourceTransformer{}
 }
+
+func (p *DefaultMigratorProvider) GetDataSourceMigrator(dataSourceType string, sourceVersion string, targetVersion string) DataSourceTransformer {
+	if p.getDataSourceFunc != nil {
+		return p.getDataSourceFunc(dataSourceType, sourceVersion, targetVersion)
+	}
+	return nil
+}
+
+func (p *DefaultMigratorProvider) GetAllDataSourceMigrators(sourceVersion string, targetVersion string) []DataSourceTransformer {
+	if p.getAllDataSourceFunc != nil {
+		return p.getAllDataSourceFunc(sourceVersion, targetVersion)
+	}
+	return []DataSourceTransformer{}
+}
@@ -0,0 +1,445 @@
+// Package verify checks a Terraform plan made after a migration. Every resource change in
+// the plan is classified and mapped back to the migrator of its resource type, with the
+// attributes whose state value the migrated configuration does not keep.
+package verify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/cloudflare/tf-migrate/internal/address"
+	"github.com/cloudflare/tf-migrate/internal/transform"
+)
+
+// Class is the kind of a resource change
+type Class string
+
+const (
+	// ClassUpdate is an in-place update, usually a value the migrated configuration
+	// normalises differently from the provider
+	ClassUpdate Class = "update"
+	// ClassReplace is a resource that would be destroyed and created again
+	ClassReplace Class = "replace"
+	// ClassCreate is a resource in the configuration that is not in the state
+	ClassCreate Class = "create"
+	// ClassDestroy is a resource in the state that is not in the configuration
+	ClassDestroy Class = "destroy"
+)
+
+// Result is the outcome of checking a plan
+type Result struct {
+	SourceVersion string   `json:"source_version"`
+	TargetVersion string   `json:"target_version"`
+	Changes       []Change `json:"changes"`
+	Summary       Summary  `json:"summary"`
+}
+
+// Change is a resource that the plan would change
+type Change struct {
+	Address string `json:"address"`
+	// PreviousAddress is the address the resource is moved from, if any
+	PreviousAddress string   `json:"previous_address,omitempty"`
+	Type            string   `json:"type"`
+	Class           Class    `json:"class"`
+	Actions         []string `json:"actions"`
+	// Migrator is the migrator that produced the resource, if one is registered
+	Migrator   string            `json:"migrator,omitempty"`
+	Attributes []AttributeChange `json:"attributes,omitempty"`
+	Hint       string            `json:"hint,omitempty"`
+}
+
+// AttributeChange is an attribute whose planned value differs from the state
+type AttributeChange struct {
+	Path   string `json:"path"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+	// CarryOver reports that the state has a value the migrated configuration drops,
+	// which the migrator should have carried over
+	CarryOver bool `json:"carry_over"`
+	// ForcesReplacement reports that the change of this attribute replaces the resource
+	ForcesReplacement bool `json:"forces_replacement,omitempty"`
+}
+
+// Summary holds the number of resources per class of change
+type Summary struct {
+	Unchanged    int `json:"unchanged"`
+	Updates      int `json:"updates"`
+	Replacements int `json:"replacements"`
+	Creates      int `json:"creates"`
+	Destroys     int `json:"destroys"`
+}
+
+// Changed returns the number of resources the plan would change
+func (s Summary) Changed() int {
+	return s.Updates + s.Replacements + s.Creates + s.Destroys
+}
+
+// Check reads a plan in the format of terraform show -json and classifies its resource
+// changes. Migrators are looked up in providers for the given version path.
+func Check(planJSON []byte, sourceVersion, targetVersion string, providers transform.MigrationProvider) (*Result, error) {
+	if !gjson.ValidBytes(planJSON) {
+		return nil, fmt.Errorf("plan is not valid JSON")
+	}
+	plan := gjson.ParseBytes(planJSON)
+	if !plan.Get("format_version").Exists() || !plan.Get("planned_values").Exists() {
+		return nil, fmt.Errorf("not a JSON plan: create it with 'terraform plan -out=plan.out' and 'terraform show -json plan.out'")
+	}
+
+	result := &Result{SourceVersion: sourceVersion, TargetVersion: targetVersion, Changes: []Change{}}
+	lookup := &migratorLookup{sourceVersion: sourceVersion, targetVersion: targetVersion, providers: providers}
+
+	plan.Get("resource_changes").ForEach(func(_, rc gjson.Result) bool {
+		if rc.Get("mode").String() == string(address.DataMode) {
+			return true
+		}
+		actions := stringArray(rc.Get("change.actions"))
+		class, changed := classify(actions)
+		if !changed {
+			result.Summary.Unchanged++
+			return true
+		}
+
+		change := Change{
+			Address:         rc.Get("address").String(),
+			PreviousAddress: rc.Get("previous_address").String(),
+			Type:            rc.Get("type").String(),
+			Class:           class,
+			Actions:         actions,
+		}
+		previousType := change.Type
+		if previous, err := address.Parse(change.PreviousAddress); err == nil {
+			previousType = previous.Type
+		}
+		if migrator := lookup.find(change.Type, previousType); migrator != nil {
+			change.Migrator = fmt.Sprintf("%T", migrator)
+		}
+
+		switch class {
+		case ClassUpdate:
+			result.Summary.Updates++
+			change.Attributes = diffAttributes(rc.Get("change"))
+			change.Hint = "The migrated configuration differs from the state. Values marked as not carried over should be copied from the state by the migrator."
+		case ClassReplace:
+			result.Summary.Replacements++
+			change.Attributes = diffAttributes(rc.Get("change"))
+			change.Hint = "The resource would be destroyed and created again."
+			if forcing := forcingAttributes(change.Attributes); len(forcing) > 0 {
+				change.Hint = fmt.Sprintf("The resource would be destroyed and created again because %s changed.", strings.Join(forcing, ", "))
+			}
+		case ClassCreate:
+			result.Summary.Creates++
+			change.Hint = "The resource is not in the state. The state may not have been migrated, or the migrator created a block without a state counterpart."
+		case ClassDestroy:
+			result.Summary.Destroys++
+			change.Hint = "The resource is in the state but not in the migrated configuration."
+		}
+		result.Changes = append(result.Changes, change)
+		return true
+	})
+
+	pairRenames(result.Changes, lookup)
+	return result, nil
+}
+
+// classify returns the class of a change from its planned actions, and false for
+// changes that do nothing
+func classify(actions []string) (Class, bool) {
+	switch strings.Join(actions, ",") {
+	case "", "no-op", "read":
+		return "", false
+	case "update":
+		return ClassUpdate, true
+	case "delete,create", "create,delete":
+		return ClassReplace, true
+	case "create":
+		return ClassCreate, true
+	}
+	// delete, and forget for resources removed from the state
+	return ClassDestroy, true
+}
+
+// pairRenames explains the create and destroy of the same resource under its old and new
+// type: the state still has the old type, so it was not migrated
+func pairRenames(changes []Change, lookup *migratorLookup) {
+	destroys := make(map[string]int)
+	for i, change := range changes {
+		if change.Class != ClassDestroy {
+			continue
+		}
+		if addr, err := address.Parse(change.Address); err == nil {
+			destroys[addr.WithType("").String()] = i
+		}
+	}
+
+	for i, change := range changes {
+		if change.Class != ClassCreate {
+			continue
+		}
+		addr, err := address.Parse(change.Address)
+		if err != nil {
+			continue
+		}
+		j, ok := destroys[addr.WithType("").String()]
+		if !ok {
+			continue
+		}
+		oldType := changes[j].Type
+		if migrator := lookup.find(change.Type, oldType); migrator == nil || migrator.GetResourceType() != change.Type {
+			continue
+		}
+		hint := fmt.Sprintf("%s is renamed to %s, but the state still has %s. Migrate the state, or use --rename-strategy moved.",
+			oldType, change.Type, changes[j].Address)
+		changes[i].Hint = hint
+		changes[j].Hint = hint
+		changes[j].Migrator = changes[i].Migrator
+	}
+}
+
+// migratorLookup finds the migrator that produced a resource of the target version
+type migratorLookup struct {
+	sourceVersion string
+	targetVersion string
+	providers     transform.MigrationProvider
+}
+
+// find returns the migrator of a resource type, or of the type it is moved from. The
+// types of the plan are target version types, so renamed types are found by the type
+// their migrator produces.
+func (l *migratorLookup) find(resourceType, previousType string) transform.ResourceTransformer {
+	if previousType != resourceType {
+		if migrator := l.providers.GetMigrator(previousType, l.sourceVersion, l.targetVersion); migrator != nil {
+			return migrator
+		}
+	}
+	for _, migrator := range l.providers.GetAllMigrators(l.sourceVersion, l.targetVersion) {
+		if migrator.GetResourceType() == resourceType {
+			return migrator
+		}
+	}
+	return l.providers.GetMigrator(resourceType, l.sourceVersion, l.targetVersion)
+}
+
+// diffAttributes compares the values of a change before and after it, and returns the
+// attributes that differ. Values only known after apply are computed by the provider and
+// are left out.
+func diffAttributes(change gjson.Result) []AttributeChange {
+	replacePaths := make(map[string]bool)
+	change.Get("replace_paths").ForEach(func(_, path gjson.Result) bool {
+		replacePaths[formatPath(path.Array())] = true
+		return true
+	})
+
+	var attributes []AttributeChange
+	var walk func(path string, before, after, unknown, beforeSensitive, afterSensitive gjson.Result)
+	walk = func(path string, before, after, unknown, beforeSensitive, afterSensitive gjson.Result) {
+		if unknown.Type == gjson.True {
+			return
+		}
+		if before.IsObject() && after.IsObject() {
+			beforeFields, afterFields := fields(before), fields(after)
+			for _, key := range unionKeys(beforeFields, afterFields) {
+				walk(joinPath(path, key), beforeFields[key], afterFields[key], field(unknown, key), field(beforeSensitive, key), field(afterSensitive, key))
+			}
+			return
+		}
+		if before.IsArray() && after.IsArray() && len(before.Array()) == len(after.Array()) {
+			for i := range before.Array() {
+				index := strconv.Itoa(i)
+				walk(path+"["+index+"]", before.Get(index), after.Get(index), unknown.Get(index), beforeSensitive.Get(index), afterSensitive.Get(index))
+			}
+			return
+		}
+		if equal(before, after) {
+			return
+		}
+
+		attribute := AttributeChange{
+			Path:      path,
+			Before:    render(before, beforeSensitive),
+			After:     render(after, afterSensitive),
+			CarryOver: !isNull(before) && isNull(after),
+		}
+		for replacePath := range replacePaths {
+			if path == replacePath || strings.HasPrefix(path, replacePath+".") || strings.HasPrefix(path, replacePath+"[") {
+				attribute.ForcesReplacement = true
+			}
+		}
+		attributes = append(attributes, attribute)
+	}
+	walk("", change.Get("before"), change.Get("after"), change.Get("after_unknown"), change.Get("before_sensitive"), change.Get("after_sensitive"))
+	return attributes
+}
+
+// forcingAttributes returns the paths of the attributes that force a replacement
+func forcingAttributes(attributes []AttributeChange) []string {
+	var paths []string
+	for _, attribute := range attributes {
+		if attribute.ForcesReplacement {
+			paths = append(paths, attribute.Path)
+		}
+	}
+	return paths
+}
+
+func fields(object gjson.Result) map[string]gjson.Result {
+	result := make(map[string]gjson.Result)
+	object.ForEach(func(key, value gjson.Result) bool {
+		result[key.String()] = value
+		return true
+	})
+	return result
+}
+
+// field returns a field of a mask such as after_unknown, which may be a single boolean
+// for the whole object
+func field(mask gjson.Result, key string) gjson.Result {
+	if !mask.IsObject() {
+		return mask
+	}
+	return fields(mask)[key]
+}
+
+func unionKeys(a, b map[string]gjson.Result) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for key := range a {
+		keys = append(keys, key)
+	}
+	for key := range b {
+		if _, ok := a[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// formatPath formats a replace path such as ["data", 0, "value"] as data[0].value
+func formatPath(steps []gjson.Result) string {
+	path := ""
+	for _, step := range steps {
+		if step.Type == gjson.Number {
+			path += "[" + step.Raw + "]"
+			continue
+		}
+		path = joinPath(path, step.String())
+	}
+	return path
+}
+
+func isNull(value gjson.Result) bool {
+	return !value.Exists() || value.Type == gjson.Null
+}
+
+// equal reports whether two values are the same, comparing numbers by value
+func equal(a, b gjson.Result) bool {
+	if isNull(a) || isNull(b) {
+		return isNull(a) && isNull(b)
+	}
+	if a.Type != b.Type {
+		return false
+	}
+	switch a.Type {
+	case gjson.Number:
+		return a.Num == b.Num
+	case gjson.String:
+		return a.Str == b.Str
+	case gjson.True, gjson.False:
+		return true
+	}
+	// Objects and arrays of different shapes
+	return a.Raw == b.Raw
+}
+
+// render formats a value for display, hiding sensitive values
+func render(value, sensitive gjson.Result) string {
+	if isNull(value) {
+		return "null"
+	}
+	if sensitive.Type == gjson.True {
+		return "(sensitive value)"
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, []byte(value.Raw)); err != nil {
+		return value.Raw
+	}
+	return compact.String()
+}
+
+func stringArray(value gjson.Result) []string {
+	values := []string{}
+	for _, item := range value.Array() {
+		values = append(values, item.String())
+	}
+	return values
+}
+
+// WriteJSON writes the result as indented JSON
+func (r *Result) WriteJSON(w io.Writer) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode verification result: %w", err)
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// WriteTable writes the result as human-readable text, with the attributes and an
+// explanation under each change
+func (r *Result) WriteTable(w io.Writer) error {
+	for _, change := range r.Changes {
+		migrator := change.Migrator
+		if migrator == "" {
+			migrator = fmt.Sprintf("no migrator (%s → %s)", r.SourceVersion, r.TargetVersion)
+		}
+		fmt.Fprintf(w, "%-7s  %s  %s\n", change.Class, change.Address, migrator)
+		for _, attribute := range change.Attributes {
+			var notes []string
+			if attribute.CarryOver {
+				notes = append(notes, "not carried over")
+			}
+			if attribute.ForcesReplacement {
+				notes = append(notes, "forces replacement")
+			}
+			note := ""
+			if len(notes) > 0 {
+				note = "  ← " + strings.Join(notes, ", ")
+			}
+			fmt.Fprintf(w, "           %s: %s → %s%s\n", attribute.Path, truncate(attribute.Before), truncate(attribute.After), note)
+		}
+		if change.Hint != "" {
+			fmt.Fprintf(w, "           %s\n", change.Hint)
+		}
+	}
+
+	s := r.Summary
+	if s.Changed() == 0 {
+		_, err := fmt.Fprintf(w, "No changes: the plan is a no-op for all %d resources\n", s.Unchanged)
+		return err
+	}
+	_, err := fmt.Fprintf(w, "\n%d resources would change: %d updates, %d replacements, %d creates, %d destroys (%d unchanged)\n",
+		s.Changed(), s.Updates, s.Replacements, s.Creates, s.Destroys, s.Unchanged)
+	return err
+}
+
+// truncate shortens long values for display
+func truncate(value string) string {
+	const max = 60
+	if len(value) <= max {
+		return value
+	}
+	return value[:max-3] + "..."
+}
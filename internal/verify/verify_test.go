@@ -0,0 +1,230 @@
+package verify
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/tf-migrate/internal/testhelpers"
+	"github.com/cloudflare/tf-migrate/internal/transform"
+)
+
+type recordMigrator struct {
+	transform.ResourceTransformer
+}
+
+func (m *recordMigrator) GetResourceType() string { return "cloudflare_dns_record" }
+
+type zoneMigrator struct {
+	transform.ResourceTransformer
+}
+
+func (m *zoneMigrator) GetResourceType() string { return "cloudflare_zone" }
+
+func newProviders() transform.MigrationProvider {
+	return testhelpers.NewMigrationProvider(map[string]transform.ResourceTransformer{
+		"cloudflare_record": &recordMigrator{},
+		"cloudflare_zone":   &zoneMigrator{},
+	}, nil)
+}
+
+const noOpPlan = `{
+  "format_version": "1.2",
+  "planned_values": {"root_module": {}},
+  "resource_changes": [
+    {
+      "address": "cloudflare_zone.example",
+      "mode": "managed",
+      "type": "cloudflare_zone",
+      "name": "example",
+      "change": {"actions": ["no-op"], "before": {"name": "example.com"}, "after": {"name": "example.com"}}
+    },
+    {
+      "address": "data.cloudflare_zones.all",
+      "mode": "data",
+      "type": "cloudflare_zones",
+      "name": "all",
+      "change": {"actions": ["read"], "before": null, "after": {}}
+    }
+  ]
+}`
+
+const changedPlan = `{
+  "format_version": "1.2",
+  "planned_values": {"root_module": {}},
+  "resource_changes": [
+    {
+      "address": "cloudflare_dns_record.www",
+      "previous_address": "cloudflare_record.www",
+      "mode": "managed",
+      "type": "cloudflare_dns_record",
+      "name": "www",
+      "change": {
+        "actions": ["update"],
+        "before": {"id": "abc", "name": "www", "ttl": 1, "comment": "kept by hand", "content": "192.0.2.1", "settings": {"ipv4_only": true}, "tags": ["a", "b"]},
+        "after": {"id": "abc", "name": "www", "ttl": 1.0, "comment": null, "content": "192.0.2.1", "settings": {"ipv4_only": false}, "tags": ["a", "c"], "modified_on": null},
+        "after_unknown": {"modified_on": true},
+        "before_sensitive": {},
+        "after_sensitive": {}
+      }
+    },
+    {
+      "address": "module.dns.cloudflare_zone.example",
+      "module_address": "module.dns",
+      "mode": "managed",
+      "type": "cloudflare_zone",
+      "name": "example",
+      "change": {
+        "actions": ["delete", "create"],
+        "before": {"id": "z1", "name": "example.com", "account": {"id": "old"}, "secret": "hunter2"},
+        "after": {"id": null, "name": "example.com", "account": {"id": "new"}, "secret": "hunter3"},
+        "after_unknown": {"id": true},
+        "before_sensitive": {"secret": true},
+        "after_sensitive": {"secret": true},
+        "replace_paths": [["account", "id"]]
+      },
+      "action_reason": "replace_because_cannot_update"
+    },
+    {
+      "address": "cloudflare_dns_record.api[\"a\"]",
+      "mode": "managed",
+      "type": "cloudflare_dns_record",
+      "name": "api",
+      "index": "a",
+      "change": {"actions": ["create"], "before": null, "after": {"name": "api"}}
+    },
+    {
+      "address": "cloudflare_record.api[\"a\"]",
+      "mode": "managed",
+      "type": "cloudflare_record",
+      "name": "api",
+      "index": "a",
+      "change": {"actions": ["delete"], "before": {"name": "api"}, "after": null}
+    },
+    {
+      "address": "cloudflare_ruleset.waf",
+      "mode": "managed",
+      "type": "cloudflare_ruleset",
+      "name": "waf",
+      "change": {"actions": ["create"], "before": null, "after": {"name": "waf"}}
+    }
+  ]
+}`
+
+func TestCheckNoOp(t *testing.T) {
+	result, err := Check([]byte(noOpPlan), "v4", "v5", newProviders())
+	require.NoError(t, err)
+
+	assert.Empty(t, result.Changes)
+	assert.Equal(t, Summary{Unchanged: 1}, result.Summary)
+
+	var out bytes.Buffer
+	require.NoError(t, result.WriteTable(&out))
+	assert.Equal(t, "No changes: the plan is a no-op for all 1 resources\n", out.String())
+}
+
+func TestCheck(t *testing.T) {
+	result, err := Check([]byte(changedPlan), "v4", "v5", newProviders())
+	require.NoError(t, err)
+
+	assert.Equal(t, Summary{Updates: 1, Replacements: 1, Creates: 2, Destroys: 1}, result.Summary)
+	assert.Equal(t, 5, result.Summary.Changed())
+	require.Len(t, result.Changes, 5)
+
+	update := result.Changes[0]
+	assert.Equal(t, ClassUpdate, update.Class)
+	assert.Equal(t, "cloudflare_record.www", update.PreviousAddress)
+	assert.Equal(t, "*verify.recordMigrator", update.Migrator)
+	assert.Equal(t, []AttributeChange{
+		{Path: "comment", Before: `"kept by hand"`, After: "null", CarryOver: true},
+		{Path: "settings.ipv4_only", Before: "true", After: "false"},
+		{Path: "tags[1]", Before: `"b"`, After: `"c"`},
+	}, update.Attributes, "numbers equal by value and unknown values are not reported")
+
+	replace := result.Changes[1]
+	assert.Equal(t, ClassReplace, replace.Class)
+	assert.Equal(t, []string{"delete", "create"}, replace.Actions)
+	assert.Equal(t, "*verify.zoneMigrator", replace.Migrator)
+	assert.Equal(t, []AttributeChange{
+		{Path: "account.id", Before: `"old"`, After: `"new"`, ForcesReplacement: true},
+		{Path: "secret", Before: "(sensitive value)", After: "(sensitive value)"},
+	}, replace.Attributes)
+	assert.Contains(t, replace.Hint, "because account.id changed")
+
+	created, destroyed := result.Changes[2], result.Changes[3]
+	assert.Equal(t, ClassCreate, created.Class)
+	assert.Equal(t, ClassDestroy, destroyed.Class)
+	assert.Equal(t, "*verify.recordMigrator", created.Migrator)
+	assert.Equal(t, "*verify.recordMigrator", destroyed.Migrator)
+	assert.Contains(t, created.Hint, `the state still has cloudflare_record.api["a"]`)
+	assert.Equal(t, created.Hint, destroyed.Hint)
+	assert.Empty(t, created.Attributes)
+
+	unknown := result.Changes[4]
+	assert.Equal(t, ClassCreate, unknown.Class)
+	assert.Empty(t, unknown.Migrator)
+	assert.Contains(t, unknown.Hint, "not in the state")
+}
+
+func TestCheckInvalidPlan(t *testing.T) {
+	tests := []struct {
+		name string
+		plan string
+		want string
+	}{
+		{name: "Not JSON", plan: `resource "x" {}`, want: "not valid JSON"},
+		{name: "State file", plan: `{"version": 4, "resources": []}`, want: "not a JSON plan"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Check([]byte(tt.plan), "v4", "v5", newProviders())
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		actions []string
+		class   Class
+		changed bool
+	}{
+		{actions: []string{"no-op"}},
+		{actions: []string{"read"}},
+		{actions: []string{"update"}, class: ClassUpdate, changed: true},
+		{actions: []string{"delete", "create"}, class: ClassReplace, changed: true},
+		{actions: []string{"create", "delete"}, class: ClassReplace, changed: true},
+		{actions: []string{"create"}, class: ClassCreate, changed: true},
+		{actions: []string{"delete"}, class: ClassDestroy, changed: true},
+		{actions: []string{"forget"}, class: ClassDestroy, changed: true},
+	}
+	for _, tt := range tests {
+		class, changed := classify(tt.actions)
+		assert.Equal(t, tt.class, class, tt.actions)
+		assert.Equal(t, tt.changed, changed, tt.actions)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	result, err := Check([]byte(changedPlan), "v4", "v5", newProviders())
+	require.NoError(t, err)
+
+	var table bytes.Buffer
+	require.NoError(t, result.WriteTable(&table))
+	assert.Contains(t, table.String(), "update   cloudflare_dns_record.www")
+	assert.Contains(t, table.String(), `comment: "kept by hand" → null  ← not carried over`)
+	assert.Contains(t, table.String(), `account.id: "old" → "new"  ← forces replacement`)
+	assert.Contains(t, table.String(), "no migrator (v4 → v5)")
+	assert.Contains(t, table.String(), "5 resources would change: 1 updates, 1 replacements, 2 creates, 1 destroys (0 unchanged)")
+	assert.NotContains(t, table.String(), "hunter2")
+
+	var out bytes.Buffer
+	require.NoError(t, result.WriteJSON(&out))
+	var decoded Result
+	require.NoError(t, json.Unmarshal(out.Bytes(), &decoded))
+	assert.Equal(t, *result, decoded)
+}